@@ -0,0 +1,1605 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: phonebook/v1/contacts.proto
+
+package phonebookv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	fieldmaskpb "google.golang.org/protobuf/types/known/fieldmaskpb"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ContactEvent_Op int32
+
+const (
+	ContactEvent_OP_UNSPECIFIED ContactEvent_Op = 0
+	ContactEvent_OP_CREATED     ContactEvent_Op = 1
+	ContactEvent_OP_UPDATED     ContactEvent_Op = 2
+	ContactEvent_OP_DELETED     ContactEvent_Op = 3
+)
+
+// Enum value maps for ContactEvent_Op.
+var (
+	ContactEvent_Op_name = map[int32]string{
+		0: "OP_UNSPECIFIED",
+		1: "OP_CREATED",
+		2: "OP_UPDATED",
+		3: "OP_DELETED",
+	}
+	ContactEvent_Op_value = map[string]int32{
+		"OP_UNSPECIFIED": 0,
+		"OP_CREATED":     1,
+		"OP_UPDATED":     2,
+		"OP_DELETED":     3,
+	}
+)
+
+func (x ContactEvent_Op) Enum() *ContactEvent_Op {
+	p := new(ContactEvent_Op)
+	*p = x
+	return p
+}
+
+func (x ContactEvent_Op) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ContactEvent_Op) Descriptor() protoreflect.EnumDescriptor {
+	return file_phonebook_v1_contacts_proto_enumTypes[0].Descriptor()
+}
+
+func (ContactEvent_Op) Type() protoreflect.EnumType {
+	return &file_phonebook_v1_contacts_proto_enumTypes[0]
+}
+
+func (x ContactEvent_Op) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ContactEvent_Op.Descriptor instead.
+func (ContactEvent_Op) EnumDescriptor() ([]byte, []int) {
+	return file_phonebook_v1_contacts_proto_rawDescGZIP(), []int{17, 0}
+}
+
+// На входе phone_e164 не читается — номер нормализует сервер.
+type Phone struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Label     string                 `protobuf:"bytes,1,opt,name=label,proto3" json:"label,omitempty"`
+	PhoneRaw  string                 `protobuf:"bytes,2,opt,name=phone_raw,json=phoneRaw,proto3" json:"phone_raw,omitempty"`
+	PhoneE164 string                 `protobuf:"bytes,3,opt,name=phone_e164,json=phoneE164,proto3" json:"phone_e164,omitempty"`
+	IsPrimary bool                   `protobuf:"varint,4,opt,name=is_primary,json=isPrimary,proto3" json:"is_primary,omitempty"`
+	// visibility — public, internal или private; пустая при замене телефонов сохраняет видимость
+	// того же номера у контакта, у нового номера — public
+	Visibility    string `protobuf:"bytes,5,opt,name=visibility,proto3" json:"visibility,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Phone) Reset() {
+	*x = Phone{}
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Phone) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Phone) ProtoMessage() {}
+
+func (x *Phone) ProtoReflect() protoreflect.Message {
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Phone.ProtoReflect.Descriptor instead.
+func (*Phone) Descriptor() ([]byte, []int) {
+	return file_phonebook_v1_contacts_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Phone) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+func (x *Phone) GetPhoneRaw() string {
+	if x != nil {
+		return x.PhoneRaw
+	}
+	return ""
+}
+
+func (x *Phone) GetPhoneE164() string {
+	if x != nil {
+		return x.PhoneE164
+	}
+	return ""
+}
+
+func (x *Phone) GetIsPrimary() bool {
+	if x != nil {
+		return x.IsPrimary
+	}
+	return false
+}
+
+func (x *Phone) GetVisibility() string {
+	if x != nil {
+		return x.Visibility
+	}
+	return ""
+}
+
+type Email struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Label         string                 `protobuf:"bytes,1,opt,name=label,proto3" json:"label,omitempty"`
+	Email         string                 `protobuf:"bytes,2,opt,name=email,proto3" json:"email,omitempty"`
+	IsPrimary     bool                   `protobuf:"varint,3,opt,name=is_primary,json=isPrimary,proto3" json:"is_primary,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Email) Reset() {
+	*x = Email{}
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Email) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Email) ProtoMessage() {}
+
+func (x *Email) ProtoReflect() protoreflect.Message {
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Email.ProtoReflect.Descriptor instead.
+func (*Email) Descriptor() ([]byte, []int) {
+	return file_phonebook_v1_contacts_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Email) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+func (x *Email) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *Email) GetIsPrimary() bool {
+	if x != nil {
+		return x.IsPrimary
+	}
+	return false
+}
+
+type Address struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Label         string                 `protobuf:"bytes,1,opt,name=label,proto3" json:"label,omitempty"`
+	Street        string                 `protobuf:"bytes,2,opt,name=street,proto3" json:"street,omitempty"`
+	City          string                 `protobuf:"bytes,3,opt,name=city,proto3" json:"city,omitempty"`
+	Region        string                 `protobuf:"bytes,4,opt,name=region,proto3" json:"region,omitempty"`
+	PostalCode    string                 `protobuf:"bytes,5,opt,name=postal_code,json=postalCode,proto3" json:"postal_code,omitempty"`
+	Country       string                 `protobuf:"bytes,6,opt,name=country,proto3" json:"country,omitempty"`
+	IsPrimary     bool                   `protobuf:"varint,7,opt,name=is_primary,json=isPrimary,proto3" json:"is_primary,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Address) Reset() {
+	*x = Address{}
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Address) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Address) ProtoMessage() {}
+
+func (x *Address) ProtoReflect() protoreflect.Message {
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Address.ProtoReflect.Descriptor instead.
+func (*Address) Descriptor() ([]byte, []int) {
+	return file_phonebook_v1_contacts_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Address) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+func (x *Address) GetStreet() string {
+	if x != nil {
+		return x.Street
+	}
+	return ""
+}
+
+func (x *Address) GetCity() string {
+	if x != nil {
+		return x.City
+	}
+	return ""
+}
+
+func (x *Address) GetRegion() string {
+	if x != nil {
+		return x.Region
+	}
+	return ""
+}
+
+func (x *Address) GetPostalCode() string {
+	if x != nil {
+		return x.PostalCode
+	}
+	return ""
+}
+
+func (x *Address) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+func (x *Address) GetIsPrimary() bool {
+	if x != nil {
+		return x.IsPrimary
+	}
+	return false
+}
+
+type Website struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Label         string                 `protobuf:"bytes,1,opt,name=label,proto3" json:"label,omitempty"`
+	Url           string                 `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`
+	IsPrimary     bool                   `protobuf:"varint,3,opt,name=is_primary,json=isPrimary,proto3" json:"is_primary,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Website) Reset() {
+	*x = Website{}
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Website) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Website) ProtoMessage() {}
+
+func (x *Website) ProtoReflect() protoreflect.Message {
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Website.ProtoReflect.Descriptor instead.
+func (*Website) Descriptor() ([]byte, []int) {
+	return file_phonebook_v1_contacts_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Website) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+func (x *Website) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *Website) GetIsPrimary() bool {
+	if x != nil {
+		return x.IsPrimary
+	}
+	return false
+}
+
+type OrganizationRef struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OrganizationRef) Reset() {
+	*x = OrganizationRef{}
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrganizationRef) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrganizationRef) ProtoMessage() {}
+
+func (x *OrganizationRef) ProtoReflect() protoreflect.Message {
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrganizationRef.ProtoReflect.Descriptor instead.
+func (*OrganizationRef) Descriptor() ([]byte, []int) {
+	return file_phonebook_v1_contacts_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *OrganizationRef) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *OrganizationRef) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type ExternalRef struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Source        string                 `protobuf:"bytes,1,opt,name=source,proto3" json:"source,omitempty"`
+	Id            string                 `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExternalRef) Reset() {
+	*x = ExternalRef{}
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExternalRef) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExternalRef) ProtoMessage() {}
+
+func (x *ExternalRef) ProtoReflect() protoreflect.Message {
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExternalRef.ProtoReflect.Descriptor instead.
+func (*ExternalRef) Descriptor() ([]byte, []int) {
+	return file_phonebook_v1_contacts_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ExternalRef) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *ExternalRef) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type Contact struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	FirstName     string                 `protobuf:"bytes,2,opt,name=first_name,json=firstName,proto3" json:"first_name,omitempty"`
+	LastName      string                 `protobuf:"bytes,3,opt,name=last_name,json=lastName,proto3" json:"last_name,omitempty"`
+	Company       string                 `protobuf:"bytes,4,opt,name=company,proto3" json:"company,omitempty"`
+	Organization  *OrganizationRef       `protobuf:"bytes,5,opt,name=organization,proto3" json:"organization,omitempty"`
+	JobTitle      string                 `protobuf:"bytes,6,opt,name=job_title,json=jobTitle,proto3" json:"job_title,omitempty"`
+	Department    string                 `protobuf:"bytes,7,opt,name=department,proto3" json:"department,omitempty"`
+	Phones        []*Phone               `protobuf:"bytes,8,rep,name=phones,proto3" json:"phones,omitempty"`
+	Emails        []*Email               `protobuf:"bytes,9,rep,name=emails,proto3" json:"emails,omitempty"`
+	Addresses     []*Address             `protobuf:"bytes,10,rep,name=addresses,proto3" json:"addresses,omitempty"`
+	Websites      []*Website             `protobuf:"bytes,11,rep,name=websites,proto3" json:"websites,omitempty"`
+	Custom        *structpb.Struct       `protobuf:"bytes,12,opt,name=custom,proto3" json:"custom,omitempty"`
+	PhotoUrl      string                 `protobuf:"bytes,13,opt,name=photo_url,json=photoUrl,proto3" json:"photo_url,omitempty"`
+	External      *ExternalRef           `protobuf:"bytes,14,opt,name=external,proto3" json:"external,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,15,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,16,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Contact) Reset() {
+	*x = Contact{}
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Contact) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Contact) ProtoMessage() {}
+
+func (x *Contact) ProtoReflect() protoreflect.Message {
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Contact.ProtoReflect.Descriptor instead.
+func (*Contact) Descriptor() ([]byte, []int) {
+	return file_phonebook_v1_contacts_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Contact) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Contact) GetFirstName() string {
+	if x != nil {
+		return x.FirstName
+	}
+	return ""
+}
+
+func (x *Contact) GetLastName() string {
+	if x != nil {
+		return x.LastName
+	}
+	return ""
+}
+
+func (x *Contact) GetCompany() string {
+	if x != nil {
+		return x.Company
+	}
+	return ""
+}
+
+func (x *Contact) GetOrganization() *OrganizationRef {
+	if x != nil {
+		return x.Organization
+	}
+	return nil
+}
+
+func (x *Contact) GetJobTitle() string {
+	if x != nil {
+		return x.JobTitle
+	}
+	return ""
+}
+
+func (x *Contact) GetDepartment() string {
+	if x != nil {
+		return x.Department
+	}
+	return ""
+}
+
+func (x *Contact) GetPhones() []*Phone {
+	if x != nil {
+		return x.Phones
+	}
+	return nil
+}
+
+func (x *Contact) GetEmails() []*Email {
+	if x != nil {
+		return x.Emails
+	}
+	return nil
+}
+
+func (x *Contact) GetAddresses() []*Address {
+	if x != nil {
+		return x.Addresses
+	}
+	return nil
+}
+
+func (x *Contact) GetWebsites() []*Website {
+	if x != nil {
+		return x.Websites
+	}
+	return nil
+}
+
+func (x *Contact) GetCustom() *structpb.Struct {
+	if x != nil {
+		return x.Custom
+	}
+	return nil
+}
+
+func (x *Contact) GetPhotoUrl() string {
+	if x != nil {
+		return x.PhotoUrl
+	}
+	return ""
+}
+
+func (x *Contact) GetExternal() *ExternalRef {
+	if x != nil {
+		return x.External
+	}
+	return nil
+}
+
+func (x *Contact) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Contact) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type ContactInput struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	FirstName string                 `protobuf:"bytes,1,opt,name=first_name,json=firstName,proto3" json:"first_name,omitempty"`
+	LastName  string                 `protobuf:"bytes,2,opt,name=last_name,json=lastName,proto3" json:"last_name,omitempty"`
+	Company   string                 `protobuf:"bytes,3,opt,name=company,proto3" json:"company,omitempty"`
+	// organization_id — ссылка на организацию; её имя подставляется в company. При обновлении 0 — отвязать.
+	OrganizationId int64      `protobuf:"varint,4,opt,name=organization_id,json=organizationId,proto3" json:"organization_id,omitempty"`
+	JobTitle       string     `protobuf:"bytes,5,opt,name=job_title,json=jobTitle,proto3" json:"job_title,omitempty"`
+	Department     string     `protobuf:"bytes,6,opt,name=department,proto3" json:"department,omitempty"`
+	Phones         []*Phone   `protobuf:"bytes,7,rep,name=phones,proto3" json:"phones,omitempty"`
+	Emails         []*Email   `protobuf:"bytes,8,rep,name=emails,proto3" json:"emails,omitempty"`
+	Addresses      []*Address `protobuf:"bytes,9,rep,name=addresses,proto3" json:"addresses,omitempty"`
+	Websites       []*Website `protobuf:"bytes,10,rep,name=websites,proto3" json:"websites,omitempty"`
+	// custom при обновлении мержится с текущими значениями; null удаляет поле
+	Custom        *structpb.Struct `protobuf:"bytes,11,opt,name=custom,proto3" json:"custom,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ContactInput) Reset() {
+	*x = ContactInput{}
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ContactInput) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContactInput) ProtoMessage() {}
+
+func (x *ContactInput) ProtoReflect() protoreflect.Message {
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContactInput.ProtoReflect.Descriptor instead.
+func (*ContactInput) Descriptor() ([]byte, []int) {
+	return file_phonebook_v1_contacts_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ContactInput) GetFirstName() string {
+	if x != nil {
+		return x.FirstName
+	}
+	return ""
+}
+
+func (x *ContactInput) GetLastName() string {
+	if x != nil {
+		return x.LastName
+	}
+	return ""
+}
+
+func (x *ContactInput) GetCompany() string {
+	if x != nil {
+		return x.Company
+	}
+	return ""
+}
+
+func (x *ContactInput) GetOrganizationId() int64 {
+	if x != nil {
+		return x.OrganizationId
+	}
+	return 0
+}
+
+func (x *ContactInput) GetJobTitle() string {
+	if x != nil {
+		return x.JobTitle
+	}
+	return ""
+}
+
+func (x *ContactInput) GetDepartment() string {
+	if x != nil {
+		return x.Department
+	}
+	return ""
+}
+
+func (x *ContactInput) GetPhones() []*Phone {
+	if x != nil {
+		return x.Phones
+	}
+	return nil
+}
+
+func (x *ContactInput) GetEmails() []*Email {
+	if x != nil {
+		return x.Emails
+	}
+	return nil
+}
+
+func (x *ContactInput) GetAddresses() []*Address {
+	if x != nil {
+		return x.Addresses
+	}
+	return nil
+}
+
+func (x *ContactInput) GetWebsites() []*Website {
+	if x != nil {
+		return x.Websites
+	}
+	return nil
+}
+
+func (x *ContactInput) GetCustom() *structpb.Struct {
+	if x != nil {
+		return x.Custom
+	}
+	return nil
+}
+
+type CreateContactRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Contact       *ContactInput          `protobuf:"bytes,1,opt,name=contact,proto3" json:"contact,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateContactRequest) Reset() {
+	*x = CreateContactRequest{}
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateContactRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateContactRequest) ProtoMessage() {}
+
+func (x *CreateContactRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateContactRequest.ProtoReflect.Descriptor instead.
+func (*CreateContactRequest) Descriptor() ([]byte, []int) {
+	return file_phonebook_v1_contacts_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *CreateContactRequest) GetContact() *ContactInput {
+	if x != nil {
+		return x.Contact
+	}
+	return nil
+}
+
+type GetContactRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetContactRequest) Reset() {
+	*x = GetContactRequest{}
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetContactRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetContactRequest) ProtoMessage() {}
+
+func (x *GetContactRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetContactRequest.ProtoReflect.Descriptor instead.
+func (*GetContactRequest) Descriptor() ([]byte, []int) {
+	return file_phonebook_v1_contacts_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetContactRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type UpdateContactRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Id      int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Contact *ContactInput          `protobuf:"bytes,2,opt,name=contact,proto3" json:"contact,omitempty"`
+	// пути — имена полей ContactInput: "first_name", "phones", "custom", ...
+	UpdateMask    *fieldmaskpb.FieldMask `protobuf:"bytes,3,opt,name=update_mask,json=updateMask,proto3" json:"update_mask,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateContactRequest) Reset() {
+	*x = UpdateContactRequest{}
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateContactRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateContactRequest) ProtoMessage() {}
+
+func (x *UpdateContactRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateContactRequest.ProtoReflect.Descriptor instead.
+func (*UpdateContactRequest) Descriptor() ([]byte, []int) {
+	return file_phonebook_v1_contacts_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *UpdateContactRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *UpdateContactRequest) GetContact() *ContactInput {
+	if x != nil {
+		return x.Contact
+	}
+	return nil
+}
+
+func (x *UpdateContactRequest) GetUpdateMask() *fieldmaskpb.FieldMask {
+	if x != nil {
+		return x.UpdateMask
+	}
+	return nil
+}
+
+type DeleteContactRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteContactRequest) Reset() {
+	*x = DeleteContactRequest{}
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteContactRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteContactRequest) ProtoMessage() {}
+
+func (x *DeleteContactRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteContactRequest.ProtoReflect.Descriptor instead.
+func (*DeleteContactRequest) Descriptor() ([]byte, []int) {
+	return file_phonebook_v1_contacts_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *DeleteContactRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type ListContactsRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	FirstName      string                 `protobuf:"bytes,1,opt,name=first_name,json=firstName,proto3" json:"first_name,omitempty"`
+	LastName       string                 `protobuf:"bytes,2,opt,name=last_name,json=lastName,proto3" json:"last_name,omitempty"`
+	Company        string                 `protobuf:"bytes,3,opt,name=company,proto3" json:"company,omitempty"`
+	Phone          string                 `protobuf:"bytes,4,opt,name=phone,proto3" json:"phone,omitempty"`
+	Email          string                 `protobuf:"bytes,5,opt,name=email,proto3" json:"email,omitempty"`
+	OrganizationId int64                  `protobuf:"varint,6,opt,name=organization_id,json=organizationId,proto3" json:"organization_id,omitempty"`
+	Custom         map[string]string      `protobuf:"bytes,7,rep,name=custom,proto3" json:"custom,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	AfterId        int64                  `protobuf:"varint,8,opt,name=after_id,json=afterId,proto3" json:"after_id,omitempty"`
+	Limit          int32                  `protobuf:"varint,9,opt,name=limit,proto3" json:"limit,omitempty"`
+	// sort — как ?sort= в REST, в том числе "cf.<name>"; order — "asc" или "desc"
+	Sort          string `protobuf:"bytes,10,opt,name=sort,proto3" json:"sort,omitempty"`
+	Order         string `protobuf:"bytes,11,opt,name=order,proto3" json:"order,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListContactsRequest) Reset() {
+	*x = ListContactsRequest{}
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListContactsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListContactsRequest) ProtoMessage() {}
+
+func (x *ListContactsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListContactsRequest.ProtoReflect.Descriptor instead.
+func (*ListContactsRequest) Descriptor() ([]byte, []int) {
+	return file_phonebook_v1_contacts_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ListContactsRequest) GetFirstName() string {
+	if x != nil {
+		return x.FirstName
+	}
+	return ""
+}
+
+func (x *ListContactsRequest) GetLastName() string {
+	if x != nil {
+		return x.LastName
+	}
+	return ""
+}
+
+func (x *ListContactsRequest) GetCompany() string {
+	if x != nil {
+		return x.Company
+	}
+	return ""
+}
+
+func (x *ListContactsRequest) GetPhone() string {
+	if x != nil {
+		return x.Phone
+	}
+	return ""
+}
+
+func (x *ListContactsRequest) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *ListContactsRequest) GetOrganizationId() int64 {
+	if x != nil {
+		return x.OrganizationId
+	}
+	return 0
+}
+
+func (x *ListContactsRequest) GetCustom() map[string]string {
+	if x != nil {
+		return x.Custom
+	}
+	return nil
+}
+
+func (x *ListContactsRequest) GetAfterId() int64 {
+	if x != nil {
+		return x.AfterId
+	}
+	return 0
+}
+
+func (x *ListContactsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListContactsRequest) GetSort() string {
+	if x != nil {
+		return x.Sort
+	}
+	return ""
+}
+
+func (x *ListContactsRequest) GetOrder() string {
+	if x != nil {
+		return x.Order
+	}
+	return ""
+}
+
+type ListContactsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Contacts      []*Contact             `protobuf:"bytes,1,rep,name=contacts,proto3" json:"contacts,omitempty"`
+	NextAfterId   int64                  `protobuf:"varint,2,opt,name=next_after_id,json=nextAfterId,proto3" json:"next_after_id,omitempty"`
+	HasMore       bool                   `protobuf:"varint,3,opt,name=has_more,json=hasMore,proto3" json:"has_more,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListContactsResponse) Reset() {
+	*x = ListContactsResponse{}
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListContactsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListContactsResponse) ProtoMessage() {}
+
+func (x *ListContactsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListContactsResponse.ProtoReflect.Descriptor instead.
+func (*ListContactsResponse) Descriptor() ([]byte, []int) {
+	return file_phonebook_v1_contacts_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ListContactsResponse) GetContacts() []*Contact {
+	if x != nil {
+		return x.Contacts
+	}
+	return nil
+}
+
+func (x *ListContactsResponse) GetNextAfterId() int64 {
+	if x != nil {
+		return x.NextAfterId
+	}
+	return 0
+}
+
+func (x *ListContactsResponse) GetHasMore() bool {
+	if x != nil {
+		return x.HasMore
+	}
+	return false
+}
+
+type SearchContactsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Query         string                 `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	Limit         int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchContactsRequest) Reset() {
+	*x = SearchContactsRequest{}
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchContactsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchContactsRequest) ProtoMessage() {}
+
+func (x *SearchContactsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchContactsRequest.ProtoReflect.Descriptor instead.
+func (*SearchContactsRequest) Descriptor() ([]byte, []int) {
+	return file_phonebook_v1_contacts_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *SearchContactsRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *SearchContactsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type SearchContactsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Contacts      []*Contact             `protobuf:"bytes,1,rep,name=contacts,proto3" json:"contacts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchContactsResponse) Reset() {
+	*x = SearchContactsResponse{}
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchContactsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchContactsResponse) ProtoMessage() {}
+
+func (x *SearchContactsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchContactsResponse.ProtoReflect.Descriptor instead.
+func (*SearchContactsResponse) Descriptor() ([]byte, []int) {
+	return file_phonebook_v1_contacts_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *SearchContactsResponse) GetContacts() []*Contact {
+	if x != nil {
+		return x.Contacts
+	}
+	return nil
+}
+
+type WatchRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// after_event_id — последний полученный id события; не задан — только новые события
+	AfterEventId  *int64 `protobuf:"varint,1,opt,name=after_event_id,json=afterEventId,proto3,oneof" json:"after_event_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_phonebook_v1_contacts_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *WatchRequest) GetAfterEventId() int64 {
+	if x != nil && x.AfterEventId != nil {
+		return *x.AfterEventId
+	}
+	return 0
+}
+
+type ContactEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	ContactId     int64                  `protobuf:"varint,2,opt,name=contact_id,json=contactId,proto3" json:"contact_id,omitempty"`
+	Op            ContactEvent_Op        `protobuf:"varint,3,opt,name=op,proto3,enum=phonebook.v1.ContactEvent_Op" json:"op,omitempty"`
+	OccurredAt    *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=occurred_at,json=occurredAt,proto3" json:"occurred_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ContactEvent) Reset() {
+	*x = ContactEvent{}
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ContactEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContactEvent) ProtoMessage() {}
+
+func (x *ContactEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_phonebook_v1_contacts_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContactEvent.ProtoReflect.Descriptor instead.
+func (*ContactEvent) Descriptor() ([]byte, []int) {
+	return file_phonebook_v1_contacts_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ContactEvent) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *ContactEvent) GetContactId() int64 {
+	if x != nil {
+		return x.ContactId
+	}
+	return 0
+}
+
+func (x *ContactEvent) GetOp() ContactEvent_Op {
+	if x != nil {
+		return x.Op
+	}
+	return ContactEvent_OP_UNSPECIFIED
+}
+
+func (x *ContactEvent) GetOccurredAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.OccurredAt
+	}
+	return nil
+}
+
+var File_phonebook_v1_contacts_proto protoreflect.FileDescriptor
+
+const file_phonebook_v1_contacts_proto_rawDesc = "" +
+	"\n" +
+	"\x1bphonebook/v1/contacts.proto\x12\fphonebook.v1\x1a\x1bgoogle/protobuf/empty.proto\x1a google/protobuf/field_mask.proto\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\x98\x01\n" +
+	"\x05Phone\x12\x14\n" +
+	"\x05label\x18\x01 \x01(\tR\x05label\x12\x1b\n" +
+	"\tphone_raw\x18\x02 \x01(\tR\bphoneRaw\x12\x1d\n" +
+	"\n" +
+	"phone_e164\x18\x03 \x01(\tR\tphoneE164\x12\x1d\n" +
+	"\n" +
+	"is_primary\x18\x04 \x01(\bR\tisPrimary\x12\x1e\n" +
+	"\n" +
+	"visibility\x18\x05 \x01(\tR\n" +
+	"visibility\"R\n" +
+	"\x05Email\x12\x14\n" +
+	"\x05label\x18\x01 \x01(\tR\x05label\x12\x14\n" +
+	"\x05email\x18\x02 \x01(\tR\x05email\x12\x1d\n" +
+	"\n" +
+	"is_primary\x18\x03 \x01(\bR\tisPrimary\"\xbd\x01\n" +
+	"\aAddress\x12\x14\n" +
+	"\x05label\x18\x01 \x01(\tR\x05label\x12\x16\n" +
+	"\x06street\x18\x02 \x01(\tR\x06street\x12\x12\n" +
+	"\x04city\x18\x03 \x01(\tR\x04city\x12\x16\n" +
+	"\x06region\x18\x04 \x01(\tR\x06region\x12\x1f\n" +
+	"\vpostal_code\x18\x05 \x01(\tR\n" +
+	"postalCode\x12\x18\n" +
+	"\acountry\x18\x06 \x01(\tR\acountry\x12\x1d\n" +
+	"\n" +
+	"is_primary\x18\a \x01(\bR\tisPrimary\"P\n" +
+	"\aWebsite\x12\x14\n" +
+	"\x05label\x18\x01 \x01(\tR\x05label\x12\x10\n" +
+	"\x03url\x18\x02 \x01(\tR\x03url\x12\x1d\n" +
+	"\n" +
+	"is_primary\x18\x03 \x01(\bR\tisPrimary\"5\n" +
+	"\x0fOrganizationRef\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\"5\n" +
+	"\vExternalRef\x12\x16\n" +
+	"\x06source\x18\x01 \x01(\tR\x06source\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\tR\x02id\"\xac\x05\n" +
+	"\aContact\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x1d\n" +
+	"\n" +
+	"first_name\x18\x02 \x01(\tR\tfirstName\x12\x1b\n" +
+	"\tlast_name\x18\x03 \x01(\tR\blastName\x12\x18\n" +
+	"\acompany\x18\x04 \x01(\tR\acompany\x12A\n" +
+	"\forganization\x18\x05 \x01(\v2\x1d.phonebook.v1.OrganizationRefR\forganization\x12\x1b\n" +
+	"\tjob_title\x18\x06 \x01(\tR\bjobTitle\x12\x1e\n" +
+	"\n" +
+	"department\x18\a \x01(\tR\n" +
+	"department\x12+\n" +
+	"\x06phones\x18\b \x03(\v2\x13.phonebook.v1.PhoneR\x06phones\x12+\n" +
+	"\x06emails\x18\t \x03(\v2\x13.phonebook.v1.EmailR\x06emails\x123\n" +
+	"\taddresses\x18\n" +
+	" \x03(\v2\x15.phonebook.v1.AddressR\taddresses\x121\n" +
+	"\bwebsites\x18\v \x03(\v2\x15.phonebook.v1.WebsiteR\bwebsites\x12/\n" +
+	"\x06custom\x18\f \x01(\v2\x17.google.protobuf.StructR\x06custom\x12\x1b\n" +
+	"\tphoto_url\x18\r \x01(\tR\bphotoUrl\x125\n" +
+	"\bexternal\x18\x0e \x01(\v2\x19.phonebook.v1.ExternalRefR\bexternal\x129\n" +
+	"\n" +
+	"created_at\x18\x0f \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\x10 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"\xbd\x03\n" +
+	"\fContactInput\x12\x1d\n" +
+	"\n" +
+	"first_name\x18\x01 \x01(\tR\tfirstName\x12\x1b\n" +
+	"\tlast_name\x18\x02 \x01(\tR\blastName\x12\x18\n" +
+	"\acompany\x18\x03 \x01(\tR\acompany\x12'\n" +
+	"\x0forganization_id\x18\x04 \x01(\x03R\x0eorganizationId\x12\x1b\n" +
+	"\tjob_title\x18\x05 \x01(\tR\bjobTitle\x12\x1e\n" +
+	"\n" +
+	"department\x18\x06 \x01(\tR\n" +
+	"department\x12+\n" +
+	"\x06phones\x18\a \x03(\v2\x13.phonebook.v1.PhoneR\x06phones\x12+\n" +
+	"\x06emails\x18\b \x03(\v2\x13.phonebook.v1.EmailR\x06emails\x123\n" +
+	"\taddresses\x18\t \x03(\v2\x15.phonebook.v1.AddressR\taddresses\x121\n" +
+	"\bwebsites\x18\n" +
+	" \x03(\v2\x15.phonebook.v1.WebsiteR\bwebsites\x12/\n" +
+	"\x06custom\x18\v \x01(\v2\x17.google.protobuf.StructR\x06custom\"L\n" +
+	"\x14CreateContactRequest\x124\n" +
+	"\acontact\x18\x01 \x01(\v2\x1a.phonebook.v1.ContactInputR\acontact\"#\n" +
+	"\x11GetContactRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"\x99\x01\n" +
+	"\x14UpdateContactRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x124\n" +
+	"\acontact\x18\x02 \x01(\v2\x1a.phonebook.v1.ContactInputR\acontact\x12;\n" +
+	"\vupdate_mask\x18\x03 \x01(\v2\x1a.google.protobuf.FieldMaskR\n" +
+	"updateMask\"&\n" +
+	"\x14DeleteContactRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"\x9d\x03\n" +
+	"\x13ListContactsRequest\x12\x1d\n" +
+	"\n" +
+	"first_name\x18\x01 \x01(\tR\tfirstName\x12\x1b\n" +
+	"\tlast_name\x18\x02 \x01(\tR\blastName\x12\x18\n" +
+	"\acompany\x18\x03 \x01(\tR\acompany\x12\x14\n" +
+	"\x05phone\x18\x04 \x01(\tR\x05phone\x12\x14\n" +
+	"\x05email\x18\x05 \x01(\tR\x05email\x12'\n" +
+	"\x0forganization_id\x18\x06 \x01(\x03R\x0eorganizationId\x12E\n" +
+	"\x06custom\x18\a \x03(\v2-.phonebook.v1.ListContactsRequest.CustomEntryR\x06custom\x12\x19\n" +
+	"\bafter_id\x18\b \x01(\x03R\aafterId\x12\x14\n" +
+	"\x05limit\x18\t \x01(\x05R\x05limit\x12\x12\n" +
+	"\x04sort\x18\n" +
+	" \x01(\tR\x04sort\x12\x14\n" +
+	"\x05order\x18\v \x01(\tR\x05order\x1a9\n" +
+	"\vCustomEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x88\x01\n" +
+	"\x14ListContactsResponse\x121\n" +
+	"\bcontacts\x18\x01 \x03(\v2\x15.phonebook.v1.ContactR\bcontacts\x12\"\n" +
+	"\rnext_after_id\x18\x02 \x01(\x03R\vnextAfterId\x12\x19\n" +
+	"\bhas_more\x18\x03 \x01(\bR\ahasMore\"C\n" +
+	"\x15SearchContactsRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\"K\n" +
+	"\x16SearchContactsResponse\x121\n" +
+	"\bcontacts\x18\x01 \x03(\v2\x15.phonebook.v1.ContactR\bcontacts\"L\n" +
+	"\fWatchRequest\x12)\n" +
+	"\x0eafter_event_id\x18\x01 \x01(\x03H\x00R\fafterEventId\x88\x01\x01B\x11\n" +
+	"\x0f_after_event_id\"\xf3\x01\n" +
+	"\fContactEvent\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x1d\n" +
+	"\n" +
+	"contact_id\x18\x02 \x01(\x03R\tcontactId\x12-\n" +
+	"\x02op\x18\x03 \x01(\x0e2\x1d.phonebook.v1.ContactEvent.OpR\x02op\x12;\n" +
+	"\voccurred_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"occurredAt\"H\n" +
+	"\x02Op\x12\x12\n" +
+	"\x0eOP_UNSPECIFIED\x10\x00\x12\x0e\n" +
+	"\n" +
+	"OP_CREATED\x10\x01\x12\x0e\n" +
+	"\n" +
+	"OP_UPDATED\x10\x02\x12\x0e\n" +
+	"\n" +
+	"OP_DELETED\x10\x032\xac\x04\n" +
+	"\bContacts\x12J\n" +
+	"\rCreateContact\x12\".phonebook.v1.CreateContactRequest\x1a\x15.phonebook.v1.Contact\x12D\n" +
+	"\n" +
+	"GetContact\x12\x1f.phonebook.v1.GetContactRequest\x1a\x15.phonebook.v1.Contact\x12J\n" +
+	"\rUpdateContact\x12\".phonebook.v1.UpdateContactRequest\x1a\x15.phonebook.v1.Contact\x12K\n" +
+	"\rDeleteContact\x12\".phonebook.v1.DeleteContactRequest\x1a\x16.google.protobuf.Empty\x12U\n" +
+	"\fListContacts\x12!.phonebook.v1.ListContactsRequest\x1a\".phonebook.v1.ListContactsResponse\x12[\n" +
+	"\x0eSearchContacts\x12#.phonebook.v1.SearchContactsRequest\x1a$.phonebook.v1.SearchContactsResponse\x12A\n" +
+	"\x05Watch\x12\x1a.phonebook.v1.WatchRequest\x1a\x1a.phonebook.v1.ContactEvent0\x01B;Z9github.com/sunzhqr/phonebook/api/phonebook/v1;phonebookv1b\x06proto3"
+
+var (
+	file_phonebook_v1_contacts_proto_rawDescOnce sync.Once
+	file_phonebook_v1_contacts_proto_rawDescData []byte
+)
+
+func file_phonebook_v1_contacts_proto_rawDescGZIP() []byte {
+	file_phonebook_v1_contacts_proto_rawDescOnce.Do(func() {
+		file_phonebook_v1_contacts_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_phonebook_v1_contacts_proto_rawDesc), len(file_phonebook_v1_contacts_proto_rawDesc)))
+	})
+	return file_phonebook_v1_contacts_proto_rawDescData
+}
+
+var file_phonebook_v1_contacts_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_phonebook_v1_contacts_proto_msgTypes = make([]protoimpl.MessageInfo, 19)
+var file_phonebook_v1_contacts_proto_goTypes = []any{
+	(ContactEvent_Op)(0),           // 0: phonebook.v1.ContactEvent.Op
+	(*Phone)(nil),                  // 1: phonebook.v1.Phone
+	(*Email)(nil),                  // 2: phonebook.v1.Email
+	(*Address)(nil),                // 3: phonebook.v1.Address
+	(*Website)(nil),                // 4: phonebook.v1.Website
+	(*OrganizationRef)(nil),        // 5: phonebook.v1.OrganizationRef
+	(*ExternalRef)(nil),            // 6: phonebook.v1.ExternalRef
+	(*Contact)(nil),                // 7: phonebook.v1.Contact
+	(*ContactInput)(nil),           // 8: phonebook.v1.ContactInput
+	(*CreateContactRequest)(nil),   // 9: phonebook.v1.CreateContactRequest
+	(*GetContactRequest)(nil),      // 10: phonebook.v1.GetContactRequest
+	(*UpdateContactRequest)(nil),   // 11: phonebook.v1.UpdateContactRequest
+	(*DeleteContactRequest)(nil),   // 12: phonebook.v1.DeleteContactRequest
+	(*ListContactsRequest)(nil),    // 13: phonebook.v1.ListContactsRequest
+	(*ListContactsResponse)(nil),   // 14: phonebook.v1.ListContactsResponse
+	(*SearchContactsRequest)(nil),  // 15: phonebook.v1.SearchContactsRequest
+	(*SearchContactsResponse)(nil), // 16: phonebook.v1.SearchContactsResponse
+	(*WatchRequest)(nil),           // 17: phonebook.v1.WatchRequest
+	(*ContactEvent)(nil),           // 18: phonebook.v1.ContactEvent
+	nil,                            // 19: phonebook.v1.ListContactsRequest.CustomEntry
+	(*structpb.Struct)(nil),        // 20: google.protobuf.Struct
+	(*timestamppb.Timestamp)(nil),  // 21: google.protobuf.Timestamp
+	(*fieldmaskpb.FieldMask)(nil),  // 22: google.protobuf.FieldMask
+	(*emptypb.Empty)(nil),          // 23: google.protobuf.Empty
+}
+var file_phonebook_v1_contacts_proto_depIdxs = []int32{
+	5,  // 0: phonebook.v1.Contact.organization:type_name -> phonebook.v1.OrganizationRef
+	1,  // 1: phonebook.v1.Contact.phones:type_name -> phonebook.v1.Phone
+	2,  // 2: phonebook.v1.Contact.emails:type_name -> phonebook.v1.Email
+	3,  // 3: phonebook.v1.Contact.addresses:type_name -> phonebook.v1.Address
+	4,  // 4: phonebook.v1.Contact.websites:type_name -> phonebook.v1.Website
+	20, // 5: phonebook.v1.Contact.custom:type_name -> google.protobuf.Struct
+	6,  // 6: phonebook.v1.Contact.external:type_name -> phonebook.v1.ExternalRef
+	21, // 7: phonebook.v1.Contact.created_at:type_name -> google.protobuf.Timestamp
+	21, // 8: phonebook.v1.Contact.updated_at:type_name -> google.protobuf.Timestamp
+	1,  // 9: phonebook.v1.ContactInput.phones:type_name -> phonebook.v1.Phone
+	2,  // 10: phonebook.v1.ContactInput.emails:type_name -> phonebook.v1.Email
+	3,  // 11: phonebook.v1.ContactInput.addresses:type_name -> phonebook.v1.Address
+	4,  // 12: phonebook.v1.ContactInput.websites:type_name -> phonebook.v1.Website
+	20, // 13: phonebook.v1.ContactInput.custom:type_name -> google.protobuf.Struct
+	8,  // 14: phonebook.v1.CreateContactRequest.contact:type_name -> phonebook.v1.ContactInput
+	8,  // 15: phonebook.v1.UpdateContactRequest.contact:type_name -> phonebook.v1.ContactInput
+	22, // 16: phonebook.v1.UpdateContactRequest.update_mask:type_name -> google.protobuf.FieldMask
+	19, // 17: phonebook.v1.ListContactsRequest.custom:type_name -> phonebook.v1.ListContactsRequest.CustomEntry
+	7,  // 18: phonebook.v1.ListContactsResponse.contacts:type_name -> phonebook.v1.Contact
+	7,  // 19: phonebook.v1.SearchContactsResponse.contacts:type_name -> phonebook.v1.Contact
+	0,  // 20: phonebook.v1.ContactEvent.op:type_name -> phonebook.v1.ContactEvent.Op
+	21, // 21: phonebook.v1.ContactEvent.occurred_at:type_name -> google.protobuf.Timestamp
+	9,  // 22: phonebook.v1.Contacts.CreateContact:input_type -> phonebook.v1.CreateContactRequest
+	10, // 23: phonebook.v1.Contacts.GetContact:input_type -> phonebook.v1.GetContactRequest
+	11, // 24: phonebook.v1.Contacts.UpdateContact:input_type -> phonebook.v1.UpdateContactRequest
+	12, // 25: phonebook.v1.Contacts.DeleteContact:input_type -> phonebook.v1.DeleteContactRequest
+	13, // 26: phonebook.v1.Contacts.ListContacts:input_type -> phonebook.v1.ListContactsRequest
+	15, // 27: phonebook.v1.Contacts.SearchContacts:input_type -> phonebook.v1.SearchContactsRequest
+	17, // 28: phonebook.v1.Contacts.Watch:input_type -> phonebook.v1.WatchRequest
+	7,  // 29: phonebook.v1.Contacts.CreateContact:output_type -> phonebook.v1.Contact
+	7,  // 30: phonebook.v1.Contacts.GetContact:output_type -> phonebook.v1.Contact
+	7,  // 31: phonebook.v1.Contacts.UpdateContact:output_type -> phonebook.v1.Contact
+	23, // 32: phonebook.v1.Contacts.DeleteContact:output_type -> google.protobuf.Empty
+	14, // 33: phonebook.v1.Contacts.ListContacts:output_type -> phonebook.v1.ListContactsResponse
+	16, // 34: phonebook.v1.Contacts.SearchContacts:output_type -> phonebook.v1.SearchContactsResponse
+	18, // 35: phonebook.v1.Contacts.Watch:output_type -> phonebook.v1.ContactEvent
+	29, // [29:36] is the sub-list for method output_type
+	22, // [22:29] is the sub-list for method input_type
+	22, // [22:22] is the sub-list for extension type_name
+	22, // [22:22] is the sub-list for extension extendee
+	0,  // [0:22] is the sub-list for field type_name
+}
+
+func init() { file_phonebook_v1_contacts_proto_init() }
+func file_phonebook_v1_contacts_proto_init() {
+	if File_phonebook_v1_contacts_proto != nil {
+		return
+	}
+	file_phonebook_v1_contacts_proto_msgTypes[16].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_phonebook_v1_contacts_proto_rawDesc), len(file_phonebook_v1_contacts_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   19,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_phonebook_v1_contacts_proto_goTypes,
+		DependencyIndexes: file_phonebook_v1_contacts_proto_depIdxs,
+		EnumInfos:         file_phonebook_v1_contacts_proto_enumTypes,
+		MessageInfos:      file_phonebook_v1_contacts_proto_msgTypes,
+	}.Build()
+	File_phonebook_v1_contacts_proto = out.File
+	file_phonebook_v1_contacts_proto_goTypes = nil
+	file_phonebook_v1_contacts_proto_depIdxs = nil
+}
@@ -0,0 +1,65 @@
+// apikey — выпуск ключа API напрямую в БД, в том числе первого ключа admin, когда выпустить его
+// через API ещё нечем:
+//
+//	go run ./cmd/apikey -name bootstrap -scopes admin
+//
+// Ключ печатается в stdout один раз; в БД остаётся только его хеш.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sunzhqr/phonebook/internal/config"
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/repository"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+func main() {
+	name := flag.String("name", "", "key name, e.g. the client it is issued to")
+	scopes := flag.String("scopes", service.ScopeAdmin, "comma-separated scopes: contacts:read, contacts:write, admin")
+	ttl := flag.Duration("ttl", 0, "key lifetime, 0 — no expiry")
+	book := flag.String("book", "", "address book slug the key is bound to, empty — any book")
+	flag.Parse()
+	if *name == "" {
+		fmt.Fprintln(os.Stderr, "apikey: -name is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg := config.Load()
+	lg := logger.New(cfg.Env)
+	defer lg.Sync()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	pool, err := repository.OpenDB(ctx, cfg.Postgres)
+	if err != nil {
+		lg.Fatal("db connect failed", logger.Err(err))
+	}
+	defer pool.Close()
+
+	in := service.APIKeyCreateIn{Name: *name, AddressBook: *book}
+	for _, s := range strings.Split(*scopes, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			in.Scopes = append(in.Scopes, s)
+		}
+	}
+	if *ttl > 0 {
+		exp := time.Now().Add(*ttl)
+		in.ExpiresAt = &exp
+	}
+	svc := service.New(lg, repository.New(pool), nil, nil)
+	k, err := svc.CreateAPIKey(ctx, in)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "apikey:", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "issued key #%d %q with scopes %s\n", k.ID, k.Name, strings.Join(k.Scopes, ","))
+	fmt.Println(k.Key)
+}
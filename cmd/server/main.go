@@ -7,11 +7,18 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/sunzhqr/phonebook/internal/audit"
+	"github.com/sunzhqr/phonebook/internal/blob"
 	"github.com/sunzhqr/phonebook/internal/config"
+	"github.com/sunzhqr/phonebook/internal/events"
+	"github.com/sunzhqr/phonebook/internal/grpcserver"
 	"github.com/sunzhqr/phonebook/internal/httpserver"
+	"github.com/sunzhqr/phonebook/internal/jwtauth"
+	"github.com/sunzhqr/phonebook/internal/ldapserver"
 	"github.com/sunzhqr/phonebook/internal/logger"
 	"github.com/sunzhqr/phonebook/internal/repository"
 	"github.com/sunzhqr/phonebook/internal/service"
+	"github.com/sunzhqr/phonebook/internal/webhook"
 )
 
 func main() {
@@ -26,8 +33,38 @@ func main() {
 	defer pool.Close()
 
 	repos := repository.New(pool)
-	svc := service.New(lg, repos.Contacts)
-	httpSrv := httpserver.New(lg, cfg, svc)
+	photos, err := blob.NewLocal(cfg.Photos.Dir)
+	if err != nil {
+		lg.Fatal("photo storage init failed", logger.Err(err))
+	}
+	svc := service.New(lg, repos, photos, cfg.Photos)
+	// журнал аудита останавливается последним, после серверов, чтобы записать все их вызовы
+	auditLog := audit.New(lg, svc, cfg.Audit)
+	svc.SetAuditSink(auditLog)
+	auditCtx, stopAudit := context.WithCancel(context.Background())
+	auditDone := make(chan struct{})
+	go func() {
+		auditLog.Run(auditCtx)
+		close(auditDone)
+	}()
+	bgCtx, stopBg := context.WithCancel(context.Background())
+	defer stopBg()
+	// фоновые задачи работают со всеми книгами — это отмечается явно, иначе запрос без книги не видит ничего
+	sysCtx := repository.WithAllBooks(bgCtx)
+	hub := events.NewHub(lg, svc)
+	go hub.Run(sysCtx)
+	go webhook.NewDispatcher(lg, svc).Run(sysCtx)
+	go purgeExpired(sysCtx, lg, svc)
+
+	var authn service.AuthService = svc
+	if cfg.JWT.JWKS != "" {
+		if authn, err = jwtauth.New(lg, cfg.JWT, svc); err != nil {
+			lg.Fatal("jwt auth init failed", logger.Err(err))
+		}
+	}
+
+	httpSrv := httpserver.New(lg, cfg, svc, hub, authn)
+	grpcSrv := grpcserver.New(lg, cfg.GRPC, svc, hub, authn)
 
 	go func() {
 		lg.Info("http listen", logger.KV("addr", cfg.HTTP.Addr))
@@ -35,13 +72,66 @@ func main() {
 			lg.Fatal("http server error", logger.Err(err))
 		}
 	}()
+	go func() {
+		lg.Info("grpc listen", logger.KV("addr", cfg.GRPC.Addr))
+		if err := grpcSrv.Start(); err != nil {
+			lg.Fatal("grpc server error", logger.Err(err))
+		}
+	}()
+
+	var ldapSrv *ldapserver.Server
+	if cfg.LDAP.Addr != "" {
+		ldapSrv = ldapserver.New(lg, cfg.LDAP, svc)
+		go func() {
+			lg.Info("ldap listen", logger.KV("addr", cfg.LDAP.Addr))
+			if err := ldapSrv.Start(); err != nil {
+				lg.Fatal("ldap server error", logger.Err(err))
+			}
+		}()
+	}
 
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 	<-stop
 
+	stopBg() // закрывает SSE-подписки и потоки Watch, иначе Shutdown ждал бы их до таймаута
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	_ = httpSrv.Stop(ctx)
+	_ = grpcSrv.Stop(ctx)
+	if ldapSrv != nil {
+		_ = ldapSrv.Stop(ctx)
+	}
+	stopAudit()
+	<-auditDone
 	lg.Info("stopped")
 }
+
+// purgeExpired — раз в час удаляет ключи идемпотентности, события ленты и обработанный outbox
+// с истёкшим сроком хранения.
+func purgeExpired(ctx context.Context, lg *logger.Logger, svc *service.Service) {
+	t := time.NewTicker(time.Hour)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if n, err := svc.PurgeIdempotencyKeys(ctx); err != nil {
+				lg.Warn("idempotency keys purge failed", logger.Err(err))
+			} else if n > 0 {
+				lg.Info("idempotency keys purged", logger.KV("count", n))
+			}
+			if n, err := svc.PurgeEvents(ctx); err != nil {
+				lg.Warn("events purge failed", logger.Err(err))
+			} else if n > 0 {
+				lg.Info("events purged", logger.KV("count", n))
+			}
+			if n, err := svc.PurgeOutbox(ctx); err != nil {
+				lg.Warn("outbox purge failed", logger.Err(err))
+			} else if n > 0 {
+				lg.Info("outbox purged", logger.KV("count", n))
+			}
+		}
+	}
+}
@@ -0,0 +1,35 @@
+create table if not exists contact_emails (
+    id          bigserial primary key,
+    contact_id  bigint not null references contacts(id) on delete cascade,
+    label       text,
+    email       text not null,
+    is_primary  boolean not null default false
+);
+
+create table if not exists contact_addresses (
+    id           bigserial primary key,
+    contact_id   bigint not null references contacts(id) on delete cascade,
+    label        text,
+    street       text not null default '',
+    city         text not null default '',
+    region       text not null default '',
+    postal_code  text not null default '',
+    country      text not null default '',
+    is_primary   boolean not null default false
+);
+
+create table if not exists contact_websites (
+    id          bigserial primary key,
+    contact_id  bigint not null references contacts(id) on delete cascade,
+    label       text,
+    url         text not null,
+    is_primary  boolean not null default false
+);
+
+create index if not exists idx_emails_contact on contact_emails (contact_id);
+create index if not exists idx_addresses_contact on contact_addresses (contact_id);
+create index if not exists idx_websites_contact on contact_websites (contact_id);
+create index if not exists idx_emails_email_trgm on contact_emails using gin (email gin_trgm_ops);
+create unique index if not exists uq_contact_primary_email on contact_emails (contact_id) where is_primary;
+create unique index if not exists uq_contact_primary_address on contact_addresses (contact_id) where is_primary;
+create unique index if not exists uq_contact_primary_website on contact_websites (contact_id) where is_primary;
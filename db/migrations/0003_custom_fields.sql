@@ -0,0 +1,16 @@
+create table if not exists custom_fields (
+    id           bigserial primary key,
+    name         text not null unique,
+    label        text not null default '',
+    type         text not null check (type in ('string', 'number', 'date', 'enum', 'bool')),
+    required     boolean not null default false,
+    constraints  jsonb not null default '{}',
+    created_at   timestamptz not null default now(),
+    updated_at   timestamptz not null default now()
+);
+
+create trigger trg_custom_fields_updated before update on custom_fields for each row execute function set_updated_at();
+
+alter table contacts add column if not exists custom jsonb not null default '{}';
+
+create index if not exists idx_contacts_custom on contacts using gin (custom jsonb_path_ops);
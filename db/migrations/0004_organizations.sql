@@ -0,0 +1,95 @@
+create table if not exists organizations (
+    id          bigserial primary key,
+    name        text not null,
+    created_at  timestamptz not null default now(),
+    updated_at  timestamptz not null default now()
+);
+
+create unique index if not exists uq_organizations_name on organizations (lower(name));
+create index if not exists idx_organizations_name_trgm on organizations using gin (name gin_trgm_ops);
+create trigger trg_organizations_updated before update on organizations for each row execute function set_updated_at();
+
+create table if not exists organization_phones (
+    id               bigserial primary key,
+    organization_id  bigint not null references organizations(id) on delete cascade,
+    label            text,
+    phone_raw        text not null,
+    phone_e164       text not null,
+    phone_digits     text not null,
+    is_primary       boolean not null default false
+);
+
+create table if not exists organization_addresses (
+    id               bigserial primary key,
+    organization_id  bigint not null references organizations(id) on delete cascade,
+    label            text,
+    street           text not null default '',
+    city             text not null default '',
+    region           text not null default '',
+    postal_code      text not null default '',
+    country          text not null default '',
+    is_primary       boolean not null default false
+);
+
+create index if not exists idx_org_phones_org on organization_phones (organization_id);
+create index if not exists idx_org_addresses_org on organization_addresses (organization_id);
+create unique index if not exists uq_org_primary_phone on organization_phones (organization_id) where is_primary;
+create unique index if not exists uq_org_primary_address on organization_addresses (organization_id) where is_primary;
+
+alter table contacts add column if not exists organization_id bigint references organizations(id) on delete set null;
+alter table contacts add column if not exists job_title text not null default '';
+alter table contacts add column if not exists department text not null default '';
+create index if not exists idx_contacts_organization on contacts (organization_id, id);
+
+-- Пары организаций с похожими именами, которые миграция не объединила: решение принимает человек
+create table if not exists organization_merge_candidates (
+    organization_id  bigint not null references organizations(id) on delete cascade,
+    similar_id       bigint not null references organizations(id) on delete cascade,
+    primary key (organization_id, similar_id)
+);
+
+-- Перенос существующих строк company в организации.
+-- Ключ: нижний регистр без организационно-правовых форм и знаков, например
+-- "Forte", "Forte Bank" и "ForteBank JSC" дают ключи forte, fortebank, fortebank.
+-- Объединяются только строки с равным ключом; ключ, начинающийся с другого ключа (не короче
+-- 4 символов: "Alfa" и "Alfastrah"), лишь попадает в organization_merge_candidates.
+create temporary table company_keys as
+select distinct
+    company as raw,
+    regexp_replace(
+        regexp_replace(lower(company), '\m(jsc|llc|llp|ltd|inc|corp|plc|gmbh|ао|тоо|оао|зао|пао|ооо)\M', '', 'g'),
+        '[^[:alnum:]]', '', 'g'
+    ) as key
+from contacts
+where coalesce(trim(company), '') <> '';
+
+delete from company_keys where key = '';
+
+-- имя организации — самое частое написание ключа, при равенстве — самое короткое
+insert into organizations (name)
+select distinct on (k.key) trim(c.company)
+from contacts c
+join company_keys k on k.raw = c.company
+group by k.key, trim(c.company)
+order by k.key, count(*) desc, length(trim(c.company)) asc
+on conflict do nothing;
+
+create temporary table company_orgs as
+select distinct k.key, o.id
+from company_keys k
+join organizations o on lower(o.name) = lower(trim(k.raw));
+
+update contacts c
+set organization_id = co.id
+from company_keys k
+join company_orgs co on co.key = k.key
+where c.company = k.raw and c.organization_id is null;
+
+insert into organization_merge_candidates (organization_id, similar_id)
+select a.id, b.id
+from company_orgs a
+join company_orgs b on b.key <> a.key and length(a.key) >= 4 and b.key like a.key || '%'
+on conflict do nothing;
+
+drop table company_orgs;
+drop table company_keys;
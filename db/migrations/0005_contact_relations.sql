@@ -0,0 +1,15 @@
+create table if not exists contact_relations (
+    id          bigserial primary key,
+    -- contact_id является type для related_id: "A assistant_of B"
+    contact_id  bigint not null references contacts(id) on delete cascade,
+    related_id  bigint not null references contacts(id) on delete cascade,
+    type        text not null,
+    -- у двусторонней связи обе записи (прямая и обратная) имеют общий pair_id
+    pair_id     bigint,
+    created_at  timestamptz not null default now(),
+    check (contact_id <> related_id)
+);
+
+create unique index if not exists uq_contact_relations on contact_relations (contact_id, related_id, type);
+create index if not exists idx_contact_relations_related on contact_relations (related_id);
+create index if not exists idx_contact_relations_pair on contact_relations (pair_id) where pair_id is not null;
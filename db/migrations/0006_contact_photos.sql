@@ -0,0 +1,9 @@
+create table if not exists contact_photos (
+    contact_id    bigint primary key references contacts(id) on delete cascade,
+    content_type  text not null,
+    size_bytes    bigint not null,
+    width         int not null,
+    height        int not null,
+    etag          text not null,
+    updated_at    timestamptz not null default now()
+);
@@ -0,0 +1,13 @@
+-- status = 0 — запрос ещё выполняется; ответ пишется по завершении
+create table if not exists idempotency_keys (
+    key           text primary key,
+    fingerprint   text not null,
+    status        int not null default 0,
+    header        jsonb not null default '{}',
+    body          bytea,
+    created_at    timestamptz not null default now(),
+    locked_until  timestamptz not null,
+    expires_at    timestamptz not null
+);
+
+create index if not exists idx_idempotency_keys_expires on idempotency_keys(expires_at);
@@ -0,0 +1,9 @@
+-- внешний ключ записи в системе-источнике (AD, ERP); null-пары не конфликтуют между собой
+alter table contacts add column if not exists external_source text;
+alter table contacts add column if not exists external_id text;
+
+alter table contacts drop constraint if exists contacts_external_pair;
+alter table contacts add constraint contacts_external_pair
+    check ((external_source is null) = (external_id is null));
+
+create unique index if not exists uq_contacts_external on contacts(external_source, external_id);
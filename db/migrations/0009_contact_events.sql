@@ -0,0 +1,28 @@
+-- журнал изменений контактов; id — позиция в ленте (Last-Event-ID у SSE-клиентов)
+create table if not exists contact_events (
+    id          bigserial primary key,
+    contact_id  bigint not null,
+    op          text not null check (op in ('created', 'updated', 'deleted')),
+    created_at  timestamptz not null default now()
+);
+
+create index if not exists idx_contact_events_created on contact_events(created_at);
+
+-- событие пишется в той же транзакции, что и изменение; уведомление уходит слушателям при коммите
+create or replace function contact_event() returns trigger as $$
+declare
+    ev contact_events;
+begin
+    insert into contact_events(contact_id, op)
+    values (
+        case when tg_op = 'DELETE' then old.id else new.id end,
+        case tg_op when 'INSERT' then 'created' when 'UPDATE' then 'updated' else 'deleted' end
+    )
+    returning * into ev;
+    perform pg_notify('contact_events', row_to_json(ev)::text);
+    return null;
+end; $$ language plpgsql;
+
+drop trigger if exists trg_contacts_events on contacts;
+create trigger trg_contacts_events after insert or update or delete on contacts
+    for each row execute function contact_event();
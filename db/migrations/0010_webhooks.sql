@@ -0,0 +1,64 @@
+-- transactional outbox: доменные события пишутся триггером в транзакции изменения контакта,
+-- диспетчер раскладывает их по подпискам и доставляет
+create table if not exists outbox (
+    id            bigserial primary key,
+    event_type    text not null,
+    contact_id    bigint not null,
+    payload       jsonb not null,
+    created_at    timestamptz not null default now(),
+    processed_at  timestamptz
+);
+
+create index if not exists idx_outbox_unprocessed on outbox(id) where processed_at is null;
+
+create table if not exists webhooks (
+    id          bigserial primary key,
+    url         text not null,
+    secret      text not null,
+    events      text[] not null default '{}', -- пусто — все события
+    active      boolean not null default true,
+    created_at  timestamptz not null default now(),
+    updated_at  timestamptz not null default now()
+);
+
+create trigger trg_webhooks_updated before update on webhooks for each row execute function set_updated_at();
+
+create table if not exists webhook_deliveries (
+    id               bigserial primary key,
+    webhook_id       bigint not null references webhooks(id) on delete cascade,
+    outbox_id        bigint not null references outbox(id) on delete cascade,
+    status           text not null default 'pending' check (status in ('pending', 'delivered', 'dead')),
+    attempts         int not null default 0,
+    next_attempt_at  timestamptz not null default now(),
+    last_status      int,
+    last_error       text,
+    created_at       timestamptz not null default now(),
+    delivered_at     timestamptz,
+    unique (webhook_id, outbox_id)
+);
+
+create index if not exists idx_webhook_deliveries_due on webhook_deliveries(next_attempt_at) where status = 'pending';
+create index if not exists idx_webhook_deliveries_log on webhook_deliveries(webhook_id, id desc);
+
+create or replace function contact_event() returns trigger as $$
+declare
+    ev contact_events;
+begin
+    insert into contact_events(contact_id, op)
+    values (
+        case when tg_op = 'DELETE' then old.id else new.id end,
+        case tg_op when 'INSERT' then 'created' when 'UPDATE' then 'updated' else 'deleted' end
+    )
+    returning * into ev;
+    perform pg_notify('contact_events', row_to_json(ev)::text);
+
+    insert into outbox(event_type, contact_id, payload)
+    values ('contact.' || ev.op, ev.contact_id, jsonb_build_object(
+        'id', ev.id,
+        'type', 'contact.' || ev.op,
+        'contact_id', ev.contact_id,
+        'occurred_at', ev.created_at,
+        'data', case when tg_op = 'DELETE' then null else to_jsonb(new) end
+    ));
+    return null;
+end; $$ language plpgsql;
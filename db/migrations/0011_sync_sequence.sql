@@ -0,0 +1,23 @@
+-- seq — позиция изменения для синхронизации клиентов. Выдаётся не триггером, а при чтении ленты,
+-- под advisory-блокировкой, только уже закоммиченным событиям: так seq растёт в порядке видимости,
+-- и клиент с токеном N не пропустит транзакцию, которая получила бы меньший номер, но закоммитилась позже.
+create sequence if not exists contact_change_seq;
+
+alter table contact_events add column if not exists seq bigint;
+create unique index if not exists uq_contact_events_seq on contact_events(seq);
+create index if not exists idx_contact_events_unsequenced on contact_events(id) where seq is null;
+
+-- purged_through — наибольший seq, удалённый по сроку хранения; токены старше него недействительны
+create table if not exists sync_state (
+    id              int primary key check (id = 1),
+    purged_through  bigint not null default 0
+);
+
+insert into sync_state(id) values (1) on conflict do nothing;
+
+-- лента SSE переходит с id журнала на seq: id выдаётся при вставке, и транзакция, закоммиченная позже,
+-- могла оказаться позади курсора клиента. Счётчик seq сдвигается за все выданные id, чтобы курсор,
+-- полученный до обновления (id), не пропустил новые события.
+select setval('contact_change_seq', greatest(
+    (select coalesce(max(id), 1) from contact_events),
+    (select last_value from contact_change_seq)));
@@ -0,0 +1,36 @@
+-- внешний ключ контакта на момент события: после удаления строки контакта клиентам синхронизации
+-- (CardDAV адресует карточки по своему имени, а не по id) нужно знать, какую запись убрать
+alter table contact_events add column if not exists external_source text;
+alter table contact_events add column if not exists external_id text;
+
+create or replace function contact_event() returns trigger as $$
+declare
+    ev contact_events;
+    c  contacts;
+begin
+    if tg_op = 'DELETE' then
+        c := old;
+    else
+        c := new;
+    end if;
+
+    insert into contact_events(contact_id, op, external_source, external_id)
+    values (
+        c.id,
+        case tg_op when 'INSERT' then 'created' when 'UPDATE' then 'updated' else 'deleted' end,
+        c.external_source,
+        c.external_id
+    )
+    returning * into ev;
+    perform pg_notify('contact_events', row_to_json(ev)::text);
+
+    insert into outbox(event_type, contact_id, payload)
+    values ('contact.' || ev.op, ev.contact_id, jsonb_build_object(
+        'id', ev.id,
+        'type', 'contact.' || ev.op,
+        'contact_id', ev.contact_id,
+        'occurred_at', ev.created_at,
+        'data', case when tg_op = 'DELETE' then null else to_jsonb(new) end
+    ));
+    return null;
+end; $$ language plpgsql;
@@ -0,0 +1,18 @@
+-- ключи API: хранится только sha256 ключа, сам ключ показывается один раз при выпуске
+create table if not exists api_keys (
+    id            bigserial primary key,
+    name          text not null,
+    prefix        text not null, -- начало ключа, чтобы узнать его в списке
+    key_hash      bytea not null unique,
+    scopes        text[] not null,
+    created_at    timestamptz not null default now(),
+    expires_at    timestamptz,
+    last_used_at  timestamptz,
+    revoked_at    timestamptz
+);
+
+-- ключи идемпотентности раздельны у каждого вызывающего: тот же Idempotency-Key от другого ключа API
+-- не получит чужой сохранённый ответ
+alter table idempotency_keys add column if not exists subject text not null default '';
+alter table idempotency_keys drop constraint if exists idempotency_keys_pkey;
+alter table idempotency_keys add primary key (subject, key);
@@ -0,0 +1,84 @@
+-- адресные книги (арендаторы): подразделения и дочерние компании не видят контакты друг друга.
+-- Существующие контакты попадают в книгу default.
+create table if not exists address_books (
+    id              bigserial primary key,
+    slug            text not null unique check (slug ~ '^[a-z0-9][a-z0-9-]{0,62}$'),
+    name            text not null,
+    default_region  text not null default '', -- ISO 3166-1 alpha-2 для номеров без кода страны
+    created_at      timestamptz not null default now(),
+    updated_at      timestamptz not null default now()
+);
+
+create trigger trg_address_books_updated before update on address_books for each row execute function set_updated_at();
+
+insert into address_books(id, slug, name) values (1, 'default', 'Default') on conflict do nothing;
+select setval(pg_get_serial_sequence('address_books', 'id'), (select max(id) from address_books));
+
+alter table contacts add column if not exists address_book_id bigint not null default 1 references address_books(id);
+create index if not exists idx_contacts_address_book on contacts(address_book_id, id);
+
+-- внешний ключ уникален в пределах книги: две книги синхронизируют одну и ту же HR-систему независимо
+drop index if exists uq_contacts_external;
+create unique index if not exists uq_contacts_external on contacts(address_book_id, external_source, external_id);
+
+-- ключ API, привязанный к книге, работает только с ней; null — книга из заголовка X-Address-Book
+alter table api_keys add column if not exists address_book_id bigint references address_books(id);
+
+-- ключи идемпотентности раздельны и по книгам
+alter table idempotency_keys add column if not exists address_book_id bigint not null default 1 references address_books(id) on delete cascade;
+alter table idempotency_keys drop constraint if exists idempotency_keys_pkey;
+alter table idempotency_keys add primary key (subject, address_book_id, key);
+
+-- реестр пользовательских полей — у каждой книги свой; существующие поля остаются книге default
+alter table custom_fields add column if not exists address_book_id bigint not null default 1 references address_books(id) on delete cascade;
+alter table custom_fields drop constraint if exists custom_fields_name_key;
+create unique index if not exists uq_custom_fields_name on custom_fields(address_book_id, name);
+
+-- книга события: лента и синхронизация отдают каждому только его книгу
+alter table contact_events add column if not exists address_book_id bigint;
+
+create or replace function contact_event() returns trigger as $$
+declare
+    ev contact_events;
+    c  contacts;
+begin
+    if tg_op = 'DELETE' then
+        c := old;
+    else
+        c := new;
+    end if;
+
+    insert into contact_events(contact_id, op, external_source, external_id, address_book_id)
+    values (
+        c.id,
+        case tg_op when 'INSERT' then 'created' when 'UPDATE' then 'updated' else 'deleted' end,
+        c.external_source,
+        c.external_id,
+        c.address_book_id
+    )
+    returning * into ev;
+    perform pg_notify('contact_events', row_to_json(ev)::text);
+
+    insert into outbox(event_type, contact_id, payload)
+    values ('contact.' || ev.op, ev.contact_id, jsonb_build_object(
+        'id', ev.id,
+        'type', 'contact.' || ev.op,
+        'contact_id', ev.contact_id,
+        'address_book_id', ev.address_book_id,
+        'occurred_at', ev.created_at,
+        'data', case when tg_op = 'DELETE' then null else to_jsonb(new) end
+    ));
+    return null;
+end; $$ language plpgsql;
+
+-- row-level security — второй рубеж на случай запроса без фильтра по книге. Книга соединения задаётся
+-- app.address_book_id (PG_ROW_SECURITY=true); пустое значение не видит ничего. Все книги — только
+-- с явным app.all_books = 'on' (системные задачи, сервис без PG_ROW_SECURITY, миграции).
+-- Суперпользователь и роли с BYPASSRLS политику не проверяют: сервису нужна отдельная роль.
+alter table contacts enable row level security;
+alter table contacts force row level security;
+
+drop policy if exists contacts_address_book on contacts;
+create policy contacts_address_book on contacts
+    using (current_setting('app.all_books', true) = 'on'
+           or address_book_id = nullif(current_setting('app.address_book_id', true), '')::bigint);
@@ -0,0 +1,116 @@
+-- доступ к отдельным контактам внутри книги: владелец, теги и выдачи ролей (viewer/editor/admin)
+-- пользователям, ключам и группам. Контакт без выдач (ни своих, ни через теги) виден всем читателям книги;
+-- с выдачами — только владельцу, получателям выдач и администраторам книги.
+alter table contacts add column if not exists owner text not null default ''; -- user:<sub> или apikey:<id>
+alter table contacts add column if not exists tags text[] not null default '{}';
+create index if not exists idx_contacts_tags on contacts using gin (tags);
+
+create table if not exists contact_grants (
+    contact_id  bigint not null references contacts(id) on delete cascade,
+    principal   text not null, -- user:<sub>, apikey:<id>, group:<роль из токена>
+    role        text not null check (role in ('viewer', 'editor', 'admin')),
+    primary key (contact_id, principal)
+);
+
+create table if not exists tag_grants (
+    address_book_id  bigint not null references address_books(id) on delete cascade,
+    tag              text not null,
+    principal        text not null,
+    role             text not null check (role in ('viewer', 'editor', 'admin')),
+    primary key (address_book_id, tag, principal)
+);
+
+-- contact_restricted — есть ли у контакта выдачи, свои или через теги
+create or replace function contact_restricted(c contacts) returns boolean language sql stable as $$
+    select exists (select 1 from contact_grants g where g.contact_id = c.id)
+        or exists (select 1 from tag_grants t where t.address_book_id = c.address_book_id and t.tag = any(c.tags))
+$$;
+
+-- contact_acl_role — старшая роль principals на контакте: владелец — admin; null — выдач нет
+create or replace function contact_acl_role(c contacts, principals text[]) returns text language sql stable as $$
+    select case when c.owner <> '' and c.owner = any(principals) then 'admin' else (
+        select r.role from (
+            select g.role from contact_grants g where g.contact_id = c.id and g.principal = any(principals)
+            union all
+            select t.role from tag_grants t
+            where t.address_book_id = c.address_book_id and t.tag = any(c.tags) and t.principal = any(principals)
+        ) r
+        order by case r.role when 'admin' then 3 when 'editor' then 2 else 1 end desc
+        limit 1)
+    end
+$$;
+
+-- contact_visible — principals null: администратор книги, видит всё
+create or replace function contact_visible(c contacts, principals text[]) returns boolean language sql stable as $$
+    select principals is null or not contact_restricted(c) or contact_acl_role(c, principals) is not null
+$$;
+
+-- contact_readers — кто, кроме администраторов книги, видит контакт: null — контакт без ограничений
+create or replace function contact_readers(c contacts) returns text[] language sql stable as $$
+    select case when contact_restricted(c) then array(
+        select c.owner where c.owner <> ''
+        union
+        select g.principal from contact_grants g where g.contact_id = c.id
+        union
+        select t.principal from tag_grants t where t.address_book_id = c.address_book_id and t.tag = any(c.tags)
+    ) end
+$$;
+
+-- событие запоминает, кому виден контакт в момент изменения: лента и синхронизация отдают его только им.
+-- События, записанные до этой миграции, остаются видимыми всем читателям книги.
+alter table contact_events add column if not exists readers text[];
+
+-- event_visible — principals как у contact_visible: null — администратор книги
+create or replace function event_visible(readers text[], principals text[]) returns boolean language sql immutable as $$
+    select readers is null or principals is null or readers && principals
+$$;
+
+create or replace function contact_event() returns trigger as $$
+declare
+    ev contact_events;
+    c  contacts;
+begin
+    if tg_op = 'DELETE' then
+        c := old;
+    else
+        c := new;
+    end if;
+
+    insert into contact_events(contact_id, op, external_source, external_id, address_book_id, readers)
+    values (
+        c.id,
+        case tg_op when 'INSERT' then 'created' when 'UPDATE' then 'updated' else 'deleted' end,
+        c.external_source,
+        c.external_id,
+        c.address_book_id,
+        contact_readers(c)
+    )
+    returning * into ev;
+    -- уведомление только будит хаб; readers в него не кладём — размер payload pg_notify ограничен
+    perform pg_notify('contact_events', json_build_object(
+        'id', ev.id, 'contact_id', ev.contact_id, 'address_book_id', ev.address_book_id, 'op', ev.op,
+        'created_at', ev.created_at)::text);
+
+    insert into outbox(event_type, contact_id, payload)
+    values ('contact.' || ev.op, ev.contact_id, jsonb_build_object(
+        'id', ev.id,
+        'type', 'contact.' || ev.op,
+        'contact_id', ev.contact_id,
+        'address_book_id', ev.address_book_id,
+        'occurred_at', ev.created_at,
+        'data', case when tg_op = 'DELETE' then null else to_jsonb(new) end
+    ));
+    if tg_op = 'DELETE' then
+        return old;
+    end if;
+    return null;
+end; $$ language plpgsql;
+
+-- удаление пишется до удаления строки: каскад снимает выдачи контакта раньше after-триггеров,
+-- и событие удаления ограниченного контакта стало бы видно всем
+drop trigger if exists trg_contacts_events on contacts;
+create trigger trg_contacts_events after insert or update on contacts
+    for each row execute function contact_event();
+drop trigger if exists trg_contacts_events_delete on contacts;
+create trigger trg_contacts_events_delete before delete on contacts
+    for each row execute function contact_event();
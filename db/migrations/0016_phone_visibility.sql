@@ -0,0 +1,11 @@
+-- видимость номера: public — всем, кто видит контакт (и LDAP, и gRPC); internal — редакторам и
+-- администраторам книги; private — администраторам книги и владельцу контакта. Остальным номер
+-- отдаётся замаскированным, и поиск по нему контакт не находит.
+alter table contact_phones add column if not exists visibility text not null default 'public'
+    check (visibility in ('public', 'internal', 'private'));
+
+-- phone_visible — levels null: видны все уровни; владелец контакта видит и свои private
+create or replace function phone_visible(visibility text, c contacts, levels text[], principals text[])
+    returns boolean language sql stable as $$
+    select levels is null or visibility = any(levels) or (c.owner <> '' and c.owner = any(principals))
+$$;
@@ -0,0 +1,16 @@
+-- журнал аудита: кто, когда и откуда читал, выгружал или менял контакты. Пишется пачками из буфера
+-- сервиса; записи, не попавшие в БД сразу, дописываются из файла-спула.
+create table if not exists audit_log (
+    id               bigserial primary key,
+    at               timestamptz not null,
+    address_book_id  bigint not null,
+    actor            text not null default '', -- user:<sub>, apikey:<id>; пусто — gRPC, LDAP
+    action           text not null,            -- contact.read, contact.export, ...
+    contact_ids      bigint[] not null default '{}',
+    request_id       text not null default '',
+    ip               text not null default ''
+);
+
+create index if not exists idx_audit_log_book_id on audit_log (address_book_id, id desc);
+create index if not exists idx_audit_log_actor on audit_log (actor, id desc);
+create index if not exists idx_audit_log_contacts on audit_log using gin (contact_ids);
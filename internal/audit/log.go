@@ -0,0 +1,220 @@
+// Package audit — асинхронная запись журнала аудита: вызовы сервиса кладут записи в ограниченный
+// буфер, фоновый цикл сбрасывает их в БД пачками. Записи, которым не хватило места в буфере или
+// которые не записались в БД, дописываются в файл-спул и переносятся в БД, когда она снова доступна.
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sunzhqr/phonebook/internal/config"
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+const (
+	flushInterval = time.Second
+	maxBatch      = 500
+	// stopTimeout — сколько при остановке ждать записи остатка буфера, прежде чем отправить его в спул
+	stopTimeout = 5 * time.Second
+)
+
+type Log struct {
+	lg    *logger.Logger
+	store service.AuditStore
+	ch    chan service.AuditEntry
+	spool string
+	mu    sync.Mutex // спул пишут и Record, и Run
+
+	stopMu  sync.RWMutex // после остановки Run записи в буфер не кладутся
+	stopped bool
+}
+
+func New(lg *logger.Logger, store service.AuditStore, cfg config.Audit) *Log {
+	return &Log{lg: lg, store: store, ch: make(chan service.AuditEntry, max(cfg.Buffer, 1)), spool: cfg.Spool}
+}
+
+// Record — не блокирует: при заполненном буфере или остановленном Run запись сразу уходит в спул
+func (l *Log) Record(e service.AuditEntry) {
+	l.stopMu.RLock()
+	sent := false
+	if !l.stopped {
+		select {
+		case l.ch <- e:
+			sent = true
+		default:
+		}
+	}
+	l.stopMu.RUnlock()
+	if !sent {
+		l.spill([]service.AuditEntry{e})
+	}
+}
+
+// Run — сбрасывает буфер в БД до отмены ctx; перед возвратом записывает остаток буфера.
+// Записи, пришедшие после отмены, Record пишет в спул — в БД они попадут при следующем запуске.
+func (l *Log) Run(ctx context.Context) {
+	// спул мог остаться от прошлого запуска
+	if err := l.replay(ctx); err != nil && ctx.Err() == nil {
+		l.lg.Warn("audit spool replay failed", logger.Err(err))
+	}
+	t := time.NewTicker(flushInterval)
+	defer t.Stop()
+	batch := make([]service.AuditEntry, 0, maxBatch)
+	for {
+		select {
+		case e := <-l.ch:
+			if batch = append(batch, e); len(batch) == maxBatch {
+				l.flush(ctx, batch)
+				batch = batch[:0]
+			}
+		case <-t.C:
+			l.flush(ctx, batch)
+			batch = batch[:0]
+			if err := l.replay(ctx); err != nil && ctx.Err() == nil {
+				l.lg.Warn("audit spool replay failed", logger.Err(err))
+			}
+		case <-ctx.Done():
+			l.stopMu.Lock()
+			l.stopped = true
+			l.stopMu.Unlock()
+		drain:
+			for {
+				select {
+				case e := <-l.ch:
+					batch = append(batch, e)
+				default:
+					break drain
+				}
+			}
+			stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), stopTimeout)
+			l.flush(stopCtx, batch)
+			cancel()
+			return
+		}
+	}
+}
+
+// flush — пачка в БД; не записалась — в спул
+func (l *Log) flush(ctx context.Context, batch []service.AuditEntry) {
+	if len(batch) == 0 {
+		return
+	}
+	if err := l.store.WriteAudit(ctx, batch); err != nil {
+		l.lg.Warn("audit write failed, spooling", logger.KV("count", len(batch)), logger.Err(err))
+		l.spill(batch)
+	}
+}
+
+// spill — дописывает записи в спул по одной JSON-строке и синхронизирует файл
+func (l *Log) spill(entries []service.AuditEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := appendEntries(l.spool, entries); err != nil {
+		l.lg.Error("audit entries lost", logger.KV("count", len(entries)), logger.Err(err))
+	}
+}
+
+func appendEntries(path string, entries []service.AuditEntry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			_ = f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// replay — переносит спул в БД пачками. Под блокировкой спул только переименовывается, запись в БД
+// идёт без неё: Record, которому не хватило буфера, не ждёт БД. При ошибке в файле остаются ещё не
+// записанные записи, и следующий вызов начинает с них.
+func (l *Log) replay(ctx context.Context) error {
+	path := l.spool + ".replay"
+	if err := l.takeSpool(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	entries, err := readEntries(path)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(entries); i += maxBatch {
+		if err := l.store.WriteAudit(ctx, entries[i:min(i+maxBatch, len(entries))]); err != nil {
+			if i > 0 {
+				if err := rewrite(path, entries[i:]); err != nil {
+					l.lg.Error("audit spool rewrite failed, entries may repeat", logger.Err(err))
+				}
+			}
+			return err
+		}
+	}
+	if len(entries) > 0 {
+		l.lg.Info("audit spool replayed", logger.KV("count", len(entries)))
+	}
+	return os.Remove(path)
+}
+
+// takeSpool — забирает спул на перенос в path; недоперенесённый прошлый раз path остаётся как есть
+func (l *Log) takeSpool(path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return os.Rename(l.spool, path)
+}
+
+// readEntries — записи спула; повреждённые строки (например, недописанная при сбое) пропускаются
+func readEntries(path string) ([]service.AuditEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var out []service.AuditEntry
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64<<10), 16<<20)
+	for sc.Scan() {
+		var e service.AuditEntry
+		if json.Unmarshal(sc.Bytes(), &e) == nil {
+			out = append(out, e)
+		}
+	}
+	return out, sc.Err()
+}
+
+// rewrite — атомарно заменяет спул оставшимися записями
+func rewrite(path string, entries []service.AuditEntry) error {
+	tmp := path + ".tmp"
+	_ = os.Remove(tmp)
+	if err := appendEntries(tmp, entries); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
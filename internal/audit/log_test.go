@@ -0,0 +1,168 @@
+package audit_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sunzhqr/phonebook/internal/audit"
+	"github.com/sunzhqr/phonebook/internal/config"
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+// memStore — БД в памяти; down — запись падает
+type memStore struct {
+	mu      sync.Mutex
+	down    bool
+	entries []service.AuditEntry
+}
+
+func (m *memStore) WriteAudit(_ context.Context, entries []service.AuditEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.down {
+		return errors.New("db is down")
+	}
+	m.entries = append(m.entries, entries...)
+	return nil
+}
+
+func (m *memStore) count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.entries)
+}
+
+// run — Run до stop; stop ждёт его завершения
+func run(l *audit.Log) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		l.Run(ctx)
+		close(done)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func TestLog_SpoolsWhileStoreIsDown(t *testing.T) {
+	cfg := config.Audit{Buffer: 2, Spool: filepath.Join(t.TempDir(), "audit", "spool")}
+	store := &memStore{down: true}
+	l := audit.New(logger.New("dev"), store, cfg)
+
+	// буфер на две записи: третья сразу уходит в спул, первые две — при остановке, БД недоступна
+	for i := range 3 {
+		l.Record(service.AuditEntry{Action: service.AuditContactRead, ContactIDs: []int64{int64(i + 1)}, RequestID: "r"})
+	}
+	run(l)()
+	if _, err := os.Stat(cfg.Spool); err != nil || store.count() != 0 {
+		t.Fatalf("spool: %v, stored %d", err, store.count())
+	}
+
+	// БД вернулась: спул переносится при запуске и удаляется
+	store.down = false
+	stop := run(audit.New(logger.New("dev"), store, cfg))
+	deadline := time.Now().Add(2 * time.Second)
+	for store.count() < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	stop()
+	if store.count() != 3 {
+		t.Fatalf("replayed %d entries, want 3", store.count())
+	}
+	seen := map[int64]bool{}
+	for _, e := range store.entries {
+		seen[e.ContactIDs[0]] = e.RequestID == "r"
+	}
+	if !seen[1] || !seen[2] || !seen[3] {
+		t.Fatalf("entries = %+v", store.entries)
+	}
+	if _, err := os.Stat(cfg.Spool); !os.IsNotExist(err) {
+		t.Fatalf("spool not removed: %v", err)
+	}
+}
+
+func TestLog_FlushesBufferOnStop(t *testing.T) {
+	store := &memStore{}
+	l := audit.New(logger.New("dev"), store, config.Audit{Buffer: 100, Spool: filepath.Join(t.TempDir(), "spool")})
+	stop := run(l)
+	for range 10 {
+		l.Record(service.AuditEntry{Action: service.AuditContactExport})
+	}
+	stop()
+	if store.count() != 10 {
+		t.Fatalf("stored %d entries, want 10", store.count())
+	}
+}
+
+// slowStore — запись в БД ждёт release
+type slowStore struct {
+	memStore
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *slowStore) WriteAudit(ctx context.Context, entries []service.AuditEntry) error {
+	select {
+	case s.started <- struct{}{}:
+	default:
+	}
+	<-s.release
+	return s.memStore.WriteAudit(ctx, entries)
+}
+
+func TestLog_ReplayDoesNotBlockSpill(t *testing.T) {
+	cfg := config.Audit{Buffer: 1, Spool: filepath.Join(t.TempDir(), "spool")}
+	// спул от прошлого запуска: БД была недоступна
+	down := audit.New(logger.New("dev"), &memStore{down: true}, cfg)
+	down.Record(service.AuditEntry{Action: service.AuditContactRead})
+	run(down)()
+
+	store := &slowStore{started: make(chan struct{}, 1), release: make(chan struct{})}
+	l := audit.New(logger.New("dev"), store, cfg)
+	stop := run(l)
+	<-store.started // перенос спула ждёт БД
+
+	// буфер на одну запись занят, вторая уходит в спул и не ждёт переноса
+	done := make(chan struct{})
+	go func() {
+		l.Record(service.AuditEntry{Action: service.AuditContactRead})
+		l.Record(service.AuditEntry{Action: service.AuditContactRead})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Record blocked by spool replay")
+	}
+	close(store.release)
+	stop()
+}
+
+func TestLog_RecordAfterStopIsSpooled(t *testing.T) {
+	cfg := config.Audit{Buffer: 10, Spool: filepath.Join(t.TempDir(), "spool")}
+	store := &memStore{}
+	l := audit.New(logger.New("dev"), store, cfg)
+	run(l)()
+	l.Record(service.AuditEntry{Action: service.AuditContactRead, RequestID: "late"})
+	if _, err := os.Stat(cfg.Spool); err != nil {
+		t.Fatalf("late entry not spooled: %v", err)
+	}
+
+	stop := run(audit.New(logger.New("dev"), store, cfg))
+	deadline := time.Now().Add(2 * time.Second)
+	for store.count() < 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	stop()
+	if store.count() != 1 || store.entries[0].RequestID != "late" {
+		t.Fatalf("entries = %+v", store.entries)
+	}
+}
@@ -0,0 +1,42 @@
+package blob_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/sunzhqr/phonebook/internal/blob"
+)
+
+func Test_Local_Put_Get_Delete(t *testing.T) {
+	ctx := context.Background()
+	st, err := blob.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+
+	if err := st.Put(ctx, "contacts/1/photo", bytes.NewReader([]byte("jpeg"))); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	rc, err := st.Get(ctx, "contacts/1/photo")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	data, _ := io.ReadAll(rc)
+	_ = rc.Close()
+	if string(data) != "jpeg" {
+		t.Fatalf("bad data: %q", data)
+	}
+
+	if err := st.Delete(ctx, "contacts/1/photo"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := st.Get(ctx, "contacts/1/photo"); !errors.Is(err, blob.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if err := st.Put(ctx, "../escape", bytes.NewReader(nil)); err == nil {
+		t.Fatalf("expected error for key outside root")
+	}
+}
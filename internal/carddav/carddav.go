@@ -0,0 +1,395 @@
+// Package carddav — сервер CardDAV (RFC 6352) для родных адресных книг (iOS, macOS, Thunderbird, DAVx5).
+// Одна книга "default" со всеми контактами. Карточки, заведённые клиентом, адресуются по его имени
+// ресурса (внешний ключ vcard.UIDSource), остальные — по id контакта: <id>.vcf.
+package carddav
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+	"github.com/sunzhqr/phonebook/internal/vcard"
+)
+
+const (
+	bookName = "default"
+	// maxCardBytes — ограничение тела PUT; карточки с фото бывают большими
+	maxCardBytes = 5 << 20
+	// maxReportBytes — ограничение тела PROPFIND/REPORT (multiget со списком href)
+	maxReportBytes = 4 << 20
+)
+
+type kind int
+
+const (
+	kindRoot kind = iota
+	kindPrincipal
+	kindHome
+	kindBook
+	kindCard
+)
+
+// resource — адресуемый ресурс; name — имя карточки без ".vcf"
+type resource struct {
+	kind kind
+	name string
+}
+
+type Handler struct {
+	lg       *logger.Logger
+	contacts service.ContactsService
+	exports  service.ExportService
+	photos   service.PhotosService
+	sync     service.SyncService
+	prefix   string
+}
+
+// New — prefix — путь, на котором смонтирован обработчик (например, "/dav").
+func New(lg *logger.Logger, prefix string, contacts service.ContactsService, exports service.ExportService, photos service.PhotosService, sync service.SyncService) *Handler {
+	return &Handler{lg: lg, contacts: contacts, exports: exports, photos: photos, sync: sync, prefix: strings.TrimSuffix(prefix, "/")}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	res, ok := h.resolve(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodOptions:
+		h.options(w, res)
+	case "PROPFIND":
+		h.propfind(w, r, res)
+	case "REPORT":
+		h.report(w, r, res)
+	case http.MethodGet, http.MethodHead:
+		h.get(w, r, res)
+	case http.MethodPut:
+		h.put(w, r, res)
+	case http.MethodDelete:
+		h.delete(w, r, res)
+	default:
+		w.Header().Set("Allow", allow(res))
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func allow(res resource) string {
+	switch res.kind {
+	case kindCard:
+		return "OPTIONS, PROPFIND, GET, HEAD, PUT, DELETE"
+	case kindBook:
+		return "OPTIONS, PROPFIND, REPORT"
+	}
+	return "OPTIONS, PROPFIND"
+}
+
+func (h *Handler) options(w http.ResponseWriter, res resource) {
+	w.Header().Set("DAV", "1, 3, addressbook")
+	w.Header().Set("Allow", allow(res))
+	w.WriteHeader(http.StatusOK)
+}
+
+// resolve — путь запроса в ресурс
+func (h *Handler) resolve(path string) (resource, bool) {
+	rest, ok := strings.CutPrefix(path, h.prefix)
+	if !ok {
+		return resource{}, false
+	}
+	rest = strings.Trim(rest, "/")
+	switch rest {
+	case "":
+		return resource{kind: kindRoot}, true
+	case "principal":
+		return resource{kind: kindPrincipal}, true
+	case "addressbooks":
+		return resource{kind: kindHome}, true
+	case "addressbooks/" + bookName:
+		return resource{kind: kindBook}, true
+	}
+	file, ok := strings.CutPrefix(rest, "addressbooks/"+bookName+"/")
+	if !ok || strings.Contains(file, "/") {
+		return resource{}, false
+	}
+	name, ok := strings.CutSuffix(file, ".vcf")
+	if !ok || name == "" {
+		return resource{}, false
+	}
+	return resource{kind: kindCard, name: name}, true
+}
+
+func (h *Handler) href(k kind) string {
+	switch k {
+	case kindPrincipal:
+		return h.prefix + "/principal/"
+	case kindHome:
+		return h.prefix + "/addressbooks/"
+	case kindBook:
+		return h.prefix + "/addressbooks/" + bookName + "/"
+	}
+	return h.prefix + "/"
+}
+
+func (h *Handler) cardHref(name string) string {
+	return h.href(kindBook) + pathEscape(name) + ".vcf"
+}
+
+// cardName — имя ресурса карточки контакта
+func cardName(c service.ContactOut) string {
+	if c.External != nil && c.External.Source == vcard.UIDSource {
+		return c.External.ID
+	}
+	return strconv.FormatInt(c.ID, 10)
+}
+
+// etag меняется с каждым изменением контакта
+func etag(c service.ContactOut) string {
+	return fmt.Sprintf(`"%d-%d"`, c.ID, c.UpdatedAt.UnixNano())
+}
+
+// lookup — контакт по имени ресурса: сначала внешний ключ клиента, затем id.
+// Контакт с ключом клиента по id не находится — у него своё имя.
+func (h *Handler) lookup(ctx context.Context, name string) (service.ContactOut, error) {
+	c, err := h.contacts.GetByExternal(ctx, vcard.UIDSource, name)
+	if err == nil || !isNotFound(err) {
+		return c, err
+	}
+	id, perr := strconv.ParseInt(name, 10, 64)
+	if perr != nil || id <= 0 {
+		return service.ContactOut{}, err
+	}
+	c, err = h.contacts.GetContact(ctx, id)
+	if err == nil && c.External != nil && c.External.Source == vcard.UIDSource {
+		return service.ContactOut{}, &service.Error{Code: http.StatusNotFound, Message: "contact not found"}
+	}
+	return c, err
+}
+
+func (h *Handler) get(w http.ResponseWriter, r *http.Request, res resource) {
+	if res.kind != kindCard {
+		w.Header().Set("Allow", allow(res))
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	c, err := h.lookup(r.Context(), res.name)
+	if err != nil {
+		h.writeSvcErr(w, err)
+		return
+	}
+	body, err := h.card(r.Context(), c)
+	if err != nil {
+		h.writeSvcErr(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", vcard.MediaType+"; charset=utf-8")
+	w.Header().Set("ETag", etag(c))
+	w.Header().Set("Last-Modified", c.UpdatedAt.UTC().Format(http.TimeFormat))
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(body)
+	}
+}
+
+// put — создание или замена карточки. If-None-Match: * — только создание, If-Match — только
+// поверх известной клиенту версии.
+func (h *Handler) put(w http.ResponseWriter, r *http.Request, res resource) {
+	if res.kind != kindCard {
+		w.Header().Set("Allow", allow(res))
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mt {
+	case vcard.MediaType, "text/x-vcard", "text/directory":
+	default:
+		writeError(w, http.StatusUnsupportedMediaType, "card:supported-address-data")
+		return
+	}
+	cards, err := vcard.Decode(http.MaxBytesReader(w, r.Body, maxCardBytes))
+	var mbe *http.MaxBytesError
+	switch {
+	case errors.As(err, &mbe):
+		writeError(w, http.StatusRequestEntityTooLarge, "card:max-resource-size")
+		return
+	case err != nil || len(cards) != 1:
+		writeError(w, http.StatusBadRequest, "card:valid-address-data")
+		return
+	}
+	card := cards[0]
+
+	ctx := r.Context()
+	old, err := h.lookup(ctx, res.name)
+	exists := err == nil
+	if err != nil && !isNotFound(err) {
+		h.writeSvcErr(w, err)
+		return
+	}
+	if !preconditions(r, old, exists) {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+
+	var c service.ContactOut
+	if exists && (old.External == nil || old.External.Source != vcard.UIDSource) {
+		c, err = h.contacts.UpdateContact(ctx, old.ID, replaceIn(card.Contact, old))
+	} else {
+		c, _, err = h.contacts.UpsertExternal(ctx, vcard.UIDSource, res.name, card.Contact)
+	}
+	if err != nil {
+		h.writeSvcErr(w, err)
+		return
+	}
+
+	switch {
+	case len(card.Photo) > 0:
+		if _, err := h.photos.PutPhoto(ctx, c.ID, bytes.NewReader(card.Photo)); err != nil {
+			h.lg.Warn("carddav photo rejected", logger.KV("contact_id", c.ID), logger.Err(err))
+		}
+	case exists && old.PhotoURL != "":
+		// клиент убрал фото из карточки
+		if err := h.photos.DeletePhoto(ctx, c.ID); err != nil && !isNotFound(err) {
+			h.lg.Warn("carddav photo delete failed", logger.KV("contact_id", c.ID), logger.Err(err))
+		}
+	}
+
+	w.Header().Set("ETag", etag(c))
+	if exists {
+		w.WriteHeader(http.StatusNoContent)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+func (h *Handler) delete(w http.ResponseWriter, r *http.Request, res resource) {
+	if res.kind != kindCard {
+		w.Header().Set("Allow", allow(res))
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	c, err := h.lookup(r.Context(), res.name)
+	if err != nil {
+		h.writeSvcErr(w, err)
+		return
+	}
+	if !preconditions(r, c, true) {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+	if err := h.contacts.DeleteContact(r.Context(), c.ID); err != nil {
+		h.writeSvcErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func preconditions(r *http.Request, c service.ContactOut, exists bool) bool {
+	if m := r.Header.Get("If-Match"); m != "" {
+		if !exists || (m != "*" && !etagListed(m, etag(c))) {
+			return false
+		}
+	}
+	if m := r.Header.Get("If-None-Match"); m != "" && exists {
+		if m == "*" || etagListed(m, etag(c)) {
+			return false
+		}
+	}
+	return true
+}
+
+func etagListed(list, tag string) bool {
+	for _, t := range strings.Split(list, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(t), "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// replaceIn — PUT заменяет карточку целиком. Пользовательские поля в vCard не передаются
+// и остаются как были; у контакта, привязанного к организации, ORG — её имя, связь не трогаем.
+func replaceIn(in service.ContactCreateIn, old service.ContactOut) service.ContactUpdateIn {
+	out := service.ContactUpdateIn{
+		FirstName:  &in.FirstName,
+		LastName:   &in.LastName,
+		JobTitle:   &in.JobTitle,
+		Department: &in.Department,
+		Phones:     &in.Phones,
+		Emails:     &in.Emails,
+		Addresses:  &in.Addresses,
+		Websites:   &in.Websites,
+	}
+	if old.Organization == nil {
+		out.Company = &in.Company
+	}
+	return out
+}
+
+// card — карточка vCard 3.0 (её понимают все клиенты) с миниатюрой фото
+func (h *Handler) card(ctx context.Context, c service.ContactOut) ([]byte, error) {
+	var b bytes.Buffer
+	if err := vcard.NewEncoder(&b, vcard.V3).Encode(c, h.photo(ctx, c)); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func (h *Handler) photo(ctx context.Context, c service.ContactOut) *vcard.Photo {
+	if c.PhotoURL == "" {
+		return nil
+	}
+	p, err := h.photos.GetPhoto(ctx, c.ID, true)
+	if err != nil {
+		return nil
+	}
+	defer p.Body.Close()
+	data, err := io.ReadAll(p.Body)
+	if err != nil {
+		h.lg.Warn("photo read failed", logger.KV("contact_id", c.ID), logger.Err(err))
+		return nil
+	}
+	return &vcard.Photo{ContentType: p.ContentType, Data: data}
+}
+
+// writeSvcErr — ошибки сервиса; невалидная карточка — предусловие valid-address-data
+func (h *Handler) writeSvcErr(w http.ResponseWriter, err error) {
+	var se *service.Error
+	if !errors.As(err, &se) {
+		h.lg.Error("carddav request failed", logger.Err(err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	switch se.Code {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		writeError(w, http.StatusForbidden, "card:valid-address-data")
+	default:
+		http.Error(w, se.Message, se.Code)
+	}
+}
+
+func isNotFound(err error) bool {
+	var se *service.Error
+	return errors.As(err, &se) && se.Code == http.StatusNotFound
+}
+
+// pathEscape — как url.PathEscape, но оставляет "@" и прочие допустимые в сегменте символы
+func pathEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("-._~!$&'()*+,;=:@", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
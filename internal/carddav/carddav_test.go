@@ -0,0 +1,475 @@
+package carddav_test
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sunzhqr/phonebook/internal/carddav"
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/repository"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+// memBook — контакты в памяти с журналом изменений; токен синхронизации — длина журнала
+type memBook struct {
+	mu       sync.Mutex
+	contacts map[int64]service.ContactOut
+	next     int64
+	log      []memChange
+}
+
+type memChange struct {
+	id  int64
+	ext *service.ExternalRef // на момент изменения; nil у удалённого без внешнего ключа
+	del bool
+}
+
+var errNotFound = &service.Error{Code: http.StatusNotFound, Message: "contact not found"}
+
+func newMemBook(in ...service.ContactCreateIn) *memBook {
+	b := &memBook{contacts: map[int64]service.ContactOut{}}
+	for _, c := range in {
+		_, _ = b.CreateContact(context.Background(), c)
+	}
+	return b
+}
+
+func (b *memBook) save(c service.ContactOut, in service.ContactCreateIn) service.ContactOut {
+	c.FirstName, c.LastName, c.Company = in.FirstName, in.LastName, in.Company
+	c.Phones, c.Emails = nil, nil
+	for _, p := range in.Phones {
+		c.Phones = append(c.Phones, service.PhoneOut{Label: p.Label, PhoneRaw: p.PhoneRaw})
+	}
+	for _, e := range in.Emails {
+		c.Emails = append(c.Emails, service.EmailOut{Label: e.Label, Email: e.Email})
+	}
+	// etag строится из времени изменения — оно должно расти и в быстрых тестах
+	c.UpdatedAt = time.Unix(0, int64(len(b.log)+1))
+	b.contacts[c.ID] = c
+	b.log = append(b.log, memChange{id: c.ID, ext: c.External})
+	return c
+}
+
+func (b *memBook) CreateContact(_ context.Context, in service.ContactCreateIn) (service.ContactOut, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.next++
+	return b.save(service.ContactOut{ID: b.next}, in), nil
+}
+
+func (b *memBook) GetContact(_ context.Context, id int64, _ ...string) (service.ContactOut, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c, ok := b.contacts[id]
+	if !ok {
+		return service.ContactOut{}, errNotFound
+	}
+	return c, nil
+}
+
+func (b *memBook) UpdateContact(_ context.Context, id int64, in service.ContactUpdateIn) (service.ContactOut, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c, ok := b.contacts[id]
+	if !ok {
+		return service.ContactOut{}, errNotFound
+	}
+	return b.save(c, service.ContactCreateIn{FirstName: *in.FirstName, LastName: *in.LastName, Phones: *in.Phones, Emails: *in.Emails}), nil
+}
+
+func (b *memBook) DeleteContact(_ context.Context, id int64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c, ok := b.contacts[id]
+	if !ok {
+		return errNotFound
+	}
+	delete(b.contacts, id)
+	b.log = append(b.log, memChange{id: id, ext: c.External, del: true})
+	return nil
+}
+
+func (b *memBook) ListContacts(context.Context, service.ListFilter) (service.ListOut, error) {
+	return service.ListOut{}, nil
+}
+
+func (b *memBook) ContactsByIDs(context.Context, []int64) ([]service.ContactOut, error) {
+	return nil, nil
+}
+
+func (b *memBook) Search(context.Context, string, int) ([]service.ContactOut, error) { return nil, nil }
+
+func (b *memBook) UpsertExternal(_ context.Context, source, extID string, in service.ContactCreateIn) (service.ContactOut, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, c := range b.contacts {
+		if c.External != nil && *c.External == (service.ExternalRef{Source: source, ID: extID}) {
+			return b.save(c, in), false, nil
+		}
+	}
+	b.next++
+	c := service.ContactOut{ID: b.next, External: &service.ExternalRef{Source: source, ID: extID}}
+	return b.save(c, in), true, nil
+}
+
+func (b *memBook) GetByExternal(_ context.Context, source, extID string) (service.ContactOut, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, c := range b.contacts {
+		if c.External != nil && *c.External == (service.ExternalRef{Source: source, ID: extID}) {
+			return c, nil
+		}
+	}
+	return service.ContactOut{}, errNotFound
+}
+
+func (b *memBook) sorted() []service.ContactOut {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make([]service.ContactOut, 0, len(b.contacts))
+	for _, c := range b.contacts {
+		out = append(out, c)
+	}
+	slices.SortFunc(out, func(x, y service.ContactOut) int { return int(x.ID - y.ID) })
+	return out
+}
+
+func (b *memBook) ExportContacts(_ context.Context, _ service.ListFilter, fn func(service.ContactOut) error) error {
+	for _, c := range b.sorted() {
+		if err := fn(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *memBook) ExportPhoneColumns(context.Context) (int, error) { return 1, nil }
+
+func (b *memBook) PutPhoto(context.Context, int64, io.Reader) (service.PhotoOut, error) {
+	return service.PhotoOut{}, nil
+}
+
+func (b *memBook) GetPhoto(context.Context, int64, bool) (service.PhotoData, error) {
+	return service.PhotoData{}, errNotFound
+}
+
+func (b *memBook) DeletePhoto(context.Context, int64) error { return nil }
+
+func (b *memBook) SyncToken(context.Context) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strconv.Itoa(len(b.log)), nil
+}
+
+func (b *memBook) Sync(ctx context.Context, token string) (service.SyncOut, error) {
+	if token == "" {
+		next, _ := b.SyncToken(ctx)
+		return service.SyncOut{Changed: b.sorted(), Deleted: []int64{}, NextToken: next}, nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	since, err := strconv.Atoi(token)
+	if err != nil || since > len(b.log) {
+		return service.SyncOut{}, &service.Error{Code: http.StatusBadRequest, Message: "bad sync token"}
+	}
+	out := service.SyncOut{Deleted: []int64{}, NextToken: strconv.Itoa(len(b.log))}
+	seen := map[int64]bool{}
+	for i := len(b.log) - 1; i >= since; i-- {
+		ch := b.log[i]
+		if seen[ch.id] {
+			continue
+		}
+		seen[ch.id] = true
+		if c, ok := b.contacts[ch.id]; ok && !ch.del {
+			out.Changed = append(out.Changed, c)
+			continue
+		}
+		out.Deleted = append(out.Deleted, ch.id)
+		if ch.ext != nil {
+			if out.DeletedExternal == nil {
+				out.DeletedExternal = map[int64]service.ExternalRef{}
+			}
+			out.DeletedExternal[ch.id] = *ch.ext
+		}
+	}
+	return out, nil
+}
+
+// multistatus — разбор ответа 207 для проверок
+type multistatus struct {
+	Responses []struct {
+		Href     string `xml:"DAV: href"`
+		Status   string `xml:"DAV: status"`
+		Propstat []struct {
+			Status string `xml:"DAV: status"`
+			Prop   struct {
+				Inner string `xml:",innerxml"`
+			} `xml:"DAV: prop"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+	SyncToken string `xml:"DAV: sync-token"`
+}
+
+// props — содержимое propstat с заданным статусом у ресурса href
+func (m multistatus) props(href string, status int) string {
+	for _, r := range m.Responses {
+		if r.Href != href {
+			continue
+		}
+		for _, ps := range r.Propstat {
+			if strings.Contains(ps.Status, " "+strconv.Itoa(status)+" ") {
+				return ps.Prop.Inner
+			}
+		}
+	}
+	return ""
+}
+
+func (m multistatus) hrefs() []string {
+	var out []string
+	for _, r := range m.Responses {
+		out = append(out, r.Href)
+	}
+	return out
+}
+
+func fixture(t *testing.T, name string) string {
+	t.Helper()
+	b, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func do(t *testing.T, h http.Handler, method, path, body string, hdr ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for i := 0; i+1 < len(hdr); i += 2 {
+		req.Header.Set(hdr[i], hdr[i+1])
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func parseMS(t *testing.T, rec *httptest.ResponseRecorder) multistatus {
+	t.Helper()
+	if rec.Code != http.StatusMultiStatus {
+		t.Fatalf("status=%d body=%s", rec.Code, rec.Body.String())
+	}
+	var ms multistatus
+	if err := xml.Unmarshal(rec.Body.Bytes(), &ms); err != nil {
+		t.Fatalf("bad multistatus: %v\n%s", err, rec.Body.String())
+	}
+	return ms
+}
+
+func ivan() service.ContactCreateIn {
+	return service.ContactCreateIn{FirstName: "Иван", LastName: "Иванов", Phones: []service.PhoneIn{{Label: "mobile", PhoneRaw: "+79161112233"}}}
+}
+
+func newHandler(b *memBook) *carddav.Handler {
+	return carddav.New(logger.New("dev"), "/dav", b, b, b, b)
+}
+
+// Discovery — путь iOS: корень → принципал → домашняя коллекция → книга
+func TestCardDAV_Discovery(t *testing.T) {
+	h := newHandler(newMemBook(ivan()))
+
+	ms := parseMS(t, do(t, h, "PROPFIND", "/dav/", fixture(t, "ios_propfind_root.xml"), "Depth", "0"))
+	if p := ms.props("/dav/", 200); !strings.Contains(p, "<d:href>/dav/principal/</d:href>") {
+		t.Fatalf("current-user-principal: %s", p)
+	}
+
+	ms = parseMS(t, do(t, h, "PROPFIND", "/dav/principal/", fixture(t, "ios_propfind_principal.xml"), "Depth", "0"))
+	if p := ms.props("/dav/principal/", 200); !strings.Contains(p, "<card:addressbook-home-set><d:href>/dav/addressbooks/</d:href>") {
+		t.Fatalf("addressbook-home-set: %s", p)
+	}
+	if p := ms.props("/dav/principal/", 404); !strings.Contains(p, "email-address-set") {
+		t.Fatalf("unknown props must be 404: %s", p)
+	}
+
+	ms = parseMS(t, do(t, h, "PROPFIND", "/dav/addressbooks/", fixture(t, "ios_propfind_home.xml"), "Depth", "1"))
+	book := ms.props("/dav/addressbooks/default/", 200)
+	for _, want := range []string{"<card:addressbook/>", "<cs:getctag>1</cs:getctag>", "<d:sync-token>urn:phonebook:sync:1</d:sync-token>", "<card:addressbook-multiget/>", "<d:write/>"} {
+		if !strings.Contains(book, want) {
+			t.Fatalf("book props lack %s: %s", want, book)
+		}
+	}
+
+	ms = parseMS(t, do(t, h, "PROPFIND", "/dav/addressbooks/default/", fixture(t, "thunderbird_propfind_book.xml"), "Depth", "1"))
+	if got := ms.hrefs(); !slices.Equal(got, []string{"/dav/addressbooks/default/", "/dav/addressbooks/default/1.vcf"}) {
+		t.Fatalf("members: %v", got)
+	}
+	if p := ms.props("/dav/addressbooks/default/1.vcf", 200); !strings.Contains(p, `<d:getetag>&#34;1-1&#34;</d:getetag>`) {
+		t.Fatalf("card etag: %s", p)
+	}
+
+	rec := do(t, h, http.MethodOptions, "/dav/addressbooks/default/", "")
+	if dav := rec.Header().Get("DAV"); !strings.Contains(dav, "addressbook") {
+		t.Fatalf("DAV header: %q", dav)
+	}
+}
+
+// PutSyncDelete — карточка с iPhone: создание, повтор с If-None-Match, multiget,
+// инкрементальная синхронизация и удаление по имени ресурса клиента
+func TestCardDAV_PutSyncDelete(t *testing.T) {
+	b := newMemBook(ivan())
+	h := newHandler(b)
+	const uid = "8F2C1E0A-4B6D-4E1F-9C3A-7D5B2E6F1A90"
+	cardPath := "/dav/addressbooks/default/" + uid + ".vcf"
+
+	ms := parseMS(t, do(t, h, "REPORT", "/dav/addressbooks/default/", fixture(t, "davx5_sync_initial.xml")))
+	if got := ms.hrefs(); !slices.Equal(got, []string{"/dav/addressbooks/default/1.vcf"}) {
+		t.Fatalf("initial sync: %v", got)
+	}
+	token := ms.SyncToken
+
+	rec := do(t, h, http.MethodPut, cardPath, fixture(t, "ios_put.vcf"), "Content-Type", "text/vcard; charset=utf-8", "If-None-Match", "*")
+	if rec.Code != http.StatusCreated || rec.Header().Get("ETag") == "" {
+		t.Fatalf("put: %d %s", rec.Code, rec.Body.String())
+	}
+	etag := rec.Header().Get("ETag")
+	if rec := do(t, h, http.MethodPut, cardPath, fixture(t, "ios_put.vcf"), "Content-Type", "text/vcard", "If-None-Match", "*"); rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("second create must fail: %d", rec.Code)
+	}
+
+	rec = do(t, h, http.MethodGet, cardPath, "")
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != etag {
+		t.Fatalf("get: %d etag=%q", rec.Code, rec.Header().Get("ETag"))
+	}
+	for _, want := range []string{"UID:" + uid, "FN:Пётр Петров", "petrov@example.com"} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Fatalf("card lacks %q:\n%s", want, rec.Body.String())
+		}
+	}
+
+	ms = parseMS(t, do(t, h, "REPORT", "/dav/addressbooks/default/", fixture(t, "ios_multiget.xml")))
+	if p := ms.props(cardPath, 200); !strings.Contains(p, "UID:"+uid) {
+		t.Fatalf("multiget address-data: %s", p)
+	}
+	if p := ms.props("/dav/addressbooks/default/1.vcf", 200); !strings.Contains(p, "FN:Иван Иванов") {
+		t.Fatalf("multiget by id: %s", p)
+	}
+	for _, r := range ms.Responses {
+		if r.Href == "/dav/addressbooks/default/404.vcf" && !strings.Contains(r.Status, "404") {
+			t.Fatalf("missing card status: %q", r.Status)
+		}
+	}
+
+	incremental := func() multistatus {
+		body := strings.Replace(fixture(t, "davx5_sync_initial.xml"), "<sync-token />", "<sync-token>"+token+"</sync-token>", 1)
+		ms := parseMS(t, do(t, h, "REPORT", "/dav/addressbooks/default/", body))
+		token = ms.SyncToken
+		return ms
+	}
+	ms = incremental()
+	if got := ms.hrefs(); !slices.Equal(got, []string{cardPath}) {
+		t.Fatalf("incremental sync: %v", got)
+	}
+
+	if rec := do(t, h, http.MethodDelete, cardPath, "", "If-Match", `"stale"`); rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("delete with stale etag: %d", rec.Code)
+	}
+	if rec := do(t, h, http.MethodDelete, cardPath, "", "If-Match", etag); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: %d", rec.Code)
+	}
+	ms = incremental()
+	if len(ms.Responses) != 1 || ms.Responses[0].Href != cardPath || !strings.Contains(ms.Responses[0].Status, "404") {
+		t.Fatalf("deletion must be reported by client href: %+v", ms.Responses)
+	}
+
+	body := strings.Replace(fixture(t, "davx5_sync_initial.xml"), "<sync-token />", "<sync-token>http://other/1</sync-token>", 1)
+	if rec := do(t, h, "REPORT", "/dav/addressbooks/default/", body); rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "valid-sync-token") {
+		t.Fatalf("foreign token: %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCardDAV_Query(t *testing.T) {
+	acme := service.ContactCreateIn{FirstName: "Анна", LastName: "Смирнова", Phones: []service.PhoneIn{{PhoneRaw: "+79160000001"}},
+		Emails: []service.EmailIn{{Email: "anna@acme.org"}}}
+	other := service.ContactCreateIn{FirstName: "Олег", LastName: "Сидоров", Phones: []service.PhoneIn{{PhoneRaw: "+79160000002"}}}
+	h := newHandler(newMemBook(ivan(), acme, other))
+
+	ms := parseMS(t, do(t, h, "REPORT", "/dav/addressbooks/default/", fixture(t, "thunderbird_query.xml"), "Depth", "1"))
+	if got := ms.hrefs(); !slices.Equal(got, []string{"/dav/addressbooks/default/1.vcf", "/dav/addressbooks/default/2.vcf"}) {
+		t.Fatalf("query: %v", got)
+	}
+}
+
+// memContact — один контакт в памяти за настоящим service.Service
+type memContact struct {
+	repository.ContactsRepository
+	c repository.Contact
+}
+
+func (m *memContact) Get(_ context.Context, id int64) (repository.Contact, error) {
+	if id != m.c.ID {
+		return repository.Contact{}, repository.ErrNotFound
+	}
+	return m.c, nil
+}
+
+func (m *memContact) GetByExternal(context.Context, string, string) (repository.Contact, error) {
+	return repository.Contact{}, repository.ErrNotFound
+}
+
+func (m *memContact) Update(ctx context.Context, id int64, p repository.ContactPatch) (repository.Contact, error) {
+	if p.Phones != nil {
+		m.c.Phones = nil
+		for _, ph := range *p.Phones {
+			m.c.Phones = append(m.c.Phones, repository.Phone{Label: ph.Label, PhoneRaw: ph.PhoneRaw, PhoneE164: ph.PhoneE164,
+				PhoneDigits: ph.PhoneDigits, IsPrimary: ph.IsPrimary, Visibility: ph.Visibility})
+		}
+	}
+	m.c.UpdatedAt = m.c.UpdatedAt.Add(time.Second)
+	return m.Get(ctx, id)
+}
+
+type openACL struct{ repository.ACLRepository }
+
+func (openACL) Access(context.Context, int64) (repository.ContactAccess, error) {
+	return repository.ContactAccess{}, nil
+}
+
+// vCard не несёт видимость номера: PUT администратора не должен открыть private-номер
+func TestCardDAV_PutKeepsPhoneVisibility(t *testing.T) {
+	repo := &memContact{c: repository.Contact{ID: 1, FirstName: "Aigerim", LastName: "Nurova", UpdatedAt: time.Unix(1, 0), Phones: []repository.Phone{
+		{Label: "work", PhoneRaw: "+77010000001", PhoneE164: "+77010000001", PhoneDigits: "77010000001", IsPrimary: true, Visibility: "public"},
+		{Label: "home", PhoneRaw: "+77010000002", PhoneE164: "+77010000002", PhoneDigits: "77010000002", Visibility: "private"},
+	}}}
+	svc := service.New(logger.New("dev"), &repository.Repos{Contacts: repo, ACL: openACL{}}, nil, nil)
+	dav := carddav.New(logger.New("dev"), "/dav", svc, svc, svc, svc)
+	admin := service.Principal{Subject: "user:admin", Scopes: []string{service.ScopeAdmin}}
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dav.ServeHTTP(w, r.WithContext(service.WithPrincipal(r.Context(), admin)))
+	})
+
+	card := "BEGIN:VCARD\r\nVERSION:3.0\r\nN:Nurova;Aigerim;;;\r\nFN:Aigerim Nurova\r\n" +
+		"TEL;TYPE=WORK,PREF:+77010000001\r\nTEL;TYPE=HOME:+77010000002\r\nTEL;TYPE=CELL:+77010000003\r\nEND:VCARD\r\n"
+	rec := do(t, h, http.MethodPut, "/dav/addressbooks/default/1.vcf", card, "Content-Type", "text/vcard")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("put: %d %s", rec.Code, rec.Body.String())
+	}
+	got := map[string]string{}
+	for _, ph := range repo.c.Phones {
+		got[ph.PhoneDigits] = ph.Visibility
+	}
+	// у нового номера видимости нет — репозиторий запишет public
+	want := map[string]string{"77010000001": "public", "77010000002": "private", "77010000003": ""}
+	if !maps.Equal(got, want) {
+		t.Fatalf("stored visibility = %v, want %v", got, want)
+	}
+}
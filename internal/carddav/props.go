@@ -0,0 +1,219 @@
+package carddav
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+// propCtx — состояние одного ответа: версия книги запрашивается один раз и только если нужна
+type propCtx struct {
+	h     *Handler
+	ctx   context.Context
+	token string
+	err   error
+}
+
+func (p *propCtx) syncToken() string {
+	if p.token == "" && p.err == nil {
+		p.token, p.err = p.h.sync.SyncToken(p.ctx)
+	}
+	return p.token
+}
+
+// allprop — свойства, которые отдаются без явного списка; address-data в их число не входит
+var allprop = map[kind][]xml.Name{
+	kindRoot:      {{Space: nsDAV, Local: "resourcetype"}, {Space: nsDAV, Local: "displayname"}, {Space: nsDAV, Local: "current-user-principal"}},
+	kindPrincipal: {{Space: nsDAV, Local: "resourcetype"}, {Space: nsDAV, Local: "displayname"}, {Space: nsDAV, Local: "current-user-principal"}, {Space: nsCard, Local: "addressbook-home-set"}},
+	kindHome:      {{Space: nsDAV, Local: "resourcetype"}, {Space: nsDAV, Local: "displayname"}},
+	kindBook: {
+		{Space: nsDAV, Local: "resourcetype"}, {Space: nsDAV, Local: "displayname"}, {Space: nsCS, Local: "getctag"},
+		{Space: nsDAV, Local: "sync-token"}, {Space: nsCard, Local: "supported-address-data"},
+	},
+	kindCard: {{Space: nsDAV, Local: "resourcetype"}, {Space: nsDAV, Local: "getetag"}, {Space: nsDAV, Local: "getcontenttype"}, {Space: nsDAV, Local: "getlastmodified"}},
+}
+
+// props — значения запрошенных свойств ресурса; c — контакт для карточки
+func (p *propCtx) props(k kind, c *service.ContactOut, names []xml.Name) (found []string, missing []xml.Name) {
+	if names == nil {
+		names = allprop[k]
+	}
+	for _, n := range names {
+		v, ok := p.prop(k, c, n)
+		if ok {
+			found = append(found, v)
+		} else {
+			missing = append(missing, n)
+		}
+	}
+	return found, missing
+}
+
+func (p *propCtx) prop(k kind, c *service.ContactOut, n xml.Name) (string, bool) {
+	h := p.h
+	switch n.Space + " " + n.Local {
+	case nsDAV + " resourcetype":
+		switch k {
+		case kindPrincipal:
+			return elem("d:resourcetype", "<d:principal/>"), true
+		case kindBook:
+			return elem("d:resourcetype", "<d:collection/><card:addressbook/>"), true
+		case kindCard:
+			return elem("d:resourcetype", ""), true
+		}
+		return elem("d:resourcetype", "<d:collection/>"), true
+	case nsDAV + " displayname":
+		switch k {
+		case kindBook:
+			return elem("d:displayname", "Phonebook"), true
+		case kindCard:
+			return elem("d:displayname", escape(strings.TrimSpace(c.FirstName+" "+c.LastName))), true
+		case kindHome:
+			return elem("d:displayname", "Address books"), true
+		}
+		return elem("d:displayname", "phonebook"), true
+	case nsDAV + " current-user-principal":
+		return elem("d:current-user-principal", elem("d:href", h.href(kindPrincipal))), true
+	case nsDAV + " principal-URL":
+		if k == kindPrincipal {
+			return elem("d:principal-URL", elem("d:href", h.href(kindPrincipal))), true
+		}
+	case nsCard + " addressbook-home-set":
+		if k == kindRoot || k == kindPrincipal {
+			return elem("card:addressbook-home-set", elem("d:href", h.href(kindHome))), true
+		}
+	case nsDAV + " current-user-privilege-set":
+		if k == kindBook || k == kindCard {
+			var b strings.Builder
+			for _, priv := range []string{"d:read", "d:write", "d:write-content", "d:write-properties", "d:bind", "d:unbind"} {
+				b.WriteString(elem("d:privilege", elem(priv, "")))
+			}
+			return elem("d:current-user-privilege-set", b.String()), true
+		}
+	case nsDAV + " supported-report-set":
+		if k == kindBook {
+			var b strings.Builder
+			for _, rep := range []string{"card:addressbook-multiget", "card:addressbook-query", "d:sync-collection"} {
+				b.WriteString(elem("d:supported-report", elem("d:report", elem(rep, ""))))
+			}
+			return elem("d:supported-report-set", b.String()), true
+		}
+	case nsCard + " supported-address-data":
+		if k == kindBook {
+			return elem("card:supported-address-data", `<card:address-data-type content-type="text/vcard" version="3.0"/>`), true
+		}
+	case nsCard + " addressbook-description":
+		if k == kindBook {
+			return elem("card:addressbook-description", "Все контакты телефонной книги"), true
+		}
+	case nsCard + " max-resource-size":
+		if k == kindBook {
+			return elem("card:max-resource-size", strconv.Itoa(maxCardBytes)), true
+		}
+	case nsCS + " getctag", nsDAV + " sync-token":
+		if k == kindBook {
+			tok := p.syncToken()
+			if n.Space == nsCS {
+				return elem("cs:getctag", escape(tok)), p.err == nil
+			}
+			return elem("d:sync-token", escape(syncURI(tok))), p.err == nil
+		}
+	case nsDAV + " getetag":
+		if k == kindCard {
+			return elem("d:getetag", escape(etag(*c))), true
+		}
+	case nsDAV + " getcontenttype":
+		if k == kindCard {
+			return elem("d:getcontenttype", "text/vcard; charset=utf-8"), true
+		}
+	case nsDAV + " getlastmodified":
+		if k == kindCard {
+			return elem("d:getlastmodified", c.UpdatedAt.UTC().Format(http.TimeFormat)), true
+		}
+	case nsCard + " address-data":
+		if k == kindCard {
+			body, err := h.card(p.ctx, *c)
+			if err != nil {
+				p.err = err
+				return "", false
+			}
+			return elem("card:address-data", escape(string(body))), true
+		}
+	}
+	return "", false
+}
+
+// syncTokenPrefix — токен в WebDAV должен быть URI; внутри — токен сервиса синхронизации
+const syncTokenPrefix = "urn:phonebook:sync:"
+
+func syncURI(token string) string { return syncTokenPrefix + token }
+
+// propfind — Depth: 0 — сам ресурс, 1 (и infinity) — с прямыми потомками.
+func (h *Handler) propfind(w http.ResponseWriter, r *http.Request, res resource) {
+	var names []xml.Name
+	root, err := parseXML(http.MaxBytesReader(w, r.Body, maxReportBytes))
+	switch {
+	case errors.Is(err, errEmptyBody):
+	case err != nil || !root.is(nsDAV, "propfind"):
+		http.Error(w, "bad propfind body", http.StatusBadRequest)
+		return
+	case root.child(nsDAV, "propname") != nil:
+		http.Error(w, "propname is not supported", http.StatusForbidden)
+		return
+	default:
+		names = propNames(root)
+	}
+	depth := r.Header.Get("Depth")
+	if depth == "" {
+		depth = "infinity"
+	}
+
+	ctx := r.Context()
+	pc := &propCtx{h: h, ctx: ctx}
+	ms := newMultistatus()
+	if res.kind == kindCard {
+		c, err := h.lookup(ctx, res.name)
+		if err != nil {
+			h.writeSvcErr(w, err)
+			return
+		}
+		found, missing := pc.props(kindCard, &c, names)
+		ms.response(h.cardHref(res.name), found, missing)
+	} else {
+		found, missing := pc.props(res.kind, nil, names)
+		ms.response(h.href(res.kind), found, missing)
+	}
+
+	if depth != "0" {
+		switch res.kind {
+		case kindRoot:
+			for _, k := range []kind{kindPrincipal, kindHome} {
+				found, missing := pc.props(k, nil, names)
+				ms.response(h.href(k), found, missing)
+			}
+		case kindHome:
+			found, missing := pc.props(kindBook, nil, names)
+			ms.response(h.href(kindBook), found, missing)
+		case kindBook:
+			err := h.exports.ExportContacts(ctx, service.ListFilter{}, func(c service.ContactOut) error {
+				found, missing := pc.props(kindCard, &c, names)
+				ms.response(h.cardHref(cardName(c)), found, missing)
+				return pc.err
+			})
+			if err != nil {
+				h.writeSvcErr(w, err)
+				return
+			}
+		}
+	}
+	if pc.err != nil {
+		h.writeSvcErr(w, pc.err)
+		return
+	}
+	ms.write(w)
+}
@@ -0,0 +1,284 @@
+package carddav
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/sunzhqr/phonebook/internal/service"
+	"github.com/sunzhqr/phonebook/internal/vcard"
+)
+
+// errLimit — достигнут limit/nresults запроса; не ошибка, а сигнал остановить перебор
+var errLimit = errors.New("result limit reached")
+
+func (h *Handler) report(w http.ResponseWriter, r *http.Request, res resource) {
+	if res.kind != kindBook {
+		writeError(w, http.StatusForbidden, "d:supported-report")
+		return
+	}
+	root, err := parseXML(http.MaxBytesReader(w, r.Body, maxReportBytes))
+	if err != nil {
+		http.Error(w, "bad report body", http.StatusBadRequest)
+		return
+	}
+	switch {
+	case root.is(nsCard, "addressbook-multiget"):
+		h.multiget(w, r, root)
+	case root.is(nsCard, "addressbook-query"):
+		h.query(w, r, root)
+	case root.is(nsDAV, "sync-collection"):
+		h.syncCollection(w, r, root)
+	default:
+		writeError(w, http.StatusForbidden, "d:supported-report")
+	}
+}
+
+// multiget — карточки по списку href; не найденные — 404 в своём response
+func (h *Handler) multiget(w http.ResponseWriter, r *http.Request, root *node) {
+	ctx := r.Context()
+	names := propNames(root)
+	pc := &propCtx{h: h, ctx: ctx}
+	ms := newMultistatus()
+	for _, hn := range root.all(nsDAV, "href") {
+		href := hn.value()
+		u, err := url.Parse(href)
+		if err != nil {
+			ms.status(href, http.StatusBadRequest)
+			continue
+		}
+		res, ok := h.resolve(u.Path)
+		if !ok || res.kind != kindCard {
+			ms.status(href, http.StatusNotFound)
+			continue
+		}
+		c, err := h.lookup(ctx, res.name)
+		switch {
+		case isNotFound(err):
+			ms.status(href, http.StatusNotFound)
+			continue
+		case err != nil:
+			h.writeSvcErr(w, err)
+			return
+		}
+		found, missing := pc.props(kindCard, &c, names)
+		ms.response(href, found, missing)
+		if pc.err != nil {
+			h.writeSvcErr(w, pc.err)
+			return
+		}
+	}
+	ms.write(w)
+}
+
+// query — карточки, подходящие под фильтр (RFC 6352, 10.5). Сравнение — без учёта регистра
+// (i;unicode-casemap); param-filter не поддерживается и считается выполненным.
+func (h *Handler) query(w http.ResponseWriter, r *http.Request, root *node) {
+	ctx := r.Context()
+	names := propNames(root)
+	filter := root.child(nsCard, "filter")
+	limit := limitOf(root, nsCard)
+	pc := &propCtx{h: h, ctx: ctx}
+	ms := newMultistatus()
+	n := 0
+	err := h.exports.ExportContacts(ctx, service.ListFilter{}, func(c service.ContactOut) error {
+		if !matchFilter(c, filter) {
+			return nil
+		}
+		if limit > 0 && n == limit {
+			return errLimit
+		}
+		n++
+		found, missing := pc.props(kindCard, &c, names)
+		ms.response(h.cardHref(cardName(c)), found, missing)
+		return pc.err
+	})
+	switch {
+	case errors.Is(err, errLimit):
+		ms.status(h.href(kindBook), http.StatusInsufficientStorage)
+	case err != nil:
+		h.writeSvcErr(w, err)
+		return
+	}
+	ms.write(w)
+}
+
+// syncCollection — изменения с токена клиента (RFC 6578): изменённые карточки со свойствами,
+// удалённые — 404. Пустой токен — вся книга. Ответ режется по страницам сервиса синхронизации;
+// если limit не вместил всё, книга помечается 507, а токен указывает на продолжение.
+func (h *Handler) syncCollection(w http.ResponseWriter, r *http.Request, root *node) {
+	ctx := r.Context()
+	names := propNames(root)
+	limit := limitOf(root, nsDAV)
+
+	token := root.child(nsDAV, "sync-token").value()
+	if token != "" {
+		var ok bool
+		if token, ok = strings.CutPrefix(token, syncTokenPrefix); !ok || token == "" {
+			writeError(w, http.StatusForbidden, "d:valid-sync-token")
+			return
+		}
+	}
+
+	pc := &propCtx{h: h, ctx: ctx}
+	ms := newMultistatus()
+	n := 0
+	for {
+		out, err := h.sync.Sync(ctx, token)
+		var se *service.Error
+		if errors.As(err, &se) && (se.Code == http.StatusBadRequest || se.Code == http.StatusGone) {
+			writeError(w, http.StatusForbidden, "d:valid-sync-token")
+			return
+		}
+		if err != nil {
+			h.writeSvcErr(w, err)
+			return
+		}
+		for i := range out.Changed {
+			c := &out.Changed[i]
+			found, missing := pc.props(kindCard, c, names)
+			ms.response(h.cardHref(cardName(*c)), found, missing)
+		}
+		for _, id := range out.Deleted {
+			name := strconv.FormatInt(id, 10)
+			if ref, ok := out.DeletedExternal[id]; ok && ref.Source == vcard.UIDSource {
+				name = ref.ID
+			}
+			ms.status(h.cardHref(name), http.StatusNotFound)
+		}
+		if pc.err != nil {
+			h.writeSvcErr(w, pc.err)
+			return
+		}
+		token = out.NextToken
+		n += len(out.Changed) + len(out.Deleted)
+		if !out.HasMore {
+			break
+		}
+		if limit > 0 && n >= limit {
+			ms.status(h.href(kindBook), http.StatusInsufficientStorage)
+			break
+		}
+	}
+	ms.syncToken(syncURI(token))
+	ms.write(w)
+}
+
+// limitOf — <limit><nresults>N</nresults></limit>; 0 — без ограничения
+func limitOf(root *node, ns string) int {
+	n, err := strconv.Atoi(root.child(ns, "limit").child(ns, "nresults").value())
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// matchFilter — test="anyof" (по умолчанию) или "allof" над prop-filter; без них подходит всё
+func matchFilter(c service.ContactOut, filter *node) bool {
+	pfs := filter.all(nsCard, "prop-filter")
+	if len(pfs) == 0 {
+		return true
+	}
+	all := filter.attr("test") == "allof"
+	for _, pf := range pfs {
+		ok := matchProp(c, pf)
+		if ok && !all {
+			return true
+		}
+		if !ok && all {
+			return false
+		}
+	}
+	return all
+}
+
+func matchProp(c service.ContactOut, pf *node) bool {
+	values := propValues(c, strings.ToUpper(pf.attr("name")))
+	if pf.child(nsCard, "is-not-defined") != nil {
+		return len(values) == 0
+	}
+	tms := pf.all(nsCard, "text-match")
+	if len(tms) == 0 {
+		return len(values) > 0
+	}
+	all := pf.attr("test") == "allof"
+	for _, tm := range tms {
+		ok := matchText(values, tm)
+		if ok && !all {
+			return true
+		}
+		if !ok && all {
+			return false
+		}
+	}
+	return all
+}
+
+func matchText(values []string, tm *node) bool {
+	needle := strings.ToLower(tm.value())
+	negate := tm.attr("negate-condition") == "yes"
+	for _, v := range values {
+		v = strings.ToLower(v)
+		var ok bool
+		switch tm.attr("match-type") {
+		case "equals":
+			ok = v == needle
+		case "starts-with":
+			ok = strings.HasPrefix(v, needle)
+		case "ends-with":
+			ok = strings.HasSuffix(v, needle)
+		default:
+			ok = strings.Contains(v, needle)
+		}
+		if ok != negate {
+			return true
+		}
+	}
+	return false
+}
+
+// propValues — значения свойства vCard контакта в том виде, в каком их видит клиент
+func propValues(c service.ContactOut, name string) []string {
+	var out []string
+	switch name {
+	case "FN":
+		out = append(out, strings.TrimSpace(c.FirstName+" "+c.LastName))
+	case "N":
+		out = append(out, c.LastName+";"+c.FirstName+";;;")
+	case "UID":
+		out = append(out, vcard.UID(c))
+	case "ORG":
+		if c.Company != "" || c.Department != "" {
+			out = append(out, c.Company+";"+c.Department)
+		}
+	case "TITLE":
+		if c.JobTitle != "" {
+			out = append(out, c.JobTitle)
+		}
+	case "TEL":
+		for _, p := range c.Phones {
+			if p.Redacted {
+				continue // поиск по скрытому номеру не должен находить контакт
+			}
+			out = append(out, p.PhoneRaw)
+			if p.PhoneE164 != "" && p.PhoneE164 != p.PhoneRaw {
+				out = append(out, p.PhoneE164)
+			}
+		}
+	case "EMAIL":
+		for _, m := range c.Emails {
+			out = append(out, m.Email)
+		}
+	case "URL":
+		for _, u := range c.Websites {
+			out = append(out, u.URL)
+		}
+	case "ADR":
+		for _, a := range c.Addresses {
+			out = append(out, strings.Join([]string{"", "", a.Street, a.City, a.Region, a.PostalCode, a.Country}, ";"))
+		}
+	}
+	return out
+}
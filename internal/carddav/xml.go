@@ -0,0 +1,216 @@
+package carddav
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	nsDAV  = "DAV:"
+	nsCard = "urn:ietf:params:xml:ns:carddav"
+	nsCS   = "http://calendarserver.org/ns/"
+)
+
+// prefixes — префиксы известных пространств имён в ответах
+var prefixes = map[string]string{nsDAV: "d", nsCard: "card", nsCS: "cs"}
+
+// node — элемент тела запроса. Запросы клиентов небольшие и разнообразны по форме
+// (allprop, prop, фильтры), поэтому разбираем их в дерево, а не в структуры.
+type node struct {
+	name     xml.Name
+	attrs    []xml.Attr
+	children []*node
+	text     string
+}
+
+var errEmptyBody = errors.New("empty body")
+
+// parseXML — корневой элемент тела; пустое тело — errEmptyBody.
+func parseXML(r io.Reader) (*node, error) {
+	dec := xml.NewDecoder(r)
+	var stack []*node
+	var root *node
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &node{name: t.Name, attrs: t.Attr}
+			if len(stack) > 0 {
+				p := stack[len(stack)-1]
+				p.children = append(p.children, n)
+			} else if root == nil {
+				root = n
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		}
+	}
+	if root == nil {
+		return nil, errEmptyBody
+	}
+	return root, nil
+}
+
+func (n *node) is(ns, local string) bool {
+	return n != nil && n.name.Space == ns && n.name.Local == local
+}
+
+// child — первый дочерний элемент с таким именем или nil
+func (n *node) child(ns, local string) *node {
+	if n == nil {
+		return nil
+	}
+	for _, c := range n.children {
+		if c.is(ns, local) {
+			return c
+		}
+	}
+	return nil
+}
+
+func (n *node) all(ns, local string) []*node {
+	if n == nil {
+		return nil
+	}
+	var out []*node
+	for _, c := range n.children {
+		if c.is(ns, local) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func (n *node) attr(local string) string {
+	for _, a := range n.attrs {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func (n *node) value() string {
+	if n == nil {
+		return ""
+	}
+	return strings.TrimSpace(n.text)
+}
+
+// propNames — запрошенные свойства из <d:prop>; nil — allprop (или пустой запрос)
+func propNames(root *node) []xml.Name {
+	prop := root.child(nsDAV, "prop")
+	if prop == nil {
+		return nil
+	}
+	names := make([]xml.Name, 0, len(prop.children))
+	for _, c := range prop.children {
+		names = append(names, c.name)
+	}
+	return names
+}
+
+// multistatus — ответ 207. Свойства пишутся готовыми фрагментами XML с префиксами из prefixes.
+type multistatus struct {
+	b bytes.Buffer
+}
+
+func newMultistatus() *multistatus {
+	m := &multistatus{}
+	m.b.WriteString(xml.Header)
+	m.b.WriteString(`<d:multistatus xmlns:d="DAV:" xmlns:card="urn:ietf:params:xml:ns:carddav" xmlns:cs="http://calendarserver.org/ns/">`)
+	return m
+}
+
+// response — propstat 200 с найденными свойствами и 404 с отсутствующими
+func (m *multistatus) response(href string, found []string, missing []xml.Name) {
+	m.b.WriteString("<d:response><d:href>")
+	m.b.WriteString(escape(href))
+	m.b.WriteString("</d:href>")
+	if len(found) > 0 || len(missing) == 0 {
+		m.b.WriteString("<d:propstat><d:prop>")
+		for _, f := range found {
+			m.b.WriteString(f)
+		}
+		m.b.WriteString("</d:prop>" + statusLine(http.StatusOK) + "</d:propstat>")
+	}
+	if len(missing) > 0 {
+		m.b.WriteString("<d:propstat><d:prop>")
+		for i, n := range missing {
+			m.b.WriteString(emptyElem(n, i))
+		}
+		m.b.WriteString("</d:prop>" + statusLine(http.StatusNotFound) + "</d:propstat>")
+	}
+	m.b.WriteString("</d:response>")
+}
+
+// status — ответ без свойств: удалённая или не найденная карточка
+func (m *multistatus) status(href string, code int) {
+	m.b.WriteString("<d:response><d:href>")
+	m.b.WriteString(escape(href))
+	m.b.WriteString("</d:href>" + statusLine(code) + "</d:response>")
+}
+
+func (m *multistatus) syncToken(token string) {
+	m.b.WriteString(elem("d:sync-token", escape(token)))
+}
+
+func (m *multistatus) write(w http.ResponseWriter) {
+	m.b.WriteString("</d:multistatus>")
+	w.Header().Set("Content-Type", `application/xml; charset="utf-8"`)
+	w.WriteHeader(http.StatusMultiStatus)
+	_, _ = w.Write(m.b.Bytes())
+}
+
+// writeError — тело ошибки с нарушенным предусловием (RFC 4918, 16)
+func writeError(w http.ResponseWriter, code int, condition string) {
+	w.Header().Set("Content-Type", `application/xml; charset="utf-8"`)
+	w.WriteHeader(code)
+	_, _ = io.WriteString(w, xml.Header+`<d:error xmlns:d="DAV:" xmlns:card="urn:ietf:params:xml:ns:carddav"><`+condition+`/></d:error>`)
+}
+
+func statusLine(code int) string {
+	return fmt.Sprintf("<d:status>HTTP/1.1 %d %s</d:status>", code, http.StatusText(code))
+}
+
+// elem — элемент с готовым содержимым; пустое содержимое даёт <name/>
+func elem(name, inner string) string {
+	if inner == "" {
+		return "<" + name + "/>"
+	}
+	return "<" + name + ">" + inner + "</" + name + ">"
+}
+
+// emptyElem — имя неизвестного свойства; чужое пространство имён объявляется на месте
+func emptyElem(n xml.Name, i int) string {
+	if p, ok := prefixes[n.Space]; ok {
+		return "<" + p + ":" + n.Local + "/>"
+	}
+	if n.Space == "" {
+		return "<" + n.Local + "/>"
+	}
+	p := fmt.Sprintf("x%d", i)
+	return "<" + p + ":" + n.Local + ` xmlns:` + p + `="` + escape(n.Space) + `"/>`
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -1,5 +1,6 @@
 package config
 
-func (p Postgres) GetURL() string     { return p.URL }
-func (p Postgres) GetMaxConns() int32 { return p.MaxConns }
-func (p Postgres) GetMinConns() int32 { return p.MinConns }
+func (p Postgres) GetURL() string       { return p.URL }
+func (p Postgres) GetMaxConns() int32   { return p.MaxConns }
+func (p Postgres) GetMinConns() int32   { return p.MinConns }
+func (p Postgres) GetRowSecurity() bool { return p.RowSecurity }
@@ -0,0 +1,4 @@
+package config
+
+func (p Photos) GetMaxBytes() int64 { return p.MaxBytes }
+func (p Photos) GetThumbSize() int  { return p.ThumbSize }
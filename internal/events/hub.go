@@ -0,0 +1,158 @@
+// Package events — раздача ленты изменений контактов подписчикам (SSE) из одного LISTEN-соединения.
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+const (
+	// subBuffer — очередь подписчика; кто не успевает её разбирать, отключается и догоняет по Last-Event-ID
+	subBuffer  = 256
+	minBackoff = time.Second
+	maxBackoff = 30 * time.Second
+)
+
+// Source — откуда хаб берёт события; реализуется service.Service
+type Source interface {
+	EventsSince(ctx context.Context, after int64, limit int) ([]service.EventOut, error)
+	EventsHead(ctx context.Context) (int64, error)
+	ListenEvents(ctx context.Context, onListen func(), notify func()) error
+}
+
+// Hub — уведомление LISTEN только будит хаб: события читаются из журнала по позиции (seq), поэтому
+// подписчики получают их в порядке коммита и без пропусков между уведомлениями.
+type Hub struct {
+	lg   *logger.Logger
+	src  Source
+	wake chan struct{}
+
+	mu   sync.Mutex
+	subs map[chan service.EventOut]struct{}
+
+	pollMu sync.Mutex // один читатель журнала за раз
+	last   int64      // позиция последнего разосланного события
+	synced bool       // last получена из журнала
+}
+
+func NewHub(lg *logger.Logger, src Source) *Hub {
+	return &Hub{lg: lg, src: src, wake: make(chan struct{}, 1), subs: make(map[chan service.EventOut]struct{})}
+}
+
+// Run — держит LISTEN до отмены ctx, переподключаясь с экспоненциальной задержкой.
+// После переподключения события, пришедшие за время обрыва, дочитываются из журнала.
+// При остановке каналы подписчиков закрываются — SSE-ответы завершаются и не держат Shutdown.
+// ctx — со всеми книгами (repository.WithAllBooks): хаб читает журнал целиком.
+func (h *Hub) Run(ctx context.Context) {
+	defer h.closeAll()
+	go h.poll(ctx)
+	backoff := minBackoff
+	for {
+		started := time.Now()
+		err := h.src.ListenEvents(ctx, func() { h.catchUp(ctx) }, h.notify)
+		if ctx.Err() != nil {
+			return
+		}
+		h.lg.Warn("events listener stopped, reconnecting", logger.Err(err), logger.KV("in", backoff.String()))
+		if time.Since(started) > maxBackoff {
+			backoff = minBackoff
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// Subscribe — канал закрывается, если подписчик отстал; cancel нужно вызвать в любом случае.
+func (h *Hub) Subscribe() (<-chan service.EventOut, func()) {
+	ch := make(chan service.EventOut, subBuffer)
+	h.mu.Lock()
+	h.subs[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := h.subs[ch]; ok {
+			delete(h.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// notify — пачка уведомлений схлопывается в одно чтение журнала
+func (h *Hub) notify() {
+	select {
+	case h.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (h *Hub) poll(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.wake:
+			h.catchUp(ctx)
+		}
+	}
+}
+
+func (h *Hub) publish(e service.EventOut) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs {
+		select {
+		case ch <- e:
+		default:
+			delete(h.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+func (h *Hub) closeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs {
+		delete(h.subs, ch)
+		close(ch)
+	}
+}
+
+// catchUp — рассылает события журнала после last. Первое чтение только запоминает позицию:
+// подписчиков до запуска хаба не было.
+func (h *Hub) catchUp(ctx context.Context) {
+	h.pollMu.Lock()
+	defer h.pollMu.Unlock()
+	if !h.synced {
+		head, err := h.src.EventsHead(ctx)
+		if err != nil {
+			h.lg.Warn("events head read failed", logger.Err(err))
+			return
+		}
+		h.last, h.synced = head, true
+		return
+	}
+	for {
+		evs, err := h.src.EventsSince(ctx, h.last, 0)
+		if err != nil {
+			h.lg.Warn("events catch-up failed", logger.Err(err))
+			return
+		}
+		for _, e := range evs {
+			h.publish(e)
+			h.last = e.ID
+		}
+		if len(evs) == 0 {
+			return
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package events_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sunzhqr/phonebook/internal/events"
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+// flakySource — журнал в памяти. Первое соединение уведомляет о событии 1 и рвётся, за время обрыва
+// в журнал попадает 2, второе соединение уведомляет о 3 и держится до отмены
+type flakySource struct {
+	mu    sync.Mutex
+	log   []service.EventOut
+	calls int
+}
+
+func (s *flakySource) add(id int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.log = append(s.log, service.EventOut{ID: id})
+}
+
+func (s *flakySource) EventsSince(_ context.Context, after int64, _ int) ([]service.EventOut, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []service.EventOut
+	for _, e := range s.log {
+		if e.ID > after {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func (s *flakySource) EventsHead(context.Context) (int64, error) { return 0, nil }
+
+func (s *flakySource) ListenEvents(ctx context.Context, onListen func(), notify func()) error {
+	s.calls++
+	onListen()
+	if s.calls == 1 {
+		s.add(1)
+		notify()
+		s.add(2) // коммит во время обрыва: уведомление о нём никто не получит
+		return errors.New("connection reset")
+	}
+	s.add(3)
+	notify()
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestHub_CatchUpAfterReconnect(t *testing.T) {
+	hub := events.NewHub(logger.New("dev"), &flakySource{})
+	sub, cancel := hub.Subscribe()
+	defer cancel()
+
+	ctx, stop := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() { hub.Run(ctx); close(done) }()
+
+	for _, want := range []int64{1, 2, 3} {
+		select {
+		case e := <-sub:
+			if e.ID != want {
+				t.Fatalf("got %d, want %d", e.ID, want)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("timeout waiting for event %d", want)
+		}
+	}
+
+	stop()
+	<-done
+	if _, ok := <-sub; ok {
+		t.Fatal("subscription must be closed when hub stops")
+	}
+}
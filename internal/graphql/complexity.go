@@ -0,0 +1,46 @@
+package graphql
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	gql "github.com/graph-gophers/graphql-go"
+)
+
+// budget — лимит сложности одного запроса. Корневые поля списывают стоимость до обращения к сервису,
+// так что тяжёлый запрос отклоняется, не нагрузив БД.
+type budget struct {
+	mu   sync.Mutex
+	left int
+}
+
+// charge — каждое выбранное поле стоит 1; поля внутри элементов списка — столько, сколько элементов
+// может вернуться (first). listPrefixes — пути, под которыми лежат элементы ("edges" у connection);
+// без них элементом считается весь выбор (список в корне, как search).
+func (b *budget) charge(ctx context.Context, first int, listPrefixes ...string) error {
+	cost := 1
+	for _, name := range gql.SelectedFieldNames(ctx) {
+		cost += fieldCost(name, first, listPrefixes)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if cost > b.left {
+		return &queryError{msg: "query is too complex", code: "QUERY_TOO_COMPLEX"}
+	}
+	b.left -= cost
+	return nil
+}
+
+func fieldCost(name string, first int, listPrefixes []string) int {
+	if len(listPrefixes) == 0 {
+		return first
+	}
+	for _, p := range listPrefixes {
+		if name == p || strings.HasPrefix(name, p+".") {
+			return first
+		}
+	}
+	return 1
+}
@@ -0,0 +1,120 @@
+// Package graphql — эндпоинт /graphql: чтение контактов с выбором полей, поиск и Relay-пагинация
+// поверх service.ContactsService, с лимитами глубины и сложности запроса.
+package graphql
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/sunzhqr/phonebook/internal/config"
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+type Handler struct {
+	lg            *logger.Logger
+	svc           service.ContactsService
+	schema        *gql.Schema
+	maxComplexity int
+}
+
+func New(lg *logger.Logger, cfg config.GraphQL, svc service.ContactsService) *Handler {
+	schema := gql.MustParseSchema(schemaSDL, &resolver{lg: lg, svc: svc},
+		gql.MaxDepth(cfg.MaxDepth),
+		gql.MaxQueryLength(cfg.MaxQueryBytes),
+	)
+	return &Handler{lg: lg, svc: svc, schema: schema, maxComplexity: cfg.MaxComplexity}
+}
+
+type request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// ServeHTTP — POST с JSON-телом или GET с параметрами query/operationName/variables.
+// Ошибки выполнения возвращаются в поле errors со статусом 200, как принято в GraphQL.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req request
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		req.Query, req.OperationName = q.Get("query"), q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				http.Error(w, "invalid variables", http.StatusBadRequest)
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if req.Query == "" {
+		http.Error(w, "query is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), loaderKey{}, newDetailsLoader(h.svc))
+	ctx = context.WithValue(ctx, budgetKey{}, &budget{left: h.maxComplexity})
+	res := h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+type (
+	loaderKey struct{}
+	budgetKey struct{}
+)
+
+func reqLoader(ctx context.Context) *detailsLoader { return ctx.Value(loaderKey{}).(*detailsLoader) }
+func reqBudget(ctx context.Context) *budget        { return ctx.Value(budgetKey{}).(*budget) }
+
+// queryError — ошибка с extensions.code для клиента
+type queryError struct {
+	msg  string
+	code string
+}
+
+func (e *queryError) Error() string              { return e.msg }
+func (e *queryError) Extensions() map[string]any { return map[string]any{"code": e.code} }
+
+// gqlErr — ошибка сервиса для клиента; неизвестные ошибки пишутся в лог и не раскрываются
+func gqlErr(lg *logger.Logger, err error) error {
+	var se *service.Error
+	switch {
+	case errors.As(err, &se):
+		return &queryError{msg: se.Message, code: errCode(se.Code)}
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return &queryError{msg: err.Error(), code: "CANCELLED"}
+	}
+	lg.Error("graphql resolver failed", logger.Err(err))
+	return &queryError{msg: http.StatusText(http.StatusInternalServerError), code: "INTERNAL"}
+}
+
+func errCode(httpCode int) string {
+	switch httpCode {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return "BAD_USER_INPUT"
+	case http.StatusNotFound:
+		return "NOT_FOUND"
+	case http.StatusConflict:
+		return "CONFLICT"
+	}
+	return "INTERNAL"
+}
@@ -0,0 +1,228 @@
+package graphql_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sunzhqr/phonebook/internal/config"
+	"github.com/sunzhqr/phonebook/internal/graphql"
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+// mockContacts — методы, не нужные тесту, паникуют через встроенный nil-интерфейс
+type mockContacts struct {
+	service.ContactsService
+	all      []service.ContactOut
+	lists    []service.ListFilter
+	batches  atomic.Int32
+	batchIDs atomic.Int32
+}
+
+func (m *mockContacts) ListContacts(_ context.Context, f service.ListFilter) (service.ListOut, error) {
+	m.lists = append(m.lists, f)
+	var items []service.ContactOut
+	for _, c := range m.all {
+		if c.ID > f.AfterID {
+			items = append(items, service.ContactOut{ID: c.ID, FirstName: c.FirstName, LastName: c.LastName})
+		}
+	}
+	var next int64
+	if len(items) > f.Limit {
+		items = items[:f.Limit]
+		next = items[len(items)-1].ID
+	}
+	return service.ListOut{Items: items, Page: service.PageOut{NextAfterID: next, HasMore: next > 0, Limit: f.Limit}}, nil
+}
+
+func (m *mockContacts) ContactsByIDs(_ context.Context, ids []int64) ([]service.ContactOut, error) {
+	m.batches.Add(1)
+	m.batchIDs.Add(int32(len(ids)))
+	var out []service.ContactOut
+	for _, c := range m.all {
+		for _, id := range ids {
+			if c.ID == id {
+				out = append(out, c)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (m *mockContacts) GetContact(_ context.Context, id int64, _ ...string) (service.ContactOut, error) {
+	for _, c := range m.all {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return service.ContactOut{}, &service.Error{Code: http.StatusNotFound, Message: "contact not found"}
+}
+
+func newMock(n int) *mockContacts {
+	m := &mockContacts{}
+	for i := 1; i <= n; i++ {
+		m.all = append(m.all, service.ContactOut{
+			ID: int64(i), FirstName: "Aru", LastName: "Sadykova",
+			Phones: []service.PhoneOut{{Label: "work", PhoneRaw: "8 701 000", PhoneE164: "+7701000", IsPrimary: true}},
+		})
+	}
+	return m
+}
+
+var testCfg = config.GraphQL{MaxDepth: 6, MaxComplexity: 500, MaxQueryBytes: 4096}
+
+type response struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Extensions map[string]any `json:"extensions"`
+	} `json:"errors"`
+}
+
+func exec(t *testing.T, h http.Handler, query string, vars map[string]any) response {
+	t.Helper()
+	body, _ := json.Marshal(map[string]any{"query": query, "variables": vars})
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var res response
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestContacts_BatchesPhonesAndPaginates(t *testing.T) {
+	svc := newMock(5)
+	h := graphql.New(logger.New("dev"), testCfg, svc)
+
+	const q = `query($after: String) {
+  contacts(first: 3, after: $after) {
+    edges { cursor node { id firstName phones { raw e164 } } }
+    pageInfo { hasNextPage endCursor }
+  }
+}`
+	res := exec(t, h, q, nil)
+	if len(res.Errors) > 0 {
+		t.Fatalf("errors: %+v", res.Errors)
+	}
+	var page struct {
+		Contacts struct {
+			Edges []struct {
+				Node struct {
+					ID     string
+					Phones []struct{ Raw, E164 string }
+				}
+			}
+			PageInfo struct {
+				HasNextPage bool
+				EndCursor   string
+			}
+		}
+	}
+	if err := json.Unmarshal(res.Data, &page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Contacts.Edges) != 3 || !page.Contacts.PageInfo.HasNextPage {
+		t.Fatalf("page: %+v", page)
+	}
+	for _, e := range page.Contacts.Edges {
+		if len(e.Node.Phones) != 1 || e.Node.Phones[0].E164 != "+7701000" {
+			t.Fatalf("phones of %s: %+v", e.Node.ID, e.Node.Phones)
+		}
+	}
+	if got := svc.batches.Load(); got != 1 {
+		t.Fatalf("ContactsByIDs calls = %d, want 1", got)
+	}
+	if got := svc.batchIDs.Load(); got != 3 {
+		t.Fatalf("ids loaded = %d, want 3", got)
+	}
+	if f := svc.lists[0]; !f.WithoutDetails || f.Sort != "id" {
+		t.Fatalf("list filter: %+v", f)
+	}
+
+	res = exec(t, h, q, map[string]any{"after": page.Contacts.PageInfo.EndCursor})
+	if err := json.Unmarshal(res.Data, &page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Contacts.Edges) != 2 || page.Contacts.Edges[0].Node.ID != "4" || page.Contacts.PageInfo.HasNextPage {
+		t.Fatalf("second page: %+v", page)
+	}
+}
+
+func TestContacts_NamesOnlySkipDetails(t *testing.T) {
+	svc := newMock(3)
+	h := graphql.New(logger.New("dev"), testCfg, svc)
+
+	res := exec(t, h, `{ contacts { edges { node { firstName lastName } } } }`, nil)
+	if len(res.Errors) > 0 {
+		t.Fatalf("errors: %+v", res.Errors)
+	}
+	if got := svc.batches.Load(); got != 0 {
+		t.Fatalf("ContactsByIDs calls = %d, want 0", got)
+	}
+}
+
+func TestContact_NotFoundIsNull(t *testing.T) {
+	h := graphql.New(logger.New("dev"), testCfg, newMock(1))
+
+	res := exec(t, h, `{ a: contact(id: "1") { phones { raw } } b: contact(id: "9") { id } }`, nil)
+	if len(res.Errors) > 0 {
+		t.Fatalf("errors: %+v", res.Errors)
+	}
+	if want := `{"a":{"phones":[{"raw":"8 701 000"}]},"b":null}`; string(res.Data) != want {
+		t.Fatalf("data = %s, want %s", res.Data, want)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	shallow := testCfg
+	shallow.MaxDepth = 3
+
+	cases := []struct {
+		name, query, wantMsg string
+		cfg                  config.GraphQL
+	}{
+		{
+			name:    "complexity",
+			query:   `{ contacts(first: 100) { edges { cursor node { id firstName lastName phones { raw } } } } }`,
+			wantMsg: "too complex",
+		},
+		{
+			name:    "depth",
+			query:   `{ contacts { edges { node { phones { raw } } } } }`,
+			wantMsg: "exceeds max depth",
+			cfg:     shallow,
+		},
+		{
+			name:    "bad first",
+			query:   `{ contacts(first: 500) { pageInfo { hasNextPage } } }`,
+			wantMsg: "first must be",
+		},
+		{
+			name:    "bad cursor",
+			query:   `{ contacts(after: "nope") { pageInfo { hasNextPage } } }`,
+			wantMsg: "bad cursor",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testCfg
+			if tc.cfg.MaxDepth > 0 {
+				cfg = tc.cfg
+			}
+			res := exec(t, graphql.New(logger.New("dev"), cfg, newMock(1)), tc.query, nil)
+			if len(res.Errors) == 0 || !strings.Contains(res.Errors[0].Message, tc.wantMsg) {
+				t.Fatalf("errors = %+v, want %q", res.Errors, tc.wantMsg)
+			}
+		})
+	}
+}
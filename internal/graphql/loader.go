@@ -0,0 +1,64 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+// detailsLoader — per-request догрузка телефонов и email. Страница списка регистрирует id своих контактов,
+// первый же резолвер реквизитов забирает их все одним ContactsByIDs — без N+1 на вложенных полях.
+type detailsLoader struct {
+	svc service.ContactsService
+
+	mu      sync.Mutex
+	pending map[int64]struct{}
+	loaded  map[int64]service.ContactOut
+}
+
+func newDetailsLoader(svc service.ContactsService) *detailsLoader {
+	return &detailsLoader{svc: svc, pending: map[int64]struct{}{}, loaded: map[int64]service.ContactOut{}}
+}
+
+// prime — id, которые стоит загрузить в той же пачке, что и первый запрошенный
+func (l *detailsLoader) prime(ids ...int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, id := range ids {
+		if _, ok := l.loaded[id]; !ok {
+			l.pending[id] = struct{}{}
+		}
+	}
+}
+
+// load — контакт с реквизитами. Блокировка держится на время запроса: параллельные резолверы той же
+// страницы дожидаются его и берут результат из кэша.
+func (l *detailsLoader) load(ctx context.Context, id int64) (service.ContactOut, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if c, ok := l.loaded[id]; ok {
+		return c, nil
+	}
+
+	l.pending[id] = struct{}{}
+	ids := make([]int64, 0, len(l.pending))
+	for pid := range l.pending {
+		ids = append(ids, pid)
+	}
+	res, err := l.svc.ContactsByIDs(ctx, ids)
+	if err != nil {
+		return service.ContactOut{}, err
+	}
+	for _, c := range res {
+		l.loaded[c.ID] = c
+	}
+	// удалённые между запросами — без реквизитов, а не повторный запрос на каждом поле
+	for _, pid := range ids {
+		if _, ok := l.loaded[pid]; !ok {
+			l.loaded[pid] = service.ContactOut{ID: pid}
+		}
+	}
+	clear(l.pending)
+	return l.loaded[id], nil
+}
@@ -0,0 +1,275 @@
+package graphql
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+// resolver — корневой Query
+type resolver struct {
+	lg  *logger.Logger
+	svc service.ContactsService
+}
+
+type contactFilter struct {
+	FirstName      *string
+	LastName       *string
+	Company        *string
+	Phone          *string
+	Email          *string
+	OrganizationID *gql.ID
+}
+
+func (r *resolver) Contact(ctx context.Context, args struct{ ID gql.ID }) (*contactResolver, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := reqBudget(ctx).charge(ctx, 1); err != nil {
+		return nil, err
+	}
+	c, err := r.svc.GetContact(ctx, id)
+	if err != nil {
+		var se *service.Error
+		if errors.As(err, &se) && se.Code == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, gqlErr(r.lg, err)
+	}
+	return &contactResolver{c: c, full: true}, nil
+}
+
+func (r *resolver) Contacts(ctx context.Context, args struct {
+	First  int32
+	After  *string
+	Filter *contactFilter
+}) (*connectionResolver, error) {
+	first := int(args.First)
+	if first <= 0 || first > 100 {
+		return nil, &queryError{msg: "first must be between 1 and 100", code: "BAD_USER_INPUT"}
+	}
+	var after int64
+	if args.After != nil && *args.After != "" {
+		var ok bool
+		if after, ok = parseCursor(*args.After); !ok {
+			return nil, &queryError{msg: "bad cursor", code: "BAD_USER_INPUT"}
+		}
+	}
+	f := service.ListFilter{AfterID: after, Limit: first, Sort: "id", Order: "asc", WithoutDetails: true}
+	if args.Filter != nil {
+		if err := applyFilter(&f, args.Filter); err != nil {
+			return nil, err
+		}
+	}
+	if err := reqBudget(ctx).charge(ctx, first, "edges"); err != nil {
+		return nil, err
+	}
+
+	out, err := r.svc.ListContacts(ctx, f)
+	if err != nil {
+		return nil, gqlErr(r.lg, err)
+	}
+	ld := reqLoader(ctx)
+	ids := make([]int64, 0, len(out.Items))
+	edges := make([]*edgeResolver, 0, len(out.Items))
+	for _, c := range out.Items {
+		ids = append(ids, c.ID)
+		edges = append(edges, &edgeResolver{node: &contactResolver{c: c, lg: r.lg, ld: ld}})
+	}
+	ld.prime(ids...)
+	return &connectionResolver{edges: edges, hasMore: out.Page.HasMore}, nil
+}
+
+func (r *resolver) Search(ctx context.Context, args struct {
+	Query string
+	First int32
+}) ([]*contactResolver, error) {
+	first := int(args.First)
+	if first <= 0 || first > 50 {
+		return nil, &queryError{msg: "first must be between 1 and 50", code: "BAD_USER_INPUT"}
+	}
+	if err := reqBudget(ctx).charge(ctx, first); err != nil {
+		return nil, err
+	}
+	res, err := r.svc.Search(ctx, args.Query, first)
+	if err != nil {
+		return nil, gqlErr(r.lg, err)
+	}
+	out := make([]*contactResolver, 0, len(res))
+	for _, c := range res {
+		out = append(out, &contactResolver{c: c, full: true})
+	}
+	return out, nil
+}
+
+func applyFilter(f *service.ListFilter, in *contactFilter) error {
+	str := func(p *string) string {
+		if p == nil {
+			return ""
+		}
+		return *p
+	}
+	f.FirstName, f.LastName, f.Company = str(in.FirstName), str(in.LastName), str(in.Company)
+	f.Phone, f.Email = str(in.Phone), str(in.Email)
+	if in.OrganizationID != nil {
+		id, err := parseID(*in.OrganizationID)
+		if err != nil {
+			return err
+		}
+		f.OrganizationID = id
+	}
+	return nil
+}
+
+type connectionResolver struct {
+	edges   []*edgeResolver
+	hasMore bool
+}
+
+func (r *connectionResolver) Edges() []*edgeResolver { return r.edges }
+
+func (r *connectionResolver) PageInfo() *pageInfoResolver {
+	p := &pageInfoResolver{hasNext: r.hasMore}
+	if n := len(r.edges); n > 0 {
+		p.start, p.end = r.edges[0].Cursor(), r.edges[n-1].Cursor()
+	}
+	return p
+}
+
+type edgeResolver struct {
+	node *contactResolver
+}
+
+func (r *edgeResolver) Cursor() string         { return cursor(r.node.c.ID) }
+func (r *edgeResolver) Node() *contactResolver { return r.node }
+
+type pageInfoResolver struct {
+	hasNext    bool
+	start, end string
+}
+
+func (r *pageInfoResolver) HasNextPage() bool { return r.hasNext }
+
+// HasPreviousPage — пагинация только вперёд; спецификация Relay допускает false
+func (r *pageInfoResolver) HasPreviousPage() bool { return false }
+
+func (r *pageInfoResolver) StartCursor() *string { return optString(r.start) }
+func (r *pageInfoResolver) EndCursor() *string   { return optString(r.end) }
+
+// contactResolver — full=false у контактов из списка: реквизиты догружаются через detailsLoader
+type contactResolver struct {
+	c    service.ContactOut
+	full bool
+	lg   *logger.Logger
+	ld   *detailsLoader
+}
+
+func (r *contactResolver) ID() gql.ID          { return gql.ID(strconv.FormatInt(r.c.ID, 10)) }
+func (r *contactResolver) FirstName() string   { return r.c.FirstName }
+func (r *contactResolver) LastName() string    { return r.c.LastName }
+func (r *contactResolver) Company() string     { return r.c.Company }
+func (r *contactResolver) JobTitle() string    { return r.c.JobTitle }
+func (r *contactResolver) Department() string  { return r.c.Department }
+func (r *contactResolver) CreatedAt() gql.Time { return gql.Time{Time: r.c.CreatedAt} }
+func (r *contactResolver) UpdatedAt() gql.Time { return gql.Time{Time: r.c.UpdatedAt} }
+
+func (r *contactResolver) Organization() *organizationResolver {
+	if r.c.Organization == nil {
+		return nil
+	}
+	return &organizationResolver{*r.c.Organization}
+}
+
+func (r *contactResolver) Phones(ctx context.Context) ([]*phoneResolver, error) {
+	c, err := r.details(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*phoneResolver, 0, len(c.Phones))
+	for _, p := range c.Phones {
+		out = append(out, &phoneResolver{p})
+	}
+	return out, nil
+}
+
+func (r *contactResolver) Emails(ctx context.Context) ([]*emailResolver, error) {
+	c, err := r.details(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*emailResolver, 0, len(c.Emails))
+	for _, e := range c.Emails {
+		out = append(out, &emailResolver{e})
+	}
+	return out, nil
+}
+
+func (r *contactResolver) details(ctx context.Context) (service.ContactOut, error) {
+	if r.full {
+		return r.c, nil
+	}
+	c, err := r.ld.load(ctx, r.c.ID)
+	if err != nil {
+		return service.ContactOut{}, gqlErr(r.lg, err)
+	}
+	return c, nil
+}
+
+type organizationResolver struct{ o service.OrganizationRef }
+
+func (r *organizationResolver) ID() gql.ID   { return gql.ID(strconv.FormatInt(r.o.ID, 10)) }
+func (r *organizationResolver) Name() string { return r.o.Name }
+
+type phoneResolver struct{ p service.PhoneOut }
+
+func (r *phoneResolver) Label() string   { return r.p.Label }
+func (r *phoneResolver) Raw() string     { return r.p.PhoneRaw }
+func (r *phoneResolver) E164() string    { return r.p.PhoneE164 }
+func (r *phoneResolver) IsPrimary() bool { return r.p.IsPrimary }
+
+type emailResolver struct{ e service.EmailOut }
+
+func (r *emailResolver) Label() string   { return r.e.Label }
+func (r *emailResolver) Email() string   { return r.e.Email }
+func (r *emailResolver) IsPrimary() bool { return r.e.IsPrimary }
+
+// cursor — непрозрачный курсор Relay; внутри id последнего контакта (keyset по id)
+func cursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("c:" + strconv.FormatInt(id, 10)))
+}
+
+func parseCursor(s string) (int64, bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return 0, false
+	}
+	v, ok := strings.CutPrefix(string(raw), "c:")
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(v, 10, 64)
+	return id, err == nil && id > 0
+}
+
+func parseID(id gql.ID) (int64, error) {
+	v, err := strconv.ParseInt(string(id), 10, 64)
+	if err != nil || v <= 0 {
+		return 0, &queryError{msg: "bad id", code: "BAD_USER_INPUT"}
+	}
+	return v, nil
+}
+
+func optString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
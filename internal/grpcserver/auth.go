@@ -0,0 +1,131 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	phonebookv1 "github.com/sunzhqr/phonebook/api/phonebook/v1"
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// methodScopes — область, нужная методу; методы Contacts не из списка требуют write,
+// прочие службы (health) доступны без учётных данных
+var methodScopes = map[string]string{
+	phonebookv1.Contacts_GetContact_FullMethodName:     service.ScopeContactsRead,
+	phonebookv1.Contacts_ListContacts_FullMethodName:   service.ScopeContactsRead,
+	phonebookv1.Contacts_SearchContacts_FullMethodName: service.ScopeContactsRead,
+	phonebookv1.Contacts_Watch_FullMethodName:          service.ScopeContactsRead,
+}
+
+var contactsPrefix = "/" + phonebookv1.Contacts_ServiceDesc.ServiceName + "/"
+
+// addressBookKey — книга вызова в метаданных, как заголовок X-Address-Book в REST
+const addressBookKey = "x-address-book"
+
+const defaultAddressBook = "default"
+
+// auth — учётные данные из метаданных authorization (Bearer) или x-api-key, проверка области
+// метода и выбор адресной книги; правила те же, что у REST
+type auth struct {
+	lg    *logger.Logger
+	authn service.AuthService
+	books service.AddressBooksService
+}
+
+func (a auth) unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (any, error) {
+		ctx, err := a.check(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return next(ctx, req)
+	}
+}
+
+func (a auth) stream() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, next grpc.StreamHandler) error {
+		ctx, err := a.check(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return next(srv, &ctxStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func (a auth) check(ctx context.Context, method string) (context.Context, error) {
+	if !strings.HasPrefix(method, contactsPrefix) {
+		return ctx, nil
+	}
+	scope, ok := methodScopes[method]
+	if !ok {
+		scope = service.ScopeContactsWrite
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	key, ok := credentials(md)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+	p, err := a.authn.Authenticate(ctx, key)
+	var se *service.Error
+	switch {
+	case errors.As(err, &se) && se.Code == http.StatusUnauthorized:
+		return nil, status.Error(codes.Unauthenticated, se.Message)
+	case err != nil:
+		return nil, statusErr(a.lg, err)
+	}
+	if !p.Allows(scope) {
+		return nil, status.Error(codes.PermissionDenied, "insufficient scope, "+scope+" required")
+	}
+
+	slug := first(md, addressBookKey)
+	if p.AddressBook != "" {
+		if slug != "" && slug != p.AddressBook {
+			return nil, status.Error(codes.PermissionDenied, "credentials are bound to address book "+p.AddressBook)
+		}
+		slug = p.AddressBook
+	}
+	if slug == "" {
+		slug = defaultAddressBook
+	}
+	b, err := a.books.ResolveAddressBook(ctx, slug)
+	switch {
+	case errors.As(err, &se) && se.Code == http.StatusNotFound:
+		return nil, status.Error(codes.NotFound, "unknown address book "+slug)
+	case err != nil:
+		return nil, statusErr(a.lg, err)
+	}
+	return service.WithAddressBook(service.WithPrincipal(ctx, p), b), nil
+}
+
+func credentials(md metadata.MD) (string, bool) {
+	if k := first(md, "x-api-key"); k != "" {
+		return k, true
+	}
+	scheme, token, ok := strings.Cut(first(md, "authorization"), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") && strings.TrimSpace(token) != "" {
+		return strings.TrimSpace(token), true
+	}
+	return "", false
+}
+
+func first(md metadata.MD, key string) string {
+	if v := md.Get(key); len(v) > 0 {
+		return strings.TrimSpace(v[0])
+	}
+	return ""
+}
+
+// ctxStream — поток с контекстом, дополненным вызывающим и книгой
+type ctxStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *ctxStream) Context() context.Context { return s.ctx }
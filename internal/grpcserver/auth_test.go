@@ -0,0 +1,117 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	phonebookv1 "github.com/sunzhqr/phonebook/api/phonebook/v1"
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// keyAuth — ключ совпадает с областью: "read", "write", "bound" (read, привязан к книге sales)
+type keyAuth struct{}
+
+func (keyAuth) Authenticate(_ context.Context, key string) (service.Principal, error) {
+	switch key {
+	case "read":
+		return service.Principal{Subject: "key:1", Scopes: []string{service.ScopeContactsRead}}, nil
+	case "write":
+		return service.Principal{Subject: "key:2", Scopes: []string{service.ScopeContactsWrite}}, nil
+	case "bound":
+		return service.Principal{Subject: "key:3", Scopes: []string{service.ScopeContactsRead}, AddressBook: "sales"}, nil
+	}
+	return service.Principal{}, &service.Error{Code: http.StatusUnauthorized, Message: "invalid api key"}
+}
+
+type slugBooks struct{ service.AddressBooksService }
+
+func (slugBooks) ResolveAddressBook(_ context.Context, slug string) (service.AddressBookOut, error) {
+	if slug != "default" && slug != "sales" {
+		return service.AddressBookOut{}, &service.Error{Code: http.StatusNotFound, Message: "not found"}
+	}
+	return service.AddressBookOut{Slug: slug}, nil
+}
+
+// whoContacts — в имени контакта вызывающий и книга, какими их видит сервис
+type whoContacts struct{ service.ContactsService }
+
+func (whoContacts) GetContact(ctx context.Context, id int64, _ ...string) (service.ContactOut, error) {
+	p, _ := service.PrincipalFrom(ctx)
+	b, _ := service.AddressBookFrom(ctx)
+	return service.ContactOut{ID: id, FirstName: p.Subject, LastName: b.Slug}, nil
+}
+
+func (whoContacts) DeleteContact(context.Context, int64) error { return nil }
+
+func TestAuth_MethodScopesAndBooks(t *testing.T) {
+	lg := logger.New("dev")
+	a := auth{lg: lg, authn: keyAuth{}, books: slugBooks{}}
+	ln := bufconn.Listen(1 << 20)
+	s := grpc.NewServer(grpc.ChainUnaryInterceptor(a.unary()), grpc.ChainStreamInterceptor(a.stream()))
+	phonebookv1.RegisterContactsServer(s, NewContacts(lg, whoContacts{}, nil, nil))
+	go func() { _ = s.Serve(ln) }()
+	t.Cleanup(s.Stop)
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	cl := phonebookv1.NewContactsClient(conn)
+
+	call := func(kv ...string) context.Context {
+		return metadata.NewOutgoingContext(context.Background(), metadata.Pairs(kv...))
+	}
+	cases := []struct {
+		name   string
+		ctx    context.Context
+		delete bool
+		want   codes.Code
+	}{
+		{"no credentials", context.Background(), false, codes.Unauthenticated},
+		{"bad key", call("authorization", "Bearer nope"), false, codes.Unauthenticated},
+		{"read get", call("authorization", "Bearer read"), false, codes.OK},
+		{"read delete", call("x-api-key", "read"), true, codes.PermissionDenied},
+		{"write delete", call("x-api-key", "write"), true, codes.OK},
+		{"unknown book", call("x-api-key", "read", addressBookKey, "hr"), false, codes.NotFound},
+		{"bound to other book", call("x-api-key", "bound", addressBookKey, "default"), false, codes.PermissionDenied},
+	}
+	for _, tc := range cases {
+		var err error
+		if tc.delete {
+			_, err = cl.DeleteContact(tc.ctx, &phonebookv1.DeleteContactRequest{Id: 1})
+		} else {
+			_, err = cl.GetContact(tc.ctx, &phonebookv1.GetContactRequest{Id: 1})
+		}
+		if got := status.Code(err); got != tc.want {
+			t.Errorf("%s: code = %v, want %v (%v)", tc.name, got, tc.want, err)
+		}
+	}
+
+	c, err := cl.GetContact(call("x-api-key", "bound"), &phonebookv1.GetContactRequest{Id: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.GetFirstName() != "key:3" || c.GetLastName() != "sales" {
+		t.Fatalf("caller = %s, book = %s", c.GetFirstName(), c.GetLastName())
+	}
+
+	// поток без учётных данных отклоняется до обработчика
+	w, err := cl.Watch(context.Background(), &phonebookv1.WatchRequest{})
+	if err == nil {
+		_, err = w.Recv()
+	}
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("watch: %v", err)
+	}
+}
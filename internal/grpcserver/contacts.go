@@ -0,0 +1,184 @@
+package grpcserver
+
+import (
+	"context"
+
+	phonebookv1 "github.com/sunzhqr/phonebook/api/phonebook/v1"
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// Subscriber — живые события; реализуется events.Hub
+type Subscriber interface {
+	Subscribe() (<-chan service.EventOut, func())
+}
+
+// Contacts — реализация phonebook.v1.Contacts поверх сервиса
+type Contacts struct {
+	phonebookv1.UnimplementedContactsServer
+
+	lg     *logger.Logger
+	svc    service.ContactsService
+	events service.EventsService
+	hub    Subscriber
+}
+
+func NewContacts(lg *logger.Logger, svc service.ContactsService, events service.EventsService, hub Subscriber) *Contacts {
+	return &Contacts{lg: lg, svc: svc, events: events, hub: hub}
+}
+
+func (s *Contacts) CreateContact(ctx context.Context, req *phonebookv1.CreateContactRequest) (*phonebookv1.Contact, error) {
+	c, err := s.svc.CreateContact(ctx, createIn(req.GetContact()))
+	if err != nil {
+		return nil, statusErr(s.lg, err)
+	}
+	return s.contact(c)
+}
+
+func (s *Contacts) GetContact(ctx context.Context, req *phonebookv1.GetContactRequest) (*phonebookv1.Contact, error) {
+	if req.GetId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "bad id")
+	}
+	c, err := s.svc.GetContact(ctx, req.GetId())
+	if err != nil {
+		return nil, statusErr(s.lg, err)
+	}
+	return s.contact(c)
+}
+
+func (s *Contacts) UpdateContact(ctx context.Context, req *phonebookv1.UpdateContactRequest) (*phonebookv1.Contact, error) {
+	if req.GetId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "bad id")
+	}
+	in, err := updateIn(req.GetContact(), req.GetUpdateMask().GetPaths())
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.svc.UpdateContact(ctx, req.GetId(), in)
+	if err != nil {
+		return nil, statusErr(s.lg, err)
+	}
+	return s.contact(c)
+}
+
+func (s *Contacts) DeleteContact(ctx context.Context, req *phonebookv1.DeleteContactRequest) (*emptypb.Empty, error) {
+	if req.GetId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "bad id")
+	}
+	if err := s.svc.DeleteContact(ctx, req.GetId()); err != nil {
+		return nil, statusErr(s.lg, err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Contacts) ListContacts(ctx context.Context, req *phonebookv1.ListContactsRequest) (*phonebookv1.ListContactsResponse, error) {
+	limit := int(req.GetLimit())
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	out, err := s.svc.ListContacts(ctx, service.ListFilter{
+		FirstName: req.GetFirstName(), LastName: req.GetLastName(), Company: req.GetCompany(),
+		Phone: req.GetPhone(), Email: req.GetEmail(), OrganizationID: req.GetOrganizationId(),
+		Custom: req.GetCustom(), AfterID: req.GetAfterId(), Limit: limit, Sort: req.GetSort(), Order: req.GetOrder(),
+	})
+	if err != nil {
+		return nil, statusErr(s.lg, err)
+	}
+	items, err := s.contacts(out.Items)
+	if err != nil {
+		return nil, err
+	}
+	return &phonebookv1.ListContactsResponse{Contacts: items, NextAfterId: out.Page.NextAfterID, HasMore: out.Page.HasMore}, nil
+}
+
+func (s *Contacts) SearchContacts(ctx context.Context, req *phonebookv1.SearchContactsRequest) (*phonebookv1.SearchContactsResponse, error) {
+	limit := int(req.GetLimit())
+	if limit <= 0 || limit > 50 {
+		limit = 20
+	}
+	res, err := s.svc.Search(ctx, req.GetQuery(), limit)
+	if err != nil {
+		return nil, statusErr(s.lg, err)
+	}
+	items, err := s.contacts(res)
+	if err != nil {
+		return nil, err
+	}
+	return &phonebookv1.SearchContactsResponse{Contacts: items}, nil
+}
+
+// Watch — как SSE /events: подписка на хаб до чтения журнала, затем пропущенные события,
+// затем живые без повторов. Отставший от хаба поток закрывается с UNAVAILABLE — клиент
+// переподключается с последним полученным id.
+func (s *Contacts) Watch(req *phonebookv1.WatchRequest, stream phonebookv1.Contacts_WatchServer) error {
+	ctx := stream.Context()
+	live, cancel := s.hub.Subscribe()
+	defer cancel()
+
+	var replayed int64
+	if req.AfterEventId != nil {
+		if req.GetAfterEventId() < 0 {
+			return status.Error(codes.InvalidArgument, "bad after_event_id")
+		}
+		replayed = req.GetAfterEventId()
+		for {
+			backlog, err := s.events.EventsSince(ctx, replayed, 0)
+			if err != nil {
+				return statusErr(s.lg, err)
+			}
+			if len(backlog) == 0 {
+				break
+			}
+			for _, e := range backlog {
+				if !service.EventVisible(ctx, e) {
+					replayed = e.ID
+					continue
+				}
+				if err := stream.Send(eventOut(e)); err != nil {
+					return err
+				}
+				replayed = e.ID
+			}
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e, ok := <-live:
+			if !ok {
+				return status.Error(codes.Unavailable, "event stream lagged behind, resubscribe with after_event_id")
+			}
+			if e.ID <= replayed || !service.EventVisible(ctx, e) {
+				continue
+			}
+			if err := stream.Send(eventOut(e)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *Contacts) contact(c service.ContactOut) (*phonebookv1.Contact, error) {
+	out, err := contactOut(c)
+	if err != nil {
+		return nil, statusErr(s.lg, err)
+	}
+	return out, nil
+}
+
+func (s *Contacts) contacts(list []service.ContactOut) ([]*phonebookv1.Contact, error) {
+	out := make([]*phonebookv1.Contact, 0, len(list))
+	for _, c := range list {
+		pc, err := s.contact(c)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, pc)
+	}
+	return out, nil
+}
@@ -0,0 +1,247 @@
+package grpcserver_test
+
+import (
+	"context"
+	"maps"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	phonebookv1 "github.com/sunzhqr/phonebook/api/phonebook/v1"
+	"github.com/sunzhqr/phonebook/internal/grpcserver"
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/repository"
+	"github.com/sunzhqr/phonebook/internal/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// mockContacts — методы, не нужные тесту, паникуют через встроенный nil-интерфейс
+type mockContacts struct {
+	service.ContactsService
+	CreateFn func(service.ContactCreateIn) (service.ContactOut, error)
+	GetFn    func(int64) (service.ContactOut, error)
+	UpdateFn func(int64, service.ContactUpdateIn) (service.ContactOut, error)
+}
+
+func (m *mockContacts) CreateContact(_ context.Context, in service.ContactCreateIn) (service.ContactOut, error) {
+	return m.CreateFn(in)
+}
+func (m *mockContacts) GetContact(_ context.Context, id int64, _ ...string) (service.ContactOut, error) {
+	return m.GetFn(id)
+}
+func (m *mockContacts) UpdateContact(_ context.Context, id int64, in service.ContactUpdateIn) (service.ContactOut, error) {
+	return m.UpdateFn(id, in)
+}
+
+type mockEvents struct {
+	service.EventsService
+	log []service.EventOut
+}
+
+func (m *mockEvents) EventsSince(_ context.Context, after int64, _ int) ([]service.EventOut, error) {
+	var out []service.EventOut
+	for _, e := range m.log {
+		if e.ID > after {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+type chanHub chan service.EventOut
+
+func (h chanHub) Subscribe() (<-chan service.EventOut, func()) { return h, func() {} }
+
+func dial(t *testing.T, srv phonebookv1.ContactsServer) phonebookv1.ContactsClient {
+	t.Helper()
+	ln := bufconn.Listen(1 << 20)
+	// книгу вызову задаёт перехватчик авторизации; здесь — всегда книга по умолчанию
+	book := service.AddressBookOut{ID: repository.DefaultAddressBookID, Slug: "default"}
+	s := grpc.NewServer(
+		grpc.UnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, h grpc.UnaryHandler) (any, error) {
+			return h(service.WithAddressBook(ctx, book), req)
+		}),
+		grpc.StreamInterceptor(func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, h grpc.StreamHandler) error {
+			return h(srv, bookStream{ss, service.WithAddressBook(ss.Context(), book)})
+		}))
+	phonebookv1.RegisterContactsServer(s, srv)
+	go func() { _ = s.Serve(ln) }()
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return phonebookv1.NewContactsClient(conn)
+}
+
+type bookStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s bookStream) Context() context.Context { return s.ctx }
+
+func TestContacts_CRUDAndErrorCodes(t *testing.T) {
+	var updated service.ContactUpdateIn
+	svc := &mockContacts{
+		CreateFn: func(in service.ContactCreateIn) (service.ContactOut, error) {
+			if len(in.Phones) == 0 {
+				return service.ContactOut{}, &service.Error{Code: http.StatusUnprocessableEntity, Message: "validation failed"}
+			}
+			return service.ContactOut{ID: 1, FirstName: in.FirstName, Custom: in.Custom,
+				Phones: []service.PhoneOut{{PhoneRaw: in.Phones[0].PhoneRaw, PhoneE164: "+77011234567", IsPrimary: true}}}, nil
+		},
+		GetFn: func(id int64) (service.ContactOut, error) {
+			return service.ContactOut{}, &service.Error{Code: http.StatusNotFound, Message: "contact not found"}
+		},
+		UpdateFn: func(id int64, in service.ContactUpdateIn) (service.ContactOut, error) {
+			updated = in
+			return service.ContactOut{ID: id, JobTitle: *in.JobTitle}, nil
+		},
+	}
+	cl := dial(t, grpcserver.NewContacts(logger.New("dev"), svc, &mockEvents{}, make(chanHub)))
+	ctx := context.Background()
+
+	c, err := cl.CreateContact(ctx, &phonebookv1.CreateContactRequest{Contact: &phonebookv1.ContactInput{
+		FirstName: "Aru", LastName: "Sadykova", Phones: []*phonebookv1.Phone{{PhoneRaw: "8 701 123 45 67"}},
+	}})
+	if err != nil || c.GetId() != 1 || c.GetPhones()[0].GetPhoneE164() != "+77011234567" {
+		t.Fatalf("create: %v %v", c, err)
+	}
+
+	_, err = cl.CreateContact(ctx, &phonebookv1.CreateContactRequest{Contact: &phonebookv1.ContactInput{FirstName: "Aru"}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("validation error must be InvalidArgument, got %v", err)
+	}
+	if _, err = cl.GetContact(ctx, &phonebookv1.GetContactRequest{Id: 9}); status.Code(err) != codes.NotFound {
+		t.Fatalf("want NotFound, got %v", err)
+	}
+
+	c, err = cl.UpdateContact(ctx, &phonebookv1.UpdateContactRequest{Id: 1,
+		Contact:    &phonebookv1.ContactInput{JobTitle: "CTO", FirstName: "ignored"},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"job_title"}},
+	})
+	if err != nil || c.GetJobTitle() != "CTO" {
+		t.Fatalf("update: %v %v", c, err)
+	}
+	if updated.FirstName != nil || updated.Phones != nil {
+		t.Fatalf("fields outside update_mask must stay untouched: %+v", updated)
+	}
+	_, err = cl.UpdateContact(ctx, &phonebookv1.UpdateContactRequest{Id: 1, Contact: &phonebookv1.ContactInput{},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"nickname"}}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("unknown mask path: %v", err)
+	}
+}
+
+// Watch с after_event_id: пропущенные события из журнала, затем живые без повторов
+func TestContacts_WatchResume(t *testing.T) {
+	evs := &mockEvents{log: []service.EventOut{{ID: 5, Op: "created"}, {ID: 6, Op: "updated"}, {ID: 7, Op: "deleted"}}}
+	hub := make(chanHub, 2)
+	hub <- service.EventOut{ID: 7, Op: "deleted"}
+	hub <- service.EventOut{ID: 8, ContactID: 3, Op: "created", At: time.Unix(100, 0)}
+	cl := dial(t, grpcserver.NewContacts(logger.New("dev"), &mockContacts{}, evs, hub))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := cl.Watch(ctx, &phonebookv1.WatchRequest{AfterEventId: proto.Int64(5)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []int64
+	for len(got) < 3 {
+		e, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("recv after %v: %v", got, err)
+		}
+		got = append(got, e.GetId())
+		if e.GetId() == 8 && (e.GetOp() != phonebookv1.ContactEvent_OP_CREATED || e.GetContactId() != 3) {
+			t.Fatalf("live event: %v", e)
+		}
+	}
+	if got[0] != 6 || got[1] != 7 || got[2] != 8 {
+		t.Fatalf("events: %v", got)
+	}
+}
+
+// memContact — один контакт в памяти за настоящим service.Service
+type memContact struct {
+	repository.ContactsRepository
+	c repository.Contact
+}
+
+func (m *memContact) Get(_ context.Context, id int64) (repository.Contact, error) {
+	if id != m.c.ID {
+		return repository.Contact{}, repository.ErrNotFound
+	}
+	return m.c, nil
+}
+
+func (m *memContact) Update(ctx context.Context, id int64, p repository.ContactPatch) (repository.Contact, error) {
+	if p.Phones != nil {
+		m.c.Phones = nil
+		for _, ph := range *p.Phones {
+			m.c.Phones = append(m.c.Phones, repository.Phone{Label: ph.Label, PhoneRaw: ph.PhoneRaw, PhoneE164: ph.PhoneE164,
+				PhoneDigits: ph.PhoneDigits, IsPrimary: ph.IsPrimary, Visibility: ph.Visibility})
+		}
+	}
+	return m.Get(ctx, id)
+}
+
+type openACL struct{ repository.ACLRepository }
+
+func (openACL) Access(context.Context, int64) (repository.ContactAccess, error) {
+	return repository.ContactAccess{}, nil
+}
+
+// asAdmin — вызовы от администратора книги (перехватчик авторизации здесь не подключён)
+type asAdmin struct{ *service.Service }
+
+func (a asAdmin) UpdateContact(ctx context.Context, id int64, in service.ContactUpdateIn) (service.ContactOut, error) {
+	ctx = service.WithPrincipal(ctx, service.Principal{Subject: "user:admin", Scopes: []string{service.ScopeAdmin}})
+	return a.Service.UpdateContact(ctx, id, in)
+}
+
+// замена телефонов без visibility не открывает private-номер, даже у администратора
+func TestContacts_UpdateKeepsPhoneVisibility(t *testing.T) {
+	repo := &memContact{c: repository.Contact{ID: 1, FirstName: "Aigerim", LastName: "Nurova", Phones: []repository.Phone{
+		{Label: "work", PhoneRaw: "+77010000001", PhoneE164: "+77010000001", PhoneDigits: "77010000001", IsPrimary: true, Visibility: "public"},
+		{Label: "home", PhoneRaw: "+77010000002", PhoneE164: "+77010000002", PhoneDigits: "77010000002", Visibility: "private"},
+	}}}
+	svc := service.New(logger.New("dev"), &repository.Repos{Contacts: repo, ACL: openACL{}}, nil, nil)
+	cl := dial(t, grpcserver.NewContacts(logger.New("dev"), asAdmin{svc}, &mockEvents{}, make(chanHub)))
+
+	c, err := cl.UpdateContact(context.Background(), &phonebookv1.UpdateContactRequest{Id: 1,
+		Contact: &phonebookv1.ContactInput{Phones: []*phonebookv1.Phone{
+			{Label: "work", PhoneRaw: "+77010000001", IsPrimary: true},
+			{Label: "home", PhoneRaw: "+77010000002"},
+			{Label: "mobile", PhoneRaw: "+77010000003", Visibility: "internal"},
+		}},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"phones"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, ph := range repo.c.Phones {
+		got[ph.PhoneDigits] = ph.Visibility
+	}
+	want := map[string]string{"77010000001": "public", "77010000002": "private", "77010000003": "internal"}
+	if !maps.Equal(got, want) {
+		t.Fatalf("stored visibility = %v, want %v", got, want)
+	}
+	if v := c.GetPhones()[1].GetVisibility(); v != "private" {
+		t.Fatalf("response visibility = %q", v)
+	}
+}
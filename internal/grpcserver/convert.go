@@ -0,0 +1,144 @@
+package grpcserver
+
+import (
+	"fmt"
+
+	phonebookv1 "github.com/sunzhqr/phonebook/api/phonebook/v1"
+	"github.com/sunzhqr/phonebook/internal/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func createIn(c *phonebookv1.ContactInput) service.ContactCreateIn {
+	return service.ContactCreateIn{
+		FirstName: c.GetFirstName(), LastName: c.GetLastName(), Company: c.GetCompany(),
+		OrganizationID: c.GetOrganizationId(), JobTitle: c.GetJobTitle(), Department: c.GetDepartment(),
+		Phones: phonesIn(c.GetPhones()), Emails: emailsIn(c.GetEmails()),
+		Addresses: addressesIn(c.GetAddresses()), Websites: websitesIn(c.GetWebsites()),
+		Custom: c.GetCustom().AsMap(),
+	}
+}
+
+// updateIn — пути маски — имена полей ContactInput; пустая маска означает все поля
+func updateIn(c *phonebookv1.ContactInput, paths []string) (service.ContactUpdateIn, error) {
+	if len(paths) == 0 {
+		paths = []string{"first_name", "last_name", "company", "organization_id", "job_title", "department",
+			"phones", "emails", "addresses", "websites", "custom"}
+	}
+	var in service.ContactUpdateIn
+	for _, p := range paths {
+		switch p {
+		case "first_name":
+			in.FirstName = ptr(c.GetFirstName())
+		case "last_name":
+			in.LastName = ptr(c.GetLastName())
+		case "company":
+			in.Company = ptr(c.GetCompany())
+		case "organization_id":
+			in.OrganizationID = ptr(c.GetOrganizationId())
+		case "job_title":
+			in.JobTitle = ptr(c.GetJobTitle())
+		case "department":
+			in.Department = ptr(c.GetDepartment())
+		case "phones":
+			in.Phones = ptr(phonesIn(c.GetPhones()))
+		case "emails":
+			in.Emails = ptr(emailsIn(c.GetEmails()))
+		case "addresses":
+			in.Addresses = ptr(addressesIn(c.GetAddresses()))
+		case "websites":
+			in.Websites = ptr(websitesIn(c.GetWebsites()))
+		case "custom":
+			in.Custom = c.GetCustom().AsMap()
+		default:
+			return service.ContactUpdateIn{}, status.Errorf(codes.InvalidArgument, "unknown update_mask path %q", p)
+		}
+	}
+	return in, nil
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func phonesIn(list []*phonebookv1.Phone) []service.PhoneIn {
+	out := make([]service.PhoneIn, 0, len(list))
+	for _, p := range list {
+		out = append(out, service.PhoneIn{Label: p.GetLabel(), PhoneRaw: p.GetPhoneRaw(), IsPrimary: p.GetIsPrimary(), Visibility: p.GetVisibility()})
+	}
+	return out
+}
+
+func emailsIn(list []*phonebookv1.Email) []service.EmailIn {
+	out := make([]service.EmailIn, 0, len(list))
+	for _, e := range list {
+		out = append(out, service.EmailIn{Label: e.GetLabel(), Email: e.GetEmail(), IsPrimary: e.GetIsPrimary()})
+	}
+	return out
+}
+
+func addressesIn(list []*phonebookv1.Address) []service.AddressIn {
+	out := make([]service.AddressIn, 0, len(list))
+	for _, a := range list {
+		out = append(out, service.AddressIn{
+			Label: a.GetLabel(), Street: a.GetStreet(), City: a.GetCity(), Region: a.GetRegion(),
+			PostalCode: a.GetPostalCode(), Country: a.GetCountry(), IsPrimary: a.GetIsPrimary(),
+		})
+	}
+	return out
+}
+
+func websitesIn(list []*phonebookv1.Website) []service.WebsiteIn {
+	out := make([]service.WebsiteIn, 0, len(list))
+	for _, w := range list {
+		out = append(out, service.WebsiteIn{Label: w.GetLabel(), URL: w.GetUrl(), IsPrimary: w.GetIsPrimary()})
+	}
+	return out
+}
+
+func contactOut(c service.ContactOut) (*phonebookv1.Contact, error) {
+	out := &phonebookv1.Contact{
+		Id: c.ID, FirstName: c.FirstName, LastName: c.LastName, Company: c.Company,
+		JobTitle: c.JobTitle, Department: c.Department, PhotoUrl: c.PhotoURL,
+		CreatedAt: timestamppb.New(c.CreatedAt), UpdatedAt: timestamppb.New(c.UpdatedAt),
+	}
+	if c.Organization != nil {
+		out.Organization = &phonebookv1.OrganizationRef{Id: c.Organization.ID, Name: c.Organization.Name}
+	}
+	if c.External != nil {
+		out.External = &phonebookv1.ExternalRef{Source: c.External.Source, Id: c.External.ID}
+	}
+	for _, p := range c.Phones {
+		out.Phones = append(out.Phones, &phonebookv1.Phone{Label: p.Label, PhoneRaw: p.PhoneRaw, PhoneE164: p.PhoneE164, IsPrimary: p.IsPrimary, Visibility: p.Visibility})
+	}
+	for _, e := range c.Emails {
+		out.Emails = append(out.Emails, &phonebookv1.Email{Label: e.Label, Email: e.Email, IsPrimary: e.IsPrimary})
+	}
+	for _, a := range c.Addresses {
+		out.Addresses = append(out.Addresses, &phonebookv1.Address{
+			Label: a.Label, Street: a.Street, City: a.City, Region: a.Region,
+			PostalCode: a.PostalCode, Country: a.Country, IsPrimary: a.IsPrimary,
+		})
+	}
+	for _, w := range c.Websites {
+		out.Websites = append(out.Websites, &phonebookv1.Website{Label: w.Label, Url: w.URL, IsPrimary: w.IsPrimary})
+	}
+	if len(c.Custom) > 0 {
+		custom, err := structpb.NewStruct(c.Custom)
+		if err != nil {
+			return nil, fmt.Errorf("contact %d custom fields: %w", c.ID, err)
+		}
+		out.Custom = custom
+	}
+	return out, nil
+}
+
+var eventOps = map[string]phonebookv1.ContactEvent_Op{
+	"created": phonebookv1.ContactEvent_OP_CREATED,
+	"updated": phonebookv1.ContactEvent_OP_UPDATED,
+	"deleted": phonebookv1.ContactEvent_OP_DELETED,
+}
+
+func eventOut(e service.EventOut) *phonebookv1.ContactEvent {
+	return &phonebookv1.ContactEvent{Id: e.ID, ContactId: e.ContactID, Op: eventOps[e.Op], OccurredAt: timestamppb.New(e.At)}
+}
@@ -0,0 +1,160 @@
+// Package grpcserver — gRPC API (phonebook.v1) для внутренних сервисов; тонкий адаптер над service,
+// работает рядом с REST на отдельном порту.
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"runtime/debug"
+
+	phonebookv1 "github.com/sunzhqr/phonebook/api/phonebook/v1"
+	"github.com/sunzhqr/phonebook/internal/config"
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type Server struct {
+	grpc *grpc.Server
+	addr string
+	lg   *logger.Logger
+}
+
+// Services — службы, которые нужны gRPC API; *service.Service реализует их все
+type Services interface {
+	service.ContactsService
+	service.EventsService
+	service.AddressBooksService
+}
+
+func New(lg *logger.Logger, cfg config.GRPC, svc Services, hub Subscriber, authn service.AuthService) *Server {
+	a := auth{lg: lg, authn: authn, books: svc}
+	s := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(recoverUnary(lg), requestInfo(), a.unary()),
+		grpc.ChainStreamInterceptor(recoverStream(lg), a.stream()),
+		grpc.MaxRecvMsgSize(cfg.MaxRecvBytes),
+		// Watch держит поток часами; пинги не дают балансировщикам закрыть простаивающее соединение
+		grpc.KeepaliveParams(keepalive.ServerParameters{Time: cfg.KeepaliveTime}),
+	)
+	phonebookv1.RegisterContactsServer(s, NewContacts(lg, svc, svc, hub))
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	return &Server{grpc: s, addr: cfg.Addr, lg: lg}
+}
+
+func (s *Server) Start() error {
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	return s.grpc.Serve(ln)
+}
+
+// Stop — дожидается текущих вызовов; по истечении ctx обрывает оставшиеся (потоки Watch).
+func (s *Server) Stop(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.grpc.Stop()
+		return ctx.Err()
+	}
+}
+
+// statusErr — ошибка сервиса в статус gRPC; неизвестные ошибки пишутся в лог и не раскрываются клиенту
+func statusErr(lg *logger.Logger, err error) error {
+	var se *service.Error
+	switch {
+	case errors.As(err, &se):
+		return status.Error(grpcCode(se.Code), se.Message)
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	lg.Error("grpc call failed", logger.Err(err))
+	return status.Error(codes.Internal, http.StatusText(http.StatusInternalServerError))
+}
+
+// grpcCode — соответствие HTTP-кодов service.Error кодам gRPC
+func grpcCode(httpCode int) codes.Code {
+	switch httpCode {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusGone, http.StatusPreconditionFailed, http.StatusFailedDependency:
+		return codes.FailedPrecondition
+	case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+	return codes.Internal
+}
+
+func recoverUnary(lg *logger.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				lg.Error("panic", logger.KV("method", info.FullMethod), logger.KV("panic", rec), logger.KV("stack", string(debug.Stack())))
+				err = status.Error(codes.Internal, http.StatusText(http.StatusInternalServerError))
+			}
+		}()
+		return next(ctx, req)
+	}
+}
+
+// requestInfo — адрес клиента и x-request-id из метаданных для журнала аудита
+func requestInfo() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, next grpc.UnaryHandler) (any, error) {
+		var ri service.RequestInfo
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			ri.IP = p.Addr.String()
+			if host, _, err := net.SplitHostPort(ri.IP); err == nil {
+				ri.IP = host
+			}
+		}
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if v := md.Get("x-request-id"); len(v) > 0 {
+				ri.ID = v[0]
+			}
+		}
+		return next(service.WithRequestInfo(ctx, ri), req)
+	}
+}
+
+func recoverStream(lg *logger.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, next grpc.StreamHandler) (err error) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				lg.Error("panic", logger.KV("method", info.FullMethod), logger.KV("panic", rec), logger.KV("stack", string(debug.Stack())))
+				err = status.Error(codes.Internal, http.StatusText(http.StatusInternalServerError))
+			}
+		}()
+		return next(srv, ss)
+	}
+}
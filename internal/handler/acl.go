@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+type ACLHandler struct {
+	lg  *logger.Logger
+	svc service.AccessService
+}
+
+func NewACL(lg *logger.Logger, svc service.AccessService) *ACLHandler {
+	return &ACLHandler{lg: lg, svc: svc}
+}
+
+func (h *ACLHandler) GetContact(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	res, err := h.svc.GetContactACL(r.Context(), id)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// SetContact — PUT /contacts/{id}/acl заменяет выдачи целиком; {"grants":[]} снимает ограничение
+func (h *ACLHandler) SetContact(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	in, ok := decodeACL(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.svc.SetContactACL(r.Context(), id, in)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (h *ACLHandler) GetTag(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.GetTagACL(r.Context(), chi.URLParam(r, "tag"))
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (h *ACLHandler) SetTag(w http.ResponseWriter, r *http.Request) {
+	in, ok := decodeACL(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.svc.SetTagACL(r.Context(), chi.URLParam(r, "tag"), in)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func decodeACL(w http.ResponseWriter, r *http.Request) (service.ACLIn, bool) {
+	var dto ACLDTO
+	dec := json.NewDecoder(io.LimitReader(r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&dto); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return service.ACLIn{}, false
+	}
+	in := service.ACLIn{Grants: make([]service.GrantIn, 0, len(dto.Grants))}
+	for _, g := range dto.Grants {
+		in.Grants = append(in.Grants, service.GrantIn{Principal: g.Principal, Role: g.Role})
+	}
+	return in, true
+}
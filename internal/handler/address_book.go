@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+type AddressBooksHandler struct {
+	lg  *logger.Logger
+	svc service.AddressBooksService
+}
+
+func NewAddressBooks(lg *logger.Logger, svc service.AddressBooksService) *AddressBooksHandler {
+	return &AddressBooksHandler{lg: lg, svc: svc}
+}
+
+func (h *AddressBooksHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var dto AddressBookCreateDTO
+	dec := json.NewDecoder(io.LimitReader(r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&dto); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	res, err := h.svc.CreateAddressBook(r.Context(), service.AddressBookIn{Slug: dto.Slug, Name: dto.Name, DefaultRegion: dto.DefaultRegion})
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, res)
+}
+
+func (h *AddressBooksHandler) List(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.ListAddressBooks(r.Context())
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (h *AddressBooksHandler) Get(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.ResolveAddressBook(r.Context(), chi.URLParam(r, "slug"))
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// Update — PUT /address-books/{slug}; slug не меняется, он зашит в клиентах и ключах
+func (h *AddressBooksHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var dto AddressBookUpdateDTO
+	dec := json.NewDecoder(io.LimitReader(r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&dto); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	res, err := h.svc.UpdateAddressBook(r.Context(), chi.URLParam(r, "slug"), service.AddressBookUpdateIn{Name: dto.Name, DefaultRegion: dto.DefaultRegion})
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+type APIKeysHandler struct {
+	lg  *logger.Logger
+	svc service.APIKeysService
+}
+
+func NewAPIKeys(lg *logger.Logger, svc service.APIKeysService) *APIKeysHandler {
+	return &APIKeysHandler{lg: lg, svc: svc}
+}
+
+// Create — POST /api-keys; ключ возвращается только в этом ответе
+func (h *APIKeysHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var dto APIKeyCreateDTO
+	dec := json.NewDecoder(io.LimitReader(r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&dto); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	res, err := h.svc.CreateAPIKey(r.Context(), service.APIKeyCreateIn{Name: dto.Name, Scopes: dto.Scopes, ExpiresAt: dto.ExpiresAt,
+		AddressBook: dto.AddressBook})
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, res)
+}
+
+func (h *APIKeysHandler) List(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.ListAPIKeys(r.Context())
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (h *APIKeysHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	res, err := h.svc.GetAPIKey(r.Context(), id)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// Revoke — DELETE /api-keys/{id}: ключ отзывается, запись с revoked_at остаётся в списке
+func (h *APIKeysHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	if _, err := h.svc.RevokeAPIKey(r.Context(), id); err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
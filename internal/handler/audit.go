@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+type AuditHandler struct {
+	lg  *logger.Logger
+	svc service.AuditService
+}
+
+func NewAudit(lg *logger.Logger, svc service.AuditService) *AuditHandler {
+	return &AuditHandler{lg: lg, svc: svc}
+}
+
+// List — GET /audit?actor=&action=&contact_id=&since=&until=&before_id=&limit=; время в RFC 3339
+func (h *AuditHandler) List(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := service.AuditFilter{Actor: q.Get("actor"), Action: q.Get("action")}
+	f.ContactID, _ = strconv.ParseInt(q.Get("contact_id"), 10, 64)
+	f.BeforeID, _ = strconv.ParseInt(q.Get("before_id"), 10, 64)
+	f.Limit, _ = strconv.Atoi(q.Get("limit"))
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"since", &f.Since}, {"until", &f.Until}} {
+		if v := q.Get(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				http.Error(w, "bad "+p.name+", expected RFC 3339", http.StatusBadRequest)
+				return
+			}
+			*p.dst = t
+		}
+	}
+	res, err := h.svc.ListAudit(r.Context(), f)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+// maxBatchBytes — тело пакета: до service.MaxBatchOps контактов
+const maxBatchBytes = 8 << 20
+
+type BatchHandler struct {
+	lg  *logger.Logger
+	svc service.BatchService
+}
+
+func NewBatch(lg *logger.Logger, svc service.BatchService) *BatchHandler {
+	return &BatchHandler{lg: lg, svc: svc}
+}
+
+// Batch — POST /contacts:batch. Отвечает результатами в порядке операций: 200, если пакет применён
+// (в режиме partial — хотя бы частично), 422, если атомарный пакет откачен.
+func (h *BatchHandler) Batch(w http.ResponseWriter, r *http.Request) {
+	var dto BatchDTO
+	dec := json.NewDecoder(io.LimitReader(r.Body, maxBatchBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&dto); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	var atomic bool
+	switch dto.Mode {
+	case "atomic", "":
+		atomic = true
+	case "partial":
+	default:
+		http.Error(w, "bad mode, expected atomic or partial", http.StatusBadRequest)
+		return
+	}
+
+	ops := make([]service.BatchOpIn, len(dto.Operations))
+	for i, o := range dto.Operations {
+		ops[i] = service.BatchOpIn{Op: o.Op, ID: o.ID}
+		var err error
+		switch o.Op {
+		case service.BatchCreate:
+			var c ContactCreateDTO
+			err = decodeStrict(o.Contact, &c)
+			ops[i].Create = createIn(c)
+		case service.BatchUpdate:
+			var c ContactUpdateDTO
+			err = decodeStrict(o.Contact, &c)
+			ops[i].Update = updateIn(c)
+		}
+		if err != nil {
+			http.Error(w, "invalid json in operation "+strconv.Itoa(i), http.StatusBadRequest)
+			return
+		}
+	}
+
+	res, err := h.svc.Batch(r.Context(), ops, atomic)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	code := http.StatusOK
+	if !res.Applied {
+		code = http.StatusUnprocessableEntity
+	}
+	writeJSON(w, code, res)
+}
+
+// decodeStrict — пустой contact допустим (update без изменений), лишние поля — нет.
+func decodeStrict(raw json.RawMessage, v any) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/sunzhqr/phonebook/internal/logger"
@@ -26,12 +28,7 @@ func (h *Handler) CreateContact(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid json", http.StatusBadRequest)
 		return
 	}
-	in := service.ContactCreateIn{FirstName: dto.FirstName, LastName: dto.LastName, Company: dto.Company}
-	in.Phones = make([]service.PhoneIn, 0, len(dto.Phones))
-	for _, p := range dto.Phones {
-		in.Phones = append(in.Phones, service.PhoneIn{Label: p.Label, PhoneRaw: p.PhoneRaw, IsPrimary: p.IsPrimary})
-	}
-	res, err := h.svc.CreateContact(r.Context(), in)
+	res, err := h.svc.CreateContact(r.Context(), createIn(dto))
 	if err != nil {
 		writeSvcErr(w, err)
 		return
@@ -45,7 +42,11 @@ func (h *Handler) GetContact(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "bad id", http.StatusBadRequest)
 		return
 	}
-	res, err := h.svc.GetContact(r.Context(), id)
+	var expand []string
+	if e := r.URL.Query().Get("expand"); e != "" {
+		expand = strings.Split(e, ",")
+	}
+	res, err := h.svc.GetContact(r.Context(), id, expand...)
 	if err != nil {
 		writeSvcErr(w, err)
 		return
@@ -68,23 +69,7 @@ func (h *Handler) UpdateContact(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	in := service.ContactUpdateIn{
-		FirstName: dto.FirstName,
-		LastName:  dto.LastName,
-		Company:   dto.Company,
-	}
-
-	if dto.Phones != nil { // ← защита от nil
-		arr := make([]service.PhoneIn, 0, len(*dto.Phones))
-		for _, p := range *dto.Phones {
-			arr = append(arr, service.PhoneIn{
-				Label: p.Label, PhoneRaw: p.PhoneRaw, IsPrimary: p.IsPrimary,
-			})
-		}
-		in.Phones = &arr
-	}
-
-	res, err := h.svc.UpdateContact(r.Context(), id, in)
+	res, err := h.svc.UpdateContact(r.Context(), id, updateIn(dto))
 	if err != nil {
 		writeSvcErr(w, err)
 		return
@@ -106,14 +91,7 @@ func (h *Handler) DeleteContact(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) ListContacts(w http.ResponseWriter, r *http.Request) {
-	q := r.URL.Query()
-	afterID, _ := strconv.ParseInt(q.Get("after_id"), 10, 64)
-	limit, _ := strconv.Atoi(q.Get("limit"))
-	if limit <= 0 || limit > 100 {
-		limit = 20
-	}
-	filter := service.ListFilter{FirstName: q.Get("first_name"), LastName: q.Get("last_name"), Company: q.Get("company"), Phone: q.Get("phone"), AfterID: afterID, Limit: limit, Sort: q.Get("sort"), Order: q.Get("order")}
-	res, err := h.svc.ListContacts(r.Context(), filter)
+	res, err := h.svc.ListContacts(r.Context(), listFilter(r.URL.Query()))
 	if err != nil {
 		writeSvcErr(w, err)
 		return
@@ -135,6 +113,26 @@ func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, res)
 }
 
+// listFilter — фильтры, сортировка и пагинация списка контактов из query-параметров
+func listFilter(q url.Values) service.ListFilter {
+	afterID, _ := strconv.ParseInt(q.Get("after_id"), 10, 64)
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	filter := service.ListFilter{FirstName: q.Get("first_name"), LastName: q.Get("last_name"), Company: q.Get("company"), Phone: q.Get("phone"), Email: q.Get("email"), Tag: q.Get("tag"), AfterID: afterID, Limit: limit, Sort: q.Get("sort"), Order: q.Get("order")}
+	// фильтры по пользовательским полям: ?cf.department=Sales
+	for k := range q {
+		if name, ok := strings.CutPrefix(k, "cf."); ok && name != "" {
+			if filter.Custom == nil {
+				filter.Custom = make(map[string]string)
+			}
+			filter.Custom[name] = q.Get(k)
+		}
+	}
+	return filter
+}
+
 func writeJSON(w http.ResponseWriter, code int, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
@@ -148,3 +146,52 @@ func writeSvcErr(w http.ResponseWriter, err error) {
 	}
 	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 }
+
+// UpsertExternal — PUT /contacts/by-external/{source}/{extID}: 201, если контакт создан, иначе 200.
+func (h *Handler) UpsertExternal(w http.ResponseWriter, r *http.Request) {
+	source, extID, ok := externalParams(w, r)
+	if !ok {
+		return
+	}
+	var dto ContactCreateDTO
+	dec := json.NewDecoder(io.LimitReader(r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&dto); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	res, created, err := h.svc.UpsertExternal(r.Context(), source, extID, createIn(dto))
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	code := http.StatusOK
+	if created {
+		code = http.StatusCreated
+	}
+	writeJSON(w, code, res)
+}
+
+func (h *Handler) GetByExternal(w http.ResponseWriter, r *http.Request) {
+	source, extID, ok := externalParams(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.svc.GetByExternal(r.Context(), source, extID)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// externalParams — внешний id может содержать «/» (DN из AD), клиент передаёт его экранированным.
+func externalParams(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	source, err1 := url.PathUnescape(chi.URLParam(r, "source"))
+	extID, err2 := url.PathUnescape(chi.URLParam(r, "extID"))
+	if err1 != nil || err2 != nil {
+		http.Error(w, "bad external id", http.StatusBadRequest)
+		return "", "", false
+	}
+	return source, extID, true
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+type CSVImportHandler struct {
+	lg  *logger.Logger
+	svc service.ImportService
+}
+
+func NewCSVImport(lg *logger.Logger, svc service.ImportService) *CSVImportHandler {
+	return &CSVImportHandler{lg: lg, svc: svc}
+}
+
+// Import — POST /import/csv[?dry_run=true], multipart/form-data: часть "mapping" (JSON) и "file" (CSV).
+func (h *CSVImportHandler) Import(w http.ResponseWriter, r *http.Request) {
+	dryRun, err := strconv.ParseBool(strings.TrimSpace(r.URL.Query().Get("dry_run")))
+	if err != nil && r.URL.Query().Has("dry_run") {
+		http.Error(w, "bad dry_run", http.StatusBadRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	if err := r.ParseMultipartForm(maxImportBytes); err != nil {
+		var mbe *http.MaxBytesError
+		if errors.As(err, &mbe) {
+			http.Error(w, "body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "expected multipart/form-data with mapping and file", http.StatusBadRequest)
+		return
+	}
+	defer func() { _ = r.MultipartForm.RemoveAll() }()
+
+	var dto CSVMappingDTO
+	dec := json.NewDecoder(strings.NewReader(r.FormValue("mapping")))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&dto); err != nil {
+		http.Error(w, "invalid mapping json", http.StatusBadRequest)
+		return
+	}
+	f, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "file is required", http.StatusBadRequest)
+		return
+	}
+	defer f.Close()
+
+	rep, err := h.svc.ImportCSV(r.Context(), f, service.CSVMapping{
+		Delimiter:  dto.Delimiter,
+		FirstName:  dto.FirstName,
+		LastName:   dto.LastName,
+		Company:    dto.Company,
+		JobTitle:   dto.JobTitle,
+		Department: dto.Department,
+		Phones:     csvColumnsIn(dto.Phones),
+		Emails:     csvColumnsIn(dto.Emails),
+		Custom:     dto.Custom,
+	}, dryRun)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, rep)
+}
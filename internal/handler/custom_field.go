@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+// CustomFieldsHandler — администрирование реестра пользовательских полей
+type CustomFieldsHandler struct {
+	lg  *logger.Logger
+	svc service.CustomFieldsService
+}
+
+func NewCustomFields(lg *logger.Logger, svc service.CustomFieldsService) *CustomFieldsHandler {
+	return &CustomFieldsHandler{lg: lg, svc: svc}
+}
+
+func (h *CustomFieldsHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var dto CustomFieldCreateDTO
+	dec := json.NewDecoder(io.LimitReader(r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&dto); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	res, err := h.svc.CreateCustomField(r.Context(), service.CustomFieldIn{
+		Name:        dto.Name,
+		Label:       dto.Label,
+		Type:        dto.Type,
+		Required:    dto.Required,
+		Constraints: service.FieldConstraints(dto.Constraints),
+	})
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, res)
+}
+
+func (h *CustomFieldsHandler) List(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.ListCustomFields(r.Context())
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (h *CustomFieldsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.GetCustomField(r.Context(), chi.URLParam(r, "name"))
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (h *CustomFieldsHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var dto CustomFieldUpdateDTO
+	dec := json.NewDecoder(io.LimitReader(r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&dto); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	in := service.CustomFieldUpdateIn{Label: dto.Label, Required: dto.Required}
+	if dto.Constraints != nil {
+		c := service.FieldConstraints(*dto.Constraints)
+		in.Constraints = &c
+	}
+	res, err := h.svc.UpdateCustomField(r.Context(), chi.URLParam(r, "name"), in)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (h *CustomFieldsHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.svc.DeleteCustomField(r.Context(), chi.URLParam(r, "name")); err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,324 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+// DirectoryHandler — XML-справочники для IP-телефонов (Cisco, Yealink, Grandstream)
+type DirectoryHandler struct {
+	lg  *logger.Logger
+	svc service.ExportService
+}
+
+func NewDirectory(lg *logger.Logger, svc service.ExportService) *DirectoryHandler {
+	return &DirectoryHandler{lg: lg, svc: svc}
+}
+
+// dirEntry — запись справочника; у Cisco на каждый номер своя запись
+type dirEntry struct {
+	First, Last, Name, Company string
+	Phones                     []dirPhone
+}
+
+type dirPhone struct {
+	Kind   string // work, mobile, home
+	Number string
+}
+
+// dirVendor — формат и ограничения прошивки: сколько записей на странице, длина имени и номера
+// в символах, сколько номеров в одной записи
+type dirVendor struct {
+	pageSize  int
+	nameLen   int
+	numberLen int
+	phones    int
+	perPhone  bool // отдельная запись на каждый номер
+	render    func(p dirPage) any
+}
+
+type dirPage struct {
+	title   string
+	entries []dirEntry
+	from    int // номер первой записи страницы, с 1
+	next    string
+}
+
+var dirVendors = map[string]dirVendor{
+	// CiscoIPPhoneDirectory: не больше 32 записей, Name и Telephone до 32 символов, следующая страница — софткей
+	"cisco": {pageSize: 32, nameLen: 32, numberLen: 32, phones: 1, perPhone: true, render: ciscoDirectory},
+	// удалённая телефонная книга Yealink: до 3 номеров в записи
+	"yealink": {pageSize: 1000, nameLen: 32, numberLen: 32, phones: 3, render: yealinkDirectory},
+	// phonebook.xml Grandstream: имя и фамилия раздельно, тип номера Work/Cell/Home
+	"grandstream": {pageSize: 1000, nameLen: 32, numberLen: 32, phones: 3, render: grandstreamDirectory},
+}
+
+// errPageFull — останавливает выгрузку, когда страница набрана
+var errPageFull = errors.New("page is full")
+
+// dirMaxPage — предел номера страницы и курсора: дальше смещение в SQL теряет смысл, а (page-1)*pageSize
+// не переполняется
+const dirMaxPage = 10000
+
+// Directory — GET /directory/{vendor}.xml с теми же фильтрами, что список контактов (в том числе cf.<поле>
+// для отдельных групп), по умолчанию по имени. ?page=N — страница размера, принятого у вендора.
+// Ссылка на следующую страницу несёт курсор: offset — сколько контактов уже отдано целиком (пропускается
+// в SQL), skip — сколько записей следующего контакта уже было на прошлой странице. Без курсора страница
+// начинается с контакта (N-1)*размер — точно, пока у каждого контакта одна запись.
+func (h *DirectoryHandler) Directory(w http.ResponseWriter, r *http.Request) {
+	v, ok := dirVendors[chi.URLParam(r, "vendor")]
+	if !ok {
+		http.Error(w, "unknown vendor, expected cisco, yealink or grandstream", http.StatusNotFound)
+		return
+	}
+	q := r.URL.Query()
+	page, ok := dirParam(q, "page", 1, 1, dirMaxPage)
+	if !ok {
+		http.Error(w, "bad page", http.StatusBadRequest)
+		return
+	}
+	offset, ok := dirParam(q, "offset", (page-1)*v.pageSize, 0, dirMaxPage*v.pageSize)
+	if !ok {
+		http.Error(w, "bad offset", http.StatusBadRequest)
+		return
+	}
+	skip, ok := dirParam(q, "skip", 0, 0, dirMaxPage*v.pageSize)
+	if !ok {
+		http.Error(w, "bad skip", http.StatusBadRequest)
+		return
+	}
+	f := listFilter(q)
+	if f.Sort == "" {
+		f.Sort, f.Order = "name", "asc"
+	}
+	f.Offset = offset
+
+	entries := make([]dirEntry, 0, min(v.pageSize, 100))
+	more := false
+	next, nextSkip := offset, 0
+	// контакт, показанный хотя бы частично, возвращает nil и попадает в аудит выгрузки; не показанный — errPageFull
+	err := h.svc.ExportContacts(r.Context(), f, func(c service.ContactOut) error {
+		if more {
+			return errPageFull
+		}
+		es := v.entries(c)
+		shown := min(skip, len(es))
+		es, skip = es[shown:], 0
+		room := v.pageSize - len(entries)
+		switch {
+		case len(es) > 0 && room == 0:
+			more, nextSkip = true, shown
+			return errPageFull
+		case len(es) > room:
+			entries = append(entries, es[:room]...)
+			more, nextSkip = true, shown+room
+			return nil
+		}
+		entries = append(entries, es...)
+		next++
+		return nil
+	})
+	if err != nil && !errors.Is(err, errPageFull) {
+		writeSvcErr(w, err)
+		return
+	}
+
+	p := dirPage{title: clip(q.Get("title"), v.nameLen), entries: entries, from: (page-1)*v.pageSize + 1}
+	if p.title == "" {
+		p.title = "Phonebook"
+	}
+	if more && page < dirMaxPage {
+		p.next = pageURL(r, page+1, next, nextSkip)
+	}
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	if err := xml.NewEncoder(&buf).Encode(v.render(p)); err != nil {
+		h.lg.Error("directory render failed", logger.Err(err))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	_, _ = w.Write(buf.Bytes())
+}
+
+// dirParam — целый параметр в [lo, hi]; def, если параметра нет
+func dirParam(q url.Values, name string, def, lo, hi int) (int, bool) {
+	s := q.Get(name)
+	if s == "" {
+		return def, true
+	}
+	n, err := strconv.Atoi(s)
+	return n, err == nil && n >= lo && n <= hi
+}
+
+// entries — контакт в записи вендора с учётом ограничений; контакт без номеров в справочник не попадает
+func (v dirVendor) entries(c service.ContactOut) []dirEntry {
+	base := dirEntry{
+		First:   clip(c.FirstName, v.nameLen),
+		Last:    clip(c.LastName, v.nameLen),
+		Name:    clip(strings.TrimSpace(c.FirstName+" "+c.LastName), v.nameLen),
+		Company: clip(c.Company, v.nameLen),
+	}
+	phones := make([]dirPhone, 0, len(c.Phones))
+	for _, p := range c.Phones {
+		if p.Redacted {
+			continue // маску не набрать
+		}
+		num := p.PhoneE164
+		if num == "" {
+			num = p.PhoneRaw
+		}
+		if len([]rune(num)) > v.numberLen {
+			continue // обрезанный номер набирал бы не туда
+		}
+		phones = append(phones, dirPhone{Kind: phoneKind(p.Label), Number: num})
+	}
+	if !v.perPhone {
+		if len(phones) == 0 {
+			return nil
+		}
+		base.Phones = phones[:min(len(phones), v.phones)]
+		return []dirEntry{base}
+	}
+	out := make([]dirEntry, 0, len(phones))
+	for _, p := range phones {
+		e := base
+		if len(phones) > 1 && p.Kind != "work" {
+			e.Name = clip(strings.TrimSpace(c.FirstName+" "+c.LastName), v.nameLen-len(p.Kind)-3) + " (" + p.Kind + ")"
+		}
+		e.Phones = []dirPhone{p}
+		out = append(out, e)
+	}
+	return out
+}
+
+func phoneKind(label string) string {
+	l := strings.ToLower(label)
+	switch {
+	case strings.Contains(l, "mob"), strings.Contains(l, "cell"), strings.Contains(l, "моб"):
+		return "mobile"
+	case strings.Contains(l, "home"), strings.Contains(l, "дом"):
+		return "home"
+	}
+	return "work"
+}
+
+// clip — не длиннее n символов (не байт: у телефонов лимиты в символах)
+func clip(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:max(n, 0)])
+	}
+	return s
+}
+
+// pageURL — абсолютный адрес страницы с курсором: телефон переходит по нему сам
+func pageURL(r *http.Request, page, offset, skip int) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	q := r.URL.Query()
+	q.Set("page", strconv.Itoa(page))
+	q.Set("offset", strconv.Itoa(offset))
+	if skip > 0 {
+		q.Set("skip", strconv.Itoa(skip))
+	} else {
+		q.Del("skip")
+	}
+	u := url.URL{Scheme: scheme, Host: r.Host, Path: r.URL.Path, RawQuery: q.Encode()}
+	return u.String()
+}
+
+type ciscoSoftKey struct {
+	Name     string `xml:"Name"`
+	URL      string `xml:"URL"`
+	Position int    `xml:"Position"`
+}
+
+type ciscoEntry struct {
+	Name      string `xml:"Name"`
+	Telephone string `xml:"Telephone"`
+}
+
+func ciscoDirectory(p dirPage) any {
+	type directory struct {
+		XMLName  xml.Name       `xml:"CiscoIPPhoneDirectory"`
+		Title    string         `xml:"Title"`
+		Prompt   string         `xml:"Prompt"`
+		Entries  []ciscoEntry   `xml:"DirectoryEntry"`
+		SoftKeys []ciscoSoftKey `xml:"SoftKeyItem"`
+	}
+	d := directory{Title: p.title}
+	for _, e := range p.entries {
+		d.Entries = append(d.Entries, ciscoEntry{Name: e.Name, Telephone: e.Phones[0].Number})
+	}
+	if n := len(p.entries); n > 0 {
+		d.Prompt = fmt.Sprintf("Records %d to %d", p.from, p.from+n-1)
+	} else {
+		d.Prompt = "No records"
+	}
+	d.SoftKeys = append(d.SoftKeys, ciscoSoftKey{Name: "Dial", URL: "SoftKey:Dial", Position: 1})
+	if p.next != "" {
+		d.SoftKeys = append(d.SoftKeys, ciscoSoftKey{Name: "Next", URL: p.next, Position: 2})
+	}
+	d.SoftKeys = append(d.SoftKeys, ciscoSoftKey{Name: "Exit", URL: "SoftKey:Exit", Position: 3})
+	return d
+}
+
+func yealinkDirectory(p dirPage) any {
+	type entry struct {
+		Name      string   `xml:"Name"`
+		Telephone []string `xml:"Telephone"`
+	}
+	type directory struct {
+		XMLName xml.Name `xml:"YealinkIPPhoneDirectory"`
+		Entries []entry  `xml:"DirectoryEntry"`
+	}
+	var d directory
+	for _, e := range p.entries {
+		ye := entry{Name: e.Name}
+		for _, ph := range e.Phones {
+			ye.Telephone = append(ye.Telephone, ph.Number)
+		}
+		d.Entries = append(d.Entries, ye)
+	}
+	return d
+}
+
+func grandstreamDirectory(p dirPage) any {
+	type phone struct {
+		Type         string `xml:"type,attr"`
+		PhoneNumber  string `xml:"phonenumber"`
+		AccountIndex int    `xml:"accountindex"`
+	}
+	type contact struct {
+		LastName  string  `xml:"LastName"`
+		FirstName string  `xml:"FirstName"`
+		Company   string  `xml:"Company,omitempty"`
+		Phones    []phone `xml:"Phone"`
+	}
+	type book struct {
+		XMLName  xml.Name  `xml:"AddressBook"`
+		Contacts []contact `xml:"Contact"`
+	}
+	types := map[string]string{"work": "Work", "mobile": "Cell", "home": "Home"}
+	var b book
+	for _, e := range p.entries {
+		c := contact{LastName: e.Last, FirstName: e.First, Company: e.Company}
+		for _, ph := range e.Phones {
+			c.Phones = append(c.Phones, phone{Type: types[ph.Kind], PhoneNumber: ph.Number, AccountIndex: 1})
+		}
+		b.Contacts = append(b.Contacts, c)
+	}
+	return b
+}
@@ -1,21 +1,276 @@
 package handler
 
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
 type PhoneDTO struct {
+	Label      string `json:"label"`
+	PhoneRaw   string `json:"phone_raw"`
+	IsPrimary  bool   `json:"is_primary"`
+	Visibility string `json:"visibility"`
+}
+
+type EmailDTO struct {
 	Label     string `json:"label"`
-	PhoneRaw  string `json:"phone_raw"`
+	Email     string `json:"email"`
+	IsPrimary bool   `json:"is_primary"`
+}
+
+type AddressDTO struct {
+	Label      string `json:"label"`
+	Street     string `json:"street"`
+	City       string `json:"city"`
+	Region     string `json:"region"`
+	PostalCode string `json:"postal_code"`
+	Country    string `json:"country"`
+	IsPrimary  bool   `json:"is_primary"`
+}
+
+type WebsiteDTO struct {
+	Label     string `json:"label"`
+	URL       string `json:"url"`
 	IsPrimary bool   `json:"is_primary"`
 }
 
 type ContactCreateDTO struct {
-	FirstName string     `json:"first_name"`
-	LastName  string     `json:"last_name"`
-	Company   string     `json:"company"`
-	Phones    []PhoneDTO `json:"phones"`
+	FirstName      string         `json:"first_name"`
+	LastName       string         `json:"last_name"`
+	Company        string         `json:"company"`
+	OrganizationID int64          `json:"organization_id"`
+	JobTitle       string         `json:"job_title"`
+	Department     string         `json:"department"`
+	Phones         []PhoneDTO     `json:"phones"`
+	Emails         []EmailDTO     `json:"emails"`
+	Addresses      []AddressDTO   `json:"addresses"`
+	Websites       []WebsiteDTO   `json:"websites"`
+	Custom         map[string]any `json:"custom"`
+	Tags           []string       `json:"tags"`
 }
 
 type ContactUpdateDTO struct {
-	FirstName *string     `json:"first_name"`
-	LastName  *string     `json:"last_name"`
-	Company   *string     `json:"company"`
-	Phones    *[]PhoneDTO `json:"phones"`
+	FirstName      *string        `json:"first_name"`
+	LastName       *string        `json:"last_name"`
+	Company        *string        `json:"company"`
+	OrganizationID *int64         `json:"organization_id"`
+	JobTitle       *string        `json:"job_title"`
+	Department     *string        `json:"department"`
+	Phones         *[]PhoneDTO    `json:"phones"`
+	Emails         *[]EmailDTO    `json:"emails"`
+	Addresses      *[]AddressDTO  `json:"addresses"`
+	Websites       *[]WebsiteDTO  `json:"websites"`
+	Custom         map[string]any `json:"custom"`
+	Tags           *[]string      `json:"tags"`
+}
+
+type OrganizationCreateDTO struct {
+	Name      string       `json:"name"`
+	Phones    []PhoneDTO   `json:"phones"`
+	Addresses []AddressDTO `json:"addresses"`
+}
+
+type OrganizationUpdateDTO struct {
+	Name      *string       `json:"name"`
+	Phones    *[]PhoneDTO   `json:"phones"`
+	Addresses *[]AddressDTO `json:"addresses"`
+}
+
+type RelationCreateDTO struct {
+	RelatedID     int64  `json:"related_id"`
+	Type          string `json:"type"`
+	Bidirectional bool   `json:"bidirectional"`
+}
+
+type CSVColumnDTO struct {
+	Column  string `json:"column"`
+	Label   string `json:"label"`
+	Primary bool   `json:"primary"`
+}
+
+// CSVMappingDTO — значения — имена колонок из заголовка CSV
+type CSVMappingDTO struct {
+	Delimiter  string            `json:"delimiter"`
+	FirstName  string            `json:"first_name"`
+	LastName   string            `json:"last_name"`
+	Company    string            `json:"company"`
+	JobTitle   string            `json:"job_title"`
+	Department string            `json:"department"`
+	Phones     []CSVColumnDTO    `json:"phones"`
+	Emails     []CSVColumnDTO    `json:"emails"`
+	Custom     map[string]string `json:"custom"`
+}
+
+type FieldConstraintsDTO struct {
+	MinLength *int     `json:"min_length"`
+	MaxLength *int     `json:"max_length"`
+	Pattern   string   `json:"pattern"`
+	Min       *float64 `json:"min"`
+	Max       *float64 `json:"max"`
+	Options   []string `json:"options"`
+}
+
+type CustomFieldCreateDTO struct {
+	Name        string              `json:"name"`
+	Label       string              `json:"label"`
+	Type        string              `json:"type"`
+	Required    bool                `json:"required"`
+	Constraints FieldConstraintsDTO `json:"constraints"`
+}
+
+type CustomFieldUpdateDTO struct {
+	Label       *string              `json:"label"`
+	Required    *bool                `json:"required"`
+	Constraints *FieldConstraintsDTO `json:"constraints"`
+}
+
+func emailsIn(dtos []EmailDTO) []service.EmailIn {
+	out := make([]service.EmailIn, 0, len(dtos))
+	for _, e := range dtos {
+		out = append(out, service.EmailIn{Label: e.Label, Email: e.Email, IsPrimary: e.IsPrimary})
+	}
+	return out
+}
+
+func addressesIn(dtos []AddressDTO) []service.AddressIn {
+	out := make([]service.AddressIn, 0, len(dtos))
+	for _, a := range dtos {
+		out = append(out, service.AddressIn{
+			Label: a.Label, Street: a.Street, City: a.City, Region: a.Region,
+			PostalCode: a.PostalCode, Country: a.Country, IsPrimary: a.IsPrimary,
+		})
+	}
+	return out
+}
+
+func websitesIn(dtos []WebsiteDTO) []service.WebsiteIn {
+	out := make([]service.WebsiteIn, 0, len(dtos))
+	for _, w := range dtos {
+		out = append(out, service.WebsiteIn{Label: w.Label, URL: w.URL, IsPrimary: w.IsPrimary})
+	}
+	return out
+}
+
+func phonesIn(dtos []PhoneDTO) []service.PhoneIn {
+	out := make([]service.PhoneIn, 0, len(dtos))
+	for _, p := range dtos {
+		out = append(out, service.PhoneIn{Label: p.Label, PhoneRaw: p.PhoneRaw, IsPrimary: p.IsPrimary})
+	}
+	return out
+}
+
+func csvColumnsIn(dtos []CSVColumnDTO) []service.CSVColumn {
+	out := make([]service.CSVColumn, 0, len(dtos))
+	for _, c := range dtos {
+		out = append(out, service.CSVColumn{Column: c.Column, Label: c.Label, Primary: c.Primary})
+	}
+	return out
+}
+
+func createIn(dto ContactCreateDTO) service.ContactCreateIn {
+	in := service.ContactCreateIn{
+		FirstName: dto.FirstName, LastName: dto.LastName, Company: dto.Company,
+		OrganizationID: dto.OrganizationID, JobTitle: dto.JobTitle, Department: dto.Department,
+		Custom: dto.Custom, Tags: dto.Tags,
+	}
+	in.Phones = make([]service.PhoneIn, 0, len(dto.Phones))
+	for _, p := range dto.Phones {
+		in.Phones = append(in.Phones, service.PhoneIn{Label: p.Label, PhoneRaw: p.PhoneRaw, IsPrimary: p.IsPrimary, Visibility: p.Visibility})
+	}
+	in.Emails = emailsIn(dto.Emails)
+	in.Addresses = addressesIn(dto.Addresses)
+	in.Websites = websitesIn(dto.Websites)
+	return in
+}
+
+func updateIn(dto ContactUpdateDTO) service.ContactUpdateIn {
+	in := service.ContactUpdateIn{
+		FirstName:      dto.FirstName,
+		LastName:       dto.LastName,
+		Company:        dto.Company,
+		OrganizationID: dto.OrganizationID,
+		JobTitle:       dto.JobTitle,
+		Department:     dto.Department,
+		Custom:         dto.Custom,
+		Tags:           dto.Tags,
+	}
+
+	if dto.Phones != nil { // ← защита от nil
+		arr := make([]service.PhoneIn, 0, len(*dto.Phones))
+		for _, p := range *dto.Phones {
+			arr = append(arr, service.PhoneIn{
+				Label: p.Label, PhoneRaw: p.PhoneRaw, IsPrimary: p.IsPrimary, Visibility: p.Visibility,
+			})
+		}
+		in.Phones = &arr
+	}
+	if dto.Emails != nil {
+		emails := emailsIn(*dto.Emails)
+		in.Emails = &emails
+	}
+	if dto.Addresses != nil {
+		addrs := addressesIn(*dto.Addresses)
+		in.Addresses = &addrs
+	}
+	if dto.Websites != nil {
+		sites := websitesIn(*dto.Websites)
+		in.Websites = &sites
+	}
+	return in
+}
+
+// BatchOpDTO — contact разбирается по op: ContactCreateDTO для create, ContactUpdateDTO для update
+type BatchOpDTO struct {
+	Op      string          `json:"op"`
+	ID      int64           `json:"id"`
+	Contact json.RawMessage `json:"contact"`
+}
+
+// BatchDTO — mode: atomic (по умолчанию) или partial
+type BatchDTO struct {
+	Mode       string       `json:"mode"`
+	Operations []BatchOpDTO `json:"operations"`
+}
+
+type WebhookCreateDTO struct {
+	URL    string   `json:"url"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events"`
+}
+
+type WebhookUpdateDTO struct {
+	URL    *string   `json:"url"`
+	Secret *string   `json:"secret"`
+	Events *[]string `json:"events"`
+	Active *bool     `json:"active"`
+}
+
+type APIKeyCreateDTO struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	// AddressBook — slug книги, к которой привязать ключ
+	AddressBook string `json:"address_book"`
+}
+
+type AddressBookCreateDTO struct {
+	Slug          string `json:"slug"`
+	Name          string `json:"name"`
+	DefaultRegion string `json:"default_region"`
+}
+
+type AddressBookUpdateDTO struct {
+	Name          *string `json:"name"`
+	DefaultRegion *string `json:"default_region"`
+}
+
+type GrantDTO struct {
+	Principal string `json:"principal"`
+	Role      string `json:"role"`
+}
+
+type ACLDTO struct {
+	Grants []GrantDTO `json:"grants"`
 }
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+const (
+	// sseHeartbeat — комментарий-пинг, чтобы прокси не закрывали простаивающее соединение
+	sseHeartbeat = 25 * time.Second
+	sseRetryMs   = 3000
+)
+
+// Subscriber — живые события; реализуется events.Hub
+type Subscriber interface {
+	Subscribe() (<-chan service.EventOut, func())
+}
+
+type EventsHandler struct {
+	lg  *logger.Logger
+	svc service.EventsService
+	hub Subscriber
+}
+
+func NewEvents(lg *logger.Logger, svc service.EventsService, hub Subscriber) *EventsHandler {
+	return &EventsHandler{lg: lg, svc: svc, hub: hub}
+}
+
+// Stream — GET /events, Server-Sent Events. С Last-Event-ID (заголовок или ?last_event_id= — браузерный
+// EventSource не умеет заголовки при первом подключении) сначала отдаются пропущенные события из журнала.
+func (h *EventsHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	last := r.Header.Get("Last-Event-ID")
+	if last == "" {
+		last = r.URL.Query().Get("last_event_id")
+	}
+	var after int64 = -1
+	if last != "" {
+		id, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || id < 0 {
+			http.Error(w, "bad last event id", http.StatusBadRequest)
+			return
+		}
+		after = id
+	}
+
+	// подписка до чтения журнала — иначе события между ними потерялись бы
+	live, cancel := h.hub.Subscribe()
+	defer cancel()
+
+	var backlog []service.EventOut
+	if after >= 0 {
+		var err error
+		if backlog, err = h.svc.EventsSince(r.Context(), after, 0); err != nil {
+			writeSvcErr(w, err)
+			return
+		}
+	}
+
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // nginx не должен буферизовать поток
+	w.WriteHeader(http.StatusOK)
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", sseRetryMs); err != nil {
+		return
+	}
+
+	// replayed — последний id из журнала: живые события до него клиент уже получил
+	var replayed int64
+	for len(backlog) > 0 {
+		for _, e := range backlog {
+			if !service.EventVisible(r.Context(), e) {
+				replayed = e.ID
+				continue
+			}
+			if writeEvent(w, e) != nil {
+				return
+			}
+			replayed = e.ID
+		}
+		if rc.Flush() != nil {
+			return
+		}
+		var err error
+		if backlog, err = h.svc.EventsSince(r.Context(), replayed, 0); err != nil {
+			h.lg.Warn("events backlog read failed", logger.Err(err))
+			return // клиент переподключится с последним полученным id
+		}
+	}
+	if rc.Flush() != nil {
+		return
+	}
+
+	ping := time.NewTicker(sseHeartbeat)
+	defer ping.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-live:
+			if !ok {
+				return // отстали от хаба — клиент переподключится и догонит по Last-Event-ID
+			}
+			if e.ID <= replayed || !service.EventVisible(r.Context(), e) {
+				continue
+			}
+			if writeEvent(w, e) != nil || rc.Flush() != nil {
+				return
+			}
+		case <-ping.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil || rc.Flush() != nil {
+				return
+			}
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, e service.EventOut) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: contact.%s\ndata: %s\n\n", e.ID, e.Op, data)
+	return err
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+// flushEvery — как часто проталкивать накопленное клиенту
+const flushEvery = 100
+
+type ExportHandler struct {
+	lg  *logger.Logger
+	svc service.ExportService
+}
+
+func NewExport(lg *logger.Logger, svc service.ExportService) *ExportHandler {
+	return &ExportHandler{lg: lg, svc: svc}
+}
+
+// Export — GET /export?format=csv|ndjson с фильтрами списка контактов. Ответ пишется по мере чтения
+// курсора; заголовки уходят с первой строкой, поэтому ошибка фильтров ещё отдаётся обычным статусом.
+func (h *ExportHandler) Export(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := listFilter(q)
+	switch q.Get("format") {
+	case "csv":
+		h.csv(w, r, f)
+	case "ndjson", "":
+		h.ndjson(w, r, f)
+	default:
+		http.Error(w, "bad format, expected csv or ndjson", http.StatusBadRequest)
+	}
+}
+
+func (h *ExportHandler) ndjson(w http.ResponseWriter, r *http.Request, f service.ListFilter) {
+	rc := http.NewResponseController(w)
+	enc := json.NewEncoder(w)
+	started := false
+	start := func() {
+		started = true
+		_ = rc.SetWriteDeadline(time.Time{}) // выгрузка дольше обычного WriteTimeout
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", `attachment; filename="contacts.ndjson"`)
+		w.WriteHeader(http.StatusOK)
+	}
+	n := 0
+	err := h.svc.ExportContacts(r.Context(), f, func(c service.ContactOut) error {
+		if !started {
+			start()
+		}
+		if err := enc.Encode(c); err != nil {
+			return err
+		}
+		if n++; n%flushEvery == 0 {
+			return rc.Flush()
+		}
+		return nil
+	})
+	if err == nil && !started {
+		start()
+	}
+	h.finish(w, r, started, err)
+}
+
+func (h *ExportHandler) csv(w http.ResponseWriter, r *http.Request, f service.ListFilter) {
+	phones, err := h.svc.ExportPhoneColumns(r.Context())
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	header := []string{"id", "first_name", "last_name", "company", "job_title", "department", "email"}
+	for i := 1; i <= phones; i++ {
+		n := strconv.Itoa(i)
+		header = append(header, "phone_"+n, "phone_"+n+"_label")
+	}
+	header = append(header, "custom", "created_at", "updated_at")
+
+	rc := http.NewResponseController(w)
+	cw := csv.NewWriter(w)
+	started := false
+	start := func() {
+		started = true
+		_ = rc.SetWriteDeadline(time.Time{})
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="contacts.csv"`)
+		w.WriteHeader(http.StatusOK)
+		_ = cw.Write(header)
+	}
+	rec := make([]string, len(header))
+	n := 0
+	err = h.svc.ExportContacts(r.Context(), f, func(c service.ContactOut) error {
+		if !started {
+			start()
+		}
+		csvRecord(rec, c, phones)
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+		if n++; n%flushEvery == 0 {
+			cw.Flush()
+			if err := cw.Error(); err != nil {
+				return err
+			}
+			return rc.Flush()
+		}
+		return nil
+	})
+	if err == nil && !started {
+		start()
+	}
+	if started {
+		cw.Flush()
+	}
+	h.finish(w, r, started, err)
+}
+
+// finish — ошибка до первой строки отдаётся обычным ответом, после — только в лог:
+// статус уже отправлен, клиент получит оборванный файл.
+func (h *ExportHandler) finish(w http.ResponseWriter, r *http.Request, started bool, err error) {
+	switch {
+	case err == nil:
+	case r.Context().Err() != nil:
+		h.lg.Info("export cancelled by client")
+	case !started:
+		writeSvcErr(w, err)
+	default:
+		h.lg.Error("export aborted", logger.Err(err))
+	}
+}
+
+// csvRecord — телефоны раскладываются по парам колонок phone_N/phone_N_label, первым идёт основной.
+func csvRecord(rec []string, c service.ContactOut, phones int) {
+	email := ""
+	if len(c.Emails) > 0 {
+		email = c.Emails[0].Email
+	}
+	i := copy(rec, []string{strconv.FormatInt(c.ID, 10), c.FirstName, c.LastName, c.Company, c.JobTitle, c.Department, email})
+	for p := 0; p < phones; p++ {
+		rec[i], rec[i+1] = "", ""
+		if p < len(c.Phones) {
+			rec[i] = c.Phones[p].PhoneE164
+			rec[i+1] = c.Phones[p].Label
+		}
+		i += 2
+	}
+	rec[i] = ""
+	if len(c.Custom) > 0 {
+		b, _ := json.Marshal(c.Custom)
+		rec[i] = string(b)
+	}
+	rec[i+1] = c.CreatedAt.UTC().Format(time.RFC3339)
+	rec[i+2] = c.UpdatedAt.UTC().Format(time.RFC3339)
+}
@@ -3,14 +3,18 @@ package handler_test
 import (
 	"bytes"
 	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/sunzhqr/phonebook/internal/handler"
 	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/repository"
 	"github.com/sunzhqr/phonebook/internal/service"
 )
 
@@ -26,7 +30,7 @@ type mockSvc struct {
 func (m *mockSvc) CreateContact(ctx context.Context, in service.ContactCreateIn) (service.ContactOut, error) {
 	return m.CreateFn(ctx, in)
 }
-func (m *mockSvc) GetContact(ctx context.Context, id int64) (service.ContactOut, error) {
+func (m *mockSvc) GetContact(ctx context.Context, id int64, _ ...string) (service.ContactOut, error) {
 	return m.GetFn(ctx, id)
 }
 func (m *mockSvc) UpdateContact(ctx context.Context, id int64, in service.ContactUpdateIn) (service.ContactOut, error) {
@@ -38,6 +42,15 @@ func (m *mockSvc) DeleteContact(ctx context.Context, id int64) error {
 func (m *mockSvc) ListContacts(ctx context.Context, f service.ListFilter) (service.ListOut, error) {
 	return m.ListFn(ctx, f)
 }
+func (m *mockSvc) UpsertExternal(context.Context, string, string, service.ContactCreateIn) (service.ContactOut, bool, error) {
+	return service.ContactOut{}, false, nil
+}
+func (m *mockSvc) GetByExternal(context.Context, string, string) (service.ContactOut, error) {
+	return service.ContactOut{}, nil
+}
+func (m *mockSvc) ContactsByIDs(context.Context, []int64) ([]service.ContactOut, error) {
+	return nil, nil
+}
 func (m *mockSvc) Search(ctx context.Context, q string, limit int) ([]service.ContactOut, error) {
 	return m.SearchFn(ctx, q, limit)
 }
@@ -126,3 +139,301 @@ func Test_Create_Get_Update_Delete_List_Search(t *testing.T) {
 		t.Fatalf("delete %v", res.Status)
 	}
 }
+
+type mockExport struct {
+	items    []service.ContactOut
+	exported []int64 // контакты, принятые fn, — то, что сервис записал бы в аудит
+}
+
+func (m *mockExport) ExportContacts(_ context.Context, f service.ListFilter, fn func(service.ContactOut) error) error {
+	for _, c := range m.items[min(f.Offset, len(m.items)):] {
+		if err := fn(c); err != nil {
+			return err
+		}
+		m.exported = append(m.exported, c.ID)
+	}
+	return nil
+}
+func (m *mockExport) ExportPhoneColumns(context.Context) (int, error) { return 2, nil }
+
+func Test_Export_CSV_NDJSON(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	ms := &mockExport{items: []service.ContactOut{
+		{ID: 1, FirstName: "Sanzhar", LastName: "Sanzharov", Company: "Forte, Bank", CreatedAt: now, UpdatedAt: now,
+			Phones: []service.PhoneOut{{Label: "work", PhoneE164: "+77711234567"}, {Label: "mobile", PhoneE164: "+77017654321"}}},
+		{ID: 2, FirstName: "Anna", LastName: "Petrova", CreatedAt: now, UpdatedAt: now},
+	}}
+	r := chi.NewRouter()
+	r.Get("/api/v1/export", handler.NewExport(logger.New("dev"), ms).Export)
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/api/v1/export?format=csv")
+	if err != nil || res.StatusCode != http.StatusOK {
+		t.Fatalf("csv status=%v err=%v", res.StatusCode, err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	want := "id,first_name,last_name,company,job_title,department,email,phone_1,phone_1_label,phone_2,phone_2_label,custom,created_at,updated_at\n" +
+		"1,Sanzhar,Sanzharov,\"Forte, Bank\",,,,+77711234567,work,+77017654321,mobile,,2024-01-02T03:04:05Z,2024-01-02T03:04:05Z\n" +
+		"2,Anna,Petrova,,,,,,,,,,2024-01-02T03:04:05Z,2024-01-02T03:04:05Z\n"
+	if string(body) != want {
+		t.Fatalf("csv body:\n%s\nwant:\n%s", body, want)
+	}
+
+	res, err = http.Get(ts.URL + "/api/v1/export?format=ndjson")
+	if err != nil || res.StatusCode != http.StatusOK || res.Header.Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("ndjson status=%v err=%v", res.StatusCode, err)
+	}
+	body, _ = io.ReadAll(res.Body)
+	if lines := strings.Split(strings.TrimSpace(string(body)), "\n"); len(lines) != 2 {
+		t.Fatalf("ndjson lines = %d", len(lines))
+	}
+
+	if res, _ := http.Get(ts.URL + "/api/v1/export?format=xml"); res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("bad format: %v", res.Status)
+	}
+}
+
+func Test_Directory_Vendors(t *testing.T) {
+	items := []service.ContactOut{
+		{ID: 1, FirstName: "Sanzhar", LastName: "Sanzharovich-Konstantinopolskiy", Company: "Forte",
+			Phones: []service.PhoneOut{{Label: "work", PhoneE164: "+77711234567"}, {Label: "Мобильный", PhoneE164: "+77017654321"}}},
+		{ID: 2, FirstName: "Anna", LastName: "Petrova"}, // без номеров — в справочник не попадает
+	}
+	for i := 3; i <= 40; i++ {
+		items = append(items, service.ContactOut{ID: int64(i), FirstName: "User", LastName: strconv.Itoa(i),
+			Phones: []service.PhoneOut{{Label: "work", PhoneRaw: "100" + strconv.Itoa(i)}}})
+	}
+	r := chi.NewRouter()
+	r.Get("/api/v1/directory/{vendor}.xml", handler.NewDirectory(logger.New("dev"), &mockExport{items: items}).Directory)
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	get := func(path string) string {
+		t.Helper()
+		res, err := http.Get(ts.URL + path)
+		if err != nil || res.StatusCode != http.StatusOK {
+			t.Fatalf("%s: status=%v err=%v", path, res.Status, err)
+		}
+		if ct := res.Header.Get("Content-Type"); ct != "text/xml; charset=utf-8" {
+			t.Fatalf("%s: content-type %q", path, ct)
+		}
+		body, _ := io.ReadAll(res.Body)
+		return string(body)
+	}
+
+	// Cisco: запись на номер, 32 на странице, дальше — софткей Next с абсолютным адресом
+	body := get("/api/v1/directory/cisco.xml?cf.dept=IT")
+	if n := strings.Count(body, "<DirectoryEntry>"); n != 32 {
+		t.Fatalf("cisco page 1 entries = %d", n)
+	}
+	for _, want := range []string{
+		"<Name>Sanzhar Sanzharovich-Konstantino</Name><Telephone>+77711234567</Telephone>",
+		"<Name>Sanzhar Sanzharovich-Ko (mobile)</Name><Telephone>+77017654321</Telephone>",
+		"<Prompt>Records 1 to 32</Prompt>",
+		"<URL>" + ts.URL + "/api/v1/directory/cisco.xml?cf.dept=IT&amp;offset=32&amp;page=2</URL>",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("cisco page 1 lacks %s:\n%s", want, body)
+		}
+	}
+	body = get("/api/v1/directory/cisco.xml?page=2")
+	if n := strings.Count(body, "<DirectoryEntry>"); n != 8 || strings.Contains(body, "<Name>Next</Name>") {
+		t.Fatalf("cisco page 2 (%d entries):\n%s", n, body)
+	}
+
+	// Yealink: все номера контакта в одной записи
+	body = get("/api/v1/directory/yealink.xml")
+	if !strings.Contains(body, "<YealinkIPPhoneDirectory><DirectoryEntry><Name>Sanzhar Sanzharovich-Konstantino</Name>"+
+		"<Telephone>+77711234567</Telephone><Telephone>+77017654321</Telephone></DirectoryEntry>") {
+		t.Fatalf("yealink:\n%s", body)
+	}
+	if strings.Contains(body, "Petrova") || strings.Count(body, "<DirectoryEntry>") != 39 {
+		t.Fatalf("yealink entries:\n%s", body)
+	}
+
+	// Grandstream: тип номера по метке
+	body = get("/api/v1/directory/grandstream.xml")
+	if !strings.Contains(body, `<Phone type="Cell"><phonenumber>+77017654321</phonenumber><accountindex>1</accountindex></Phone>`) {
+		t.Fatalf("grandstream:\n%s", body)
+	}
+
+	if res, _ := http.Get(ts.URL + "/api/v1/directory/polycom.xml"); res.StatusCode != http.StatusNotFound {
+		t.Fatalf("unknown vendor: %v", res.Status)
+	}
+}
+
+// контакт, чьи записи не влезли в страницу целиком, продолжается на следующей по курсору; в аудит попадают
+// только показанные контакты, а номер страницы не переполняет смещение
+func Test_Directory_Cursor(t *testing.T) {
+	var items []service.ContactOut
+	for i := 1; i <= 31; i++ {
+		items = append(items, service.ContactOut{ID: int64(i), FirstName: "User", LastName: strconv.Itoa(i),
+			Phones: []service.PhoneOut{{Label: "work", PhoneRaw: "100" + strconv.Itoa(i)}}})
+	}
+	items = append(items,
+		service.ContactOut{ID: 32, FirstName: "Split", LastName: "Contact", Phones: []service.PhoneOut{
+			{Label: "work", PhoneRaw: "2001"}, {Label: "mobile", PhoneRaw: "2002"}, {Label: "home", PhoneRaw: "2003"}}},
+		service.ContactOut{ID: 33, FirstName: "Last", LastName: "One", Phones: []service.PhoneOut{{Label: "work", PhoneRaw: "3001"}}},
+	)
+	ms := &mockExport{items: items}
+	r := chi.NewRouter()
+	r.Get("/api/v1/directory/{vendor}.xml", handler.NewDirectory(logger.New("dev"), ms).Directory)
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	get := func(path string) string {
+		t.Helper()
+		res, err := http.Get(ts.URL + path)
+		if err != nil || res.StatusCode != http.StatusOK {
+			t.Fatalf("%s: status=%v err=%v", path, res.Status, err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		return string(body)
+	}
+
+	body := get("/api/v1/directory/cisco.xml")
+	if !strings.Contains(body, "<Telephone>2001</Telephone>") || strings.Contains(body, "2002") ||
+		!strings.Contains(body, "/api/v1/directory/cisco.xml?offset=31&amp;page=2&amp;skip=1</URL>") {
+		t.Fatalf("page 1:\n%s", body)
+	}
+	if n := len(ms.exported); n != 32 || ms.exported[n-1] != 32 {
+		t.Fatalf("page 1 audited %v, want 1..32", ms.exported)
+	}
+
+	ms.exported = nil
+	body = get("/api/v1/directory/cisco.xml?offset=31&page=2&skip=1")
+	for _, want := range []string{
+		"<Prompt>Records 33 to 35</Prompt>",
+		"<Name>Split Contact (mobile)</Name><Telephone>2002</Telephone>",
+		"<Name>Split Contact (home)</Name><Telephone>2003</Telephone>",
+		"<Telephone>3001</Telephone>",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("page 2 lacks %s:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "2001") || strings.Contains(body, "<Name>Next</Name>") {
+		t.Fatalf("page 2:\n%s", body)
+	}
+	if len(ms.exported) != 2 {
+		t.Fatalf("page 2 audited %v, want [32 33]", ms.exported)
+	}
+
+	for _, q := range []string{"page=0", "page=99999999999999999999", "page=10001", "offset=-1", "skip=x"} {
+		if res, _ := http.Get(ts.URL + "/api/v1/directory/cisco.xml?" + q); res.StatusCode != http.StatusBadRequest {
+			t.Fatalf("%s: %v", q, res.Status)
+		}
+	}
+}
+
+type mockEvents struct {
+	log  []service.EventOut
+	live []service.EventOut
+}
+
+func (m *mockEvents) EventsSince(_ context.Context, after int64, _ int) ([]service.EventOut, error) {
+	var out []service.EventOut
+	for _, e := range m.log {
+		if e.ID > after {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+func (m *mockEvents) EventsHead(context.Context) (int64, error)          { return 0, nil }
+func (m *mockEvents) ListenEvents(context.Context, func(), func()) error { return nil }
+
+// Subscribe — живые события сразу в буфере, канал закрыт: обработчик отдаёт их и завершается
+func (m *mockEvents) Subscribe() (<-chan service.EventOut, func()) {
+	ch := make(chan service.EventOut, len(m.live))
+	for _, e := range m.live {
+		ch <- e
+	}
+	close(ch)
+	return ch, func() {}
+}
+
+// inDefaultBook — книгу запросу задаёт middleware сервера; здесь — всегда книга по умолчанию
+func inDefaultBook(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b := service.AddressBookOut{ID: repository.DefaultAddressBookID, Slug: "default"}
+		next.ServeHTTP(w, r.WithContext(service.WithAddressBook(r.Context(), b)))
+	})
+}
+
+func Test_Events_SSE_ResumeFromLastEventID(t *testing.T) {
+	ev := func(id int64, op string) service.EventOut {
+		return service.EventOut{ID: id, ContactID: 10 + id, Op: op}
+	}
+	me := &mockEvents{
+		log:  []service.EventOut{ev(1, "created"), ev(2, "updated"), ev(3, "deleted")},
+		live: []service.EventOut{ev(3, "deleted"), ev(4, "created")}, // 3 уже отдан из журнала
+	}
+	r := chi.NewRouter()
+	r.Use(inDefaultBook)
+	r.Get("/api/v1/events", handler.NewEvents(logger.New("dev"), me, me).Stream)
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/v1/events", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil || res.StatusCode != http.StatusOK || res.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("status=%v err=%v", res.Status, err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	var ids []string
+	for _, line := range strings.Split(string(body), "\n") {
+		if id, ok := strings.CutPrefix(line, "id: "); ok {
+			ids = append(ids, id)
+		}
+	}
+	if strings.Join(ids, ",") != "2,3,4" {
+		t.Fatalf("ids = %v, body:\n%s", ids, body)
+	}
+	if !strings.Contains(string(body), "event: contact.deleted\n") {
+		t.Fatalf("no event name in:\n%s", body)
+	}
+
+	if res, _ := http.Get(ts.URL + "/api/v1/events?last_event_id=x"); res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("bad id: %v", res.Status)
+	}
+}
+
+// живые события хаба общие для всех: подписчику не уходят события контактов, которые ему не видны
+func Test_Events_SSE_FiltersByContactAccess(t *testing.T) {
+	me := &mockEvents{live: []service.EventOut{
+		{ID: 1, ContactID: 11, Op: "updated", Readers: []string{"user:bob"}},
+		{ID: 2, ContactID: 12, Op: "updated", Readers: []string{"user:bob", "group:sales"}},
+		{ID: 3, ContactID: 13, Op: "created"},
+	}}
+	p := service.Principal{Subject: "user:alice", Groups: []string{"sales"}, Scopes: []string{service.ScopeContactsRead}}
+	r := chi.NewRouter()
+	r.Use(inDefaultBook, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(service.WithPrincipal(r.Context(), p)))
+		})
+	})
+	r.Get("/api/v1/events", handler.NewEvents(logger.New("dev"), me, me).Stream)
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/api/v1/events")
+	if err != nil || res.StatusCode != http.StatusOK {
+		t.Fatalf("status=%v err=%v", res.Status, err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	var ids []string
+	for _, line := range strings.Split(string(body), "\n") {
+		if id, ok := strings.CutPrefix(line, "id: "); ok {
+			ids = append(ids, id)
+		}
+	}
+	if strings.Join(ids, ",") != "2,3" {
+		t.Fatalf("ids = %v, body:\n%s", ids, body)
+	}
+	if strings.Contains(string(body), "readers") {
+		t.Fatalf("readers leaked into the stream:\n%s", body)
+	}
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+type OrganizationsHandler struct {
+	lg  *logger.Logger
+	svc service.OrganizationsService
+}
+
+func NewOrganizations(lg *logger.Logger, svc service.OrganizationsService) *OrganizationsHandler {
+	return &OrganizationsHandler{lg: lg, svc: svc}
+}
+
+func (h *OrganizationsHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var dto OrganizationCreateDTO
+	dec := json.NewDecoder(io.LimitReader(r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&dto); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	res, err := h.svc.CreateOrganization(r.Context(), service.OrganizationIn{
+		Name:      dto.Name,
+		Phones:    phonesIn(dto.Phones),
+		Addresses: addressesIn(dto.Addresses),
+	})
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, res)
+}
+
+func (h *OrganizationsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	res, err := h.svc.GetOrganization(r.Context(), id)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (h *OrganizationsHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	var dto OrganizationUpdateDTO
+	dec := json.NewDecoder(io.LimitReader(r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&dto); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	in := service.OrganizationUpdateIn{Name: dto.Name}
+	if dto.Phones != nil {
+		phones := phonesIn(*dto.Phones)
+		in.Phones = &phones
+	}
+	if dto.Addresses != nil {
+		addrs := addressesIn(*dto.Addresses)
+		in.Addresses = &addrs
+	}
+	res, err := h.svc.UpdateOrganization(r.Context(), id, in)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (h *OrganizationsHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	if err := h.svc.DeleteOrganization(r.Context(), id); err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *OrganizationsHandler) List(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	afterID, _ := strconv.ParseInt(q.Get("after_id"), 10, 64)
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	res, err := h.svc.ListOrganizations(r.Context(), service.OrganizationListFilter{Name: q.Get("name"), AfterID: afterID, Limit: limit})
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (h *OrganizationsHandler) Members(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	res, err := h.svc.ListOrganizationMembers(r.Context(), id, listFilter(r.URL.Query()))
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+type PhotosHandler struct {
+	lg  *logger.Logger
+	svc service.PhotosService
+}
+
+func NewPhotos(lg *logger.Logger, svc service.PhotosService) *PhotosHandler {
+	return &PhotosHandler{lg: lg, svc: svc}
+}
+
+// Put — тело запроса целиком является изображением; тип определяется по содержимому.
+func (h *PhotosHandler) Put(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	res, err := h.svc.PutPhoto(r.Context(), id, r.Body)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// Get — ?size=thumb отдаёт JPEG-миниатюру; поддерживает If-None-Match.
+func (h *PhotosHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	var thumb bool
+	switch r.URL.Query().Get("size") {
+	case "", "original":
+	case "thumb":
+		thumb = true
+	default:
+		http.Error(w, "bad size", http.StatusBadRequest)
+		return
+	}
+	p, err := h.svc.GetPhoto(r.Context(), id, thumb)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	defer p.Body.Close()
+
+	etag := `"` + p.ETag + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", p.ContentType)
+	w.Header().Set("Last-Modified", p.UpdatedAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, p.Body); err != nil {
+		h.lg.Warn("photo write failed", logger.Err(err))
+	}
+}
+
+func (h *PhotosHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	if err := h.svc.DeletePhoto(r.Context(), id); err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+type RelationsHandler struct {
+	lg  *logger.Logger
+	svc service.RelationsService
+}
+
+func NewRelations(lg *logger.Logger, svc service.RelationsService) *RelationsHandler {
+	return &RelationsHandler{lg: lg, svc: svc}
+}
+
+func (h *RelationsHandler) Create(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	var dto RelationCreateDTO
+	dec := json.NewDecoder(io.LimitReader(r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&dto); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	res, err := h.svc.CreateRelation(r.Context(), id, service.RelationIn{
+		RelatedID:     dto.RelatedID,
+		Type:          dto.Type,
+		Bidirectional: dto.Bidirectional,
+	})
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, res)
+}
+
+func (h *RelationsHandler) List(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	res, err := h.svc.ListRelations(r.Context(), id)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (h *RelationsHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	relID, err := strconv.ParseInt(chi.URLParam(r, "relID"), 10, 64)
+	if err != nil || relID <= 0 {
+		http.Error(w, "bad relation id", http.StatusBadRequest)
+		return
+	}
+	if err := h.svc.DeleteRelation(r.Context(), id, relID); err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
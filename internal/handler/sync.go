@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+type SyncHandler struct {
+	lg  *logger.Logger
+	svc service.SyncService
+}
+
+func NewSync(lg *logger.Logger, svc service.SyncService) *SyncHandler {
+	return &SyncHandler{lg: lg, svc: svc}
+}
+
+// Sync — GET /sync?token=; без токена — первичная выгрузка, 410 — токен устарел.
+func (h *SyncHandler) Sync(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.Sync(r.Context(), r.URL.Query().Get("token"))
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, res)
+}
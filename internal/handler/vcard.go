@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+	"github.com/sunzhqr/phonebook/internal/vcard"
+)
+
+// maxImportBytes — ограничение тела импорта; фото в карточках заметно раздувают файл
+const maxImportBytes = 32 << 20
+
+type VCardHandler struct {
+	lg       *logger.Logger
+	contacts service.ContactsService
+	exports  service.ExportService
+	photos   service.PhotosService
+	imports  service.ImportService
+}
+
+func NewVCard(lg *logger.Logger, contacts service.ContactsService, exports service.ExportService, photos service.PhotosService, imports service.ImportService) *VCardHandler {
+	return &VCardHandler{lg: lg, contacts: contacts, exports: exports, photos: photos, imports: imports}
+}
+
+// Contact — GET /contacts/{id}.vcf; ?version=4.0 переключает формат (по умолчанию 3.0).
+func (h *VCardHandler) Contact(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	c, err := h.contacts.GetContact(r.Context(), id)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", vcard.MediaType+"; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="contact-%d.vcf"`, id))
+	enc := vcard.NewEncoder(w, r.URL.Query().Get("version"))
+	if err := enc.Encode(c, h.photo(r, c)); err != nil {
+		h.lg.Warn("vcard write failed", logger.Err(err))
+	}
+}
+
+// Export — GET /export.vcf; понимает те же фильтры, что и список контактов, читает курсором.
+func (h *VCardHandler) Export(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	rc := http.NewResponseController(w)
+	var enc *vcard.Encoder
+	start := func() {
+		_ = rc.SetWriteDeadline(time.Time{})
+		w.Header().Set("Content-Type", vcard.MediaType+"; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="contacts.vcf"`)
+		w.WriteHeader(http.StatusOK)
+		enc = vcard.NewEncoder(w, q.Get("version"))
+	}
+	err := h.exports.ExportContacts(r.Context(), listFilter(q), func(c service.ContactOut) error {
+		if enc == nil {
+			start()
+		}
+		return enc.Encode(c, h.photo(r, c))
+	})
+	switch {
+	case err == nil && enc == nil:
+		start()
+	case err == nil:
+	case r.Context().Err() != nil:
+		h.lg.Info("vcard export cancelled by client")
+	case enc == nil:
+		writeSvcErr(w, err)
+	default:
+		// статус уже отправлен — клиент увидит неполный файл
+		h.lg.Error("vcard export aborted", logger.Err(err))
+	}
+}
+
+// Import — POST /import с телом text/vcard; отвечает построчным отчётом.
+func (h *VCardHandler) Import(w http.ResponseWriter, r *http.Request) {
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mt {
+	case vcard.MediaType, "text/x-vcard", "text/directory":
+	default:
+		http.Error(w, "unsupported content type, expected text/vcard", http.StatusUnsupportedMediaType)
+		return
+	}
+	cards, err := vcard.Decode(http.MaxBytesReader(w, r.Body, maxImportBytes))
+	var mbe *http.MaxBytesError
+	switch {
+	case errors.As(err, &mbe):
+		http.Error(w, "body too large", http.StatusRequestEntityTooLarge)
+		return
+	case errors.Is(err, vcard.ErrNoCards):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, "cannot read body", http.StatusBadRequest)
+		return
+	}
+
+	items := make([]service.ImportItem, 0, len(cards))
+	for _, c := range cards {
+		items = append(items, service.ImportItem{Contact: c.Contact, Photo: c.Photo})
+	}
+	rep, err := h.imports.ImportContacts(r.Context(), items)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, rep)
+}
+
+// photo — миниатюра для PHOTO: адресные книги показывают аватар, оригинал им не нужен.
+func (h *VCardHandler) photo(r *http.Request, c service.ContactOut) *vcard.Photo {
+	if c.PhotoURL == "" {
+		return nil
+	}
+	p, err := h.photos.GetPhoto(r.Context(), c.ID, true)
+	if err != nil {
+		return nil
+	}
+	defer p.Body.Close()
+	data, err := io.ReadAll(p.Body)
+	if err != nil {
+		h.lg.Warn("photo read failed", logger.KV("contact_id", c.ID), logger.Err(err))
+		return nil
+	}
+	return &vcard.Photo{ContentType: p.ContentType, Data: data}
+}
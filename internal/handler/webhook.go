@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+type WebhooksHandler struct {
+	lg  *logger.Logger
+	svc service.WebhooksService
+}
+
+func NewWebhooks(lg *logger.Logger, svc service.WebhooksService) *WebhooksHandler {
+	return &WebhooksHandler{lg: lg, svc: svc}
+}
+
+func (h *WebhooksHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var dto WebhookCreateDTO
+	dec := json.NewDecoder(io.LimitReader(r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&dto); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	res, err := h.svc.CreateWebhook(r.Context(), service.WebhookCreateIn{URL: dto.URL, Secret: dto.Secret, Events: dto.Events})
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, res)
+}
+
+func (h *WebhooksHandler) List(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.ListWebhooks(r.Context())
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (h *WebhooksHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	res, err := h.svc.GetWebhook(r.Context(), id)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (h *WebhooksHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	var dto WebhookUpdateDTO
+	dec := json.NewDecoder(io.LimitReader(r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&dto); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	res, err := h.svc.UpdateWebhook(r.Context(), id, service.WebhookUpdateIn{
+		URL: dto.URL, Secret: dto.Secret, Events: dto.Events, Active: dto.Active,
+	})
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (h *WebhooksHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	if err := h.svc.DeleteWebhook(r.Context(), id); err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Deliveries — GET /webhooks/{id}/deliveries?status=dead&before_id=&limit=
+func (h *WebhooksHandler) Deliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	q := r.URL.Query()
+	f := service.DeliveryListFilter{Status: q.Get("status")}
+	f.BeforeID, _ = strconv.ParseInt(q.Get("before_id"), 10, 64)
+	f.Limit, _ = strconv.Atoi(q.Get("limit"))
+	res, err := h.svc.ListDeliveries(r.Context(), id, f)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// Redeliver — POST /webhooks/{id}/deliveries/{deliveryID}/redeliver
+func (h *WebhooksHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	id, err1 := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	did, err2 := strconv.ParseInt(chi.URLParam(r, "deliveryID"), 10, 64)
+	if err1 != nil || err2 != nil || id <= 0 || did <= 0 {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	if err := h.svc.Redeliver(r.Context(), id, did); err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
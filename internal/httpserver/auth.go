@@ -0,0 +1,153 @@
+package httpserver
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+// authenticate — определяет, от чьего имени запрос: ключ API из Authorization: Bearer, X-API-Key или
+// пароля Basic (адресные книги CardDAV и телефоны умеют только его, имя пользователя не проверяется).
+// Запрос без учётных данных идёт дальше анонимным — доступ решает requireScope; с неверными — сразу 401.
+func authenticate(lg *logger.Logger, svc service.AuthService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, ok := credentials(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			p, err := svc.Authenticate(r.Context(), key)
+			var se *service.Error
+			switch {
+			case errors.As(err, &se) && se.Code == http.StatusUnauthorized:
+				unauthorized(w, se.Message)
+				return
+			case err != nil:
+				lg.Error("authentication failed", logger.Err(err))
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(service.WithPrincipal(r.Context(), p)))
+		})
+	}
+}
+
+func credentials(r *http.Request) (string, bool) {
+	if k := r.Header.Get("X-API-Key"); k != "" {
+		return k, true
+	}
+	if _, pw, ok := r.BasicAuth(); ok {
+		return pw, true
+	}
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") && token != "" {
+		return strings.TrimSpace(token), true
+	}
+	return "", false
+}
+
+// requireScope — маршрут доступен только с областью scope: без учётных данных 401, без области 403
+func requireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !allowed(w, r, scope) {
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requireMethodScope — для CardDAV, где один путь и читает, и пишет: безопасные методы требуют read, остальные write
+func requireMethodScope(read, write string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scope := write
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions, "PROPFIND", "REPORT":
+				scope = read
+			}
+			if !allowed(w, r, scope) {
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func allowed(w http.ResponseWriter, r *http.Request, scope string) bool {
+	p, ok := service.PrincipalFrom(r.Context())
+	if !ok {
+		unauthorized(w, "authentication required")
+		return false
+	}
+	if !p.Allows(scope) {
+		http.Error(w, "insufficient scope, "+scope+" required", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// addressBookHeader — книга запроса; без заголовка — книга ключа или токена, иначе default
+const addressBookHeader = "X-Address-Book"
+
+// addressBook — выбирает книгу, с контактами которой работает запрос. Ключ или токен, привязанный
+// к книге, с другой книгой в заголовке — 403; неизвестная книга — 404.
+func addressBook(lg *logger.Logger, svc service.AddressBooksService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, ok := service.PrincipalFrom(r.Context())
+			if !ok {
+				next.ServeHTTP(w, r) // анонимному запросу requireScope ответит 401, не выдавая, есть ли книга
+				return
+			}
+			slug := strings.TrimSpace(r.Header.Get(addressBookHeader))
+			if p.AddressBook != "" {
+				if slug != "" && slug != p.AddressBook {
+					http.Error(w, "credentials are bound to address book "+p.AddressBook, http.StatusForbidden)
+					return
+				}
+				slug = p.AddressBook
+			}
+			if slug == "" {
+				slug = defaultAddressBook
+			}
+			b, err := svc.ResolveAddressBook(r.Context(), slug)
+			var se *service.Error
+			switch {
+			case errors.As(err, &se) && se.Code == http.StatusNotFound:
+				http.Error(w, "unknown address book "+slug, http.StatusNotFound)
+				return
+			case err != nil:
+				lg.Error("address book lookup failed", logger.KV("address_book", slug), logger.Err(err))
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(service.WithAddressBook(r.Context(), b)))
+		})
+	}
+}
+
+const defaultAddressBook = "default"
+
+// requireGlobal — настройки, общие для всех книг (вебхуки, метрики), недоступны
+// ключам и токенам, привязанным к одной книге
+func requireGlobal(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p, _ := service.PrincipalFrom(r.Context()); p.AddressBook != "" {
+			http.Error(w, "credentials are bound to address book "+p.AddressBook, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func unauthorized(w http.ResponseWriter, msg string) {
+	w.Header().Add("WWW-Authenticate", `Bearer realm="phonebook"`)
+	w.Header().Add("WWW-Authenticate", `Basic realm="phonebook", charset="UTF-8"`)
+	http.Error(w, msg, http.StatusUnauthorized)
+}
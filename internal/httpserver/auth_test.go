@@ -0,0 +1,217 @@
+package httpserver
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sunzhqr/phonebook/internal/config"
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/repository"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+// memBooks — адресные книги в памяти, книга default есть всегда
+type memBooks struct {
+	books []repository.AddressBook
+}
+
+func newMemBooks(slugs ...string) *memBooks {
+	m := &memBooks{}
+	for i, slug := range append([]string{"default"}, slugs...) {
+		m.books = append(m.books, repository.AddressBook{ID: int64(i + 1), Slug: slug, Name: slug})
+	}
+	return m
+}
+
+func (m *memBooks) Create(_ context.Context, in repository.AddressBookInput) (repository.AddressBook, error) {
+	b := repository.AddressBook{ID: int64(len(m.books) + 1), Slug: in.Slug, Name: in.Name, DefaultRegion: in.DefaultRegion}
+	m.books = append(m.books, b)
+	return b, nil
+}
+func (m *memBooks) Get(_ context.Context, id int64) (repository.AddressBook, error) {
+	if id < 1 || int(id) > len(m.books) {
+		return repository.AddressBook{}, repository.ErrNotFound
+	}
+	return m.books[id-1], nil
+}
+func (m *memBooks) BySlug(_ context.Context, slug string) (repository.AddressBook, error) {
+	for _, b := range m.books {
+		if b.Slug == slug {
+			return b, nil
+		}
+	}
+	return repository.AddressBook{}, repository.ErrNotFound
+}
+func (m *memBooks) List(context.Context) ([]repository.AddressBook, error) { return m.books, nil }
+func (m *memBooks) Update(ctx context.Context, id int64, _ repository.AddressBookPatch) (repository.AddressBook, error) {
+	return m.Get(ctx, id)
+}
+
+// memKeys — ключи API в памяти
+type memKeys struct {
+	books *memBooks
+	keys  []repository.APIKey
+}
+
+func (m *memKeys) Create(ctx context.Context, in repository.APIKeyInput) (repository.APIKey, error) {
+	k := repository.APIKey{ID: int64(len(m.keys) + 1), Name: in.Name, Prefix: in.Prefix, KeyHash: in.KeyHash,
+		Scopes: in.Scopes, CreatedAt: time.Now(), ExpiresAt: in.ExpiresAt, AddressBookID: in.AddressBookID}
+	if in.AddressBookID != nil {
+		b, _ := m.books.Get(ctx, *in.AddressBookID)
+		k.AddressBook = b.Slug
+	}
+	m.keys = append(m.keys, k)
+	return k, nil
+}
+func (m *memKeys) Get(_ context.Context, id int64) (repository.APIKey, error) {
+	if id < 1 || int(id) > len(m.keys) {
+		return repository.APIKey{}, repository.ErrNotFound
+	}
+	return m.keys[id-1], nil
+}
+func (m *memKeys) ByHash(_ context.Context, hash []byte) (repository.APIKey, error) {
+	for _, k := range m.keys {
+		if bytes.Equal(k.KeyHash, hash) {
+			return k, nil
+		}
+	}
+	return repository.APIKey{}, repository.ErrNotFound
+}
+func (m *memKeys) List(context.Context) ([]repository.APIKey, error) { return m.keys, nil }
+func (m *memKeys) Revoke(_ context.Context, id int64) (repository.APIKey, error) {
+	now := time.Now()
+	m.keys[id-1].RevokedAt = &now
+	return m.keys[id-1], nil
+}
+func (m *memKeys) Touch(context.Context, int64) error { return nil }
+
+func TestAuth_RouteScopes(t *testing.T) {
+	books := newMemBooks()
+	svc := service.New(logger.New("dev"), &repository.Repos{APIKeys: &memKeys{books: books}, AddressBooks: books}, nil, nil)
+	srv := New(logger.New("dev"), config.Config{}, svc, nil, svc).http.Handler
+	ctx := context.Background()
+
+	mint := func(scopes ...string) string {
+		k, err := svc.CreateAPIKey(ctx, service.APIKeyCreateIn{Name: "test", Scopes: scopes})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k.Key
+	}
+	reader, writer, admin := mint(service.ScopeContactsRead), mint(service.ScopeContactsWrite), mint(service.ScopeAdmin)
+	revoked := mint(service.ScopeAdmin)
+	if _, err := svc.RevokeAPIKey(ctx, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name, method, path, key string
+		basic                   bool
+		want                    int
+	}{
+		{"health is public", http.MethodGet, "/healthz", "", false, http.StatusOK},
+		{"metrics anonymous", http.MethodGet, "/metrics", "", false, http.StatusUnauthorized},
+		{"metrics reader", http.MethodGet, "/metrics", reader, false, http.StatusForbidden},
+		{"metrics admin", http.MethodGet, "/metrics", admin, false, http.StatusOK},
+		{"unknown key", http.MethodGet, "/metrics", "pb_AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA", false, http.StatusUnauthorized},
+		{"revoked key", http.MethodGet, "/metrics", revoked, false, http.StatusUnauthorized},
+		{"contacts anonymous", http.MethodGet, "/api/v1/contacts", "", false, http.StatusUnauthorized},
+		{"create with read key", http.MethodPost, "/api/v1/contacts", reader, false, http.StatusForbidden},
+		{"webhooks with write key", http.MethodGet, "/api/v1/webhooks", writer, false, http.StatusForbidden},
+		{"api keys with admin key", http.MethodGet, "/api/v1/api-keys", admin, false, http.StatusOK},
+		{"carddav write with read key", http.MethodPut, "/dav/addressbooks/contacts/1.vcf", reader, true, http.StatusForbidden},
+		{"carddav anonymous", "PROPFIND", "/dav/", "", false, http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			switch {
+			case tc.key != "" && tc.basic:
+				req.SetBasicAuth("phone", tc.key)
+			case tc.key != "":
+				req.Header.Set("Authorization", "Bearer "+tc.key)
+			}
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, req)
+			if rec.Code != tc.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tc.want, rec.Body)
+			}
+			if tc.want == http.StatusUnauthorized && len(rec.Header().Values("WWW-Authenticate")) == 0 {
+				t.Fatal("401 without WWW-Authenticate")
+			}
+		})
+	}
+}
+
+func TestAuth_AddressBookBinding(t *testing.T) {
+	books := newMemBooks("kaspi", "forte")
+	svc := service.New(logger.New("dev"), &repository.Repos{APIKeys: &memKeys{books: books}, AddressBooks: books}, nil, nil)
+	srv := New(logger.New("dev"), config.Config{}, svc, nil, svc).http.Handler
+	ctx := context.Background()
+
+	mint := func(book string) string {
+		k, err := svc.CreateAPIKey(ctx, service.APIKeyCreateIn{Name: "test", Scopes: []string{service.ScopeAdmin}, AddressBook: book})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k.Key
+	}
+	global, kaspi := mint(""), mint("kaspi")
+
+	cases := []struct {
+		name, method, path, key, book string
+		want                          int
+	}{
+		{"global key any book", http.MethodGet, "/api/v1/api-keys", global, "forte", http.StatusOK},
+		{"unknown book", http.MethodGet, "/api/v1/api-keys", global, "nope", http.StatusNotFound},
+		{"anonymous unknown book", http.MethodGet, "/api/v1/api-keys", "", "nope", http.StatusUnauthorized},
+		{"bound key own book", http.MethodGet, "/api/v1/api-keys", kaspi, "kaspi", http.StatusOK},
+		{"bound key without header", http.MethodGet, "/api/v1/api-keys", kaspi, "", http.StatusOK},
+		{"bound key foreign book", http.MethodGet, "/api/v1/api-keys", kaspi, "forte", http.StatusForbidden},
+		{"bound key webhooks", http.MethodGet, "/api/v1/webhooks", kaspi, "", http.StatusForbidden},
+		{"bound key creates book", http.MethodPost, "/api/v1/address-books", kaspi, "", http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var body *bytes.Reader
+			if tc.method == http.MethodPost {
+				body = bytes.NewReader([]byte(`{"slug":"halyk","name":"Halyk"}`))
+			} else {
+				body = bytes.NewReader(nil)
+			}
+			req := httptest.NewRequest(tc.method, tc.path, body)
+			if tc.key != "" {
+				req.Header.Set("X-API-Key", tc.key)
+			}
+			if tc.book != "" {
+				req.Header.Set("X-Address-Book", tc.book)
+			}
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, req)
+			if rec.Code != tc.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tc.want, rec.Body)
+			}
+		})
+	}
+
+	// привязанный ключ видит и выпускает ключи только своей книги
+	bound := service.WithPrincipal(ctx, service.Principal{AddressBook: "kaspi", Scopes: []string{service.ScopeAdmin}})
+	list, err := svc.ListAPIKeys(bound)
+	if err != nil || len(list) != 1 || list[0].AddressBook != "kaspi" {
+		t.Fatalf("bound list = %+v, %v", list, err)
+	}
+	if _, err := svc.GetAPIKey(bound, 1); err == nil {
+		t.Fatal("global key visible to bound admin")
+	}
+	k, err := svc.CreateAPIKey(bound, service.APIKeyCreateIn{Name: "child", Scopes: []string{service.ScopeContactsRead}})
+	if err != nil || k.AddressBook != "kaspi" {
+		t.Fatalf("bound issue = %+v, %v", k, err)
+	}
+	if _, err := svc.CreateAPIKey(bound, service.APIKeyCreateIn{Name: "x", Scopes: []string{service.ScopeAdmin}, AddressBook: "forte"}); err == nil {
+		t.Fatal("bound admin issued key for foreign book")
+	}
+}
@@ -0,0 +1,141 @@
+package httpserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+// maxIdempotentBody — тело читается целиком ради отпечатка; предел как у импорта
+const maxIdempotentBody = 32 << 20
+
+// replayHeaders — заголовки, которые сохраняются вместе с ответом
+var replayHeaders = []string{"Content-Type", "Location", "ETag", "Content-Disposition"}
+
+// idempotency — POST/PUT/DELETE с заголовком Idempotency-Key выполняются один раз: повтор получает
+// сохранённый ответ с Idempotent-Replayed: true. Ответы 5xx не сохраняются — такой запрос можно повторить.
+func idempotency(lg *logger.Logger, svc service.IdempotencyService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get("Idempotency-Key")
+			if key == "" || (r.Method != http.MethodPost && r.Method != http.MethodPut && r.Method != http.MethodDelete) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBody))
+			if err != nil {
+				var mbe *http.MaxBytesError
+				if errors.As(err, &mbe) {
+					http.Error(w, "body too large", http.StatusRequestEntityTooLarge)
+				} else {
+					http.Error(w, "cannot read body", http.StatusBadRequest)
+				}
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			stored, err := svc.BeginIdempotent(r.Context(), key, fingerprint(r, body))
+			var se *service.Error
+			switch {
+			case errors.As(err, &se):
+				http.Error(w, se.Message, se.Code)
+				return
+			case err != nil: // клиент ушёл, пока ждали первый запрос
+				return
+			case stored != nil:
+				for k, v := range stored.Header {
+					w.Header().Set(k, v)
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(stored.Status)
+				_, _ = w.Write(stored.Body)
+				return
+			}
+
+			// запись ключа не должна зависеть от того, дождался ли клиент ответа
+			ctx := context.WithoutCancel(r.Context())
+			rec := &recordingWriter{ResponseWriter: w}
+			completed := false
+			stop := svc.HoldIdempotent(ctx, key)
+			defer func() {
+				stop()
+				if completed {
+					return
+				}
+				if err := svc.ReleaseIdempotent(ctx, key); err != nil {
+					lg.Error("idempotency key release failed", logger.Err(err))
+				}
+			}()
+			next.ServeHTTP(rec, r)
+
+			if rec.status() >= http.StatusInternalServerError {
+				return
+			}
+			resp := service.StoredResponse{Status: rec.status(), Header: map[string]string{}, Body: rec.body.Bytes()}
+			for _, h := range replayHeaders {
+				if v := w.Header().Get(h); v != "" {
+					resp.Header[h] = v
+				}
+			}
+			if err := svc.CompleteIdempotent(ctx, key, resp); err != nil {
+				lg.Error("idempotency key store failed", logger.Err(err))
+				return
+			}
+			completed = true
+		})
+	}
+}
+
+// fingerprint — вызывающий, метод, путь с query, книга и тело: тот же ключ с другим запросом — ошибка
+// клиента. Ключи и так раздельны у каждого вызывающего и книги; отпечаток сверяет их ещё раз.
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	if p, ok := service.PrincipalFrom(r.Context()); ok {
+		h.Write([]byte(p.Subject + "\n"))
+	}
+	if b, ok := service.AddressBookFrom(r.Context()); ok {
+		h.Write([]byte(b.Slug + "\n"))
+	}
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n" + strconv.Itoa(len(body)) + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recordingWriter — пишет ответ клиенту и копит его для сохранения.
+type recordingWriter struct {
+	http.ResponseWriter
+	code int
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func (w *recordingWriter) status() int {
+	if w.code == 0 {
+		return http.StatusOK
+	}
+	return w.code
+}
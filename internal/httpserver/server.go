@@ -7,10 +7,13 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/httprate"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sunzhqr/phonebook/internal/carddav"
 	"github.com/sunzhqr/phonebook/internal/config"
+	"github.com/sunzhqr/phonebook/internal/graphql"
 	"github.com/sunzhqr/phonebook/internal/handler"
 	"github.com/sunzhqr/phonebook/internal/logger"
 	"github.com/sunzhqr/phonebook/internal/service"
+	"net"
 	"net/http"
 	"runtime/debug"
 	"time"
@@ -21,7 +24,36 @@ type Server struct {
 	lg   *logger.Logger
 }
 
-func New(lg *logger.Logger, cfg config.Config, svc service.ContactsService) *Server {
+// davPrefix — путь сервера CardDAV
+const davPrefix = "/dav"
+
+// Services — всё, что маршруты берут у слоя service; *service.Service реализует его целиком,
+// в тестах маршрутов его можно заменить заглушкой
+type Services interface {
+	service.ContactsService
+	service.IdempotencyService
+	service.EventsService
+	service.WebhooksService
+	service.SyncService
+	service.BatchService
+	service.CustomFieldsService
+	service.OrganizationsService
+	service.RelationsService
+	service.PhotosService
+	service.ImportService
+	service.ExportService
+	service.APIKeysService
+	service.AccessService
+	service.AddressBooksService
+	service.AuditService
+}
+
+// New — authn проверяет учётные данные запросов: ключи API сервиса или цепочка с JWT (jwtauth)
+func New(lg *logger.Logger, cfg config.Config, svc Services, hub handler.Subscriber, authn service.AuthService) *Server {
+	// методы WebDAV, которых chi не знает
+	chi.RegisterMethod("PROPFIND")
+	chi.RegisterMethod("REPORT")
+
 	r := chi.NewRouter()
 	r.Use(requestID())
 	r.Use(recoverer(lg))
@@ -29,17 +61,127 @@ func New(lg *logger.Logger, cfg config.Config, svc service.ContactsService) *Ser
 	r.Use(httprate.LimitByIP(200, time.Minute))
 
 	h := handler.New(lg, svc)
+	fh := handler.NewCustomFields(lg, svc)
+	oh := handler.NewOrganizations(lg, svc)
+	rh := handler.NewRelations(lg, svc)
+	ph := handler.NewPhotos(lg, svc)
+	vh := handler.NewVCard(lg, svc, svc, svc, svc)
+	eh := handler.NewExport(lg, svc)
+	ch := handler.NewCSVImport(lg, svc)
+	bh := handler.NewBatch(lg, svc)
+	evh := handler.NewEvents(lg, svc, hub)
+	wh := handler.NewWebhooks(lg, svc)
+	sh := handler.NewSync(lg, svc)
+	dh := handler.NewDirectory(lg, svc)
+	dav := carddav.New(lg, davPrefix, svc, svc, svc, svc)
+
+	kh := handler.NewAPIKeys(lg, svc)
+	abh := handler.NewAddressBooks(lg, svc)
+	auh := handler.NewAudit(lg, svc)
+	ah := handler.NewACL(lg, svc)
+	read := requireScope(service.ScopeContactsRead)
+	write := requireScope(service.ScopeContactsWrite)
+	admin := requireScope(service.ScopeAdmin)
+	idem := idempotency(lg, svc)
 
-	r.Get("/metrics", promhttp.Handler().ServeHTTP)
 	r.Get("/healthz", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+	// адресные книги находят сервер по /.well-known/carddav (RFC 6764)
+	r.HandleFunc("/.well-known/carddav", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, davPrefix+"/", http.StatusMovedPermanently)
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(authenticate(lg, authn), addressBook(lg, svc))
+
+		r.With(admin, requireGlobal).Get("/metrics", promhttp.Handler().ServeHTTP)
+		r.With(requireMethodScope(service.ScopeContactsRead, service.ScopeContactsWrite)).Mount(davPrefix, dav)
+		r.With(read).Handle("/graphql", graphql.New(lg, cfg.GraphQL, svc))
+
+		// проверка области — до идемпотентности, чтобы отказ не сохранился как ответ на ключ
+		r.Route("/api/v1", func(r chi.Router) {
+			r.Group(func(r chi.Router) {
+				r.Use(read, idem)
+				r.Get("/contacts", h.ListContacts)
+				r.Get("/contacts/search", h.Search)
+				r.Get("/contacts/by-external/{source}/{extID}", h.GetByExternal)
+				r.Get("/contacts/{id}", h.GetContact)
+				r.Get("/contacts/{id}.vcf", vh.Contact)
+				r.Get("/contacts/{id}/relations", rh.List)
+				r.Get("/contacts/{id}/photo", ph.Get)
+
+				r.Get("/events", evh.Stream)
+				r.Get("/sync", sh.Sync)
+
+				r.Get("/export", eh.Export)
+				r.Get("/export.vcf", vh.Export)
+
+				r.Get("/directory/{vendor}.xml", dh.Directory)
 
-	r.Route("/api/v1", func(r chi.Router) {
-		r.Get("/contacts", h.ListContacts)
-		r.Get("/contacts/search", h.Search)
-		r.Get("/contacts/{id}", h.GetContact)
-		r.Post("/contacts", h.CreateContact)
-		r.Put("/contacts/{id}", h.UpdateContact)
-		r.Delete("/contacts/{id}", h.DeleteContact)
+				r.Get("/custom-fields", fh.List)
+				r.Get("/custom-fields/{name}", fh.Get)
+
+				r.Get("/organizations", oh.List)
+				r.Get("/organizations/{id}", oh.Get)
+				r.Get("/organizations/{id}/members", oh.Members)
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(write, idem)
+				r.Put("/contacts/by-external/{source}/{extID}", h.UpsertExternal)
+				r.Post("/contacts", h.CreateContact)
+				r.Post("/contacts:batch", bh.Batch)
+				r.Put("/contacts/{id}", h.UpdateContact)
+				r.Delete("/contacts/{id}", h.DeleteContact)
+				r.Post("/contacts/{id}/relations", rh.Create)
+				r.Delete("/contacts/{id}/relations/{relID}", rh.Delete)
+				r.Put("/contacts/{id}/photo", ph.Put)
+				r.Delete("/contacts/{id}/photo", ph.Delete)
+				// управляющему доступом нужна ещё роль admin на самом контакте — её проверяет сервис
+				r.Get("/contacts/{id}/acl", ah.GetContact)
+				r.Put("/contacts/{id}/acl", ah.SetContact)
+
+				r.Post("/import", vh.Import)
+				r.Post("/import/csv", ch.Import)
+
+				r.Post("/organizations", oh.Create)
+				r.Put("/organizations/{id}", oh.Update)
+				r.Delete("/organizations/{id}", oh.Delete)
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(admin, idem)
+				r.Get("/api-keys", kh.List)
+				r.Post("/api-keys", kh.Create)
+				r.Get("/api-keys/{id}", kh.Get)
+				r.Delete("/api-keys/{id}", kh.Revoke)
+
+				r.Get("/address-books", abh.List)
+				r.Post("/address-books", abh.Create)
+				r.Get("/address-books/{slug}", abh.Get)
+				r.Put("/address-books/{slug}", abh.Update)
+
+				r.Get("/tags/{tag}/acl", ah.GetTag)
+				r.Put("/tags/{tag}/acl", ah.SetTag)
+
+				r.Get("/audit", auh.List)
+
+				r.Post("/custom-fields", fh.Create)
+				r.Put("/custom-fields/{name}", fh.Update)
+				r.Delete("/custom-fields/{name}", fh.Delete)
+			})
+
+			// общее для всех книг — только администраторам, не привязанным к книге
+			r.Group(func(r chi.Router) {
+				r.Use(admin, requireGlobal, idem)
+				r.Get("/webhooks", wh.List)
+				r.Post("/webhooks", wh.Create)
+				r.Get("/webhooks/{id}", wh.Get)
+				r.Put("/webhooks/{id}", wh.Update)
+				r.Delete("/webhooks/{id}", wh.Delete)
+				r.Get("/webhooks/{id}/deliveries", wh.Deliveries)
+				r.Post("/webhooks/{id}/deliveries/{deliveryID}/redeliver", wh.Redeliver)
+			})
+		})
 	})
 
 	srv := &http.Server{
@@ -66,7 +208,13 @@ func requestID() func(http.Handler) http.Handler {
 			_, _ = rand.Read(b[:])
 			id := make([]byte, hex.EncodedLen(len(b)))
 			hex.Encode(id, b[:])
-			r = r.WithContext(context.WithValue(r.Context(), reqIDKey, string(id)))
+			ctx := context.WithValue(r.Context(), reqIDKey, string(id))
+			ip, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				ip = r.RemoteAddr
+			}
+			// id и адрес клиента попадают в журнал аудита
+			r = r.WithContext(service.WithRequestInfo(ctx, service.RequestInfo{ID: string(id), IP: ip}))
 			w.Header().Set("X-Request-ID", string(id))
 			next.ServeHTTP(w, r)
 		})
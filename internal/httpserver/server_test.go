@@ -0,0 +1,73 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sunzhqr/phonebook/internal/config"
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+// stubAuth — ключ "read" с областью contacts:read, прочие ключи неизвестны
+type stubAuth struct{}
+
+func (stubAuth) Authenticate(_ context.Context, key string) (service.Principal, error) {
+	if key != "read" {
+		return service.Principal{}, &service.Error{Code: http.StatusUnauthorized, Message: "invalid api key"}
+	}
+	return service.Principal{Subject: "key:1", Scopes: []string{service.ScopeContactsRead}}, nil
+}
+
+// stubServices — заглушка слоя service: методы, которых тест не ждёт, паникуют на nil-интерфейсе
+type stubServices struct {
+	Services
+	deleted bool
+}
+
+func (*stubServices) ResolveAddressBook(_ context.Context, slug string) (service.AddressBookOut, error) {
+	return service.AddressBookOut{Slug: slug}, nil
+}
+
+func (*stubServices) GetContact(ctx context.Context, id int64, _ ...string) (service.ContactOut, error) {
+	b, _ := service.AddressBookFrom(ctx)
+	return service.ContactOut{ID: id, FirstName: "Aigerim", LastName: b.Slug}, nil
+}
+
+func (s *stubServices) DeleteContact(context.Context, int64) error {
+	s.deleted = true
+	return nil
+}
+
+func TestRoutes_WithStubServices(t *testing.T) {
+	svc := &stubServices{}
+	srv := New(logger.New("dev"), config.Config{}, svc, nil, stubAuth{}).http.Handler
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/contacts/7", nil)
+	req.Header.Set("Authorization", "Bearer read")
+	req.Header.Set("X-Address-Book", "sales")
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("get: status = %d: %s", rec.Code, rec.Body)
+	}
+	var c service.ContactOut
+	if err := json.Unmarshal(rec.Body.Bytes(), &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.ID != 7 || c.LastName != "sales" {
+		t.Fatalf("contact = %+v", c)
+	}
+
+	// области проверяются до обработчика: сервис не вызывается
+	req = httptest.NewRequest(http.MethodDelete, "/api/v1/contacts/7", nil)
+	req.Header.Set("Authorization", "Bearer read")
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden || svc.deleted {
+		t.Fatalf("delete with read key: status = %d, deleted = %v", rec.Code, svc.deleted)
+	}
+}
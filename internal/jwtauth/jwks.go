@@ -0,0 +1,250 @@
+package jwtauth
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sunzhqr/phonebook/internal/logger"
+)
+
+const (
+	// minRefetch — незнакомый kid перечитывает набор не чаще этого, иначе поток мусорных токенов
+	// превратился бы в поток запросов к IdP
+	minRefetch   = time.Minute
+	fetchTimeout = 10 * time.Second
+	maxJWKSBytes = 1 << 20
+)
+
+var errUnknownKey = errors.New("unknown signing key")
+
+// publicKey — ключ проверки подписи и алгоритм, для которого он годится
+type publicKey struct {
+	alg string
+	rsa *rsa.PublicKey
+	ec  *ecdsa.PublicKey
+}
+
+// keySet — JWKS из файла или по URL с кешем. Перечитывается, когда прошло refresh или пришёл токен
+// с незнакомым kid (IdP сменил ключ); если перечитать не удалось, работают прежние ключи.
+// Чтение идёт в фоне, одно на всех: пока оно идёт, знакомые ключи отдаются из кеша, а незнакомый kid
+// ждёт результата, пока жив контекст его запроса.
+type keySet struct {
+	lg      *logger.Logger
+	src     string
+	refresh time.Duration
+	client  *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]publicKey
+	fetched time.Time
+	tried   time.Time
+	loading chan struct{} // закрывается по окончании текущего чтения; nil — чтения нет
+}
+
+func newKeySet(lg *logger.Logger, src string, refresh time.Duration) *keySet {
+	return &keySet{lg: lg, src: src, refresh: refresh, client: &http.Client{Timeout: fetchTimeout}}
+}
+
+func (s *keySet) remote() bool {
+	return strings.HasPrefix(s.src, "https://") || strings.HasPrefix(s.src, "http://")
+}
+
+// key — ключ по kid; пустой kid допустим, если в наборе ровно один ключ
+func (s *keySet) key(ctx context.Context, kid string) (publicKey, error) {
+	s.mu.Lock()
+	k, ok := s.lookup(kid)
+	if ok && time.Since(s.fetched) < s.refresh {
+		s.mu.Unlock()
+		return k, nil
+	}
+	wait := s.loading
+	if wait == nil && time.Since(s.tried) >= minRefetch {
+		s.tried = time.Now()
+		wait = make(chan struct{})
+		s.loading = wait
+		go s.reload(wait)
+	}
+	s.mu.Unlock()
+	if ok {
+		return k, nil // устаревший набор обновляется в фоне
+	}
+	if wait == nil {
+		return publicKey{}, errUnknownKey
+	}
+	select {
+	case <-wait:
+	case <-ctx.Done():
+		return publicKey{}, ctx.Err()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if k, ok = s.lookup(kid); !ok {
+		return publicKey{}, errUnknownKey
+	}
+	return k, nil
+}
+
+// reload — фоновое чтение набора: его не прерывает отмена запроса, который его запустил
+func (s *keySet) reload(done chan struct{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+	keys, err := s.fetch(ctx)
+	if err != nil {
+		s.lg.Warn("jwks refresh failed", logger.KV("src", s.src), logger.Err(err))
+	}
+	s.mu.Lock()
+	if err == nil {
+		s.keys, s.fetched = keys, time.Now()
+	}
+	s.loading = nil
+	s.mu.Unlock()
+	close(done)
+}
+
+// load — первое чтение при старте
+func (s *keySet) load(ctx context.Context) error {
+	keys, err := s.fetch(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tried = time.Now()
+	if err != nil {
+		return err
+	}
+	s.keys, s.fetched = keys, s.tried
+	return nil
+}
+
+func (s *keySet) lookup(kid string) (publicKey, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, k := range s.keys {
+			return k, true
+		}
+	}
+	k, ok := s.keys[kid]
+	return k, ok
+}
+
+// fetch — читает и разбирает набор; mu не нужен
+func (s *keySet) fetch(ctx context.Context) (map[string]publicKey, error) {
+	raw, err := s.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("decode jwks: %w", err)
+	}
+	keys := make(map[string]publicKey, len(doc.Keys))
+	for _, j := range doc.Keys {
+		k, err := j.publicKey()
+		if err != nil {
+			s.lg.Warn("jwks key skipped", logger.KV("kid", j.Kid), logger.Err(err))
+			continue
+		}
+		if k.alg != "" {
+			keys[j.Kid] = k
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("jwks has no usable RS256/ES256 keys")
+	}
+	return keys, nil
+}
+
+func (s *keySet) read(ctx context.Context) ([]byte, error) {
+	if !s.remote() {
+		return os.ReadFile(s.src)
+	}
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.src, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks: unexpected status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxJWKSBytes))
+}
+
+// jwk — ключ из набора (RFC 7517); нужны только открытые параметры RSA и EC
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey — alg пустой: ключ не для подписи RS256/ES256 и пропускается без ошибки
+func (j jwk) publicKey() (publicKey, error) {
+	if j.Use != "" && j.Use != "sig" {
+		return publicKey{}, nil
+	}
+	switch j.Kty {
+	case "RSA":
+		if j.Alg != "" && j.Alg != "RS256" {
+			return publicKey{}, nil
+		}
+		n, err1 := b64int(j.N)
+		e, err2 := b64int(j.E)
+		if err1 != nil || err2 != nil || !e.IsInt64() || e.Int64() < 3 {
+			return publicKey{}, errors.New("bad RSA parameters")
+		}
+		if n.BitLen() < 2048 {
+			return publicKey{}, errors.New("RSA key shorter than 2048 bits")
+		}
+		return publicKey{alg: "RS256", rsa: &rsa.PublicKey{N: n, E: int(e.Int64())}}, nil
+	case "EC":
+		if (j.Alg != "" && j.Alg != "ES256") || j.Crv != "P-256" {
+			return publicKey{}, nil
+		}
+		x, err1 := base64.RawURLEncoding.DecodeString(j.X)
+		y, err2 := base64.RawURLEncoding.DecodeString(j.Y)
+		if err1 != nil || err2 != nil || len(x) != 32 || len(y) != 32 {
+			return publicKey{}, errors.New("bad EC parameters")
+		}
+		// проверка, что точка лежит на кривой
+		if _, err := ecdh.P256().NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return publicKey{}, err
+		}
+		return publicKey{alg: "ES256", ec: &ecdsa.PublicKey{
+			Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y),
+		}}, nil
+	}
+	return publicKey{}, nil
+}
+
+func b64int(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("bad integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
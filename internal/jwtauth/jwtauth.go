@@ -0,0 +1,280 @@
+// Package jwtauth — проверка JWT от SSO (OIDC) по набору ключей JWKS.
+package jwtauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sunzhqr/phonebook/internal/config"
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+var errUnauthenticated = &service.Error{Code: http.StatusUnauthorized, Message: "invalid or expired credentials"}
+
+// Authenticator — проверяет токены вида header.payload.signature, остальные (ключи API) передаёт next
+type Authenticator struct {
+	lg         *logger.Logger
+	cfg        config.JWT
+	keys       *keySet
+	rolesPath  []string
+	tenantPath []string
+	roleScopes map[string][]string
+	next       service.AuthService
+	now        func() time.Time
+}
+
+func New(lg *logger.Logger, cfg config.JWT, next service.AuthService) (*Authenticator, error) {
+	if cfg.Issuer == "" {
+		return nil, errors.New("JWT_ISSUER is required when JWT_JWKS is set")
+	}
+	// без aud сервис принял бы токен, выданный IdP любому другому клиенту той же области
+	if cfg.Audience == "" {
+		return nil, errors.New("JWT_AUDIENCE is required when JWT_JWKS is set")
+	}
+	roleScopes, err := parseRoleScopes(cfg.RoleScopes)
+	if err != nil {
+		return nil, err
+	}
+	a := &Authenticator{
+		lg:         lg,
+		cfg:        cfg,
+		keys:       newKeySet(lg, cfg.JWKS, cfg.JWKSRefresh),
+		rolesPath:  strings.Split(cfg.RolesClaim, "."),
+		tenantPath: strings.Split(cfg.TenantClaim, "."),
+		roleScopes: roleScopes,
+		next:       next,
+		now:        time.Now,
+	}
+	// недоступный при старте IdP не должен ронять сервис: ключи подгрузятся с первым токеном;
+	// нечитаемый файл — ошибка конфигурации
+	if err = a.keys.load(context.Background()); err != nil {
+		if !a.keys.remote() {
+			return nil, fmt.Errorf("load jwks: %w", err)
+		}
+		lg.Warn("jwks not loaded yet", logger.KV("src", cfg.JWKS), logger.Err(err))
+	}
+	return a, nil
+}
+
+// parseRoleScopes — "admins=admin,staff=contacts:read"; у роли может быть несколько областей
+func parseRoleScopes(s string) (map[string][]string, error) {
+	out := map[string][]string{}
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		role, scope, ok := strings.Cut(pair, "=")
+		role, scope = strings.TrimSpace(role), strings.TrimSpace(scope)
+		if !ok || role == "" || !validScope(scope) {
+			return nil, fmt.Errorf("bad JWT_ROLE_SCOPES entry %q", pair)
+		}
+		out[role] = append(out[role], scope)
+	}
+	return out, nil
+}
+
+func validScope(s string) bool {
+	return s == service.ScopeContactsRead || s == service.ScopeContactsWrite || s == service.ScopeAdmin
+}
+
+func (a *Authenticator) Authenticate(ctx context.Context, token string) (service.Principal, error) {
+	if strings.Count(token, ".") != 2 {
+		return a.next.Authenticate(ctx, token)
+	}
+	c, err := a.verify(ctx, token)
+	if err != nil {
+		a.lg.Debug("jwt rejected", logger.Err(err))
+		return service.Principal{}, errUnauthenticated
+	}
+	roles := a.roles(c.raw)
+	p := service.Principal{Subject: "user:" + c.Subject, Name: c.displayName(), Scopes: a.scopes(roles), Groups: roles}
+	if a.cfg.TenantClaim != "" {
+		// токен без книги при настроенном JWT_TENANT_CLAIM не даёт доступа ни к одной
+		book, _ := claimAt(c.raw, a.tenantPath).(string)
+		if book == "" {
+			return service.Principal{}, errUnauthenticated
+		}
+		p.AddressBook = book
+	}
+	return p, nil
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// claims — зарегистрированные утверждения (RFC 7519) и весь payload для ролей
+type claims struct {
+	Issuer            string   `json:"iss"`
+	Subject           string   `json:"sub"`
+	Audience          audience `json:"aud"`
+	Expires           *float64 `json:"exp"`
+	NotBefore         *float64 `json:"nbf"`
+	PreferredUsername string   `json:"preferred_username"`
+	Email             string   `json:"email"`
+	Name              string   `json:"name"`
+	raw               map[string]any
+}
+
+func (c claims) displayName() string {
+	for _, s := range []string{c.PreferredUsername, c.Email, c.Name} {
+		if s != "" {
+			return s
+		}
+	}
+	return c.Subject
+}
+
+// audience — aud бывает и строкой, и массивом
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var one string
+	if err := json.Unmarshal(b, &one); err == nil {
+		*a = audience{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+func (a *Authenticator) verify(ctx context.Context, token string) (claims, error) {
+	parts := strings.Split(token, ".")
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return claims{}, fmt.Errorf("header: %w", err)
+	}
+	// алгоритм выбирает сервер, а не токен: none и HS256 с открытым ключом в роли секрета не проходят
+	if h.Alg != "RS256" && h.Alg != "ES256" {
+		return claims{}, fmt.Errorf("unsupported alg %q", h.Alg)
+	}
+	key, err := a.keys.key(ctx, h.Kid)
+	if err != nil {
+		return claims{}, err
+	}
+	if key.alg != h.Alg {
+		return claims{}, fmt.Errorf("key %q is not for %s", h.Kid, h.Alg)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return claims{}, errors.New("bad signature encoding")
+	}
+	if err := key.verify(parts[0]+"."+parts[1], sig); err != nil {
+		return claims{}, err
+	}
+
+	var c claims
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return claims{}, fmt.Errorf("payload: %w", err)
+	}
+	if err := decodeSegment(parts[1], &c.raw); err != nil {
+		return claims{}, fmt.Errorf("payload: %w", err)
+	}
+	return c, a.validate(c)
+}
+
+func (a *Authenticator) validate(c claims) error {
+	now := a.now()
+	switch {
+	case c.Issuer != a.cfg.Issuer:
+		return fmt.Errorf("unexpected iss %q", c.Issuer)
+	case !slices.Contains(c.Audience, a.cfg.Audience):
+		return fmt.Errorf("audience %q not in %v", a.cfg.Audience, []string(c.Audience))
+	case c.Expires == nil:
+		return errors.New("exp is required")
+	case !now.Before(unix(*c.Expires).Add(a.cfg.Leeway)):
+		return errors.New("token expired")
+	case c.NotBefore != nil && now.Add(a.cfg.Leeway).Before(unix(*c.NotBefore)):
+		return errors.New("token not valid yet")
+	case c.Subject == "":
+		return errors.New("sub is required")
+	}
+	return nil
+}
+
+func unix(f float64) time.Time { return time.Unix(int64(f), 0) }
+
+func (k publicKey) verify(signed string, sig []byte) error {
+	sum := sha256.Sum256([]byte(signed))
+	switch {
+	case k.rsa != nil:
+		return rsa.VerifyPKCS1v15(k.rsa, crypto.SHA256, sum[:], sig)
+	case k.ec != nil:
+		// подпись JWS для ES256 — r||s по 32 байта, не ASN.1
+		if len(sig) != 64 {
+			return errors.New("bad ES256 signature length")
+		}
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(k.ec, sum[:], r, s) {
+			return errors.New("bad signature")
+		}
+		return nil
+	}
+	return errors.New("no key")
+}
+
+// roles — роли из утверждения по пути RolesClaim; они же группы для выдач group:<роль> на контакты
+func (a *Authenticator) roles(raw map[string]any) []string {
+	var roles []string
+	switch t := claimAt(raw, a.rolesPath).(type) {
+	case string: // как scope в OAuth: через пробел
+		roles = strings.Fields(t)
+	case []any:
+		for _, r := range t {
+			if s, ok := r.(string); ok {
+				roles = append(roles, s)
+			}
+		}
+	}
+	return roles
+}
+
+// scopes — роли, переведённые в области только по JWT_ROLE_SCOPES: роль, случайно названная как
+// область в IdP, сама ничего не даёт. Без ролей токен аутентифицирует, но ничего не разрешает.
+func (a *Authenticator) scopes(roles []string) []string {
+	var out []string
+	for _, r := range roles {
+		out = append(out, a.roleScopes[r]...)
+	}
+	slices.Sort(out)
+	return slices.Compact(out)
+}
+
+// claimAt — значение утверждения по пути через точку (realm_access.roles); nil, если его нет
+func claimAt(raw map[string]any, path []string) any {
+	var v any = raw
+	for _, p := range path {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[p]
+	}
+	return v
+}
+
+func decodeSegment(seg string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
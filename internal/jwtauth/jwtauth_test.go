@@ -0,0 +1,340 @@
+package jwtauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sunzhqr/phonebook/internal/config"
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+const issuer = "https://sso.example.kz/realms/staff"
+
+// signer — ключ подписи и его запись в JWKS
+type signer struct {
+	kid string
+	rsa *rsa.PrivateKey
+	ec  *ecdsa.PrivateKey
+}
+
+func newRSA(t *testing.T, kid string) signer {
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer{kid: kid, rsa: k}
+}
+
+func newEC(t *testing.T, kid string) signer {
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer{kid: kid, ec: k}
+}
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func (s signer) jwk() map[string]string {
+	if s.rsa != nil {
+		return map[string]string{"kty": "RSA", "kid": s.kid, "use": "sig", "alg": "RS256",
+			"n": b64(s.rsa.N.Bytes()), "e": b64([]byte{1, 0, 1})}
+	}
+	return map[string]string{"kty": "EC", "kid": s.kid, "crv": "P-256",
+		"x": b64(s.ec.X.FillBytes(make([]byte, 32))), "y": b64(s.ec.Y.FillBytes(make([]byte, 32)))}
+}
+
+func jwks(ss ...signer) []byte {
+	keys := make([]map[string]string, 0, len(ss))
+	for _, s := range ss {
+		keys = append(keys, s.jwk())
+	}
+	b, _ := json.Marshal(map[string]any{"keys": keys})
+	return b
+}
+
+func (s signer) sign(t *testing.T, alg string, claims map[string]any) string {
+	t.Helper()
+	h, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT", "kid": s.kid})
+	p, _ := json.Marshal(claims)
+	signed := b64(h) + "." + b64(p)
+	sum := sha256.Sum256([]byte(signed))
+	var sig []byte
+	if s.rsa != nil {
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, s.rsa, crypto.SHA256, sum[:]); err != nil {
+			t.Fatal(err)
+		}
+	} else {
+		r, ss, err := ecdsa.Sign(rand.Reader, s.ec, sum[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = append(r.FillBytes(make([]byte, 32)), ss.FillBytes(make([]byte, 32))...)
+	}
+	return signed + "." + b64(sig)
+}
+
+func payload(over map[string]any) map[string]any {
+	c := map[string]any{
+		"iss": issuer, "sub": "f3a1c2", "aud": []string{"phonebook", "account"},
+		"exp": time.Now().Add(5 * time.Minute).Unix(), "preferred_username": "a.sadykova",
+		"realm_access": map[string]any{"roles": []string{"phonebook-editors", "offline_access"}},
+	}
+	for k, v := range over {
+		if v == nil {
+			delete(c, k)
+		} else {
+			c[k] = v
+		}
+	}
+	return c
+}
+
+// keysFn — API-ключи: всё, что не JWT, попадает сюда
+type keysFn func(context.Context, string) (service.Principal, error)
+
+func (f keysFn) Authenticate(ctx context.Context, key string) (service.Principal, error) {
+	return f(ctx, key)
+}
+
+func testConfig(src string) config.JWT {
+	return config.JWT{JWKS: src, Issuer: issuer, Audience: "phonebook", RolesClaim: "realm_access.roles",
+		RoleScopes: "phonebook-editors=contacts:write,phonebook-admins=admin", JWKSRefresh: time.Hour, Leeway: time.Minute}
+}
+
+func TestAuthenticate_Claims(t *testing.T) {
+	rs, es := newRSA(t, "rs-1"), newEC(t, "es-1")
+	file := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(file, jwks(rs, es), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	apiKey := keysFn(func(_ context.Context, key string) (service.Principal, error) {
+		return service.Principal{Subject: "apikey:1", Scopes: []string{service.ScopeAdmin}}, nil
+	})
+	a, err := New(logger.New("dev"), testConfig(file), apiKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	p, err := a.Authenticate(ctx, rs.sign(t, "RS256", payload(nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Subject != "user:f3a1c2" || p.Name != "a.sadykova" || !slices.Equal(p.Scopes, []string{service.ScopeContactsWrite}) {
+		t.Fatalf("principal = %+v", p)
+	}
+	// все роли, и без области, — группы для выдач на контакты
+	if !slices.Equal(p.Groups, []string{"phonebook-editors", "offline_access"}) {
+		t.Fatalf("groups = %v", p.Groups)
+	}
+	p, err = a.Authenticate(ctx, es.sign(t, "ES256", payload(map[string]any{"aud": "phonebook",
+		"realm_access": map[string]any{"roles": []string{"phonebook-admins", "contacts:read"}}})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// роль с именем области без записи в JWT_ROLE_SCOPES ничего не даёт
+	if !slices.Equal(p.Scopes, []string{service.ScopeAdmin}) {
+		t.Fatalf("es256 scopes = %v", p.Scopes)
+	}
+	if p, err := a.Authenticate(ctx, "pb_not-a-jwt"); err != nil || p.Subject != "apikey:1" {
+		t.Fatalf("api key not delegated: %+v, %v", p, err)
+	}
+
+	other := newRSA(t, "rs-1")
+	rejected := map[string]string{
+		"foreign issuer": rs.sign(t, "RS256", payload(map[string]any{"iss": "https://evil.example"})),
+		"foreign aud":    rs.sign(t, "RS256", payload(map[string]any{"aud": "crm"})),
+		"expired":        rs.sign(t, "RS256", payload(map[string]any{"exp": time.Now().Add(-2 * time.Minute).Unix()})),
+		"no exp":         rs.sign(t, "RS256", payload(map[string]any{"exp": nil})),
+		"not yet valid":  rs.sign(t, "RS256", payload(map[string]any{"nbf": time.Now().Add(time.Hour).Unix()})),
+		"wrong key":      other.sign(t, "RS256", payload(nil)),
+		"alg mismatch":   es.sign(t, "RS256", payload(nil)),
+		"alg none":       b64([]byte(`{"alg":"none","kid":"rs-1"}`)) + "." + b64([]byte(`{"iss":"`+issuer+`"}`)) + ".",
+		"garbage":        "a.b.c",
+	}
+	for name, token := range rejected {
+		t.Run(name, func(t *testing.T) {
+			_, err := a.Authenticate(ctx, token)
+			var se *service.Error
+			if !errors.As(err, &se) || se.Code != http.StatusUnauthorized {
+				t.Fatalf("want 401, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAuthenticate_TenantClaim(t *testing.T) {
+	rs := newRSA(t, "rs-1")
+	file := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(file, jwks(rs), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig(file)
+	cfg.TenantClaim = "org.book"
+	a, err := New(logger.New("dev"), cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	p, err := a.Authenticate(ctx, rs.sign(t, "RS256", payload(map[string]any{"org": map[string]any{"book": "kaspi"}})))
+	if err != nil || p.AddressBook != "kaspi" {
+		t.Fatalf("principal = %+v, %v", p, err)
+	}
+	// без книги в токене доступа нет: иначе он работал бы с любой книгой
+	_, err = a.Authenticate(ctx, rs.sign(t, "RS256", payload(nil)))
+	var se *service.Error
+	if !errors.As(err, &se) || se.Code != http.StatusUnauthorized {
+		t.Fatalf("token without tenant: want 401, got %v", err)
+	}
+}
+
+func TestAuthenticate_RemoteRotation(t *testing.T) {
+	oldKey, newKey := newRSA(t, "2025"), newEC(t, "2026")
+	var (
+		mu      sync.Mutex
+		current = jwks(oldKey)
+		fetches int
+	)
+	idp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		fetches++
+		_, _ = w.Write(current)
+	}))
+	defer idp.Close()
+	fetched := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return fetches
+	}
+
+	a, err := New(logger.New("dev"), testConfig(idp.URL), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for range 3 {
+		if _, err := a.Authenticate(ctx, oldKey.sign(t, "RS256", payload(nil))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fetched() != 1 {
+		t.Fatalf("jwks fetched %d times, want cached", fetched())
+	}
+
+	// IdP сменил ключ: незнакомый kid перечитывает набор, но не чаще minRefetch
+	mu.Lock()
+	current = jwks(newKey)
+	mu.Unlock()
+	a.keys.tried = time.Now().Add(-minRefetch)
+	if _, err := a.Authenticate(ctx, newKey.sign(t, "ES256", payload(nil))); err != nil {
+		t.Fatalf("rotated key: %v", err)
+	}
+	if _, err := a.Authenticate(ctx, oldKey.sign(t, "RS256", payload(nil))); err == nil {
+		t.Fatal("retired key still accepted")
+	}
+	if _, err := a.Authenticate(ctx, newRSA(t, "unknown").sign(t, "RS256", payload(nil))); err == nil {
+		t.Fatal("unknown kid accepted")
+	}
+	if fetched() != 2 {
+		t.Fatalf("jwks fetched %d times, want 2 (refetch is rate limited)", fetched())
+	}
+}
+
+func TestKeySet_RefreshInBackground(t *testing.T) {
+	oldKey, newKey := newRSA(t, "2025"), newRSA(t, "2026")
+	var (
+		mu      sync.Mutex
+		fetches int
+	)
+	release := make(chan struct{})
+	idp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		fetches++
+		n := fetches
+		mu.Unlock()
+		if n == 1 {
+			_, _ = w.Write(jwks(oldKey))
+			return
+		}
+		<-release // IdP отвечает медленно
+		_, _ = w.Write(jwks(oldKey, newKey))
+	}))
+	defer idp.Close()
+
+	a, err := New(logger.New("dev"), testConfig(idp.URL), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.keys.mu.Lock()
+	a.keys.fetched, a.keys.tried = time.Now().Add(-2*time.Hour), time.Now().Add(-minRefetch)
+	a.keys.mu.Unlock()
+
+	// набор устарел: знакомый ключ отдаётся из кеша, не дожидаясь IdP
+	start := time.Now()
+	if _, err := a.Authenticate(context.Background(), oldKey.sign(t, "RS256", payload(nil))); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatal("cached key waited for jwks refresh")
+	}
+
+	// клиент с незнакомым kid ушёл — чтение набора продолжается и пригодится следующему
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := a.Authenticate(ctx, newKey.sign(t, "RS256", payload(nil))); err == nil {
+		t.Fatal("unknown kid accepted before refresh")
+	}
+	close(release)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		a.keys.mu.Lock()
+		loading := a.keys.loading
+		a.keys.mu.Unlock()
+		if loading == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := a.Authenticate(context.Background(), newKey.sign(t, "RS256", payload(nil))); err != nil {
+		t.Fatalf("rotated key after background refresh: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if fetches != 2 {
+		t.Fatalf("jwks fetched %d times, want 2", fetches)
+	}
+}
+
+func TestNew_RequiresIssuerAndAudience(t *testing.T) {
+	for name, cfg := range map[string]func(*config.JWT){
+		"no issuer":   func(c *config.JWT) { c.Issuer = "" },
+		"no audience": func(c *config.JWT) { c.Audience = "" },
+	} {
+		c := testConfig("jwks.json")
+		cfg(&c)
+		if _, err := New(logger.New("dev"), c, nil); err == nil {
+			t.Errorf("%s: config accepted", name)
+		}
+	}
+}
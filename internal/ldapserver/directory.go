@@ -0,0 +1,166 @@
+package ldapserver
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+type attribute struct {
+	name string
+	vals []string
+}
+
+// entry — контакт в виде записи inetOrgPerson
+type entry struct {
+	dn    string
+	attrs []attribute
+}
+
+var objectClasses = []string{"top", "person", "organizationalPerson", "inetOrgPerson"}
+
+func toEntry(c service.ContactOut, baseDN string) entry {
+	uid := strconv.FormatInt(c.ID, 10)
+	e := entry{dn: "uid=" + uid + "," + baseDN}
+	add := func(name string, vals ...string) {
+		out := vals[:0:0]
+		for _, v := range vals {
+			if v != "" {
+				out = append(out, v)
+			}
+		}
+		if len(out) > 0 {
+			e.attrs = append(e.attrs, attribute{name: name, vals: out})
+		}
+	}
+
+	add("objectClass", objectClasses...)
+	add("uid", uid)
+	add("cn", strings.TrimSpace(c.FirstName+" "+c.LastName))
+	add("sn", c.LastName)
+	add("givenName", c.FirstName)
+	add("o", c.Company)
+	add("title", c.JobTitle)
+
+	var work, mobile []string
+	for _, p := range c.Phones {
+		if p.Redacted {
+			continue
+		}
+		num := p.PhoneE164
+		if num == "" {
+			num = p.PhoneRaw
+		}
+		if isMobileLabel(p.Label) {
+			mobile = append(mobile, num)
+		} else {
+			work = append(work, num)
+		}
+	}
+	add("telephoneNumber", work...)
+	add("mobile", mobile...)
+	mails := make([]string, 0, len(c.Emails))
+	for _, m := range c.Emails {
+		mails = append(mails, m.Email)
+	}
+	add("mail", mails...)
+	return e
+}
+
+func isMobileLabel(label string) bool {
+	l := strings.ToLower(label)
+	return strings.Contains(l, "mob") || strings.Contains(l, "cell") || strings.Contains(l, "моб")
+}
+
+func (e entry) get(name string) []string {
+	for _, a := range e.attrs {
+		if strings.EqualFold(a.name, name) {
+			return a.vals
+		}
+	}
+	return nil
+}
+
+// packet — SearchResultEntry; пустой список атрибутов или "*" — все, "1.1" — ни одного
+func (e entry) packet(want []string, typesOnly bool) *ber.Packet {
+	p := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.dn, "Object Name"))
+	list := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	for _, a := range e.attrs {
+		if !selected(want, a.name) {
+			continue
+		}
+		attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, a.name, "Type"))
+		vals := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		if !typesOnly {
+			for _, v := range a.vals {
+				vals.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "Value"))
+			}
+		}
+		attr.AppendChild(vals)
+		list.AppendChild(attr)
+	}
+	p.AppendChild(list)
+	return p
+}
+
+func selected(want []string, name string) bool {
+	if len(want) == 0 {
+		return true
+	}
+	for _, w := range want {
+		if w == "*" || strings.EqualFold(w, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// candidates — контакты, среди которых надо искать подходящие под фильтр: результаты Search по значениям
+// из фильтра или, если фильтр их не даёт, первая страница списка по имени. Окончательно отбирает match.
+func (s *Server) candidates(ctx context.Context, f filter, limit int) ([]service.ContactOut, error) {
+	terms, bounded := f.terms()
+	if !bounded {
+		out, err := s.svc.ListContacts(ctx, service.ListFilter{Limit: min(limit, 100), Sort: "name", Order: "asc"})
+		return out.Items, err
+	}
+
+	var (
+		res  []service.ContactOut
+		seen = map[int64]bool{}
+		done = map[term]bool{}
+	)
+	for _, t := range terms {
+		if done[t] {
+			continue
+		}
+		done[t] = true
+
+		var (
+			list []service.ContactOut
+			err  error
+		)
+		if t.company {
+			var out service.ListOut
+			out, err = s.svc.ListContacts(ctx, service.ListFilter{Company: t.value, Limit: min(limit, 100), Sort: "name", Order: "asc"})
+			list = out.Items
+		} else {
+			list, err = s.svc.Search(ctx, t.value, min(limit, 50))
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range list {
+			if !seen[c.ID] {
+				seen[c.ID] = true
+				res = append(res, c)
+			}
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,232 @@
+package ldapserver
+
+import (
+	"errors"
+	"strings"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+var errBadFilter = errors.New("malformed filter")
+
+// filter — разобранный фильтр search (RFC 4511, 4.5.1.7)
+type filter struct {
+	tag   ber.Tag
+	attr  string
+	value string // equality, approx, >=, <=
+	// substrings: attr=initial*any*...*final
+	initial string
+	any     []string
+	final   string
+	sub     []filter // and, or, not
+}
+
+func parseFilter(p *ber.Packet) (filter, error) {
+	if p.ClassType != ber.ClassContext {
+		return filter{}, errBadFilter
+	}
+	f := filter{tag: p.Tag}
+	switch p.Tag {
+	case ldap.FilterAnd, ldap.FilterOr, ldap.FilterNot:
+		if p.Tag == ldap.FilterNot && len(p.Children) != 1 {
+			return filter{}, errBadFilter
+		}
+		for _, c := range p.Children {
+			sf, err := parseFilter(c)
+			if err != nil {
+				return filter{}, err
+			}
+			f.sub = append(f.sub, sf)
+		}
+	case ldap.FilterEqualityMatch, ldap.FilterApproxMatch, ldap.FilterGreaterOrEqual, ldap.FilterLessOrEqual:
+		if len(p.Children) != 2 {
+			return filter{}, errBadFilter
+		}
+		f.attr, f.value = str(p.Children[0]), str(p.Children[1])
+	case ldap.FilterSubstrings:
+		if len(p.Children) != 2 {
+			return filter{}, errBadFilter
+		}
+		f.attr = str(p.Children[0])
+		for _, c := range p.Children[1].Children {
+			switch c.Tag {
+			case ldap.FilterSubstringsInitial:
+				f.initial = str(c)
+			case ldap.FilterSubstringsAny:
+				f.any = append(f.any, str(c))
+			case ldap.FilterSubstringsFinal:
+				f.final = str(c)
+			}
+		}
+	case ldap.FilterPresent:
+		f.attr = str(p)
+	case ldap.FilterExtensibleMatch:
+		// не поддерживается: такой фильтр ничему не соответствует
+	default:
+		return filter{}, errBadFilter
+	}
+	return f, nil
+}
+
+func (f filter) match(e entry) bool {
+	switch f.tag {
+	case ldap.FilterAnd:
+		for _, sf := range f.sub {
+			if !sf.match(e) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, sf := range f.sub {
+			if sf.match(e) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return !f.sub[0].match(e)
+	case ldap.FilterPresent:
+		return len(e.get(f.attr)) > 0
+	}
+
+	want := normValue(f.attr, f.value)
+	for _, v := range e.get(f.attr) {
+		v = normValue(f.attr, v)
+		switch f.tag {
+		case ldap.FilterEqualityMatch, ldap.FilterApproxMatch:
+			if want != "" && v == want {
+				return true
+			}
+		case ldap.FilterGreaterOrEqual:
+			if v >= want {
+				return true
+			}
+		case ldap.FilterLessOrEqual:
+			if v <= want {
+				return true
+			}
+		case ldap.FilterSubstrings:
+			if f.matchSubstrings(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (f filter) matchSubstrings(v string) bool {
+	piece := func(s string) (string, bool) {
+		n := normValue(f.attr, s)
+		// "*Ivan*" против телефона: без цифр кусок не должен совпадать со всем подряд
+		return n, n != "" || s == ""
+	}
+	p, ok := piece(f.initial)
+	if !ok || !strings.HasPrefix(v, p) {
+		return false
+	}
+	v = v[len(p):]
+	for _, a := range f.any {
+		p, ok = piece(a)
+		if !ok {
+			return false
+		}
+		i := strings.Index(v, p)
+		if i < 0 {
+			return false
+		}
+		v = v[i+len(p):]
+	}
+	p, ok = piece(f.final)
+	return ok && strings.HasSuffix(v, p)
+}
+
+// term — значение из фильтра, по которому кандидатов можно найти через сервис
+type term struct {
+	company bool // по "o" — фильтр списка по компании, иначе Search
+	value   string
+}
+
+// terms — запросы к сервису, объединение результатов которых покрывает все записи, подходящие под фильтр.
+// bounded=false — по фильтру кандидатов не сузить (objectClass=*, отрицание и т.п.), нужен обход списка.
+func (f filter) terms() (out []term, bounded bool) {
+	switch f.tag {
+	case ldap.FilterAnd:
+		// достаточно одного сужающего условия: запись обязана ему соответствовать
+		for _, sf := range f.sub {
+			if t, ok := sf.terms(); ok {
+				return t, true
+			}
+		}
+		return nil, false
+	case ldap.FilterOr:
+		for _, sf := range f.sub {
+			t, ok := sf.terms()
+			if !ok {
+				return nil, false
+			}
+			out = append(out, t...)
+		}
+		return out, true
+	case ldap.FilterEqualityMatch, ldap.FilterApproxMatch:
+		return f.term(f.value)
+	case ldap.FilterSubstrings:
+		longest := f.final
+		for _, s := range append([]string{f.initial}, f.any...) {
+			if len(s) > len(longest) {
+				longest = s
+			}
+		}
+		return f.term(longest)
+	}
+	return nil, false
+}
+
+func (f filter) term(v string) ([]term, bool) {
+	switch strings.ToLower(f.attr) {
+	case "cn", "sn", "givenname", "mail":
+		if v = strings.TrimSpace(v); v == "" {
+			return nil, false
+		}
+		return []term{{value: v}}, true
+	case "o":
+		if v = strings.TrimSpace(v); v == "" {
+			return nil, false
+		}
+		return []term{{company: true, value: v}}, true
+	case "telephonenumber", "mobile":
+		if v = strings.TrimSpace(v); v == "" {
+			return nil, false
+		}
+		// буквы против номера не совпадут ни с чем — сужает до пустого множества
+		if digits(v) == "" {
+			return nil, true
+		}
+		return []term{{value: digits(v)}}, true
+	}
+	return nil, false
+}
+
+// normValue — телефоны сравниваются по цифрам, остальное без учёта регистра
+func normValue(attr, v string) string {
+	if isPhoneAttr(attr) {
+		return digits(v)
+	}
+	return strings.ToLower(v)
+}
+
+func isPhoneAttr(attr string) bool {
+	a := strings.ToLower(attr)
+	return a == "telephonenumber" || a == "mobile"
+}
+
+func digits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,118 @@
+// Package ldapserver — read-only LDAPv3 справочник для настольных телефонов (Yealink, Cisco и т.п.):
+// bind и search поверх service.ContactsService в книге LDAP_ADDRESS_BOOK, остальные операции отклоняются.
+package ldapserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+
+	"github.com/sunzhqr/phonebook/internal/config"
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+// Services — что справочник берёт из слоя service: контакты и книгу, которую он отдаёт
+type Services interface {
+	service.ContactsService
+	service.AddressBooksService
+}
+
+type Server struct {
+	lg  *logger.Logger
+	cfg config.LDAP
+	svc Services
+
+	mu     sync.Mutex
+	ln     net.Listener
+	conns  map[net.Conn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func New(lg *logger.Logger, cfg config.LDAP, svc Services) *Server {
+	return &Server{lg: lg, cfg: cfg, svc: svc, conns: map[net.Conn]struct{}{}}
+}
+
+func (s *Server) Start() error {
+	ln, err := net.Listen("tcp", s.cfg.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Serve — принимает соединения до Stop; каждое обслуживается в своей горутине.
+func (s *Server) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		_ = ln.Close()
+		return net.ErrClosed
+	}
+	s.ln = ln
+	s.mu.Unlock()
+
+	for {
+		c, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		if !s.track(c) {
+			_ = c.Close()
+			return nil
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer s.untrack(c)
+			newSession(s, c).serve()
+		}()
+	}
+}
+
+// Stop — закрывает листенер и открытые соединения, ждёт их обработчиков до истечения ctx.
+func (s *Server) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	if s.ln != nil {
+		_ = s.ln.Close()
+	}
+	for c := range s.conns {
+		_ = c.Close()
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Server) track(c net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.conns[c] = struct{}{}
+	return true
+}
+
+func (s *Server) untrack(c net.Conn) {
+	s.mu.Lock()
+	delete(s.conns, c)
+	s.mu.Unlock()
+	_ = c.Close()
+}
@@ -0,0 +1,252 @@
+package ldapserver_test
+
+import (
+	"context"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/sunzhqr/phonebook/internal/config"
+	"github.com/sunzhqr/phonebook/internal/ldapserver"
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+// bookSvc — Search и ListContacts по срезу в памяти с той же семантикой «содержит», что у репозитория
+type bookSvc struct {
+	service.ContactsService
+	service.AddressBooksService
+	all      []service.ContactOut
+	searches []string
+	books    []string // книга каждого вызова
+}
+
+func (b *bookSvc) ResolveAddressBook(_ context.Context, slug string) (service.AddressBookOut, error) {
+	if slug != "default" && slug != "phones" {
+		return service.AddressBookOut{}, &service.Error{Code: 404, Message: "address book not found"}
+	}
+	return service.AddressBookOut{ID: 2, Slug: slug}, nil
+}
+
+func (b *bookSvc) inBook(ctx context.Context) {
+	book, _ := service.AddressBookFrom(ctx)
+	b.books = append(b.books, book.Slug)
+}
+
+func (b *bookSvc) Search(ctx context.Context, q string, limit int) ([]service.ContactOut, error) {
+	b.inBook(ctx)
+	b.searches = append(b.searches, q)
+	var out []service.ContactOut
+	for _, c := range b.all {
+		name := strings.ToLower(c.FirstName + " " + c.LastName)
+		hit := strings.Contains(name, strings.ToLower(q))
+		for _, p := range c.Phones {
+			if strings.Contains(p.PhoneE164, q) {
+				hit = true
+			}
+		}
+		if hit && len(out) < limit {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (b *bookSvc) ListContacts(ctx context.Context, f service.ListFilter) (service.ListOut, error) {
+	b.inBook(ctx)
+	var out []service.ContactOut
+	for _, c := range b.all {
+		if f.Company != "" && !strings.Contains(strings.ToLower(c.Company), strings.ToLower(f.Company)) {
+			continue
+		}
+		if len(out) < f.Limit {
+			out = append(out, c)
+		}
+	}
+	return service.ListOut{Items: out}, nil
+}
+
+var book = []service.ContactOut{
+	{ID: 1, FirstName: "Aru", LastName: "Sadykova", Company: "Forte",
+		Phones: []service.PhoneOut{
+			{Label: "work", PhoneE164: "+77272000101"},
+			{Label: "mobile", PhoneE164: "+77011234567"},
+		}},
+	{ID: 2, FirstName: "Dias", LastName: "Nurlanov", Company: "Kaspi",
+		Phones: []service.PhoneOut{{Label: "work", PhoneE164: "+77272000202"}}},
+	{ID: 3, FirstName: "Saule", LastName: "Sadykova", Company: "Kaspi",
+		Phones: []service.PhoneOut{{Label: "Мобильный", PhoneE164: "+77079998877"}}},
+}
+
+const baseDN = "ou=contacts,dc=phonebook"
+
+func start(t *testing.T, cfg config.LDAP) (*ldap.Conn, *bookSvc) {
+	t.Helper()
+	svc := &bookSvc{all: book}
+	cfg.BaseDN = baseDN
+	if cfg.SizeLimit == 0 {
+		cfg.SizeLimit = 50
+	}
+	if cfg.AddressBook == "" {
+		cfg.AddressBook = "default"
+	}
+	srv := ldapserver.New(logger.New("dev"), cfg, svc)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = srv.Serve(ln) }()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = srv.Stop(ctx)
+	})
+
+	conn, err := ldap.DialURL("ldap://" + ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn, svc
+}
+
+func search(t *testing.T, conn *ldap.Conn, filter string, attrs ...string) []*ldap.Entry {
+	t.Helper()
+	res, err := conn.Search(ldap.NewSearchRequest(baseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		0, 0, false, filter, attrs, nil))
+	if err != nil {
+		t.Fatalf("%s: %v", filter, err)
+	}
+	return res.Entries
+}
+
+func names(es []*ldap.Entry) []string {
+	var out []string
+	for _, e := range es {
+		out = append(out, e.GetAttributeValue("cn"))
+	}
+	slices.Sort(out)
+	return out
+}
+
+func TestSearch_PhoneFilters(t *testing.T) {
+	conn, svc := start(t, config.LDAP{})
+	if err := conn.UnauthenticatedBind(""); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name, filter string
+		want         []string
+	}{
+		// Yealink: один и тот же ввод по имени и номерам
+		{"yealink name", "(|(cn=*sady*)(telephoneNumber=*sady*)(mobile=*sady*))", []string{"Aru Sadykova", "Saule Sadykova"}},
+		{"yealink number", "(|(cn=*2000202*)(telephoneNumber=*2000202*)(mobile=*2000202*))", []string{"Dias Nurlanov"}},
+		// Cisco: фамилия по префиксу внутри and с objectClass
+		{"cisco prefix", "(&(objectClass=person)(sn=Nurl*))", []string{"Dias Nurlanov"}},
+		{"given and surname", "(&(givenName=Saule)(sn=Sadykova))", []string{"Saule Sadykova"}},
+		{"mobile label", "(mobile=*7079998877)", []string{"Saule Sadykova"}},
+		{"formatted number", `(telephoneNumber=+7 \28727\29 200-01-01)`, []string{"Aru Sadykova"}},
+		{"company", "(&(o=kaspi)(!(sn=Nurlanov)))", []string{"Saule Sadykova"}},
+		{"browse", "(objectClass=*)", []string{"Aru Sadykova", "Dias Nurlanov", "Saule Sadykova"}},
+		{"no match", "(cn=Nobody*)", nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := names(search(t, conn, tc.filter)); !slices.Equal(got, tc.want) {
+				t.Fatalf("%s = %v, want %v", tc.filter, got, tc.want)
+			}
+		})
+	}
+	if slices.Contains(svc.searches, "") {
+		t.Fatalf("empty search term sent: %q", svc.searches)
+	}
+}
+
+func TestSearch_EntryAttributes(t *testing.T) {
+	conn, _ := start(t, config.LDAP{})
+
+	es := search(t, conn, "(sn=Sadykova)", "cn", "telephoneNumber", "mobile", "o")
+	if len(es) != 2 {
+		t.Fatalf("entries = %d", len(es))
+	}
+	e := es[0]
+	if e.DN != "uid=1,"+baseDN {
+		t.Fatalf("dn = %q", e.DN)
+	}
+	if got := e.GetAttributeValues("telephoneNumber"); !slices.Equal(got, []string{"+77272000101"}) {
+		t.Fatalf("telephoneNumber = %v", got)
+	}
+	if got := e.GetAttributeValue("mobile"); got != "+77011234567" {
+		t.Fatalf("mobile = %q", got)
+	}
+	if e.GetAttributeValue("o") != "Forte" || e.GetAttributeValue("sn") != "" {
+		t.Fatalf("attributes not limited to requested: %+v", e.Attributes)
+	}
+}
+
+// справочник ищет только в своей книге; неизвестная книга — ошибка, а не поиск без книги
+func TestSearch_AddressBook(t *testing.T) {
+	conn, svc := start(t, config.LDAP{AddressBook: "phones"})
+	search(t, conn, "(sn=Sadykova)")
+	search(t, conn, "(objectClass=*)")
+	if len(svc.books) == 0 || slices.ContainsFunc(svc.books, func(b string) bool { return b != "phones" }) {
+		t.Fatalf("books = %v", svc.books)
+	}
+
+	conn, svc = start(t, config.LDAP{AddressBook: "missing"})
+	_, err := conn.Search(ldap.NewSearchRequest(baseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		0, 0, false, "(objectClass=*)", nil, nil))
+	if !ldap.IsErrorWithCode(err, ldap.LDAPResultOther) || len(svc.books) != 0 {
+		t.Fatalf("unknown book: err = %v, calls = %v", err, svc.books)
+	}
+}
+
+func TestSearch_SizeLimit(t *testing.T) {
+	conn, _ := start(t, config.LDAP{SizeLimit: 2})
+
+	res, err := conn.Search(ldap.NewSearchRequest(baseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		0, 0, false, "(objectClass=person)", nil, nil))
+	if !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		t.Fatalf("err = %v, want sizeLimitExceeded", err)
+	}
+	if res == nil || len(res.Entries) != 2 {
+		t.Fatalf("res = %+v", res)
+	}
+}
+
+func TestBind_Credentials(t *testing.T) {
+	conn, _ := start(t, config.LDAP{BindDN: "cn=phones,dc=phonebook", BindPassword: "secret"})
+
+	_, err := conn.Search(ldap.NewSearchRequest(baseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		0, 0, false, "(cn=*)", nil, nil))
+	if !ldap.IsErrorWithCode(err, ldap.LDAPResultInsufficientAccessRights) {
+		t.Fatalf("search before bind: %v", err)
+	}
+	if err := conn.Bind("cn=phones,dc=phonebook", "wrong"); !ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+		t.Fatalf("wrong password: %v", err)
+	}
+	if err := conn.Bind("CN=phones, DC=phonebook", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if got := search(t, conn, "(cn=Dias*)"); len(got) != 1 {
+		t.Fatalf("entries = %d", len(got))
+	}
+}
+
+func TestReadOnlyAndBase(t *testing.T) {
+	conn, _ := start(t, config.LDAP{})
+
+	err := conn.Del(ldap.NewDelRequest("uid=1,"+baseDN, nil))
+	if !ldap.IsErrorWithCode(err, ldap.LDAPResultUnwillingToPerform) {
+		t.Fatalf("delete: %v", err)
+	}
+	_, err = conn.Search(ldap.NewSearchRequest("ou=other,dc=example", ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		0, 0, false, "(cn=*)", nil, nil))
+	if !ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+		t.Fatalf("foreign base: %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+package ldapserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+// maxRequestBytes — телефоны шлют короткие bind и search; больший пакет — ошибка протокола, а не повод
+// выделять под него память (по умолчанию asn1-ber разрешает до 2 ГБ)
+const maxRequestBytes = 64 << 10
+
+// searchTimeout — на один search, включая запросы к БД
+const searchTimeout = 10 * time.Second
+
+func init() { ber.MaxPacketLengthBytes = maxRequestBytes }
+
+// session — одно соединение; запросы обрабатываются по очереди, ответы пишутся в порядке запросов
+type session struct {
+	srv   *Server
+	conn  net.Conn
+	bound bool
+}
+
+func newSession(srv *Server, conn net.Conn) *session { return &session{srv: srv, conn: conn} }
+
+func (s *session) serve() {
+	for {
+		if s.srv.cfg.IdleTimeout > 0 {
+			_ = s.conn.SetReadDeadline(time.Now().Add(s.srv.cfg.IdleTimeout))
+		}
+		p, err := ber.ReadPacket(s.conn)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				s.srv.lg.Debug("ldap read failed", logger.KV("remote", s.conn.RemoteAddr().String()), logger.Err(err))
+			}
+			return
+		}
+		if len(p.Children) < 2 {
+			return
+		}
+		id, ok := p.Children[0].Value.(int64)
+		op := p.Children[1]
+		if !ok || op.ClassType != ber.ClassApplication {
+			return
+		}
+
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			err = s.bind(id, op)
+		case ldap.ApplicationUnbindRequest:
+			return
+		case ldap.ApplicationSearchRequest:
+			err = s.search(id, op)
+		case ldap.ApplicationAbandonRequest:
+			// search выполняется синхронно — к приходу abandon отменять уже нечего
+		case ldap.ApplicationModifyRequest, ldap.ApplicationAddRequest, ldap.ApplicationDelRequest,
+			ldap.ApplicationModifyDNRequest, ldap.ApplicationCompareRequest:
+			err = s.write(id, result(op.Tag+1, ldap.LDAPResultUnwillingToPerform, "directory is read-only"))
+		case ldap.ApplicationExtendedRequest:
+			err = s.write(id, result(ldap.ApplicationExtendedResponse, ldap.LDAPResultProtocolError, "unsupported extended operation"))
+		default:
+			return
+		}
+		if err != nil {
+			s.srv.lg.Debug("ldap write failed", logger.KV("remote", s.conn.RemoteAddr().String()), logger.Err(err))
+			return
+		}
+	}
+}
+
+// bind — только simple; без настроенного LDAP_BIND_DN принимается любой, в том числе анонимный
+func (s *session) bind(id int64, op *ber.Packet) error {
+	if len(op.Children) < 3 {
+		return s.write(id, result(ldap.ApplicationBindResponse, ldap.LDAPResultProtocolError, "malformed bind request"))
+	}
+	if v, _ := op.Children[0].Value.(int64); v != 3 {
+		return s.write(id, result(ldap.ApplicationBindResponse, ldap.LDAPResultProtocolError, "only LDAPv3 is supported"))
+	}
+	auth := op.Children[2]
+	if auth.ClassType != ber.ClassContext || auth.Tag != 0 {
+		return s.write(id, result(ldap.ApplicationBindResponse, ldap.LDAPResultAuthMethodNotSupported, "only simple bind is supported"))
+	}
+
+	s.bound = false
+	cfg := s.srv.cfg
+	if cfg.BindDN != "" {
+		dn, pw := str(op.Children[1]), str(auth)
+		if !strings.EqualFold(normDN(dn), normDN(cfg.BindDN)) || subtle.ConstantTimeCompare([]byte(pw), []byte(cfg.BindPassword)) != 1 {
+			return s.write(id, result(ldap.ApplicationBindResponse, ldap.LDAPResultInvalidCredentials, ""))
+		}
+	}
+	s.bound = true
+	return s.write(id, result(ldap.ApplicationBindResponse, ldap.LDAPResultSuccess, ""))
+}
+
+func (s *session) search(id int64, op *ber.Packet) error {
+	done := func(code uint16, msg string) error {
+		return s.write(id, result(ldap.ApplicationSearchResultDone, code, msg))
+	}
+	cfg := s.srv.cfg
+	if cfg.BindDN != "" && !s.bound {
+		return done(ldap.LDAPResultInsufficientAccessRights, "bind required")
+	}
+	if len(op.Children) < 8 {
+		return done(ldap.LDAPResultProtocolError, "malformed search request")
+	}
+	base := str(op.Children[0])
+	scope, _ := op.Children[1].Value.(int64)
+	sizeLimit, _ := op.Children[3].Value.(int64)
+	typesOnly, _ := op.Children[5].Value.(bool)
+	f, err := parseFilter(op.Children[6])
+	if err != nil {
+		return done(ldap.LDAPResultProtocolError, err.Error())
+	}
+	var attrs []string
+	for _, a := range op.Children[7].Children {
+		attrs = append(attrs, str(a))
+	}
+
+	if !s.srv.inBase(base) {
+		return done(ldap.LDAPResultNoSuchObject, "")
+	}
+	if scope == ldap.ScopeBaseObject {
+		// контейнер контактов сам записью не является
+		return done(ldap.LDAPResultSuccess, "")
+	}
+
+	limit := cfg.SizeLimit
+	if sizeLimit > 0 && int(sizeLimit) < limit {
+		limit = int(sizeLimit)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), searchTimeout)
+	defer cancel()
+	book, err := s.srv.svc.ResolveAddressBook(ctx, cfg.AddressBook)
+	if err != nil {
+		s.srv.lg.Error("ldap address book unavailable", logger.KV("book", cfg.AddressBook), logger.Err(err))
+		return done(ldap.LDAPResultOther, "address book unavailable")
+	}
+	list, err := s.srv.candidates(service.WithAddressBook(ctx, book), f, limit+1)
+	if err != nil {
+		s.srv.lg.Error("ldap search failed", logger.KV("filter", describe(op.Children[6])), logger.Err(err))
+		return done(ldap.LDAPResultOther, "search failed")
+	}
+
+	n := 0
+	for _, c := range list {
+		e := toEntry(c, cfg.BaseDN)
+		if !f.match(e) {
+			continue
+		}
+		if n == limit {
+			return done(ldap.LDAPResultSizeLimitExceeded, "")
+		}
+		if err := s.write(id, e.packet(attrs, typesOnly)); err != nil {
+			return err
+		}
+		n++
+	}
+	return done(ldap.LDAPResultSuccess, "")
+}
+
+func (s *session) write(id int64, op *ber.Packet) error {
+	p := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "Message ID"))
+	p.AppendChild(op)
+	_, err := s.conn.Write(p.Bytes())
+	return err
+}
+
+// result — LDAPResult ответа на операцию tag
+func result(tag ber.Tag, code uint16, msg string) *ber.Packet {
+	p := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, ldap.ApplicationMap[uint8(tag)])
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "Result Code"))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, msg, "Diagnostic Message"))
+	return p
+}
+
+// str — содержимое примитивного элемента (OCTET STRING или context-specific)
+func str(p *ber.Packet) string { return ber.DecodeString(p.Data.Bytes()) }
+
+func describe(p *ber.Packet) string {
+	s, err := ldap.DecompileFilter(p)
+	if err != nil {
+		return "?"
+	}
+	return s
+}
+
+// inBase — базой поиска может быть наш контейнер, любой его родитель или пустой DN
+func (s *Server) inBase(base string) bool {
+	b, own := normDN(base), normDN(s.cfg.BaseDN)
+	return b == "" || b == own || strings.HasSuffix(own, ","+b)
+}
+
+// normDN — DN для сравнения: нижний регистр, без пробелов вокруг разделителей
+func normDN(dn string) string {
+	parts := strings.Split(dn, ",")
+	for i, p := range parts {
+		k, v, _ := strings.Cut(p, "=")
+		parts[i] = strings.ToLower(strings.TrimSpace(k)) + "=" + strings.ToLower(strings.TrimSpace(v))
+	}
+	if len(parts) == 1 && parts[0] == "=" {
+		return ""
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Viewer — кто читает контакты: All — администратор книги, иначе Principals — его идентификаторы
+// (user:<sub>, apikey:<id>, group:<имя>), по которым сверяются выдачи на контактах с ограниченным доступом.
+// Phones — уровни видимости номеров, по которым ему разрешён поиск (свои контакты — по всем).
+type Viewer struct {
+	All        bool
+	Principals []string
+	Phones     []string
+}
+
+type viewerKey struct{}
+
+// WithViewer — запросы к контактам с этим ctx видят только доступные v контакты
+func WithViewer(ctx context.Context, v Viewer) context.Context {
+	return context.WithValue(ctx, viewerKey{}, v)
+}
+
+// viewerArg — аргумент principals для contact_visible: null — все контакты; без Viewer в ctx (LDAP, gRPC)
+// видны только контакты без ограничений
+func viewerArg(ctx context.Context) any {
+	v, _ := ctx.Value(viewerKey{}).(Viewer)
+	if v.All {
+		return nil
+	}
+	if v.Principals == nil {
+		return []string{}
+	}
+	return v.Principals
+}
+
+// phoneLevelsArg — аргумент levels для phone_visible: null — все уровни; без Viewer — только public
+func phoneLevelsArg(ctx context.Context) any {
+	v, _ := ctx.Value(viewerKey{}).(Viewer)
+	if v.All {
+		return nil
+	}
+	if v.Phones == nil {
+		return []string{"public"}
+	}
+	return v.Phones
+}
+
+type aclRepo struct {
+	pool *pgxpool.Pool
+}
+
+// Access — доступ читающего из ctx к контакту id; невидимый контакт — ErrNotFound
+func (r *aclRepo) Access(ctx context.Context, id int64) (ContactAccess, error) {
+	var (
+		a    ContactAccess
+		role *string
+	)
+	err := r.pool.QueryRow(ctx,
+		`select contact_restricted(c), contact_acl_role(c, coalesce($3, '{}'::text[]))
+         from contacts c
+         where c.id = $1 and c.address_book_id = $2 and contact_visible(c, $3)`,
+		id, bookID(ctx), viewerArg(ctx),
+	).Scan(&a.Restricted, &role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ContactAccess{}, ErrNotFound
+	}
+	if role != nil {
+		a.Role = *role
+	}
+	return a, err
+}
+
+func (r *aclRepo) ContactGrants(ctx context.Context, id int64) ([]Grant, error) {
+	rows, err := r.pool.Query(ctx,
+		`select principal, role from contact_grants where contact_id = $1 order by principal`, id)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[Grant])
+}
+
+// SetContactGrants — заменяет все выдачи контакта; пустой список снимает ограничение.
+// Контакт отмечается изменённым: событие в журнале несёт новый круг читателей.
+func (r *aclRepo) SetContactGrants(ctx context.Context, id int64, grants []Grant) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var locked int64
+	err = tx.QueryRow(ctx,
+		`select id from contacts c where c.id = $1 and c.address_book_id = $2 and contact_visible(c, $3) for update`,
+		id, bookID(ctx), viewerArg(ctx)).Scan(&locked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	var b pgx.Batch
+	b.Queue(`delete from contact_grants where contact_id = $1`, id)
+	for _, g := range grants {
+		b.Queue(`insert into contact_grants(contact_id, principal, role) values ($1, $2, $3)`, id, g.Principal, g.Role)
+	}
+	b.Queue(`update contacts set updated_at = now() where id = $1`, id)
+	if err := tx.SendBatch(ctx, &b).Close(); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *aclRepo) TagGrants(ctx context.Context, tag string) ([]Grant, error) {
+	rows, err := r.pool.Query(ctx,
+		`select principal, role from tag_grants where address_book_id = $1 and tag = $2 order by principal`,
+		bookID(ctx), tag)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[Grant])
+}
+
+// SetTagGrants — заменяет выдачи тега в книге из ctx; контакты с тегом отмечаются изменёнными
+func (r *aclRepo) SetTagGrants(ctx context.Context, tag string, grants []Grant) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	book := bookID(ctx)
+	var b pgx.Batch
+	b.Queue(`delete from tag_grants where address_book_id = $1 and tag = $2`, book, tag)
+	for _, g := range grants {
+		b.Queue(`insert into tag_grants(address_book_id, tag, principal, role) values ($1, $2, $3, $4)`,
+			book, tag, g.Principal, g.Role)
+	}
+	b.Queue(`update contacts set updated_at = now() where address_book_id = $1 and $2 = any(tags)`, book, tag)
+	if err := tx.SendBatch(ctx, &b).Close(); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,315 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/sunzhqr/phonebook/internal/repository"
+)
+
+// aclFixture — контакты с разными источниками доступа; у каждого номера трёх уровней видимости,
+// private-номер контакта owned — 77010000203
+type aclFixture struct {
+	open, owned, user, group, tag int64
+}
+
+func seedACL(t *testing.T, r *repository.Repos) aclFixture {
+	t.Helper()
+	ctx := viewer(repository.Viewer{All: true})
+	n := 0
+	create := func(name, owner string, tags ...string) int64 {
+		t.Helper()
+		n++
+		d := func(k int) string { return fmt.Sprintf("7701000%02d%02d", n, k) }
+		c, err := r.Contacts.Create(ctx, repository.ContactInput{
+			FirstName: name, LastName: "Testov", Owner: owner, Tags: tags,
+			Phones: []repository.PhoneInput{
+				{Label: "work", PhoneRaw: "+" + d(1), PhoneE164: "+" + d(1), PhoneDigits: d(1), IsPrimary: true},
+				{Label: "mobile", PhoneRaw: "+" + d(2), PhoneE164: "+" + d(2), PhoneDigits: d(2), Visibility: "internal"},
+				{Label: "home", PhoneRaw: "+" + d(3), PhoneE164: "+" + d(3), PhoneDigits: d(3), Visibility: "private"},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c.ID
+	}
+	f := aclFixture{
+		open:  create("Open", ""),
+		owned: create("Owned", "user:o"),
+		user:  create("User", ""),
+		group: create("Group", ""),
+		tag:   create("Tagged", "", "vip"),
+	}
+	grant := func(id int64, principal, role string) {
+		t.Helper()
+		if err := r.ACL.SetContactGrants(ctx, id, []repository.Grant{{Principal: principal, Role: role}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	grant(f.owned, "user:x", "editor")
+	grant(f.user, "user:u", "viewer")
+	grant(f.group, "group:board", "editor")
+	if err := r.ACL.SetTagGrants(ctx, "vip", []repository.Grant{{Principal: "user:t", Role: "admin"}}); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestACL_SQLFunctions(t *testing.T) {
+	pool := testPool(t)
+	f := seedACL(t, repository.New(pool))
+	ctx := context.Background()
+
+	cases := []struct {
+		name       string
+		id         int64
+		principals []string // nil — администратор книги
+		visible    bool
+		role       string // "" — роли нет
+	}{
+		{"open, stranger", f.open, []string{"user:z"}, true, ""},
+		{"open, no principal", f.open, []string{}, true, ""},
+		{"owner", f.owned, []string{"user:o"}, true, "admin"},
+		{"owned, contact grant", f.owned, []string{"user:x"}, true, "editor"},
+		{"owned, stranger", f.owned, []string{"user:z"}, false, ""},
+		{"owned, no principal", f.owned, []string{}, false, ""},
+		{"owned, book admin", f.owned, nil, true, ""},
+		{"user grant", f.user, []string{"user:u"}, true, "viewer"},
+		{"group grant", f.group, []string{"user:z", "group:board"}, true, "editor"},
+		{"group grant, other group", f.group, []string{"user:z", "group:sales"}, false, ""},
+		{"tag grant", f.tag, []string{"user:t"}, true, "admin"},
+		{"tag grant, stranger", f.tag, []string{"user:u"}, false, ""},
+	}
+	for _, tc := range cases {
+		var (
+			visible bool
+			role    *string
+		)
+		err := pool.QueryRow(ctx,
+			`select contact_visible(c, $2), contact_acl_role(c, coalesce($2, '{}'::text[])) from contacts c where c.id = $1`,
+			tc.id, tc.principals).Scan(&visible, &role)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		got := ""
+		if role != nil {
+			got = *role
+		}
+		if visible != tc.visible || got != tc.role {
+			t.Errorf("%s: visible = %v, role = %q; want %v, %q", tc.name, visible, got, tc.visible, tc.role)
+		}
+	}
+
+	phones := []struct {
+		name       string
+		levels     []string // nil — все уровни
+		principals []string
+		want       []string
+	}{
+		{"viewer", []string{"public"}, []string{"user:z"}, []string{"public"}},
+		{"editor", []string{"public", "internal"}, []string{"user:x"}, []string{"internal", "public"}},
+		{"book admin", nil, nil, []string{"internal", "private", "public"}},
+		{"owner", []string{"public"}, []string{"user:o"}, []string{"internal", "private", "public"}},
+	}
+	for _, tc := range phones {
+		rows, err := pool.Query(ctx,
+			`select p.visibility from contact_phones p join contacts c on c.id = p.contact_id
+             where c.id = $1 and phone_visible(p.visibility, c, $2, $3) order by p.visibility`,
+			f.owned, tc.levels, tc.principals)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for rows.Next() {
+			var v string
+			if err := rows.Scan(&v); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, v)
+		}
+		rows.Close()
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("phones, %s: %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestContacts_VisibilityFiltering(t *testing.T) {
+	r := repository.New(testPool(t))
+	f := seedACL(t, r)
+	all := []int64{f.open, f.owned, f.user, f.group, f.tag}
+
+	cases := []struct {
+		name    string
+		ctx     context.Context
+		want    []int64
+		private bool // видит ли private-номер контакта owned
+	}{
+		{"no principal", inBook(context.Background()), []int64{f.open}, false},
+		{"book admin", viewer(repository.Viewer{All: true}), all, true},
+		{"owner", viewer(repository.Viewer{Principals: []string{"user:o"}, Phones: []string{"public"}}), []int64{f.open, f.owned}, true},
+		{"contact grant", viewer(repository.Viewer{Principals: []string{"user:x"}, Phones: []string{"public", "internal"}}), []int64{f.open, f.owned}, false},
+		{"user grant", viewer(repository.Viewer{Principals: []string{"user:u"}}), []int64{f.open, f.user}, false},
+		{"group grant", viewer(repository.Viewer{Principals: []string{"user:z", "group:board"}}), []int64{f.open, f.group}, false},
+		{"tag grant", viewer(repository.Viewer{Principals: []string{"user:t"}}), []int64{f.open, f.tag}, false},
+		{"stranger", viewer(repository.Viewer{Principals: []string{"user:z"}}), []int64{f.open}, false},
+	}
+	for _, tc := range cases {
+		for _, id := range all {
+			_, err := r.Contacts.Get(tc.ctx, id)
+			switch {
+			case slices.Contains(tc.want, id) && err != nil:
+				t.Errorf("%s: get %d: %v", tc.name, id, err)
+			case !slices.Contains(tc.want, id) && !errors.Is(err, repository.ErrNotFound):
+				t.Errorf("%s: get %d: want ErrNotFound, got %v", tc.name, id, err)
+			}
+		}
+
+		list, _, err := r.Contacts.List(tc.ctx, repository.ListFilter{Limit: 100})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ids(list); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: list = %v, want %v", tc.name, got, tc.want)
+		}
+
+		found, err := r.Contacts.Search(tc.ctx, "Testov", 50)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ids(found); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: search = %v, want %v", tc.name, got, tc.want)
+		}
+
+		// поиск по номеру: скрытый номер не находит контакт, даже видимый
+		found, err = r.Contacts.Search(tc.ctx, "77010000203", 50)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []int64{}
+		if tc.private {
+			want = []int64{f.owned}
+		}
+		if got := ids(found); !slices.Equal(got, want) {
+			t.Errorf("%s: phone search = %v, want %v", tc.name, got, want)
+		}
+	}
+}
+
+// viewer — запрос v в книге по умолчанию
+func viewer(v repository.Viewer) context.Context {
+	return inBook(repository.WithViewer(context.Background(), v))
+}
+
+func inBook(ctx context.Context) context.Context {
+	return repository.WithAddressBook(ctx, repository.DefaultAddressBookID)
+}
+
+func ids(cs []repository.Contact) []int64 {
+	out := make([]int64, 0, len(cs))
+	for _, c := range cs {
+		out = append(out, c.ID)
+	}
+	slices.Sort(out)
+	return out
+}
+
+// журнал помнит, кому был виден контакт: чужие изменения ограниченного контакта (и его удаление —
+// оно пишется до каскада, снимающего выдачи) не попадают ни в ленту, ни в синхронизацию
+func TestEvents_FilteredByContactAccess(t *testing.T) {
+	pool := testPool(t)
+	r := repository.New(pool)
+	f := seedACL(t, r)
+	admin := viewer(repository.Viewer{All: true})
+	if err := r.Contacts.Delete(admin, f.user); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Events.Sequence(admin); err != nil {
+		t.Fatal(err)
+	}
+
+	ops := func(v repository.Viewer) (changes, feed []string) {
+		t.Helper()
+		ctx := viewer(v)
+		evs, err := r.Events.ChangesSince(ctx, 0, 1000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range evs {
+			if e.ContactID == f.user {
+				changes = append(changes, e.Op)
+			}
+		}
+		if evs, err = r.Events.Since(ctx, 0, 1000); err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range evs {
+			if e.ContactID == f.user {
+				feed = append(feed, e.Op)
+			}
+		}
+		return changes, feed
+	}
+	// до выдачи контакт был открыт — создание видно всем
+	for _, tc := range []struct {
+		name string
+		v    repository.Viewer
+		want []string
+	}{
+		{"stranger", repository.Viewer{Principals: []string{"user:z"}}, []string{"created"}},
+		{"grantee", repository.Viewer{Principals: []string{"user:u"}}, []string{"created", "updated", "deleted"}},
+		{"book admin", repository.Viewer{All: true}, []string{"created", "updated", "deleted"}},
+	} {
+		changes, feed := ops(tc.v)
+		if !slices.Equal(changes, tc.want) || !slices.Equal(feed, tc.want) {
+			t.Errorf("%s: changes = %v, feed = %v; want %v", tc.name, changes, feed, tc.want)
+		}
+	}
+
+	// хаб читает журнал всех книг и получает события вместе с readers, чтобы отбирать для подписчиков сам;
+	// без книги и без явного обхода журнал пуст
+	if evs, err := r.Events.Since(context.Background(), 0, 1000); err != nil || len(evs) != 0 {
+		t.Fatalf("no book: %d events, err = %v", len(evs), err)
+	}
+	evs, err := r.Events.Since(repository.WithAllBooks(context.Background()), 0, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var readers []string
+	for _, e := range evs {
+		if e.ContactID == f.user && e.Op == "deleted" {
+			readers = e.Readers
+		}
+	}
+	if !slices.Equal(readers, []string{"user:u"}) {
+		t.Fatalf("hub: readers of the delete event = %v", readers)
+	}
+}
+
+// ширина CSV-экспорта не выдаёт скрытые номера и контакты
+func TestContacts_MaxPhonesVisibility(t *testing.T) {
+	pool := testPool(t)
+	r := repository.New(pool)
+	seedACL(t, r)
+	for _, tc := range []struct {
+		name string
+		v    repository.Viewer
+		want int
+	}{
+		{"viewer", repository.Viewer{Principals: []string{"user:z"}, Phones: []string{"public"}}, 1},
+		{"editor", repository.Viewer{Principals: []string{"user:z"}, Phones: []string{"public", "internal"}}, 2},
+		{"book admin", repository.Viewer{All: true}, 3},
+	} {
+		n, err := r.Contacts.MaxPhones(viewer(tc.v))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != tc.want {
+			t.Errorf("%s: max phones = %d, want %d", tc.name, n, tc.want)
+		}
+	}
+}
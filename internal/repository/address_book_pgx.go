@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DefaultAddressBookID — книга, в которую перенесены контакты, созданные до появления книг; в неё же
+// попадают события журнала, записанные раньше
+const DefaultAddressBookID int64 = 1
+
+type addressBookKey struct{}
+
+// WithAddressBook — запросы контактов с этим ctx видят и меняют только книгу id
+func WithAddressBook(ctx context.Context, id int64) context.Context {
+	return context.WithValue(ctx, addressBookKey{}, id)
+}
+
+// addressBookOf — книга запроса; ok=false у системных задач без книги
+func addressBookOf(ctx context.Context) (int64, bool) {
+	id, ok := ctx.Value(addressBookKey{}).(int64)
+	return id, ok
+}
+
+type allBooksKey struct{}
+
+// WithAllBooks — системная задача (хаб ленты, очистка журналов), которой нужны все книги сразу. Обход
+// книги только явный: ctx без книги и без этой отметки не видит ничего.
+func WithAllBooks(ctx context.Context) context.Context {
+	return context.WithValue(ctx, allBooksKey{}, true)
+}
+
+func allBooks(ctx context.Context) bool {
+	all, _ := ctx.Value(allBooksKey{}).(bool)
+	return all
+}
+
+// bookID — книга для запросов к контактам. Без книги — 0: такой книги нет, запрос не найдёт ни одного
+// контакта и не создаст новый, а не уйдёт молча в книгу по умолчанию. Все книги сразу — никогда.
+func bookID(ctx context.Context) int64 {
+	id, _ := addressBookOf(ctx)
+	return id
+}
+
+type addressBookRepo struct {
+	pool *pgxpool.Pool
+}
+
+const addressBookCols = `id, slug, name, default_region, created_at, updated_at`
+
+func scanAddressBook(row pgx.Row) (AddressBook, error) {
+	var b AddressBook
+	err := row.Scan(&b.ID, &b.Slug, &b.Name, &b.DefaultRegion, &b.CreatedAt, &b.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return AddressBook{}, ErrNotFound
+	}
+	return b, err
+}
+
+func (r *addressBookRepo) Create(ctx context.Context, in AddressBookInput) (AddressBook, error) {
+	b, err := scanAddressBook(r.pool.QueryRow(ctx,
+		`insert into address_books(slug, name, default_region) values ($1, $2, $3) returning `+addressBookCols,
+		in.Slug, in.Name, in.DefaultRegion))
+	if isUniqueViolation(err) {
+		return AddressBook{}, ErrConflict
+	}
+	return b, err
+}
+
+func (r *addressBookRepo) Get(ctx context.Context, id int64) (AddressBook, error) {
+	return scanAddressBook(r.pool.QueryRow(ctx, `select `+addressBookCols+` from address_books where id=$1`, id))
+}
+
+func (r *addressBookRepo) BySlug(ctx context.Context, slug string) (AddressBook, error) {
+	return scanAddressBook(r.pool.QueryRow(ctx, `select `+addressBookCols+` from address_books where slug=$1`, slug))
+}
+
+func (r *addressBookRepo) List(ctx context.Context) ([]AddressBook, error) {
+	rows, err := r.pool.Query(ctx, `select `+addressBookCols+` from address_books order by id`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (AddressBook, error) { return scanAddressBook(row) })
+}
+
+func (r *addressBookRepo) Update(ctx context.Context, id int64, p AddressBookPatch) (AddressBook, error) {
+	set := make([]string, 0, 2)
+	args := []any{id}
+	if p.Name != nil {
+		args = append(args, *p.Name)
+		set = append(set, fmt.Sprintf("name=$%d", len(args)))
+	}
+	if p.DefaultRegion != nil {
+		args = append(args, *p.DefaultRegion)
+		set = append(set, fmt.Sprintf("default_region=$%d", len(args)))
+	}
+	if len(set) == 0 {
+		return r.Get(ctx, id)
+	}
+	return scanAddressBook(r.pool.QueryRow(ctx,
+		`update address_books set `+strings.Join(set, ",")+` where id=$1 returning `+addressBookCols, args...))
+}
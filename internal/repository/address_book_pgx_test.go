@@ -0,0 +1,68 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// политика книги закрыта по умолчанию: соединение без книги не видит контактов, все книги — только
+// с явным app.all_books. Суперпользователь политику не проверяет, поэтому запросы идут от отдельной роли.
+func TestRowSecurity_FailsClosed(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	if _, err := pool.Exec(ctx,
+		`insert into address_books(id, slug, name) values (2, 'sales', 'Sales');
+         insert into contacts(first_name, last_name, address_book_id) values ('A', 'One', 1), ('B', 'Two', 2)`); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Release()
+	role := fmt.Sprintf("phonebook_rls_%d", time.Now().UnixNano())
+	if _, err := conn.Exec(ctx, "create role "+role+" nologin"); err != nil {
+		t.Skipf("cannot create role: %v", err)
+	}
+	t.Cleanup(func() {
+		_, _ = pool.Exec(context.Background(), "drop owned by "+role+"; drop role "+role)
+	})
+	var schema string
+	if err := conn.QueryRow(ctx, "select current_schema()").Scan(&schema); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Exec(ctx, "grant usage on schema "+schema+" to "+role); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Exec(ctx, "grant select on contacts to "+role+"; set role "+role); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _, _ = conn.Exec(context.Background(), "reset role") }()
+
+	for _, tc := range []struct {
+		book, all string
+		want      int
+	}{
+		{"", "", 0},
+		{"", "off", 0},
+		{"1", "off", 1},
+		{"2", "", 1},
+		{"", "on", 2},
+	} {
+		if _, err := conn.Exec(ctx,
+			`select set_config('app.address_book_id', $1, false), set_config('app.all_books', $2, false)`,
+			tc.book, tc.all); err != nil {
+			t.Fatal(err)
+		}
+		var n int
+		if err := conn.QueryRow(ctx, `select count(*) from contacts`).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		if n != tc.want {
+			t.Errorf("book %q, all_books %q: %d contacts, want %d", tc.book, tc.all, n, tc.want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type apiKeyRepo struct {
+	pool *pgxpool.Pool
+}
+
+const apiKeyCols = `id, name, prefix, key_hash, scopes, created_at, expires_at, last_used_at, revoked_at,
+  address_book_id, coalesce((select b.slug from address_books b where b.id = api_keys.address_book_id), '')`
+
+func scanAPIKey(row pgx.Row) (APIKey, error) {
+	var k APIKey
+	err := row.Scan(&k.ID, &k.Name, &k.Prefix, &k.KeyHash, &k.Scopes, &k.CreatedAt, &k.ExpiresAt, &k.LastUsedAt, &k.RevokedAt,
+		&k.AddressBookID, &k.AddressBook)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return APIKey{}, ErrNotFound
+	}
+	return k, err
+}
+
+func (r *apiKeyRepo) Create(ctx context.Context, in APIKeyInput) (APIKey, error) {
+	k, err := scanAPIKey(r.pool.QueryRow(ctx,
+		`insert into api_keys(name, prefix, key_hash, scopes, expires_at, address_book_id)
+         values ($1, $2, $3, $4, $5, $6) returning `+apiKeyCols,
+		in.Name, in.Prefix, in.KeyHash, in.Scopes, in.ExpiresAt, in.AddressBookID))
+	if isUniqueViolation(err) {
+		return APIKey{}, ErrConflict
+	}
+	return k, err
+}
+
+func (r *apiKeyRepo) Get(ctx context.Context, id int64) (APIKey, error) {
+	return scanAPIKey(r.pool.QueryRow(ctx, `select `+apiKeyCols+` from api_keys where id=$1`, id))
+}
+
+func (r *apiKeyRepo) ByHash(ctx context.Context, hash []byte) (APIKey, error) {
+	return scanAPIKey(r.pool.QueryRow(ctx, `select `+apiKeyCols+` from api_keys where key_hash=$1`, hash))
+}
+
+func (r *apiKeyRepo) List(ctx context.Context) ([]APIKey, error) {
+	rows, err := r.pool.Query(ctx, `select `+apiKeyCols+` from api_keys order by id`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (APIKey, error) { return scanAPIKey(row) })
+}
+
+// Revoke — отзыв необратим; повторный отзыв не меняет revoked_at
+func (r *apiKeyRepo) Revoke(ctx context.Context, id int64) (APIKey, error) {
+	return scanAPIKey(r.pool.QueryRow(ctx,
+		`update api_keys set revoked_at = coalesce(revoked_at, now()) where id=$1 returning `+apiKeyCols, id))
+}
+
+// Touch — отмечает использование не чаще раза в минуту, чтобы не писать в БД на каждый запрос
+func (r *apiKeyRepo) Touch(ctx context.Context, id int64) error {
+	_, err := r.pool.Exec(ctx,
+		`update api_keys set last_used_at = now()
+         where id=$1 and (last_used_at is null or last_used_at < now() - interval '1 minute')`, id)
+	return err
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type auditRepo struct {
+	pool *pgxpool.Pool
+}
+
+// Insert — пачка записей одним COPY
+func (r *auditRepo) Insert(ctx context.Context, entries []AuditEntry) error {
+	rows := make([][]any, 0, len(entries))
+	for _, e := range entries {
+		ids := e.ContactIDs
+		if ids == nil {
+			ids = []int64{}
+		}
+		rows = append(rows, []any{e.At, e.AddressBookID, e.Actor, e.Action, ids, e.RequestID, e.IP})
+	}
+	_, err := r.pool.CopyFrom(ctx, pgx.Identifier{"audit_log"},
+		[]string{"at", "address_book_id", "actor", "action", "contact_ids", "request_id", "ip"},
+		pgx.CopyFromRows(rows))
+	return err
+}
+
+// List — журнал книги из ctx по фильтру; BeforeID — keyset-пагинация
+func (r *auditRepo) List(ctx context.Context, f AuditFilter) ([]AuditEntry, error) {
+	var since, until any
+	if !f.Since.IsZero() {
+		since = f.Since
+	}
+	if !f.Until.IsZero() {
+		until = f.Until
+	}
+	rows, err := r.pool.Query(ctx,
+		`select id, at, address_book_id, actor, action, contact_ids, request_id, ip
+         from audit_log
+         where address_book_id = $1
+           and ($2 = '' or actor = $2) and ($3 = '' or action = $3)
+           and ($4::bigint = 0 or contact_ids @> array[$4::bigint])
+           and ($5::timestamptz is null or at >= $5) and ($6::timestamptz is null or at < $6)
+           and ($7::bigint = 0 or id < $7)
+         order by id desc
+         limit $8`,
+		bookID(ctx), f.Actor, f.Action, f.ContactID, since, until, f.BeforeID, f.Limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (AuditEntry, error) {
+		var e AuditEntry
+		err := row.Scan(&e.ID, &e.At, &e.AddressBookID, &e.Actor, &e.Action, &e.ContactIDs, &e.RequestID, &e.IP)
+		return e, err
+	})
+}
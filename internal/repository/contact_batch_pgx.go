@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Batch — сначала быстрый путь: id для новых контактов берутся из последовательности одним запросом,
+// контакты и реквизиты вставляются через COPY, update и delete идут по очереди в той же транзакции.
+// Если быстрый путь упал на ошибке БД, неизвестно, какая операция виновата, — пакет повторяется
+// медленно: каждая операция в своём savepoint.
+func (r *contactRepo) Batch(ctx context.Context, ops []BatchOp, atomic bool) ([]BatchResult, error) {
+	res, ok, err := r.batchFast(ctx, ops, atomic)
+	if err != nil || ok {
+		return res, err
+	}
+	return r.batchSlow(ctx, ops, atomic)
+}
+
+// batchFast — ok=false означает «ошибка БД, повторить медленно»; транзакция при этом откачена.
+func (r *contactRepo) batchFast(ctx context.Context, ops []BatchOp, atomic bool) ([]BatchResult, bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	res := make([]BatchResult, len(ops))
+	if err := copyCreates(ctx, tx, ops, res); err != nil {
+		return nil, false, unlessPgError(err)
+	}
+	failed := false
+	for i, op := range ops {
+		switch op.Kind {
+		case BatchUpdate:
+			err = updateTx(ctx, tx, op.ID, op.Patch)
+		case BatchDelete:
+			err = deleteContact(ctx, tx, op.ID)
+		default:
+			continue
+		}
+		res[i].ID = op.ID
+		switch {
+		case errors.Is(err, ErrNotFound):
+			// «не найдено» не ломает транзакцию — остальные операции продолжаем
+			res[i].Err = err
+			failed = true
+		case err != nil:
+			return nil, false, unlessPgError(err)
+		}
+	}
+	if failed && atomic {
+		return res, true, nil
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, false, unlessPgError(err)
+	}
+	return res, true, nil
+}
+
+func (r *contactRepo) batchSlow(ctx context.Context, ops []BatchOp, atomic bool) ([]BatchResult, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	res := make([]BatchResult, len(ops))
+	failed := false
+	for i, op := range ops {
+		sp, err := tx.Begin(ctx) // вложенная транзакция pgx — это savepoint
+		if err != nil {
+			return nil, err
+		}
+		id, err := applyOp(ctx, sp, op)
+		if err != nil {
+			_ = sp.Rollback(ctx)
+			if unlessPgError(err) != nil && !errors.Is(err, ErrNotFound) {
+				return nil, err
+			}
+			res[i].Err = batchErr(err)
+			failed = true
+			continue
+		}
+		if err := sp.Commit(ctx); err != nil {
+			return nil, err
+		}
+		res[i].ID = id
+	}
+	if failed && atomic {
+		return res, nil
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func applyOp(ctx context.Context, tx pgx.Tx, op BatchOp) (int64, error) {
+	switch op.Kind {
+	case BatchCreate:
+		return createTx(ctx, tx, op.Create)
+	case BatchUpdate:
+		return op.ID, updateTx(ctx, tx, op.ID, op.Patch)
+	case BatchDelete:
+		return op.ID, deleteContact(ctx, tx, op.ID)
+	}
+	return 0, errors.New("unknown batch op: " + op.Kind)
+}
+
+// copyCreates — все create пакета: id заранее, затем COPY в contacts и таблицы реквизитов.
+func copyCreates(ctx context.Context, tx pgx.Tx, ops []BatchOp, res []BatchResult) error {
+	var idx []int
+	for i, op := range ops {
+		if op.Kind == BatchCreate {
+			idx = append(idx, i)
+		}
+	}
+	if len(idx) == 0 {
+		return nil
+	}
+	rows, err := tx.Query(ctx,
+		`select nextval(pg_get_serial_sequence('contacts', 'id')) from generate_series(1, $1)`, len(idx))
+	if err != nil {
+		return err
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return err
+	}
+
+	var contacts, phones, emails, addrs, sites [][]any
+	book := bookID(ctx)
+	for n, i := range idx {
+		in, id := ops[i].Create, ids[n]
+		res[i].ID = id
+		if in.Custom == nil {
+			in.Custom = map[string]any{}
+		}
+		if in.Tags == nil {
+			in.Tags = []string{}
+		}
+		contacts = append(contacts, []any{id, book, in.FirstName, in.LastName, in.Company, nullID(in.OrganizationID), in.JobTitle, in.Department, in.Custom,
+			in.Owner, in.Tags})
+		pp := primaryFlags(in.Phones, func(p PhoneInput) bool { return p.IsPrimary })
+		for k, p := range in.Phones {
+			phones = append(phones, []any{id, p.Label, p.PhoneRaw, p.PhoneE164, p.PhoneDigits, pp[k], p.visibility()})
+		}
+		ep := primaryFlags(in.Emails, func(e EmailInput) bool { return e.IsPrimary })
+		for k, e := range in.Emails {
+			emails = append(emails, []any{id, e.Label, e.Email, ep[k]})
+		}
+		ap := primaryFlags(in.Addresses, func(a AddressInput) bool { return a.IsPrimary })
+		for k, a := range in.Addresses {
+			addrs = append(addrs, []any{id, a.Label, a.Street, a.City, a.Region, a.PostalCode, a.Country, ap[k]})
+		}
+		wp := primaryFlags(in.Websites, func(w WebsiteInput) bool { return w.IsPrimary })
+		for k, w := range in.Websites {
+			sites = append(sites, []any{id, w.Label, w.URL, wp[k]})
+		}
+	}
+
+	for _, c := range []struct {
+		table string
+		cols  []string
+		rows  [][]any
+	}{
+		{"contacts", []string{"id", "address_book_id", "first_name", "last_name", "company", "organization_id", "job_title", "department", "custom", "owner", "tags"}, contacts},
+		{"contact_phones", []string{"contact_id", "label", "phone_raw", "phone_e164", "phone_digits", "is_primary", "visibility"}, phones},
+		{"contact_emails", []string{"contact_id", "label", "email", "is_primary"}, emails},
+		{"contact_addresses", []string{"contact_id", "label", "street", "city", "region", "postal_code", "country", "is_primary"}, addrs},
+		{"contact_websites", []string{"contact_id", "label", "url", "is_primary"}, sites},
+	} {
+		if len(c.rows) == 0 {
+			continue
+		}
+		if _, err := tx.CopyFrom(ctx, pgx.Identifier{c.table}, c.cols, pgx.CopyFromRows(c.rows)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// primaryFlags — как в queueEmails: единственный основной элемент, без явного — первый.
+func primaryFlags[T any](items []T, isPrimary func(T) bool) []bool {
+	out := make([]bool, len(items))
+	for i, it := range items {
+		if isPrimary(it) {
+			out[i] = true
+			return out
+		}
+	}
+	if len(out) > 0 {
+		out[0] = true
+	}
+	return out
+}
+
+// unlessPgError — ошибка сервера БД (нарушение ограничения и т.п.) даёт nil: её разберёт медленный путь.
+// Сетевые ошибки и отмена контекста возвращаются как есть.
+func unlessPgError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return nil
+	}
+	return err
+}
+
+func batchErr(err error) error {
+	switch {
+	case isUniqueViolation(err):
+		return ErrConflict
+	case isForeignKeyViolation(err):
+		return ErrInvalidReference
+	}
+	return err
+}
@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// loadDetails подтягивает телефоны, email, адреса и сайты контакта.
+func (r *contactRepo) loadDetails(ctx context.Context, c *Contact) error {
+	var err error
+	if c.Phones, err = r.getPhones(ctx, c.ID); err != nil {
+		return err
+	}
+	if c.Emails, err = r.getEmails(ctx, c.ID); err != nil {
+		return err
+	}
+	if c.Addresses, err = r.getAddresses(ctx, c.ID); err != nil {
+		return err
+	}
+	if c.Websites, err = r.getWebsites(ctx, c.ID); err != nil {
+		return err
+	}
+	return nil
+}
+
+// queueEmails ставит в батч вставку email; если primary не задан — им становится первый.
+func queueEmails(b *pgx.Batch, contactID int64, emails []EmailInput) {
+	hasPrimary := false
+	for i := range emails {
+		if emails[i].IsPrimary {
+			hasPrimary = true
+			break
+		}
+	}
+	for i, e := range emails {
+		b.Queue(
+			`insert into contact_emails(contact_id, label, email, is_primary)
+             values ($1, $2, $3, $4)`,
+			contactID, e.Label, e.Email, e.IsPrimary || (!hasPrimary && i == 0),
+		)
+	}
+}
+
+func queueAddresses(b *pgx.Batch, contactID int64, addrs []AddressInput) {
+	hasPrimary := false
+	for i := range addrs {
+		if addrs[i].IsPrimary {
+			hasPrimary = true
+			break
+		}
+	}
+	for i, a := range addrs {
+		b.Queue(
+			`insert into contact_addresses(contact_id, label, street, city, region, postal_code, country, is_primary)
+             values ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			contactID, a.Label, a.Street, a.City, a.Region, a.PostalCode, a.Country, a.IsPrimary || (!hasPrimary && i == 0),
+		)
+	}
+}
+
+func queueWebsites(b *pgx.Batch, contactID int64, sites []WebsiteInput) {
+	hasPrimary := false
+	for i := range sites {
+		if sites[i].IsPrimary {
+			hasPrimary = true
+			break
+		}
+	}
+	for i, w := range sites {
+		b.Queue(
+			`insert into contact_websites(contact_id, label, url, is_primary)
+             values ($1, $2, $3, $4)`,
+			contactID, w.Label, w.URL, w.IsPrimary || (!hasPrimary && i == 0),
+		)
+	}
+}
+
+func (r *contactRepo) getEmails(ctx context.Context, contactID int64) ([]Email, error) {
+	rows, err := r.pool.Query(ctx,
+		`select coalesce(label,''), email, is_primary
+         from contact_emails
+         where contact_id = $1
+         order by is_primary desc, id asc`,
+		contactID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]Email, 0, 2)
+	for rows.Next() {
+		var e Email
+		if err := rows.Scan(&e.Label, &e.Email, &e.IsPrimary); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+func (r *contactRepo) getAddresses(ctx context.Context, contactID int64) ([]Address, error) {
+	rows, err := r.pool.Query(ctx,
+		`select coalesce(label,''), street, city, region, postal_code, country, is_primary
+         from contact_addresses
+         where contact_id = $1
+         order by is_primary desc, id asc`,
+		contactID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]Address, 0, 1)
+	for rows.Next() {
+		var a Address
+		if err := rows.Scan(&a.Label, &a.Street, &a.City, &a.Region, &a.PostalCode, &a.Country, &a.IsPrimary); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+func (r *contactRepo) getWebsites(ctx context.Context, contactID int64) ([]Website, error) {
+	rows, err := r.pool.Query(ctx,
+		`select coalesce(label,''), url, is_primary
+         from contact_websites
+         where contact_id = $1
+         order by is_primary desc, id asc`,
+		contactID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]Website, 0, 1)
+	for rows.Next() {
+		var w Website
+		if err := rows.Scan(&w.Label, &w.URL, &w.IsPrimary); err != nil {
+			return nil, err
+		}
+		out = append(out, w)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// UpsertExternal — создание или полная замена контакта по ключу системы-источника одним
+// insert ... on conflict: параллельные синхронизации одного ключа не создают дубликатов.
+// created=true, если контакт новый.
+func (r *contactRepo) UpsertExternal(ctx context.Context, source, externalID string, in ContactInput) (Contact, bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return Contact{}, false, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if in.Custom == nil {
+		in.Custom = map[string]any{}
+	}
+	if in.Tags == nil {
+		in.Tags = []string{}
+	}
+	var (
+		id      int64
+		created bool
+	)
+	if err := tx.QueryRow(ctx,
+		`insert into contacts as c (external_source, external_id, first_name, last_name, company, organization_id,
+                                   job_title, department, custom, address_book_id, owner, tags)
+         values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+         on conflict (address_book_id, external_source, external_id) do update set
+           first_name      = excluded.first_name,
+           last_name       = excluded.last_name,
+           company         = excluded.company,
+           organization_id = excluded.organization_id,
+           job_title       = excluded.job_title,
+           department      = excluded.department,
+           custom          = excluded.custom,
+           tags            = excluded.tags
+         where contact_visible(c, $13)
+         returning id, xmax = 0`,
+		source, externalID, in.FirstName, in.LastName, in.Company, nullID(in.OrganizationID),
+		in.JobTitle, in.Department, in.Custom, bookID(ctx), in.Owner, in.Tags, viewerArg(ctx),
+	).Scan(&id, &created); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Contact{}, false, ErrNotFound // занят контактом, который вызывающему не виден
+		}
+		return Contact{}, false, err
+	}
+
+	if !created {
+		var b pgx.Batch
+		for _, t := range []string{"contact_phones", "contact_emails", "contact_addresses", "contact_websites"} {
+			b.Queue(`delete from `+t+` where contact_id=$1`, id)
+		}
+		if err := tx.SendBatch(ctx, &b).Close(); err != nil {
+			return Contact{}, false, err
+		}
+	}
+	if err := insertDetails(ctx, tx, id, in); err != nil {
+		return Contact{}, false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return Contact{}, false, err
+	}
+
+	c, err := r.Get(ctx, id)
+	return c, created, err
+}
+
+func (r *contactRepo) GetByExternal(ctx context.Context, source, externalID string) (Contact, error) {
+	var c Contact
+	err := r.pool.QueryRow(ctx,
+		`select `+contactCols+`
+         `+contactFrom+`
+         where c.external_source=$1 and c.external_id=$2 and c.address_book_id=$3 and contact_visible(c, $4)`,
+		source, externalID, bookID(ctx), viewerArg(ctx),
+	).Scan(contactDest(&c)...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Contact{}, ErrNotFound
+	}
+	if err != nil {
+		return Contact{}, err
+	}
+	if err := r.loadDetails(ctx, &c); err != nil {
+		return Contact{}, err
+	}
+	return c, nil
+}
@@ -2,11 +2,12 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
-	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -14,6 +15,30 @@ type contactRepo struct {
 	pool *pgxpool.Pool
 }
 
+// contactCols — колонки контакта для select; company берётся из организации, если контакт к ней привязан.
+const contactCols = `c.id, c.first_name, c.last_name, coalesce(o.name, c.company, ''),
+  coalesce(c.organization_id, 0), c.job_title, c.department, c.custom, c.owner, c.tags,
+  exists (select 1 from contact_photos cp where cp.contact_id = c.id),
+  coalesce(c.external_source, ''), coalesce(c.external_id, ''), c.created_at, c.updated_at`
+
+const contactFrom = `from contacts c
+left join organizations o on o.id = c.organization_id`
+
+// contactDest — приёмники для Scan в порядке contactCols
+func contactDest(c *Contact) []any {
+	return []any{&c.ID, &c.FirstName, &c.LastName, &c.Company,
+		&c.OrganizationID, &c.JobTitle, &c.Department, &c.Custom, &c.Owner, &c.Tags, &c.HasPhoto,
+		&c.ExternalSource, &c.ExternalID, &c.CreatedAt, &c.UpdatedAt}
+}
+
+// nullID — 0 в nullable-внешнем ключе означает отсутствие ссылки
+func nullID(id int64) any {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
 func (r *contactRepo) Create(ctx context.Context, in ContactInput) (Contact, error) {
 	tx, err := r.pool.Begin(ctx)
 	if err != nil {
@@ -21,20 +46,45 @@ func (r *contactRepo) Create(ctx context.Context, in ContactInput) (Contact, err
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
-	var (
-		id        int64
-		createdAt time.Time
-		updatedAt time.Time
-	)
-	if err := tx.QueryRow(ctx,
-		`insert into contacts(first_name, last_name, company)
-         values ($1, $2, $3)
-         returning id, created_at, updated_at`,
-		in.FirstName, in.LastName, in.Company,
-	).Scan(&id, &createdAt, &updatedAt); err != nil {
+	id, err := createTx(ctx, tx, in)
+	if err != nil {
+		return Contact{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
 		return Contact{}, err
 	}
 
+	return r.Get(ctx, id)
+}
+
+// createTx — вставка контакта с реквизитами в рамках транзакции tx.
+func createTx(ctx context.Context, tx pgx.Tx, in ContactInput) (int64, error) {
+	var id int64
+	if in.Custom == nil {
+		in.Custom = map[string]any{}
+	}
+	if in.Tags == nil {
+		in.Tags = []string{}
+	}
+	if err := tx.QueryRow(ctx,
+		`insert into contacts(address_book_id, first_name, last_name, company, organization_id, job_title, department, custom,
+                              owner, tags)
+         values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+         returning id`,
+		bookID(ctx), in.FirstName, in.LastName, in.Company, nullID(in.OrganizationID), in.JobTitle, in.Department, in.Custom,
+		in.Owner, in.Tags,
+	).Scan(&id); err != nil {
+		return 0, err
+	}
+
+	if err := insertDetails(ctx, tx, id, in); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// insertDetails — телефоны, email, адреса и сайты нового (или очищенного) контакта.
+func insertDetails(ctx context.Context, tx pgx.Tx, id int64, in ContactInput) error {
 	// гарантируем единственный primary
 	hasPrimary := false
 	for i := range in.Phones {
@@ -52,48 +102,49 @@ func (r *contactRepo) Create(ctx context.Context, in ContactInput) (Contact, err
 		var b pgx.Batch
 		for _, p := range in.Phones {
 			b.Queue(
-				`insert into contact_phones(contact_id, label, phone_raw, phone_e164, phone_digits, is_primary)
-                 values ($1, $2, $3, $4, $5, $6)`,
-				id, p.Label, p.PhoneRaw, p.PhoneE164, p.PhoneDigits, p.IsPrimary,
+				`insert into contact_phones(contact_id, label, phone_raw, phone_e164, phone_digits, is_primary, visibility)
+                 values ($1, $2, $3, $4, $5, $6, $7)`,
+				id, p.Label, p.PhoneRaw, p.PhoneE164, p.PhoneDigits, p.IsPrimary, p.visibility(),
 			)
 		}
 		if br := tx.SendBatch(ctx, &b); br != nil {
 			if err := br.Close(); err != nil {
-				return Contact{}, err
+				return err
 			}
 		}
 	}
 
-	if err := tx.Commit(ctx); err != nil {
-		return Contact{}, err
+	// email, адреса и сайты — одним батчем
+	var db pgx.Batch
+	queueEmails(&db, id, in.Emails)
+	queueAddresses(&db, id, in.Addresses)
+	queueWebsites(&db, id, in.Websites)
+	if db.Len() > 0 {
+		if err := tx.SendBatch(ctx, &db).Close(); err != nil {
+			return err
+		}
 	}
-	phones, _ := r.getPhones(ctx, id)
-
-	return Contact{
-		ID:        id,
-		FirstName: in.FirstName,
-		LastName:  in.LastName,
-		Company:   in.Company,
-		Phones:    phones,
-		CreatedAt: createdAt,
-		UpdatedAt: updatedAt,
-	}, nil
+
+	return nil
 }
 
 func (r *contactRepo) Get(ctx context.Context, id int64) (Contact, error) {
 	var c Contact
 	err := r.pool.QueryRow(ctx,
-		`select id, first_name, last_name, coalesce(company,''), created_at, updated_at
-         from contacts where id=$1`,
-		id,
-	).Scan(&c.ID, &c.FirstName, &c.LastName, &c.Company, &c.CreatedAt, &c.UpdatedAt)
+		`select `+contactCols+`
+         `+contactFrom+`
+         where c.id=$1 and c.address_book_id=$2 and contact_visible(c, $3)`,
+		id, bookID(ctx), viewerArg(ctx),
+	).Scan(contactDest(&c)...)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return Contact{}, ErrNotFound
 		}
 		return Contact{}, err
 	}
-	c.Phones, _ = r.getPhones(ctx, id)
+	if err := r.loadDetails(ctx, &c); err != nil {
+		return Contact{}, err
+	}
 	return c, nil
 }
 
@@ -104,10 +155,31 @@ func (r *contactRepo) Update(ctx context.Context, id int64, p ContactPatch) (Con
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
+	if err := updateTx(ctx, tx, id, p); err != nil {
+		return Contact{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return Contact{}, err
+	}
+	return r.Get(ctx, id)
+}
+
+// updateTx — частичное обновление в рамках tx; строка контакта блокируется до конца транзакции.
+func updateTx(ctx context.Context, tx pgx.Tx, id int64, p ContactPatch) error {
+	var locked int64
+	if err := tx.QueryRow(ctx, `select id from contacts c where c.id=$1 and c.address_book_id=$2 and contact_visible(c, $3) for update`,
+		id, bookID(ctx), viewerArg(ctx)).Scan(&locked); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+
 	// частичное обновление скалярных полей
-	if p.FirstName != nil || p.LastName != nil || p.Company != nil {
-		set := make([]string, 0, 3)
-		args := make([]any, 0, 4)
+	if p.FirstName != nil || p.LastName != nil || p.Company != nil || p.Custom != nil ||
+		p.OrganizationID != nil || p.JobTitle != nil || p.Department != nil || p.Tags != nil {
+		set := make([]string, 0, 8)
+		args := make([]any, 0, 8)
 		idx := 1
 
 		if p.FirstName != nil {
@@ -125,18 +197,47 @@ func (r *contactRepo) Update(ctx context.Context, id int64, p ContactPatch) (Con
 			args = append(args, strings.TrimSpace(*p.Company))
 			idx++
 		}
+		if p.OrganizationID != nil {
+			set = append(set, fmt.Sprintf("organization_id=$%d", idx))
+			args = append(args, nullID(*p.OrganizationID))
+			idx++
+		}
+		if p.JobTitle != nil {
+			set = append(set, fmt.Sprintf("job_title=$%d", idx))
+			args = append(args, strings.TrimSpace(*p.JobTitle))
+			idx++
+		}
+		if p.Department != nil {
+			set = append(set, fmt.Sprintf("department=$%d", idx))
+			args = append(args, strings.TrimSpace(*p.Department))
+			idx++
+		}
+		if p.Custom != nil {
+			// мерж поверх текущих значений; null-значения удаляют ключ
+			set = append(set, fmt.Sprintf("custom=jsonb_strip_nulls(custom || $%d)", idx))
+			args = append(args, p.Custom)
+			idx++
+		}
+		if p.Tags != nil {
+			set = append(set, fmt.Sprintf("tags=$%d", idx))
+			args = append(args, *p.Tags)
+			idx++
+		}
 
 		args = append(args, id)
 		query := "update contacts set " + strings.Join(set, ",") + " where id=$" + fmt.Sprint(idx)
 		if _, err := tx.Exec(ctx, query, args...); err != nil {
-			return Contact{}, err
+			return err
 		}
+	} else if _, err := tx.Exec(ctx, `update contacts set updated_at=now() where id=$1`, id); err != nil {
+		// правка только реквизитов тоже меняет контакт: updated_at и событие в ленте
+		return err
 	}
 
 	// полная замена набора телефонов
 	if p.Phones != nil {
 		if _, err := tx.Exec(ctx, `delete from contact_phones where contact_id=$1`, id); err != nil {
-			return Contact{}, err
+			return err
 		}
 		phones := *p.Phones
 
@@ -155,27 +256,54 @@ func (r *contactRepo) Update(ctx context.Context, id int64, p ContactPatch) (Con
 			var b pgx.Batch
 			for _, ph := range phones {
 				b.Queue(
-					`insert into contact_phones(contact_id, label, phone_raw, phone_e164, phone_digits, is_primary)
-                     values ($1, $2, $3, $4, $5, $6)`,
-					id, ph.Label, ph.PhoneRaw, ph.PhoneE164, ph.PhoneDigits, ph.IsPrimary,
+					`insert into contact_phones(contact_id, label, phone_raw, phone_e164, phone_digits, is_primary, visibility)
+                     values ($1, $2, $3, $4, $5, $6, $7)`,
+					id, ph.Label, ph.PhoneRaw, ph.PhoneE164, ph.PhoneDigits, ph.IsPrimary, ph.visibility(),
 				)
 			}
 			if br := tx.SendBatch(ctx, &b); br != nil {
 				if err := br.Close(); err != nil {
-					return Contact{}, err
+					return err
 				}
 			}
 		}
 	}
 
-	if err := tx.Commit(ctx); err != nil {
-		return Contact{}, err
+	// полная замена email, адресов и сайтов (nil — не трогаем)
+	var db pgx.Batch
+	if p.Emails != nil {
+		db.Queue(`delete from contact_emails where contact_id=$1`, id)
+		queueEmails(&db, id, *p.Emails)
 	}
-	return r.Get(ctx, id)
+	if p.Addresses != nil {
+		db.Queue(`delete from contact_addresses where contact_id=$1`, id)
+		queueAddresses(&db, id, *p.Addresses)
+	}
+	if p.Websites != nil {
+		db.Queue(`delete from contact_websites where contact_id=$1`, id)
+		queueWebsites(&db, id, *p.Websites)
+	}
+	if db.Len() > 0 {
+		if err := tx.SendBatch(ctx, &db).Close(); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func (r *contactRepo) Delete(ctx context.Context, id int64) error {
-	ct, err := r.pool.Exec(ctx, `delete from contacts where id=$1`, id)
+	return deleteContact(ctx, r.pool, id)
+}
+
+// execer — общее у пула и транзакции для одиночных команд
+type execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+func deleteContact(ctx context.Context, db execer, id int64) error {
+	ct, err := db.Exec(ctx, `delete from contacts c where c.id=$1 and c.address_book_id=$2 and contact_visible(c, $3)`,
+		id, bookID(ctx), viewerArg(ctx))
 	if err != nil {
 		return err
 	}
@@ -186,80 +314,14 @@ func (r *contactRepo) Delete(ctx context.Context, id int64) error {
 }
 
 func (r *contactRepo) List(ctx context.Context, f ListFilter) ([]Contact, int64, error) {
-	var sb strings.Builder
-	args := make([]any, 0, 8)
-	idx := 1
-
-	sb.WriteString(`
-select
-  c.id,
-  c.first_name,
-  c.last_name,
-  coalesce(c.company,''),
-  c.created_at,
-  c.updated_at
-from contacts c
-`)
-
-	where := make([]string, 0, 4)
-	if f.FirstName != "" {
-		where = append(where, fmt.Sprintf("c.first_name ilike $%d", idx))
-		args = append(args, "%"+f.FirstName+"%")
-		idx++
-	}
-	if f.LastName != "" {
-		where = append(where, fmt.Sprintf("c.last_name ilike $%d", idx))
-		args = append(args, "%"+f.LastName+"%")
-		idx++
-	}
-	if f.Company != "" {
-		where = append(where, fmt.Sprintf("c.company ilike $%d", idx))
-		args = append(args, "%"+f.Company+"%")
-		idx++
-	}
-	if f.Phone != "" {
-		sb.WriteString("join contact_phones p on p.contact_id = c.id\n")
-		where = append(where, fmt.Sprintf("p.phone_digits ilike $%d", idx))
-		args = append(args, "%"+digitsOnly(f.Phone)+"%")
-		idx++
-	}
-
-	if len(where) > 0 {
-		sb.WriteString("where " + strings.Join(where, " and ") + "\n")
-	}
-
-	// keyset
-	if f.AfterID > 0 {
-		cond := fmt.Sprintf("c.id > $%d", idx)
-		if len(where) > 0 {
-			sb.WriteString("and " + cond + "\n")
-		} else {
-			sb.WriteString("where " + cond + "\n")
-		}
-		args = append(args, f.AfterID)
-		idx++
-	}
-
-	order := strings.ToLower(f.Order)
-	if order != "asc" && order != "desc" {
-		order = "desc"
-	}
-	switch f.SortBy {
-	case "name":
-		sb.WriteString("order by c.last_name " + order + ", c.first_name " + order + ", c.id asc\n")
-	case "created_at", "updated_at":
-		sb.WriteString("order by c." + f.SortBy + " " + order + ", c.id asc\n")
-	default:
-		sb.WriteString("order by c.updated_at " + order + ", c.id asc\n")
-	}
-
+	q, args := listQuery(ctx, f, contactCols)
 	limit := f.Limit
 	if limit <= 0 || limit > 100 {
 		limit = 20
 	}
-	sb.WriteString("limit " + fmt.Sprint(limit+1))
+	q += "limit " + fmt.Sprint(limit+1)
 
-	rows, err := r.pool.Query(ctx, sb.String(), args...)
+	rows, err := r.pool.Query(ctx, q, args...)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -269,7 +331,7 @@ from contacts c
 	var lastID int64
 	for rows.Next() {
 		var c Contact
-		if err := rows.Scan(&c.ID, &c.FirstName, &c.LastName, &c.Company, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		if err := rows.Scan(contactDest(&c)...); err != nil {
 			return nil, 0, err
 		}
 		list = append(list, c)
@@ -281,10 +343,13 @@ from contacts c
 		list = list[:limit]
 	}
 
-	// подтягиваем телефоны (MVP; можно оптимизировать батчем)
-	for i := range list {
-		ph, _ := r.getPhones(ctx, list[i].ID)
-		list[i].Phones = ph
+	// подтягиваем телефоны и прочие реквизиты (MVP; можно оптимизировать батчем)
+	if !f.WithoutDetails {
+		for i := range list {
+			if err := r.loadDetails(ctx, &list[i]); err != nil {
+				return nil, 0, err
+			}
+		}
 	}
 
 	var next int64
@@ -309,23 +374,20 @@ func (r *contactRepo) Search(ctx context.Context, q string, limit int) ([]Contac
 		digits := digitsOnly(q)
 		sql := `
 select
-  c.id,
-  c.first_name,
-  c.last_name,
-  coalesce(c.company,''),
-  c.created_at,
-  c.updated_at,
+  ` + contactCols + `,
   p.label,
   p.phone_raw,
   p.phone_e164,
   p.phone_digits,
-  p.is_primary
-from contacts c
+  p.is_primary,
+  p.visibility
+` + contactFrom + `
 join contact_phones p on p.contact_id = c.id
-where p.phone_digits ilike '%' || $1 || '%'
+where c.address_book_id = $3 and contact_visible(c, $4) and p.phone_digits ilike '%' || $1 || '%'
+  and phone_visible(p.visibility, c, $5, $4)
 order by c.updated_at desc, c.id asc
 limit $2`
-		rows, err := r.pool.Query(ctx, sql, digits, limit)
+		rows, err := r.pool.Query(ctx, sql, digits, limit, bookID(ctx), viewerArg(ctx), phoneLevelsArg(ctx))
 		if err != nil {
 			return nil, err
 		}
@@ -337,19 +399,16 @@ limit $2`
 
 		for rows.Next() {
 			var (
-				id int64
 				c  Contact
 				ph Phone
 			)
-			if err := rows.Scan(
-				&id, &c.FirstName, &c.LastName, &c.Company, &c.CreatedAt, &c.UpdatedAt,
-				&ph.Label, &ph.PhoneRaw, &ph.PhoneE164, &ph.PhoneDigits, &ph.IsPrimary,
-			); err != nil {
+			dest := append(contactDest(&c), &ph.Label, &ph.PhoneRaw, &ph.PhoneE164, &ph.PhoneDigits, &ph.IsPrimary, &ph.Visibility)
+			if err := rows.Scan(dest...); err != nil {
 				return nil, err
 			}
+			id := c.ID
 			node, ok := byID[id]
 			if !ok {
-				c.ID = id
 				c.Phones = []Phone{ph}
 				byID[id] = &c
 				order = append(order, id)
@@ -357,23 +416,39 @@ limit $2`
 				node.Phones = append(node.Phones, ph)
 			}
 		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
 
 		out := make([]Contact, 0, len(order))
 		for _, id := range order {
-			out = append(out, *byID[id])
+			c := byID[id]
+			if c.Emails, err = r.getEmails(ctx, id); err != nil {
+				return nil, err
+			}
+			if c.Addresses, err = r.getAddresses(ctx, id); err != nil {
+				return nil, err
+			}
+			if c.Websites, err = r.getWebsites(ctx, id); err != nil {
+				return nil, err
+			}
+			out = append(out, *c)
 		}
 		return out, nil
 	}
 
+	// по имени или email
 	sql := `
 select
-  c.id, c.first_name, c.last_name, coalesce(c.company,''), c.created_at, c.updated_at
-from contacts c
-where (c.first_name || ' ' || c.last_name) ilike $1
+  ` + contactCols + `
+` + contactFrom + `
+where c.address_book_id = $3 and contact_visible(c, $4)
+  and ((c.first_name || ' ' || c.last_name) ilike $1
+   or exists (select 1 from contact_emails e where e.contact_id = c.id and e.email ilike $1))
 order by similarity(c.first_name || ' ' || c.last_name, $1) desc, c.updated_at desc
 limit $2`
 	like := "%" + q + "%"
-	rows, err := r.pool.Query(ctx, sql, like, limit)
+	rows, err := r.pool.Query(ctx, sql, like, limit, bookID(ctx), viewerArg(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -382,10 +457,12 @@ limit $2`
 	res := make([]Contact, 0, limit)
 	for rows.Next() {
 		var c Contact
-		if err := rows.Scan(&c.ID, &c.FirstName, &c.LastName, &c.Company, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		if err := rows.Scan(contactDest(&c)...); err != nil {
+			return nil, err
+		}
+		if err := r.loadDetails(ctx, &c); err != nil {
 			return nil, err
 		}
-		c.Phones, _ = r.getPhones(ctx, c.ID)
 		res = append(res, c)
 	}
 	return res, nil
@@ -393,7 +470,7 @@ limit $2`
 
 func (r *contactRepo) getPhones(ctx context.Context, contactID int64) ([]Phone, error) {
 	rows, err := r.pool.Query(ctx,
-		`select label, phone_raw, phone_e164, phone_digits, is_primary
+		`select label, phone_raw, phone_e164, phone_digits, is_primary, visibility
          from contact_phones
          where contact_id = $1
          order by is_primary desc, id asc`,
@@ -407,10 +484,172 @@ func (r *contactRepo) getPhones(ctx context.Context, contactID int64) ([]Phone,
 	out := make([]Phone, 0, 4)
 	for rows.Next() {
 		var p Phone
-		if err := rows.Scan(&p.Label, &p.PhoneRaw, &p.PhoneE164, &p.PhoneDigits, &p.IsPrimary); err != nil {
+		if err := rows.Scan(&p.Label, &p.PhoneRaw, &p.PhoneE164, &p.PhoneDigits, &p.IsPrimary, &p.Visibility); err != nil {
 			return nil, err
 		}
 		out = append(out, p)
 	}
 	return out, nil
 }
+
+func (r *contactRepo) PhoneOwners(ctx context.Context, digits []string) (map[string]int64, error) {
+	out := make(map[string]int64, len(digits))
+	if len(digits) == 0 {
+		return out, nil
+	}
+	rows, err := r.pool.Query(ctx,
+		`select distinct on (p.phone_digits) p.phone_digits, p.contact_id
+         from contact_phones p
+         join contacts c on c.id = p.contact_id and c.address_book_id = $2 and contact_visible(c, $3)
+         where p.phone_digits = any($1) and phone_visible(p.visibility, c, $4, $3)
+         order by p.phone_digits, p.contact_id`,
+		digits, bookID(ctx), viewerArg(ctx), phoneLevelsArg(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			d  string
+			id int64
+		)
+		if err := rows.Scan(&d, &id); err != nil {
+			return nil, err
+		}
+		out[d] = id
+	}
+	return out, rows.Err()
+}
+
+// listQuery — select видимых контактов книги из ctx с фильтрами, keyset, сортировкой и offset из ListFilter, без limit.
+func listQuery(ctx context.Context, f ListFilter, cols string) (string, []any) {
+	var sb strings.Builder
+	args := make([]any, 0, 8)
+	idx := 1
+
+	sb.WriteString(`
+select
+  ` + cols + `
+` + contactFrom + `
+`)
+
+	where := make([]string, 0, 4)
+	where = append(where, fmt.Sprintf("c.address_book_id = $%d and contact_visible(c, $%d)", idx, idx+1))
+	args = append(args, bookID(ctx), viewerArg(ctx))
+	viewer := idx + 1
+	idx += 2
+	if f.Tag != "" {
+		where = append(where, fmt.Sprintf("c.tags @> array[$%d]::text[]", idx))
+		args = append(args, f.Tag)
+		idx++
+	}
+	if f.FirstName != "" {
+		where = append(where, fmt.Sprintf("c.first_name ilike $%d", idx))
+		args = append(args, "%"+f.FirstName+"%")
+		idx++
+	}
+	if f.LastName != "" {
+		where = append(where, fmt.Sprintf("c.last_name ilike $%d", idx))
+		args = append(args, "%"+f.LastName+"%")
+		idx++
+	}
+	if f.Company != "" {
+		where = append(where, fmt.Sprintf("coalesce(o.name, c.company) ilike $%d", idx))
+		args = append(args, "%"+f.Company+"%")
+		idx++
+	}
+	if f.Phone != "" {
+		sb.WriteString("join contact_phones p on p.contact_id = c.id\n")
+		// скрытый от читающего номер контакт не находит
+		where = append(where, fmt.Sprintf("p.phone_digits ilike $%d and phone_visible(p.visibility, c, $%d, $%d)", idx, idx+1, viewer))
+		args = append(args, "%"+digitsOnly(f.Phone)+"%", phoneLevelsArg(ctx))
+		idx += 2
+	}
+	if f.OrganizationID > 0 {
+		where = append(where, fmt.Sprintf("c.organization_id = $%d", idx))
+		args = append(args, f.OrganizationID)
+		idx++
+	}
+	if f.Email != "" {
+		where = append(where, fmt.Sprintf("exists (select 1 from contact_emails e where e.contact_id = c.id and e.email ilike $%d)", idx))
+		args = append(args, "%"+strings.ToLower(f.Email)+"%")
+		idx++
+	}
+	for _, cf := range f.Custom {
+		switch cf.Type {
+		case "number":
+			where = append(where, fmt.Sprintf("(c.custom->>$%d)::numeric = $%d::numeric", idx, idx+1))
+		case "date":
+			where = append(where, fmt.Sprintf("(c.custom->>$%d)::date = $%d::date", idx, idx+1))
+		case "bool":
+			where = append(where, fmt.Sprintf("(c.custom->>$%d)::boolean = $%d::boolean", idx, idx+1))
+		case "enum":
+			where = append(where, fmt.Sprintf("c.custom->>$%d = $%d", idx, idx+1))
+		default:
+			where = append(where, fmt.Sprintf("c.custom->>$%d ilike '%%' || $%d || '%%'", idx, idx+1))
+		}
+		args = append(args, cf.Field, cf.Value)
+		idx += 2
+	}
+
+	// keyset
+	if f.AfterID > 0 {
+		where = append(where, fmt.Sprintf("c.id > $%d", idx))
+		args = append(args, f.AfterID)
+		idx++
+	}
+	sb.WriteString("where " + strings.Join(where, " and ") + "\n")
+
+	order := strings.ToLower(f.Order)
+	if order != "asc" && order != "desc" {
+		order = "desc"
+	}
+	switch f.SortBy {
+	case "id":
+		// совпадает с keyset по id — полный обход без пропусков
+		sb.WriteString("order by c.id asc\n")
+	case "name":
+		sb.WriteString("order by c.last_name " + order + ", c.first_name " + order + ", c.id asc\n")
+	case "created_at", "updated_at":
+		sb.WriteString("order by c." + f.SortBy + " " + order + ", c.id asc\n")
+	case "custom":
+		key := fmt.Sprintf("(c.custom->>$%d)", idx)
+		switch f.SortType {
+		case "number":
+			key += "::numeric"
+		case "date":
+			key += "::date"
+		case "bool":
+			key += "::boolean"
+		}
+		sb.WriteString("order by " + key + " " + order + " nulls last, c.id asc\n")
+		args = append(args, f.SortField)
+	default:
+		sb.WriteString("order by c.updated_at " + order + ", c.id asc\n")
+	}
+	if f.Offset > 0 {
+		sb.WriteString("offset " + fmt.Sprint(f.Offset) + "\n")
+	}
+
+	return sb.String(), args
+}
+
+func (r *contactRepo) ByIDs(ctx context.Context, ids []int64) ([]Contact, error) {
+	if len(ids) == 0 {
+		return []Contact{}, nil
+	}
+	rows, err := r.pool.Query(ctx,
+		`select `+contactCols+","+detailCols+`
+         `+contactFrom+`
+         where c.id = any($1) and c.address_book_id = $2 and contact_visible(c, $3)
+         order by c.id`, ids, bookID(ctx), viewerArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Contact, error) {
+		var c Contact
+		err := row.Scan(append(contactDest(&c), &c.Phones, &c.Emails, &c.Addresses, &c.Websites)...)
+		return c, err
+	})
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// streamBatch — сколько строк за раз забирать из курсора
+const streamBatch = 500
+
+// detailCols — реквизиты контакта одной строкой (json), чтобы не делать запросов на каждый контакт.
+// Ключи совпадают с именами полей моделей — pgx раскладывает json сразу в слайсы.
+const detailCols = `
+  (select coalesce(json_agg(json_build_object('Label', coalesce(x.label, ''), 'PhoneRaw', x.phone_raw,
+      'PhoneE164', x.phone_e164, 'PhoneDigits', x.phone_digits, 'IsPrimary', x.is_primary, 'Visibility', x.visibility)
+      order by x.is_primary desc, x.id), '[]') from contact_phones x where x.contact_id = c.id),
+  (select coalesce(json_agg(json_build_object('Label', coalesce(x.label, ''), 'Email', x.email,
+      'IsPrimary', x.is_primary) order by x.is_primary desc, x.id), '[]') from contact_emails x where x.contact_id = c.id),
+  (select coalesce(json_agg(json_build_object('Label', coalesce(x.label, ''), 'Street', x.street, 'City', x.city,
+      'Region', x.region, 'PostalCode', x.postal_code, 'Country', x.country, 'IsPrimary', x.is_primary)
+      order by x.is_primary desc, x.id), '[]') from contact_addresses x where x.contact_id = c.id),
+  (select coalesce(json_agg(json_build_object('Label', coalesce(x.label, ''), 'URL', x.url,
+      'IsPrimary', x.is_primary) order by x.is_primary desc, x.id), '[]') from contact_websites x where x.contact_id = c.id)`
+
+// Stream — все контакты по фильтру (limit игнорируется) через серверный курсор в read-only транзакции:
+// память не зависит от размера книги. Отмена ctx (клиент отключился) прерывает запрос.
+func (r *contactRepo) Stream(ctx context.Context, f ListFilter, fn func(Contact) error) error {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	q, args := listQuery(ctx, f, contactCols+","+detailCols)
+	if _, err := tx.Exec(ctx, "declare export_cur no scroll cursor for "+q, args...); err != nil {
+		return err
+	}
+	fetch := fmt.Sprintf("fetch forward %d from export_cur", streamBatch)
+	for {
+		rows, err := tx.Query(ctx, fetch)
+		if err != nil {
+			return err
+		}
+		n := 0
+		for rows.Next() {
+			var c Contact
+			dest := append(contactDest(&c), &c.Phones, &c.Emails, &c.Addresses, &c.Websites)
+			if err := rows.Scan(dest...); err != nil {
+				rows.Close()
+				return err
+			}
+			n++
+			if err := fn(c); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if n < streamBatch {
+			return nil
+		}
+	}
+}
+
+// MaxPhones — наибольшее число видимых читающему телефонов у одного видимого ему контакта
+// (ширина CSV-экспорта): иначе ширина выдавала бы скрытые номера и контакты.
+func (r *contactRepo) MaxPhones(ctx context.Context) (int, error) {
+	var n int
+	err := r.pool.QueryRow(ctx,
+		`select coalesce(max(cnt), 0) from (
+           select count(*) as cnt from contact_phones p
+           join contacts c on c.id = p.contact_id and c.address_book_id = $1 and contact_visible(c, $2)
+           where phone_visible(p.visibility, c, $3, $2)
+           group by p.contact_id) t`, bookID(ctx), viewerArg(ctx), phoneLevelsArg(ctx),
+	).Scan(&n)
+	return n, err
+}
@@ -0,0 +1,31 @@
+package repository_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/sunzhqr/phonebook/internal/repository"
+)
+
+// Offset пропускает первые контакты в порядке сортировки на стороне базы
+func TestContacts_StreamOffset(t *testing.T) {
+	pool := testPool(t)
+	r := repository.New(pool)
+	ctx := viewer(repository.Viewer{All: true})
+	for i := 1; i <= 5; i++ {
+		if _, err := r.Contacts.Create(ctx, repository.ContactInput{FirstName: "User", LastName: "Offset" + strconv.Itoa(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var got []string
+	err := r.Contacts.Stream(ctx, repository.ListFilter{SortBy: "name", Order: "asc", Offset: 3}, func(c repository.Contact) error {
+		got = append(got, c.LastName)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != "Offset4" || got[1] != "Offset5" {
+		t.Fatalf("stream with offset 3 = %v", got)
+	}
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type customFieldRepo struct {
+	pool *pgxpool.Pool
+}
+
+const customFieldCols = `id, name, label, type, required, constraints, created_at, updated_at`
+
+func scanCustomField(row pgx.Row) (CustomField, error) {
+	var f CustomField
+	err := row.Scan(&f.ID, &f.Name, &f.Label, &f.Type, &f.Required, &f.Constraints, &f.CreatedAt, &f.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return CustomField{}, ErrNotFound
+	}
+	return f, err
+}
+
+// Create — поле в реестре книги из ctx: у каждой книги свой реестр, одно имя в разных книгах — разные поля
+func (r *customFieldRepo) Create(ctx context.Context, in CustomFieldInput) (CustomField, error) {
+	f, err := scanCustomField(r.pool.QueryRow(ctx,
+		`insert into custom_fields(address_book_id, name, label, type, required, constraints)
+         values ($1, $2, $3, $4, $5, $6)
+         returning `+customFieldCols,
+		bookID(ctx), in.Name, in.Label, in.Type, in.Required, in.Constraints,
+	))
+	if isUniqueViolation(err) {
+		return CustomField{}, ErrConflict
+	}
+	return f, err
+}
+
+func (r *customFieldRepo) Get(ctx context.Context, name string) (CustomField, error) {
+	return scanCustomField(r.pool.QueryRow(ctx,
+		`select `+customFieldCols+` from custom_fields where address_book_id=$1 and name=$2`, bookID(ctx), name))
+}
+
+func (r *customFieldRepo) List(ctx context.Context) ([]CustomField, error) {
+	rows, err := r.pool.Query(ctx,
+		`select `+customFieldCols+` from custom_fields where address_book_id=$1 order by name asc`, bookID(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]CustomField, 0, 8)
+	for rows.Next() {
+		f, err := scanCustomField(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, f)
+	}
+	return out, rows.Err()
+}
+
+func (r *customFieldRepo) Update(ctx context.Context, name string, p CustomFieldPatch) (CustomField, error) {
+	// имя и тип неизменяемы: от них зависят уже сохранённые значения
+	return scanCustomField(r.pool.QueryRow(ctx,
+		`update custom_fields set
+           label       = coalesce($2, label),
+           required    = coalesce($3, required),
+           constraints = coalesce($4, constraints)
+         where address_book_id=$5 and name=$1
+         returning `+customFieldCols,
+		name, p.Label, p.Required, p.Constraints, bookID(ctx),
+	))
+}
+
+func (r *customFieldRepo) Delete(ctx context.Context, name string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	book := bookID(ctx)
+	ct, err := tx.Exec(ctx, `delete from custom_fields where address_book_id=$1 and name=$2`, book, name)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	// значения удалённого поля вычищаются у контактов той же книги: в других книгах поле с этим именем своё
+	if _, err := tx.Exec(ctx,
+		`update contacts set custom = custom - $2 where address_book_id = $1 and custom ? $2`, book, name); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,59 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sunzhqr/phonebook/internal/repository"
+)
+
+// у каждой книги свой реестр полей: одно имя в двух книгах — два поля, удаление в одной книге
+// не трогает ни поле, ни значения контактов другой
+func TestCustomFields_PerAddressBook(t *testing.T) {
+	pool := testPool(t)
+	r := repository.New(pool)
+	if _, err := pool.Exec(context.Background(),
+		`insert into address_books(id, slug, name) values (2, 'sales', 'Sales')`); err != nil {
+		t.Fatal(err)
+	}
+	all := repository.WithViewer(context.Background(), repository.Viewer{All: true})
+	first := repository.WithAddressBook(all, repository.DefaultAddressBookID)
+	second := repository.WithAddressBook(all, 2)
+
+	for _, ctx := range []context.Context{first, second} {
+		if _, err := r.CustomFields.Create(ctx, repository.CustomFieldInput{Name: "department", Type: "string"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := r.CustomFields.Create(second, repository.CustomFieldInput{Name: "department", Type: "string"}); !errors.Is(err, repository.ErrConflict) {
+		t.Fatalf("duplicate in one book: %v, want conflict", err)
+	}
+	keep, err := r.Contacts.Create(second, repository.ContactInput{
+		FirstName: "B", LastName: "Two", Custom: map[string]any{"department": "Sales"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.CustomFields.Delete(first, "department"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.CustomFields.Get(first, "department"); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("deleted field: %v, want not found", err)
+	}
+	list, err := r.CustomFields.List(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Name != "department" {
+		t.Fatalf("second book registry = %+v", list)
+	}
+	got, err := r.Contacts.Get(second, keep.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Custom["department"] != "Sales" {
+		t.Fatalf("value in another book removed: %v", got.Custom)
+	}
+}
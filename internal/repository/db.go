@@ -2,7 +2,9 @@ package repository
 
 import (
 	"context"
+	"strconv"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -11,6 +13,7 @@ type DBConfig interface {
 	GetURL() string
 	GetMaxConns() int32
 	GetMinConns() int32
+	GetRowSecurity() bool
 }
 
 // OpenDB открывает пул соединений pgxpool по URL из конфига.
@@ -21,5 +24,23 @@ func OpenDB(ctx context.Context, cfg DBConfig) (*pgxpool.Pool, error) {
 	}
 	conf.MaxConns = cfg.GetMaxConns()
 	conf.MinConns = cfg.GetMinConns()
+	if !cfg.GetRowSecurity() {
+		// политика книги остаётся в схеме: без PG_ROW_SECURITY соединения явно идут в обход неё
+		conf.ConnConfig.RuntimeParams["app.all_books"] = "on"
+	} else {
+		// книга запроса задаётся соединению при каждой выдаче из пула. Без книги соединение не видит
+		// ни одного контакта; все книги — только системным задачам с WithAllBooks.
+		conf.BeforeAcquire = func(ctx context.Context, conn *pgx.Conn) bool {
+			book, all := "", "off"
+			if id, ok := addressBookOf(ctx); ok {
+				book = strconv.FormatInt(id, 10)
+			} else if allBooks(ctx) {
+				all = "on"
+			}
+			_, err := conn.Exec(ctx,
+				`select set_config('app.address_book_id', $1, false), set_config('app.all_books', $2, false)`, book, all)
+			return err == nil
+		}
+	}
 	return pgxpool.NewWithConfig(ctx, conf)
 }
@@ -1,9 +1,29 @@
 package repository
 
-import "errors"
+import (
+	"errors"
 
-var ErrNotFound = errors.New("not found")
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrNotFound         = errors.New("not found")
+	ErrConflict         = errors.New("conflict")
+	ErrInvalidReference = errors.New("invalid reference")
+)
 
 func IsBadRequest(err error) bool {
 	return err != nil && (errors.Is(err, ErrNotFound))
 }
+
+// isUniqueViolation — нарушение уникального индекса (SQLSTATE 23505)
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// isForeignKeyViolation — ссылка на несуществующую запись (SQLSTATE 23503)
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
+}
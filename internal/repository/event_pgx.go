@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// eventsChannel — канал pg_notify, в который пишет триггер contact_event
+const eventsChannel = "contact_events"
+
+type eventRepo struct {
+	pool *pgxpool.Pool
+}
+
+// Since — упорядоченные события с seq > afterSeq книги из ctx, видимые читающему; хабу ленты (WithAllBooks) —
+// все события всех книг, он отбирает их для каждого подписчика сам.
+// События, которым Sequence ещё не выдал seq, не возвращаются.
+func (r *eventRepo) Since(ctx context.Context, afterSeq int64, limit int) ([]ContactEvent, error) {
+	_, scoped := addressBookOf(ctx)
+	rows, err := r.pool.Query(ctx,
+		`select id, seq, contact_id, coalesce(address_book_id, 0), op, created_at, readers from contact_events
+         where seq > $1 and ($3 or (coalesce(address_book_id, $5) = $4 and event_visible(readers, $6)))
+         order by seq limit $2`,
+		afterSeq, limit, !scoped && allBooks(ctx), bookID(ctx), DefaultAddressBookID, viewerArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (ContactEvent, error) {
+		var e ContactEvent
+		err := row.Scan(&e.ID, &e.Seq, &e.ContactID, &e.AddressBookID, &e.Op, &e.CreatedAt, &e.Readers)
+		return e, err
+	})
+}
+
+// Listen — отдельное соединение с LISTEN; onListen вызывается, когда подписка активна
+// (после этого события уже не теряются). Возвращается при отмене ctx или обрыве соединения.
+func (r *eventRepo) Listen(ctx context.Context, onListen func(), fn func(ContactEvent)) error {
+	pc, err := r.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// соединение с активным LISTEN не возвращаем в пул — закрываем
+	conn := pc.Hijack()
+	defer func() { _ = conn.Close(context.Background()) }()
+
+	if _, err := conn.Exec(ctx, "listen "+eventsChannel); err != nil {
+		return err
+	}
+	onListen()
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		var e ContactEvent
+		if err := json.Unmarshal([]byte(n.Payload), &e); err != nil {
+			continue
+		}
+		fn(e)
+	}
+}
+
+// DeleteBefore — удаляет события старше before; запоминает наибольший удалённый seq, чтобы
+// отличать устаревший токен синхронизации от актуального. Неупорядоченные события не трогаем.
+func (r *eventRepo) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	var n int64
+	err := r.pool.QueryRow(ctx,
+		`with del as (
+           delete from contact_events where created_at < $1 and seq is not null returning seq
+         ), upd as (
+           update sync_state set purged_through = greatest(purged_through, coalesce((select max(seq) from del), 0))
+           where id = 1
+         )
+         select count(*) from del`, before).Scan(&n)
+	return n, err
+}
+
+// seqLockKey — advisory-блокировка выдачи seq
+const seqLockKey int64 = 0x70686f6e65 // "phone"
+
+// Sequence — выдаёт seq всем закоммиченным событиям без него (по порядку id) и возвращает состояние ленты.
+func (r *eventRepo) Sequence(ctx context.Context) (SyncState, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return SyncState{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, `select pg_advisory_xact_lock($1)`, seqLockKey); err != nil {
+		return SyncState{}, err
+	}
+	if _, err := tx.Exec(ctx,
+		`update contact_events e set seq = n.seq
+         from (select id, nextval('contact_change_seq') as seq
+               from (select id from contact_events where seq is null order by id) u) n
+         where e.id = n.id`); err != nil {
+		return SyncState{}, err
+	}
+	var st SyncState
+	if err := tx.QueryRow(ctx,
+		`select greatest(coalesce((select max(seq) from contact_events), 0), purged_through), purged_through
+         from sync_state where id = 1`).Scan(&st.Max, &st.PurgedThrough); err != nil {
+		return SyncState{}, err
+	}
+	return st, tx.Commit(ctx)
+}
+
+// ChangesSince — изменения книги из ctx, видимые читающему (синхронизация всегда идёт в рамках книги)
+func (r *eventRepo) ChangesSince(ctx context.Context, seq int64, limit int) ([]ContactEvent, error) {
+	rows, err := r.pool.Query(ctx,
+		`select id, seq, contact_id, coalesce(address_book_id, 0), op, created_at,
+                coalesce(external_source, ''), coalesce(external_id, '')
+         from contact_events where seq > $1 and coalesce(address_book_id, $4) = $3 and event_visible(readers, $5)
+         order by seq limit $2`, seq, limit, bookID(ctx), DefaultAddressBookID, viewerArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (ContactEvent, error) {
+		var e ContactEvent
+		err := row.Scan(&e.ID, &e.Seq, &e.ContactID, &e.AddressBookID, &e.Op, &e.CreatedAt, &e.ExternalSource, &e.ExternalID)
+		return e, err
+	})
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type idempotencyRepo struct {
+	pool *pgxpool.Pool
+}
+
+// idemKeyWhere — ключ находится по вызывающему, книге и самому ключу ($1, $2, $3)
+const idemKeyWhere = `subject=$1 and address_book_id=$2 and key=$3`
+
+func (r *idempotencyRepo) Reserve(ctx context.Context, k IdempotencyKey) (IdempotencyKey, bool, error) {
+	_, err := r.pool.Exec(ctx,
+		`delete from idempotency_keys
+         where `+idemKeyWhere+` and (expires_at < now() or (status = 0 and locked_until < now()))`,
+		k.Subject, k.AddressBookID, k.Key)
+	if err != nil {
+		return IdempotencyKey{}, false, err
+	}
+	ct, err := r.pool.Exec(ctx,
+		`insert into idempotency_keys(subject, address_book_id, key, fingerprint, locked_until, expires_at)
+         values ($1, $2, $3, $4, $5, $6)
+         on conflict (subject, address_book_id, key) do nothing`,
+		k.Subject, k.AddressBookID, k.Key, k.Fingerprint, k.LockedUntil, k.ExpiresAt)
+	if err != nil {
+		return IdempotencyKey{}, false, err
+	}
+	if ct.RowsAffected() == 1 {
+		return k, true, nil
+	}
+	cur, err := r.Get(ctx, k)
+	if errors.Is(err, ErrNotFound) {
+		// владелец успел снять ключ между insert и select — пусть клиент повторит
+		return IdempotencyKey{}, false, ErrConflict
+	}
+	return cur, false, err
+}
+
+func (r *idempotencyRepo) Get(ctx context.Context, id IdempotencyKey) (IdempotencyKey, error) {
+	var k IdempotencyKey
+	err := r.pool.QueryRow(ctx,
+		`select subject, address_book_id, key, fingerprint, status, header, body, locked_until, expires_at
+         from idempotency_keys where `+idemKeyWhere, id.Subject, id.AddressBookID, id.Key,
+	).Scan(&k.Subject, &k.AddressBookID, &k.Key, &k.Fingerprint, &k.Status, &k.Header, &k.Body, &k.LockedUntil, &k.ExpiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return IdempotencyKey{}, ErrNotFound
+	}
+	return k, err
+}
+
+func (r *idempotencyRepo) Complete(ctx context.Context, k IdempotencyKey) error {
+	_, err := r.pool.Exec(ctx,
+		`update idempotency_keys set status=$4, header=$5, body=$6 where `+idemKeyWhere+` and status = 0`,
+		k.Subject, k.AddressBookID, k.Key, k.Status, k.Header, k.Body)
+	return err
+}
+
+func (r *idempotencyRepo) Extend(ctx context.Context, k IdempotencyKey, until time.Time) error {
+	_, err := r.pool.Exec(ctx,
+		`update idempotency_keys set locked_until=$4 where `+idemKeyWhere+` and status = 0`,
+		k.Subject, k.AddressBookID, k.Key, until)
+	return err
+}
+
+// Release — снимает незавершённый ключ, чтобы повтор выполнился заново.
+func (r *idempotencyRepo) Release(ctx context.Context, k IdempotencyKey) error {
+	_, err := r.pool.Exec(ctx, `delete from idempotency_keys where `+idemKeyWhere+` and status = 0`,
+		k.Subject, k.AddressBookID, k.Key)
+	return err
+}
+
+func (r *idempotencyRepo) DeleteExpired(ctx context.Context) (int64, error) {
+	ct, err := r.pool.Exec(ctx, `delete from idempotency_keys where expires_at < now()`)
+	if err != nil {
+		return 0, err
+	}
+	return ct.RowsAffected(), nil
+}
@@ -7,9 +7,23 @@ type Contact struct {
 	FirstName string
 	LastName  string
 	Company   string
-	Phones    []Phone
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	// OrganizationID == 0 — контакт не привязан к организации
+	OrganizationID int64
+	JobTitle       string
+	Department     string
+	Phones         []Phone
+	Emails         []Email
+	Addresses      []Address
+	Websites       []Website
+	Custom         map[string]any
+	Owner          string // кто создал контакт: user:<sub> или apikey:<id>
+	Tags           []string
+	HasPhoto       bool
+	// ExternalSource и ExternalID — ключ в системе-источнике; пустые, если контакт заведён вручную
+	ExternalSource string
+	ExternalID     string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
 }
 type Phone struct {
 	Label       string
@@ -17,6 +31,29 @@ type Phone struct {
 	PhoneE164   string
 	PhoneDigits string
 	IsPrimary   bool
+	Visibility  string // public, internal или private; у организаций не используется
+}
+
+type Email struct {
+	Label     string
+	Email     string
+	IsPrimary bool
+}
+
+type Address struct {
+	Label      string
+	Street     string
+	City       string
+	Region     string
+	PostalCode string
+	Country    string
+	IsPrimary  bool
+}
+
+type Website struct {
+	Label     string
+	URL       string
+	IsPrimary bool
 }
 
 type PhoneInput struct {
@@ -25,29 +62,398 @@ type PhoneInput struct {
 	PhoneE164   string
 	PhoneDigits string
 	IsPrimary   bool
+	Visibility  string // пустая — public
+}
+
+func (p PhoneInput) visibility() string {
+	if p.Visibility == "" {
+		return "public"
+	}
+	return p.Visibility
+}
+
+type EmailInput struct {
+	Label     string
+	Email     string
+	IsPrimary bool
+}
+
+type AddressInput struct {
+	Label      string
+	Street     string
+	City       string
+	Region     string
+	PostalCode string
+	Country    string
+	IsPrimary  bool
+}
+
+type WebsiteInput struct {
+	Label     string
+	URL       string
+	IsPrimary bool
 }
 
 type ContactInput struct {
-	FirstName string
-	LastName  string
-	Company   string
-	Phones    []PhoneInput
+	FirstName      string
+	LastName       string
+	Company        string
+	OrganizationID int64
+	JobTitle       string
+	Department     string
+	Phones         []PhoneInput
+	Emails         []EmailInput
+	Addresses      []AddressInput
+	Websites       []WebsiteInput
+	Custom         map[string]any
+	Owner          string
+	Tags           []string
 }
 
 type ContactPatch struct {
 	FirstName *string
 	LastName  *string
 	Company   *string
-	Phones    *[]PhoneInput
+	// OrganizationID: nil — не трогаем, 0 — отвязать
+	OrganizationID *int64
+	JobTitle       *string
+	Department     *string
+	Phones         *[]PhoneInput
+	Emails         *[]EmailInput
+	Addresses      *[]AddressInput
+	Websites       *[]WebsiteInput
+	// Custom мержится с текущими значениями; ключ со значением nil удаляется
+	Custom map[string]any
+	Tags   *[]string
+}
+
+// BatchOp — операция пакета: Kind одно из BatchCreate, BatchUpdate, BatchDelete
+type BatchOp struct {
+	Kind   string
+	ID     int64 // для update и delete
+	Create ContactInput
+	Patch  ContactPatch
+}
+
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// BatchResult — итог операции пакета в порядке входа; ID — id созданного или изменённого контакта
+type BatchResult struct {
+	ID  int64
+	Err error
 }
 
 type ListFilter struct {
-	FirstName string
-	LastName  string
-	Company   string
-	Phone     string
-	AfterID   int64
+	FirstName      string
+	LastName       string
+	Company        string
+	Phone          string
+	Email          string
+	OrganizationID int64
+	Tag            string
+	Custom         []CustomFilter
+	AfterID        int64
+	Limit          int
+	Offset         int    // пропуск первых контактов в порядке сортировки
+	SortBy         string // "custom" — сортировка по SortField
+	SortField      string
+	SortType       string
+	Order          string
+	// WithoutDetails — без телефонов, email, адресов и сайтов: вызывающий догрузит их пачкой через ByIDs
+	WithoutDetails bool
+}
+
+// CustomFilter — условие по пользовательскому полю; Type берётся из реестра
+type CustomFilter struct {
+	Field string
+	Type  string
+	Value string
+}
+
+type FieldConstraints struct {
+	MinLength *int     `json:"min_length,omitempty"`
+	MaxLength *int     `json:"max_length,omitempty"`
+	Pattern   string   `json:"pattern,omitempty"`
+	Min       *float64 `json:"min,omitempty"`
+	Max       *float64 `json:"max,omitempty"`
+	Options   []string `json:"options,omitempty"`
+}
+
+type CustomField struct {
+	ID          int64
+	Name        string
+	Label       string
+	Type        string
+	Required    bool
+	Constraints FieldConstraints
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type CustomFieldInput struct {
+	Name        string
+	Label       string
+	Type        string
+	Required    bool
+	Constraints FieldConstraints
+}
+
+type CustomFieldPatch struct {
+	Label       *string
+	Required    *bool
+	Constraints *FieldConstraints
+}
+
+type Organization struct {
+	ID          int64
+	Name        string
+	Phones      []Phone
+	Addresses   []Address
+	MemberCount int64
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type OrganizationInput struct {
+	Name      string
+	Phones    []PhoneInput
+	Addresses []AddressInput
+}
+
+type OrganizationPatch struct {
+	Name      *string
+	Phones    *[]PhoneInput
+	Addresses *[]AddressInput
+}
+
+type OrganizationFilter struct {
+	Name    string
+	AfterID int64
+	Limit   int
+}
+
+type Relation struct {
+	ID            int64
+	ContactID     int64
+	RelatedID     int64
+	Type          string
+	Bidirectional bool
+	// имя связанного контакта — чтобы не тянуть его отдельным запросом
+	RelatedFirstName string
+	RelatedLastName  string
+	CreatedAt        time.Time
+}
+
+type RelationInput struct {
+	ContactID int64
+	RelatedID int64
+	Type      string
+	// InverseType непустой — создаётся и обратная запись
+	InverseType string
+}
+
+// Photo — метаданные фото контакта; сами байты лежат в blob-хранилище
+type Photo struct {
+	ContactID   int64
+	ContentType string
+	Size        int64
+	Width       int
+	Height      int
+	ETag        string
+	UpdatedAt   time.Time
+}
+
+// IdempotencyKey — сохранённый ответ на запрос с заголовком Idempotency-Key; Status = 0 — ещё выполняется.
+// Ключ свой у каждого вызывающего в каждой книге: тот же Idempotency-Key от другого субъекта — другой
+// ключ, чужой сохранённый ответ ему не достаётся.
+type IdempotencyKey struct {
+	Subject       string // пустой — вызов без субъекта
+	AddressBookID int64
+	Key           string
+	Fingerprint   string
+	Status        int
+	Header        map[string]string
+	Body          []byte
+	LockedUntil   time.Time
+	ExpiresAt     time.Time
+}
+
+// ContactEvent — запись журнала изменений; Op: created, updated или deleted
+type ContactEvent struct {
+	ID        int64 `json:"id"`
+	Seq       int64 `json:"seq"` // 0 — ещё не упорядочено для синхронизации
+	ContactID int64 `json:"contact_id"`
+	// AddressBookID — 0 у событий, записанных до появления книг
+	AddressBookID int64     `json:"address_book_id"`
+	Op            string    `json:"op"`
+	CreatedAt     time.Time `json:"created_at"`
+	// внешний ключ контакта на момент события (пустой, если не задан)
+	ExternalSource string `json:"external_source,omitempty"`
+	ExternalID     string `json:"external_id,omitempty"`
+	// Readers — кому, кроме администраторов книги, виден контакт на момент события; nil — всем читателям книги
+	Readers []string `json:"readers,omitempty"`
+}
+
+// Статусы доставки вебхука
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// Webhook — подписка на события; Events пустой — все события
+type Webhook struct {
+	ID        int64
+	URL       string
+	Secret    string
+	Events    []string
+	Active    bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type WebhookInput struct {
+	URL    string
+	Secret string
+	Events []string
+}
+
+type WebhookPatch struct {
+	URL    *string
+	Secret *string
+	Events *[]string
+	Active *bool
+}
+
+// Delivery — доставка одного события одной подписке (журнал доставок)
+type Delivery struct {
+	ID            int64
+	WebhookID     int64
+	EventID       int64 // id события в outbox
+	EventType     string
+	Status        string
+	Attempts      int
+	NextAttemptAt time.Time
+	LastStatus    int
+	LastError     string
+	CreatedAt     time.Time
+	DeliveredAt   *time.Time
+}
+
+type DeliveryFilter struct {
+	WebhookID int64
+	Status    string
+	BeforeID  int64
+	Limit     int
+}
+
+// PendingDelivery — доставка, взятая диспетчером в работу; Attempts уже учитывает текущую попытку
+type PendingDelivery struct {
+	ID        int64
+	WebhookID int64
+	Attempts  int
+	URL       string
+	Secret    string
+	EventType string
+	Payload   []byte
+}
+
+// DeliveryUpdate — итог попытки доставки
+type DeliveryUpdate struct {
+	Status        string
+	NextAttemptAt time.Time
+	LastStatus    int
+	LastError     string
+}
+
+// SyncState — Max: последний выданный seq; PurgedThrough: всё до него включительно удалено по сроку хранения
+type SyncState struct {
+	Max           int64
+	PurgedThrough int64
+}
+
+// APIKey — выпущенный ключ API; KeyHash — sha256 ключа, сам ключ не хранится
+type APIKey struct {
+	ID         int64
+	Name       string
+	Prefix     string
+	KeyHash    []byte
+	Scopes     []string
+	CreatedAt  time.Time
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	// AddressBookID — книга, к которой привязан ключ; nil — любая книга
+	AddressBookID *int64
+	AddressBook   string // slug книги
+}
+
+type APIKeyInput struct {
+	Name          string
+	Prefix        string
+	KeyHash       []byte
+	Scopes        []string
+	ExpiresAt     *time.Time
+	AddressBookID *int64
+}
+
+// AddressBook — адресная книга (арендатор) со своими настройками
+type AddressBook struct {
+	ID            int64
+	Slug          string
+	Name          string
+	DefaultRegion string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+type AddressBookInput struct {
+	Slug          string
+	Name          string
+	DefaultRegion string
+}
+
+type AddressBookPatch struct {
+	Name          *string
+	DefaultRegion *string
+}
+
+// Grant — выдача роли viewer, editor или admin на контакт или тег
+type Grant struct {
+	Principal string
+	Role      string
+}
+
+// ContactAccess — доступ к видимому контакту: Restricted — у контакта есть выдачи, Role — старшая роль
+// читающего по выдачам и владению (пустая — выдач ему нет)
+type ContactAccess struct {
+	Restricted bool
+	Role       string
+}
+
+// AuditEntry — запись журнала аудита; книга и время фиксируются при вызове, а не при записи в БД
+type AuditEntry struct {
+	ID            int64
+	At            time.Time
+	AddressBookID int64
+	Actor         string
+	Action        string
+	ContactIDs    []int64
+	RequestID     string
+	IP            string
+}
+
+// AuditFilter — выборка журнала книги из ctx, новые сверху; пустые поля не фильтруют
+type AuditFilter struct {
+	Actor     string
+	Action    string
+	ContactID int64
+	Since     time.Time
+	Until     time.Time
+	BeforeID  int64
 	Limit     int
-	SortBy    string
-	Order     string
 }
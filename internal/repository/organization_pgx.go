@@ -0,0 +1,268 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type organizationRepo struct {
+	pool *pgxpool.Pool
+}
+
+// organizationCols — организации общие для всех книг, участники считаются только в книге запроса ($1)
+const organizationCols = `o.id, o.name,
+  (select count(*) from contacts c where c.organization_id = o.id and c.address_book_id = $1),
+  o.created_at, o.updated_at`
+
+func (r *organizationRepo) Create(ctx context.Context, in OrganizationInput) (Organization, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return Organization{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var id int64
+	if err := tx.QueryRow(ctx,
+		`insert into organizations(name) values ($1) returning id`, in.Name,
+	).Scan(&id); err != nil {
+		if isUniqueViolation(err) {
+			return Organization{}, ErrConflict
+		}
+		return Organization{}, err
+	}
+
+	var b pgx.Batch
+	queueOrgPhones(&b, id, in.Phones)
+	queueOrgAddresses(&b, id, in.Addresses)
+	if b.Len() > 0 {
+		if err := tx.SendBatch(ctx, &b).Close(); err != nil {
+			return Organization{}, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Organization{}, err
+	}
+	return r.Get(ctx, id)
+}
+
+func (r *organizationRepo) Get(ctx context.Context, id int64) (Organization, error) {
+	var o Organization
+	err := r.pool.QueryRow(ctx,
+		`select `+organizationCols+` from organizations o where o.id=$2`, bookID(ctx), id,
+	).Scan(&o.ID, &o.Name, &o.MemberCount, &o.CreatedAt, &o.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Organization{}, ErrNotFound
+		}
+		return Organization{}, err
+	}
+	if o.Phones, err = r.getPhones(ctx, id); err != nil {
+		return Organization{}, err
+	}
+	if o.Addresses, err = r.getAddresses(ctx, id); err != nil {
+		return Organization{}, err
+	}
+	return o, nil
+}
+
+func (r *organizationRepo) Update(ctx context.Context, id int64, p OrganizationPatch) (Organization, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return Organization{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if p.Name != nil {
+		ct, err := tx.Exec(ctx, `update organizations set name=$1 where id=$2`, strings.TrimSpace(*p.Name), id)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return Organization{}, ErrConflict
+			}
+			return Organization{}, err
+		}
+		if ct.RowsAffected() == 0 {
+			return Organization{}, ErrNotFound
+		}
+	}
+
+	var b pgx.Batch
+	if p.Phones != nil {
+		b.Queue(`delete from organization_phones where organization_id=$1`, id)
+		queueOrgPhones(&b, id, *p.Phones)
+	}
+	if p.Addresses != nil {
+		b.Queue(`delete from organization_addresses where organization_id=$1`, id)
+		queueOrgAddresses(&b, id, *p.Addresses)
+	}
+	if b.Len() > 0 {
+		if err := tx.SendBatch(ctx, &b).Close(); err != nil {
+			return Organization{}, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Organization{}, err
+	}
+	return r.Get(ctx, id)
+}
+
+func (r *organizationRepo) Delete(ctx context.Context, id int64) error {
+	// контакты остаются, organization_id обнуляется (on delete set null)
+	ct, err := r.pool.Exec(ctx, `delete from organizations where id=$1`, id)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *organizationRepo) List(ctx context.Context, f OrganizationFilter) ([]Organization, int64, error) {
+	var sb strings.Builder
+	args := []any{bookID(ctx)}
+	idx := 2
+
+	sb.WriteString("select " + organizationCols + "\nfrom organizations o\n")
+	where := make([]string, 0, 2)
+	if f.Name != "" {
+		where = append(where, fmt.Sprintf("o.name ilike $%d", idx))
+		args = append(args, "%"+f.Name+"%")
+		idx++
+	}
+	if f.AfterID > 0 {
+		where = append(where, fmt.Sprintf("o.id > $%d", idx))
+		args = append(args, f.AfterID)
+		idx++
+	}
+	if len(where) > 0 {
+		sb.WriteString("where " + strings.Join(where, " and ") + "\n")
+	}
+
+	limit := f.Limit
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	sb.WriteString("order by o.id asc\nlimit " + fmt.Sprint(limit+1))
+
+	rows, err := r.pool.Query(ctx, sb.String(), args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	list := make([]Organization, 0, limit)
+	for rows.Next() {
+		var o Organization
+		if err := rows.Scan(&o.ID, &o.Name, &o.MemberCount, &o.CreatedAt, &o.UpdatedAt); err != nil {
+			return nil, 0, err
+		}
+		list = append(list, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var next int64
+	if len(list) > limit {
+		list = list[:limit]
+		next = list[limit-1].ID
+	}
+	for i := range list {
+		if list[i].Phones, err = r.getPhones(ctx, list[i].ID); err != nil {
+			return nil, 0, err
+		}
+		if list[i].Addresses, err = r.getAddresses(ctx, list[i].ID); err != nil {
+			return nil, 0, err
+		}
+	}
+	return list, next, nil
+}
+
+func queueOrgPhones(b *pgx.Batch, orgID int64, phones []PhoneInput) {
+	hasPrimary := false
+	for i := range phones {
+		if phones[i].IsPrimary {
+			hasPrimary = true
+			break
+		}
+	}
+	for i, p := range phones {
+		b.Queue(
+			`insert into organization_phones(organization_id, label, phone_raw, phone_e164, phone_digits, is_primary)
+             values ($1, $2, $3, $4, $5, $6)`,
+			orgID, p.Label, p.PhoneRaw, p.PhoneE164, p.PhoneDigits, p.IsPrimary || (!hasPrimary && i == 0),
+		)
+	}
+}
+
+func queueOrgAddresses(b *pgx.Batch, orgID int64, addrs []AddressInput) {
+	hasPrimary := false
+	for i := range addrs {
+		if addrs[i].IsPrimary {
+			hasPrimary = true
+			break
+		}
+	}
+	for i, a := range addrs {
+		b.Queue(
+			`insert into organization_addresses(organization_id, label, street, city, region, postal_code, country, is_primary)
+             values ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			orgID, a.Label, a.Street, a.City, a.Region, a.PostalCode, a.Country, a.IsPrimary || (!hasPrimary && i == 0),
+		)
+	}
+}
+
+func (r *organizationRepo) getPhones(ctx context.Context, orgID int64) ([]Phone, error) {
+	rows, err := r.pool.Query(ctx,
+		`select coalesce(label,''), phone_raw, phone_e164, phone_digits, is_primary
+         from organization_phones
+         where organization_id = $1
+         order by is_primary desc, id asc`,
+		orgID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]Phone, 0, 2)
+	for rows.Next() {
+		var p Phone
+		if err := rows.Scan(&p.Label, &p.PhoneRaw, &p.PhoneE164, &p.PhoneDigits, &p.IsPrimary); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+func (r *organizationRepo) getAddresses(ctx context.Context, orgID int64) ([]Address, error) {
+	rows, err := r.pool.Query(ctx,
+		`select coalesce(label,''), street, city, region, postal_code, country, is_primary
+         from organization_addresses
+         where organization_id = $1
+         order by is_primary desc, id asc`,
+		orgID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]Address, 0, 1)
+	for rows.Next() {
+		var a Address
+		if err := rows.Scan(&a.Label, &a.Street, &a.City, &a.Region, &a.PostalCode, &a.Country, &a.IsPrimary); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
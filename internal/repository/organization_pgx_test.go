@@ -0,0 +1,65 @@
+package repository_test
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestMigration_OrganizationsFromCompany(t *testing.T) {
+	companies := []string{"Forte", "Forte Bank", "ForteBank JSC", "Alfa", "Alfastrah", "Alfastrah", "Kaspi"}
+	pool := testPoolSeeded(t, "0004_organizations.sql", func(ctx context.Context, pool *pgxpool.Pool) {
+		for _, c := range companies {
+			if _, err := pool.Exec(ctx, `insert into contacts (first_name, last_name, company) values ('A', 'B', $1)`, c); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+	ctx := context.Background()
+
+	// компания контакта → организация; сливаются только равные ключи
+	rows, err := pool.Query(ctx, `select c.company, o.name from contacts c join organizations o on o.id = c.organization_id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for rows.Next() {
+		var company, org string
+		if err := rows.Scan(&company, &org); err != nil {
+			t.Fatal(err)
+		}
+		got[company] = org
+	}
+	rows.Close()
+	want := map[string]string{
+		"Forte": "Forte", "Forte Bank": "Forte Bank", "ForteBank JSC": "Forte Bank",
+		"Alfa": "Alfa", "Alfastrah": "Alfastrah", "Kaspi": "Kaspi",
+	}
+	if !maps.Equal(got, want) {
+		t.Fatalf("organizations = %v, want %v", got, want)
+	}
+
+	rows, err = pool.Query(ctx, `
+select a.name || ' ~ ' || b.name from organization_merge_candidates m
+join organizations a on a.id = m.organization_id
+join organizations b on b.id = m.similar_id
+order by 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pairs []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			t.Fatal(err)
+		}
+		pairs = append(pairs, p)
+	}
+	rows.Close()
+	if want := []string{"Alfa ~ Alfastrah", "Forte ~ Forte Bank"}; !slices.Equal(pairs, want) {
+		t.Fatalf("merge candidates = %v, want %v", pairs, want)
+	}
+}
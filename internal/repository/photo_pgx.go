@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type photoRepo struct {
+	pool *pgxpool.Pool
+}
+
+// Upsert — метаданные фото; контакт отмечается изменённым в той же транзакции: фото входит в vCard,
+// поэтому ETag, синхронизация, лента и вебхуки должны увидеть замену
+func (r *photoRepo) Upsert(ctx context.Context, p Photo) (Photo, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return Photo{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	err = tx.QueryRow(ctx,
+		`insert into contact_photos(contact_id, content_type, size_bytes, width, height, etag)
+         values ($1, $2, $3, $4, $5, $6)
+         on conflict (contact_id) do update set
+           content_type = excluded.content_type,
+           size_bytes   = excluded.size_bytes,
+           width        = excluded.width,
+           height       = excluded.height,
+           etag         = excluded.etag,
+           updated_at   = now()
+         returning updated_at`,
+		p.ContactID, p.ContentType, p.Size, p.Width, p.Height, p.ETag,
+	).Scan(&p.UpdatedAt)
+	if isForeignKeyViolation(err) {
+		return Photo{}, ErrNotFound
+	}
+	if err != nil {
+		return Photo{}, err
+	}
+	if err := touchContact(ctx, tx, p.ContactID); err != nil {
+		return Photo{}, err
+	}
+	return p, tx.Commit(ctx)
+}
+
+func (r *photoRepo) Get(ctx context.Context, contactID int64) (Photo, error) {
+	var p Photo
+	err := r.pool.QueryRow(ctx,
+		`select contact_id, content_type, size_bytes, width, height, etag, updated_at
+         from contact_photos
+         where contact_id = (select id from contacts c where c.id=$1 and c.address_book_id=$2 and contact_visible(c, $3))`,
+		contactID, bookID(ctx), viewerArg(ctx),
+	).Scan(&p.ContactID, &p.ContentType, &p.Size, &p.Width, &p.Height, &p.ETag, &p.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Photo{}, ErrNotFound
+	}
+	return p, err
+}
+
+// Delete — как Upsert, отмечает контакт изменённым в той же транзакции
+func (r *photoRepo) Delete(ctx context.Context, contactID int64) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	ct, err := tx.Exec(ctx,
+		`delete from contact_photos
+         where contact_id = (select id from contacts c where c.id=$1 and c.address_book_id=$2 and contact_visible(c, $3))`,
+		contactID, bookID(ctx), viewerArg(ctx))
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	if err := touchContact(ctx, tx, contactID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// touchContact — новое updated_at контакта; триггер пишет событие изменения
+func touchContact(ctx context.Context, tx pgx.Tx, id int64) error {
+	_, err := tx.Exec(ctx, `update contacts set updated_at = now() where id = $1`, id)
+	return err
+}
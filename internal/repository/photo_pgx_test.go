@@ -0,0 +1,52 @@
+package repository_test
+
+import (
+	"testing"
+
+	"github.com/sunzhqr/phonebook/internal/repository"
+)
+
+// фото входит в vCard: его замена и удаление меняют updated_at контакта (ETag) и пишут событие
+func TestPhotos_TouchContact(t *testing.T) {
+	pool := testPool(t)
+	r := repository.New(pool)
+	ctx := viewer(repository.Viewer{All: true})
+	c, err := r.Contacts.Create(ctx, repository.ContactInput{FirstName: "Photo", LastName: "Testov"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updated := func() int {
+		t.Helper()
+		var n int
+		if err := pool.QueryRow(ctx,
+			`select count(*) from contact_events where contact_id = $1 and op = 'updated'`, c.ID).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	prev := c.UpdatedAt
+	step := func(name string, fn func() error) {
+		t.Helper()
+		events := updated()
+		if err := fn(); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		got, err := r.Contacts.Get(ctx, c.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.UpdatedAt.After(prev) {
+			t.Fatalf("%s: updated_at %v not after %v — ETag would not change", name, got.UpdatedAt, prev)
+		}
+		if updated() != events+1 {
+			t.Fatalf("%s: no change event", name)
+		}
+		prev = got.UpdatedAt
+	}
+	photo := repository.Photo{ContactID: c.ID, ContentType: "image/png", Size: 10, Width: 1, Height: 1, ETag: "a"}
+	step("put", func() error { _, err := r.Photos.Upsert(ctx, photo); return err })
+	photo.ETag = "b"
+	step("replace", func() error { _, err := r.Photos.Upsert(ctx, photo); return err })
+	step("delete", func() error { return r.Photos.Delete(ctx, c.ID) })
+}
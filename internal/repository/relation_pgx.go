@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type relationRepo struct {
+	pool *pgxpool.Pool
+}
+
+func (r *relationRepo) Create(ctx context.Context, in RelationInput) (Relation, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return Relation{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// контакт из другой книги для этой книги не существует — как и несуществующий related_id
+	var id int64
+	err = tx.QueryRow(ctx,
+		`insert into contact_relations(contact_id, related_id, type)
+         select $1, $2, $3
+         where exists (select 1 from contacts c where c.id = $2 and c.address_book_id = $4 and contact_visible(c, $5))
+         returning id`,
+		in.ContactID, in.RelatedID, in.Type, bookID(ctx), viewerArg(ctx),
+	).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Relation{}, ErrInvalidReference
+	}
+	if err != nil {
+		return Relation{}, relationErr(err)
+	}
+
+	if in.InverseType != "" {
+		// обратная запись; pair_id общий для обеих — id прямой
+		if _, err := tx.Exec(ctx,
+			`insert into contact_relations(contact_id, related_id, type, pair_id) values ($1, $2, $3, $4)`,
+			in.RelatedID, in.ContactID, in.InverseType, id,
+		); err != nil {
+			return Relation{}, relationErr(err)
+		}
+		if _, err := tx.Exec(ctx, `update contact_relations set pair_id=$1 where id=$1`, id); err != nil {
+			return Relation{}, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Relation{}, err
+	}
+	return r.get(ctx, in.ContactID, id)
+}
+
+func (r *relationRepo) List(ctx context.Context, contactID int64) ([]Relation, error) {
+	rows, err := r.pool.Query(ctx,
+		`select rel.id, rel.contact_id, rel.related_id, rel.type, rel.pair_id is not null,
+                c.first_name, c.last_name, rel.created_at
+         from contact_relations rel
+         join contacts c on c.id = rel.related_id and c.address_book_id = $2 and contact_visible(c, $3)
+         where rel.contact_id = $1
+         order by rel.type asc, rel.id asc`,
+		contactID, bookID(ctx), viewerArg(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]Relation, 0, 4)
+	for rows.Next() {
+		var rel Relation
+		if err := rows.Scan(&rel.ID, &rel.ContactID, &rel.RelatedID, &rel.Type, &rel.Bidirectional,
+			&rel.RelatedFirstName, &rel.RelatedLastName, &rel.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, rel)
+	}
+	return out, rows.Err()
+}
+
+func (r *relationRepo) Delete(ctx context.Context, contactID, relationID int64) error {
+	ct, err := r.pool.Exec(ctx,
+		`delete from contact_relations
+         where (id = $2 and contact_id = $1
+            or pair_id = (select pair_id from contact_relations where id = $2 and contact_id = $1))
+           and exists (select 1 from contacts c where c.id = $1 and c.address_book_id = $3)`,
+		contactID, relationID, bookID(ctx),
+	)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *relationRepo) get(ctx context.Context, contactID, id int64) (Relation, error) {
+	var rel Relation
+	err := r.pool.QueryRow(ctx,
+		`select rel.id, rel.contact_id, rel.related_id, rel.type, rel.pair_id is not null,
+                c.first_name, c.last_name, rel.created_at
+         from contact_relations rel
+         join contacts c on c.id = rel.related_id and c.address_book_id = $3 and contact_visible(c, $4)
+         where rel.id = $2 and rel.contact_id = $1`,
+		contactID, id, bookID(ctx), viewerArg(ctx),
+	).Scan(&rel.ID, &rel.ContactID, &rel.RelatedID, &rel.Type, &rel.Bidirectional,
+		&rel.RelatedFirstName, &rel.RelatedLastName, &rel.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Relation{}, ErrNotFound
+	}
+	return rel, err
+}
+
+func relationErr(err error) error {
+	switch {
+	case isUniqueViolation(err):
+		return ErrConflict
+	case isForeignKeyViolation(err):
+		return ErrInvalidReference
+	}
+	return err
+}
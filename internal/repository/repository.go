@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -14,14 +15,157 @@ type ContactsRepository interface {
 	Delete(ctx context.Context, id int64) error
 	List(ctx context.Context, f ListFilter) ([]Contact, int64, error)
 	Search(ctx context.Context, q string, limit int) ([]Contact, error)
+	// UpsertExternal — создаёт или целиком заменяет контакт с ключом (source, externalID)
+	UpsertExternal(ctx context.Context, source, externalID string, in ContactInput) (c Contact, created bool, err error)
+	GetByExternal(ctx context.Context, source, externalID string) (Contact, error)
+	// ByIDs — существующие из ids, с реквизитами; удалённые просто отсутствуют
+	ByIDs(ctx context.Context, ids []int64) ([]Contact, error)
+	// PhoneOwners — владельцы номеров: phone_digits -> id контакта (для поиска дубликатов при импорте)
+	PhoneOwners(ctx context.Context, digits []string) (map[string]int64, error)
+	// Stream — все контакты по фильтру без пагинации, с реквизитами; fn вызывается на каждый контакт
+	Stream(ctx context.Context, f ListFilter, fn func(Contact) error) error
+	MaxPhones(ctx context.Context) (int, error)
+	// Batch — пакет операций в одной транзакции. atomic: при любой ошибке откатывается весь пакет,
+	// иначе применяются все успешные операции. Ошибки операций — в результатах, не в error.
+	Batch(ctx context.Context, ops []BatchOp, atomic bool) ([]BatchResult, error)
+}
+
+// CustomFieldsRepository - реестр пользовательских полей контактов книги из ctx
+type CustomFieldsRepository interface {
+	Create(ctx context.Context, in CustomFieldInput) (CustomField, error)
+	Get(ctx context.Context, name string) (CustomField, error)
+	List(ctx context.Context) ([]CustomField, error)
+	Update(ctx context.Context, name string, patch CustomFieldPatch) (CustomField, error)
+	Delete(ctx context.Context, name string) error
+}
+
+// OrganizationsRepository - организации; участники выбираются через ContactsRepository.List
+type OrganizationsRepository interface {
+	Create(ctx context.Context, in OrganizationInput) (Organization, error)
+	Get(ctx context.Context, id int64) (Organization, error)
+	Update(ctx context.Context, id int64, patch OrganizationPatch) (Organization, error)
+	Delete(ctx context.Context, id int64) error
+	List(ctx context.Context, f OrganizationFilter) ([]Organization, int64, error)
+}
+
+// RelationsRepository - типизированные связи между контактами
+type RelationsRepository interface {
+	Create(ctx context.Context, in RelationInput) (Relation, error)
+	List(ctx context.Context, contactID int64) ([]Relation, error)
+	// Delete удаляет связь контакта; у двусторонней связи удаляется и обратная запись
+	Delete(ctx context.Context, contactID, relationID int64) error
+}
+
+// PhotosRepository - метаданные фото контактов
+type PhotosRepository interface {
+	Upsert(ctx context.Context, p Photo) (Photo, error)
+	Get(ctx context.Context, contactID int64) (Photo, error)
+	Delete(ctx context.Context, contactID int64) error
+}
+
+// IdempotencyRepository - ключи идемпотентности и сохранённые ответы
+type IdempotencyRepository interface {
+	// Reserve — занимает ключ; если он уже занят, created=false и возвращается существующая запись.
+	// Истёкшие и брошенные (locked_until в прошлом) записи перезанимаются.
+	Reserve(ctx context.Context, k IdempotencyKey) (cur IdempotencyKey, created bool, err error)
+	// Get, Complete, Extend и Release находят ключ по Subject, AddressBookID и Key
+	Get(ctx context.Context, k IdempotencyKey) (IdempotencyKey, error)
+	Complete(ctx context.Context, k IdempotencyKey) error
+	// Extend — продлевает locked_until незавершённого ключа, пока первый запрос ещё выполняется
+	Extend(ctx context.Context, k IdempotencyKey, until time.Time) error
+	Release(ctx context.Context, k IdempotencyKey) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// EventsRepository - журнал изменений контактов (пишется триггером) и уведомления о новых записях
+type EventsRepository interface {
+	// Since — события с seq > afterSeq; новые события сначала упорядочивает Sequence
+	Since(ctx context.Context, afterSeq int64, limit int) ([]ContactEvent, error)
+	Listen(ctx context.Context, onListen func(), fn func(ContactEvent)) error
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+	// Sequence — упорядочивает новые события для синхронизации; ChangesSince читает их по seq
+	Sequence(ctx context.Context) (SyncState, error)
+	ChangesSince(ctx context.Context, seq int64, limit int) ([]ContactEvent, error)
+}
+
+// WebhooksRepository - подписки на события, их журнал доставок и очередь диспетчера
+type WebhooksRepository interface {
+	Create(ctx context.Context, in WebhookInput) (Webhook, error)
+	Get(ctx context.Context, id int64) (Webhook, error)
+	List(ctx context.Context) ([]Webhook, error)
+	Update(ctx context.Context, id int64, p WebhookPatch) (Webhook, error)
+	Delete(ctx context.Context, id int64) error
+	Deliveries(ctx context.Context, f DeliveryFilter) ([]Delivery, error)
+	Redeliver(ctx context.Context, webhookID, deliveryID int64) error
+	// FanOut — создаёт доставки для необработанных событий outbox; возвращает число обработанных событий
+	FanOut(ctx context.Context, limit int) (int64, error)
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]PendingDelivery, error)
+	Finish(ctx context.Context, id int64, u DeliveryUpdate) error
+	PurgeOutbox(ctx context.Context, before time.Time) (int64, error)
+}
+
+// APIKeysRepository - ключи API; ключ ищется по хешу
+type APIKeysRepository interface {
+	Create(ctx context.Context, in APIKeyInput) (APIKey, error)
+	Get(ctx context.Context, id int64) (APIKey, error)
+	ByHash(ctx context.Context, hash []byte) (APIKey, error)
+	List(ctx context.Context) ([]APIKey, error)
+	Revoke(ctx context.Context, id int64) (APIKey, error)
+	Touch(ctx context.Context, id int64) error
+}
+
+// ACLRepository - выдачи ролей на контакты и теги; читающий берётся из WithViewer
+type ACLRepository interface {
+	Access(ctx context.Context, id int64) (ContactAccess, error)
+	ContactGrants(ctx context.Context, id int64) ([]Grant, error)
+	SetContactGrants(ctx context.Context, id int64, grants []Grant) error
+	TagGrants(ctx context.Context, tag string) ([]Grant, error)
+	SetTagGrants(ctx context.Context, tag string, grants []Grant) error
+}
+
+// AuditRepository - журнал аудита
+type AuditRepository interface {
+	Insert(ctx context.Context, entries []AuditEntry) error
+	List(ctx context.Context, f AuditFilter) ([]AuditEntry, error)
+}
+
+// AddressBooksRepository - адресные книги; книга запроса к контактам передаётся через WithAddressBook
+type AddressBooksRepository interface {
+	Create(ctx context.Context, in AddressBookInput) (AddressBook, error)
+	Get(ctx context.Context, id int64) (AddressBook, error)
+	BySlug(ctx context.Context, slug string) (AddressBook, error)
+	List(ctx context.Context) ([]AddressBook, error)
+	Update(ctx context.Context, id int64, p AddressBookPatch) (AddressBook, error)
 }
 
 type Repos struct {
-	Contacts ContactsRepository
+	Contacts      ContactsRepository
+	CustomFields  CustomFieldsRepository
+	Organizations OrganizationsRepository
+	Relations     RelationsRepository
+	Photos        PhotosRepository
+	Idempotency   IdempotencyRepository
+	Events        EventsRepository
+	Webhooks      WebhooksRepository
+	APIKeys       APIKeysRepository
+	AddressBooks  AddressBooksRepository
+	ACL           ACLRepository
+	Audit         AuditRepository
 }
 
 func New(pool *pgxpool.Pool) *Repos {
 	return &Repos{
-		Contacts: &contactRepo{pool: pool},
+		Contacts:      &contactRepo{pool: pool},
+		CustomFields:  &customFieldRepo{pool: pool},
+		Organizations: &organizationRepo{pool: pool},
+		Relations:     &relationRepo{pool: pool},
+		Photos:        &photoRepo{pool: pool},
+		Idempotency:   &idempotencyRepo{pool: pool},
+		Events:        &eventRepo{pool: pool},
+		Webhooks:      &webhookRepo{pool: pool},
+		APIKeys:       &apiKeyRepo{pool: pool},
+		AddressBooks:  &addressBookRepo{pool: pool},
+		ACL:           &aclRepo{pool: pool},
+		Audit:         &auditRepo{pool: pool},
 	}
 }
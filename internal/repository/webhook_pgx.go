@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type webhookRepo struct {
+	pool *pgxpool.Pool
+}
+
+const webhookCols = `id, url, secret, events, active, created_at, updated_at`
+
+func scanWebhook(row pgx.Row) (Webhook, error) {
+	var w Webhook
+	err := row.Scan(&w.ID, &w.URL, &w.Secret, &w.Events, &w.Active, &w.CreatedAt, &w.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Webhook{}, ErrNotFound
+	}
+	return w, err
+}
+
+func (r *webhookRepo) Create(ctx context.Context, in WebhookInput) (Webhook, error) {
+	if in.Events == nil {
+		in.Events = []string{}
+	}
+	return scanWebhook(r.pool.QueryRow(ctx,
+		`insert into webhooks(url, secret, events) values ($1, $2, $3) returning `+webhookCols,
+		in.URL, in.Secret, in.Events))
+}
+
+func (r *webhookRepo) Get(ctx context.Context, id int64) (Webhook, error) {
+	return scanWebhook(r.pool.QueryRow(ctx, `select `+webhookCols+` from webhooks where id=$1`, id))
+}
+
+func (r *webhookRepo) List(ctx context.Context) ([]Webhook, error) {
+	rows, err := r.pool.Query(ctx, `select `+webhookCols+` from webhooks order by id`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Webhook, error) { return scanWebhook(row) })
+}
+
+func (r *webhookRepo) Update(ctx context.Context, id int64, p WebhookPatch) (Webhook, error) {
+	set := make([]string, 0, 4)
+	args := []any{id}
+	add := func(col string, v any) {
+		args = append(args, v)
+		set = append(set, fmt.Sprintf("%s=$%d", col, len(args)))
+	}
+	if p.URL != nil {
+		add("url", *p.URL)
+	}
+	if p.Secret != nil {
+		add("secret", *p.Secret)
+	}
+	if p.Events != nil {
+		events := *p.Events
+		if events == nil {
+			events = []string{}
+		}
+		add("events", events)
+	}
+	if p.Active != nil {
+		add("active", *p.Active)
+	}
+	if len(set) == 0 {
+		return r.Get(ctx, id)
+	}
+	return scanWebhook(r.pool.QueryRow(ctx,
+		`update webhooks set `+strings.Join(set, ",")+` where id=$1 returning `+webhookCols, args...))
+}
+
+func (r *webhookRepo) Delete(ctx context.Context, id int64) error {
+	ct, err := r.pool.Exec(ctx, `delete from webhooks where id=$1`, id)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Deliveries — журнал доставок подписки, новые сверху; BeforeID — keyset-пагинация.
+func (r *webhookRepo) Deliveries(ctx context.Context, f DeliveryFilter) ([]Delivery, error) {
+	rows, err := r.pool.Query(ctx,
+		`select d.id, d.webhook_id, d.outbox_id, o.event_type, d.status, d.attempts, d.next_attempt_at,
+                coalesce(d.last_status, 0), coalesce(d.last_error, ''), d.created_at, d.delivered_at
+         from webhook_deliveries d
+         join outbox o on o.id = d.outbox_id
+         where d.webhook_id = $1 and ($2 = '' or d.status = $2) and ($3 = 0 or d.id < $3)
+         order by d.id desc
+         limit $4`,
+		f.WebhookID, f.Status, f.BeforeID, f.Limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Delivery, error) {
+		var d Delivery
+		err := row.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&d.LastStatus, &d.LastError, &d.CreatedAt, &d.DeliveredAt)
+		return d, err
+	})
+}
+
+// Redeliver — возвращает доставку (обычно из dead) в очередь с полным запасом попыток.
+func (r *webhookRepo) Redeliver(ctx context.Context, webhookID, deliveryID int64) error {
+	ct, err := r.pool.Exec(ctx,
+		`update webhook_deliveries set status='pending', attempts=0, next_attempt_at=now(), delivered_at=null
+         where id=$1 and webhook_id=$2`, deliveryID, webhookID)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// FanOut — раскладывает необработанные события outbox по активным подпискам одним запросом.
+// skip locked позволяет запускать несколько диспетчеров.
+func (r *webhookRepo) FanOut(ctx context.Context, limit int) (int64, error) {
+	ct, err := r.pool.Exec(ctx,
+		`with batch as (
+           select id, event_type from outbox where processed_at is null
+           order by id limit $1 for update skip locked
+         ), fan as (
+           insert into webhook_deliveries(webhook_id, outbox_id)
+           select w.id, b.id from batch b
+           join webhooks w on w.active and (cardinality(w.events) = 0 or b.event_type = any(w.events))
+           on conflict do nothing
+         )
+         update outbox set processed_at = now() where id in (select id from batch)`, limit)
+	if err != nil {
+		return 0, err
+	}
+	return ct.RowsAffected(), nil
+}
+
+// Claim — берёт готовые к отправке доставки: next_attempt_at сдвигается на lease, чтобы другой
+// диспетчер не взял их, пока идёт HTTP-запрос; если процесс упадёт, доставка вернётся после lease.
+func (r *webhookRepo) Claim(ctx context.Context, limit int, lease time.Duration) ([]PendingDelivery, error) {
+	rows, err := r.pool.Query(ctx,
+		`with due as (
+           select d.id from webhook_deliveries d
+           join webhooks w on w.id = d.webhook_id
+           where d.status = 'pending' and d.next_attempt_at <= now() and w.active
+           order by d.next_attempt_at, d.id
+           limit $1
+           for update of d skip locked
+         )
+         update webhook_deliveries d set next_attempt_at = $2, attempts = d.attempts + 1
+         from due, webhooks w, outbox o
+         where d.id = due.id and w.id = d.webhook_id and o.id = d.outbox_id
+         returning d.id, d.webhook_id, d.attempts, w.url, w.secret, o.event_type, o.payload`,
+		limit, time.Now().Add(lease))
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (PendingDelivery, error) {
+		var p PendingDelivery
+		err := row.Scan(&p.ID, &p.WebhookID, &p.Attempts, &p.URL, &p.Secret, &p.EventType, &p.Payload)
+		return p, err
+	})
+}
+
+func (r *webhookRepo) Finish(ctx context.Context, id int64, u DeliveryUpdate) error {
+	_, err := r.pool.Exec(ctx,
+		`update webhook_deliveries set status=$2, next_attempt_at=$3, last_status=nullif($4, 0), last_error=nullif($5, ''),
+                delivered_at = case when $2 = 'delivered' then now() end
+         where id=$1`,
+		id, u.Status, u.NextAttemptAt, u.LastStatus, u.LastError)
+	return err
+}
+
+// PurgeOutbox — удаляет обработанные события старше before вместе с журналом доставок,
+// кроме тех, что ещё ждут отправки.
+func (r *webhookRepo) PurgeOutbox(ctx context.Context, before time.Time) (int64, error) {
+	ct, err := r.pool.Exec(ctx,
+		`delete from outbox o where o.processed_at < $1
+         and not exists (select 1 from webhook_deliveries d where d.outbox_id = o.id and d.status = 'pending')`, before)
+	if err != nil {
+		return 0, err
+	}
+	return ct.RowsAffected(), nil
+}
@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/sunzhqr/phonebook/internal/repository"
+)
+
+// Роли на контактах. В книге роль задаётся областью доступа: contacts:read — viewer, contacts:write —
+// editor, admin — admin. Выдачи на контакт или тег ограничивают круг тех, кто контакт видит, но не
+// расширяют область: viewer с выдачей editor всё равно не пишет.
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+var roleRank = map[string]int{RoleViewer: 1, RoleEditor: 2, RoleAdmin: 3}
+
+// тег попадает в query-параметр и путь /tags/{tag}/acl
+var tagRe = regexp.MustCompile(`^[\p{L}\p{N}][\p{L}\p{N}_.-]{0,39}$`)
+
+// principalRe — user:<sub>, apikey:<id> или group:<имя>
+var principalRe = regexp.MustCompile(`^(user|apikey|group):\S{1,200}$`)
+
+var errForbidden = &Error{Code: http.StatusForbidden, Message: "insufficient role on contact"}
+
+// BookRole — роль в книге по областям доступа; пустая — доступа к контактам нет
+func (p Principal) BookRole() string {
+	switch {
+	case p.Allows(ScopeAdmin):
+		return RoleAdmin
+	case p.Allows(ScopeContactsWrite):
+		return RoleEditor
+	case p.Allows(ScopeContactsRead):
+		return RoleViewer
+	}
+	return ""
+}
+
+// principals — идентификаторы, по которым сверяются выдачи: сам субъект и его группы
+func (p Principal) principals() []string {
+	out := make([]string, 0, 1+len(p.Groups))
+	if p.Subject != "" {
+		out = append(out, p.Subject)
+	}
+	for _, g := range p.Groups {
+		out = append(out, "group:"+g)
+	}
+	return out
+}
+
+// viewer — как репозиторий отбирает контакты для p: администратор книги видит все
+func (p Principal) viewer() repository.Viewer {
+	return repository.Viewer{All: p.BookRole() == RoleAdmin, Principals: p.principals(), Phones: p.phoneLevels()}
+}
+
+// authorize — может ли p выполнить действие с ролью need над видимым ему контактом с доступом a.
+// На контакте без выдач действует роль в книге; с выдачами — младшая из роли в книге и роли по выдачам,
+// а администратор книги — admin везде.
+func authorize(p Principal, a repository.ContactAccess, need string) error {
+	role := p.BookRole()
+	if a.Restricted && role != RoleAdmin && roleRank[a.Role] < roleRank[role] {
+		role = a.Role
+	}
+	// владелец или получивший admin на контакт управляет доступом к нему, если может писать в книге
+	if need == RoleAdmin && a.Role == RoleAdmin && roleRank[p.BookRole()] >= roleRank[RoleEditor] {
+		role = RoleAdmin
+	}
+	if roleRank[role] < roleRank[need] {
+		return errForbidden
+	}
+	return nil
+}
+
+// requireRole — невидимый контакт — 404, как несуществующий; видимый без нужной роли — 403.
+// Вызовы без субъекта (CLI, фоновые задачи) видят только открытые контакты, и роль для них не проверяется.
+func (s *Service) requireRole(ctx context.Context, id int64, need string) error {
+	a, err := s.acl.Access(ctx, id)
+	if err != nil {
+		return s.repoErr(err)
+	}
+	p, ok := PrincipalFrom(ctx)
+	if !ok {
+		return nil
+	}
+	return authorize(p, a, need)
+}
+
+// requireTagsRole — теги несут выдачи: смена набора тегов открывает или закрывает контакт, поэтому
+// требует той же роли admin на контакте, что и правка его выдач. Прежний набор менять не нужно.
+func (s *Service) requireTagsRole(ctx context.Context, cur repository.Contact, tags []string) error {
+	was := slices.Clone(cur.Tags)
+	slices.Sort(was)
+	if slices.Equal(slices.Compact(was), tags) {
+		return nil
+	}
+	return s.requireRole(ctx, cur.ID, RoleAdmin)
+}
+
+// requireTagsPatch — requireTagsRole для правки: без tags в патче теги не меняются
+func (s *Service) requireTagsPatch(ctx context.Context, id int64, tags *[]string) error {
+	if tags == nil {
+		return nil
+	}
+	cur, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return s.repoErr(err)
+	}
+	return s.requireTagsRole(ctx, cur, *tags)
+}
+
+// owner — владелец нового контакта
+func owner(ctx context.Context) string {
+	p, _ := PrincipalFrom(ctx)
+	return p.Subject
+}
+
+// normalizeTags — нижний регистр, без дубликатов, в порядке сортировки
+func normalizeTags(in []string) ([]string, error) {
+	out := make([]string, 0, len(in))
+	for _, t := range in {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if !tagRe.MatchString(t) {
+			return nil, &Error{Code: http.StatusUnprocessableEntity, Message: "invalid tag: " + t}
+		}
+		out = append(out, t)
+	}
+	slices.Sort(out)
+	return slices.Compact(out), nil
+}
+
+func normalizeGrants(in []GrantIn) ([]repository.Grant, error) {
+	out := make([]repository.Grant, 0, len(in))
+	seen := make(map[string]bool, len(in))
+	for _, g := range in {
+		principal := strings.TrimSpace(g.Principal)
+		if !principalRe.MatchString(principal) {
+			return nil, &Error{Code: http.StatusUnprocessableEntity, Message: "invalid principal, expected user:<sub>, apikey:<id> or group:<name>"}
+		}
+		if _, ok := roleRank[g.Role]; !ok {
+			return nil, &Error{Code: http.StatusUnprocessableEntity, Message: "invalid role, expected viewer, editor or admin"}
+		}
+		if seen[principal] {
+			return nil, &Error{Code: http.StatusUnprocessableEntity, Message: "duplicate principal: " + principal}
+		}
+		seen[principal] = true
+		out = append(out, repository.Grant{Principal: principal, Role: g.Role})
+	}
+	return out, nil
+}
+
+func toGrantsOut(in []repository.Grant) []GrantOut {
+	out := make([]GrantOut, 0, len(in))
+	for _, g := range in {
+		out = append(out, GrantOut{Principal: g.Principal, Role: g.Role})
+	}
+	return out
+}
+
+// GetContactACL — выдачи контакта видит тот, кто может ими управлять
+func (s *Service) GetContactACL(ctx context.Context, id int64) (ACLOut, error) {
+	if err := s.requireRole(ctx, id, RoleAdmin); err != nil {
+		return ACLOut{}, err
+	}
+	grants, err := s.acl.ContactGrants(ctx, id)
+	if err != nil {
+		return ACLOut{}, s.repoErr(err)
+	}
+	s.record(ctx, AuditACLRead, id)
+	return ACLOut{Grants: toGrantsOut(grants)}, nil
+}
+
+// SetContactACL — заменяет выдачи контакта; пустой список снова открывает контакт всей книге
+func (s *Service) SetContactACL(ctx context.Context, id int64, in ACLIn) (ACLOut, error) {
+	grants, err := normalizeGrants(in.Grants)
+	if err != nil {
+		return ACLOut{}, err
+	}
+	if err := s.requireRole(ctx, id, RoleAdmin); err != nil {
+		return ACLOut{}, err
+	}
+	if err := s.acl.SetContactGrants(ctx, id, grants); err != nil {
+		return ACLOut{}, s.repoErr(err)
+	}
+	s.record(ctx, AuditACLUpdate, id)
+	return ACLOut{Grants: toGrantsOut(grants)}, nil
+}
+
+func (s *Service) GetTagACL(ctx context.Context, tag string) (ACLOut, error) {
+	tags, err := normalizeTags([]string{tag})
+	if err != nil {
+		return ACLOut{}, err
+	}
+	grants, err := s.acl.TagGrants(ctx, tags[0])
+	if err != nil {
+		return ACLOut{}, s.repoErr(err)
+	}
+	return ACLOut{Grants: toGrantsOut(grants)}, nil
+}
+
+// SetTagACL — выдачи на тег действуют на все контакты книги с этим тегом; только администратору книги
+func (s *Service) SetTagACL(ctx context.Context, tag string, in ACLIn) (ACLOut, error) {
+	if p, _ := PrincipalFrom(ctx); p.BookRole() != RoleAdmin {
+		return ACLOut{}, &Error{Code: http.StatusForbidden, Message: "admin role required"}
+	}
+	tags, err := normalizeTags([]string{tag})
+	if err != nil {
+		return ACLOut{}, err
+	}
+	grants, err := normalizeGrants(in.Grants)
+	if err != nil {
+		return ACLOut{}, err
+	}
+	if err := s.acl.SetTagGrants(ctx, tags[0], grants); err != nil {
+		return ACLOut{}, s.repoErr(err)
+	}
+	return ACLOut{Grants: toGrantsOut(grants)}, nil
+}
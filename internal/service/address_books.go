@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/sunzhqr/phonebook/internal/repository"
+	"github.com/sunzhqr/phonebook/pkg/normalizer"
+)
+
+// slug книги передаётся в заголовке X-Address-Book и claim токена
+var bookSlugRe = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+type addressBookKey struct{}
+
+// WithAddressBook — запрос работает с контактами книги b; номера без кода страны читаются по её региону
+func WithAddressBook(ctx context.Context, b AddressBookOut) context.Context {
+	return context.WithValue(repository.WithAddressBook(ctx, b.ID), addressBookKey{}, b)
+}
+
+// AddressBookFrom — ok=false у запросов без выбранной книги (работают с книгой по умолчанию)
+func AddressBookFrom(ctx context.Context) (AddressBookOut, bool) {
+	b, ok := ctx.Value(addressBookKey{}).(AddressBookOut)
+	return b, ok
+}
+
+// regionOf — регион номеров книги запроса
+func regionOf(ctx context.Context) string {
+	b, _ := AddressBookFrom(ctx)
+	return b.DefaultRegion
+}
+
+func (s *Service) ResolveAddressBook(ctx context.Context, slug string) (AddressBookOut, error) {
+	if !bookSlugRe.MatchString(slug) {
+		return AddressBookOut{}, &Error{Code: http.StatusNotFound, Message: "address book not found"}
+	}
+	b, err := s.books.BySlug(ctx, slug)
+	if err != nil {
+		return AddressBookOut{}, s.bookErr(err)
+	}
+	return toAddressBookOut(b), nil
+}
+
+// errBoundPrincipal — ключ или токен, привязанный к книге, не управляет книгами
+var errBoundPrincipal = &Error{Code: http.StatusForbidden, Message: "credentials are bound to a single address book"}
+
+// boundTo — книга, которой ограничен доступ вызывающего; пустая — не ограничен
+func boundTo(ctx context.Context) string {
+	p, _ := PrincipalFrom(ctx)
+	return p.AddressBook
+}
+
+func (s *Service) CreateAddressBook(ctx context.Context, in AddressBookIn) (AddressBookOut, error) {
+	if boundTo(ctx) != "" {
+		return AddressBookOut{}, errBoundPrincipal
+	}
+	if err := s.v.Struct(in); err != nil {
+		return AddressBookOut{}, &Error{Code: http.StatusUnprocessableEntity, Message: err.Error()}
+	}
+	if !bookSlugRe.MatchString(in.Slug) {
+		return AddressBookOut{}, &Error{Code: http.StatusUnprocessableEntity, Message: "invalid slug"}
+	}
+	region, err := checkRegion(in.DefaultRegion)
+	if err != nil {
+		return AddressBookOut{}, err
+	}
+	b, err := s.books.Create(ctx, repository.AddressBookInput{
+		Slug:          in.Slug,
+		Name:          strings.TrimSpace(in.Name),
+		DefaultRegion: region,
+	})
+	if err != nil {
+		return AddressBookOut{}, s.repoErr(err)
+	}
+	return toAddressBookOut(b), nil
+}
+
+func (s *Service) ListAddressBooks(ctx context.Context) ([]AddressBookOut, error) {
+	list, err := s.books.List(ctx)
+	if err != nil {
+		return nil, s.repoErr(err)
+	}
+	bound := boundTo(ctx)
+	out := make([]AddressBookOut, 0, len(list))
+	for _, b := range list {
+		if bound != "" && b.Slug != bound {
+			continue
+		}
+		out = append(out, toAddressBookOut(b))
+	}
+	return out, nil
+}
+
+// UpdateAddressBook — смена региона не переписывает уже сохранённые номера
+func (s *Service) UpdateAddressBook(ctx context.Context, slug string, in AddressBookUpdateIn) (AddressBookOut, error) {
+	if boundTo(ctx) != "" {
+		return AddressBookOut{}, errBoundPrincipal
+	}
+	if err := s.v.Struct(in); err != nil {
+		return AddressBookOut{}, &Error{Code: http.StatusUnprocessableEntity, Message: err.Error()}
+	}
+	b, err := s.ResolveAddressBook(ctx, slug)
+	if err != nil {
+		return AddressBookOut{}, err
+	}
+	p := repository.AddressBookPatch{}
+	if in.Name != nil {
+		name := strings.TrimSpace(*in.Name)
+		p.Name = &name
+	}
+	if in.DefaultRegion != nil {
+		region, err := checkRegion(*in.DefaultRegion)
+		if err != nil {
+			return AddressBookOut{}, err
+		}
+		p.DefaultRegion = &region
+	}
+	u, err := s.books.Update(ctx, b.ID, p)
+	if err != nil {
+		return AddressBookOut{}, s.bookErr(err)
+	}
+	return toAddressBookOut(u), nil
+}
+
+func (s *Service) bookErr(err error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return &Error{Code: http.StatusNotFound, Message: "address book not found"}
+	}
+	return s.repoErr(err)
+}
+
+// checkRegion — регион из таблицы правил набора номера; пустой — номера только с кодом страны
+func checkRegion(region string) (string, error) {
+	region = strings.ToUpper(strings.TrimSpace(region))
+	if region != "" && !normalizer.IsRegion(region) {
+		return "", &Error{Code: http.StatusUnprocessableEntity, Message: "unsupported default_region"}
+	}
+	return region, nil
+}
+
+func toAddressBookOut(b repository.AddressBook) AddressBookOut {
+	return AddressBookOut{ID: b.ID, Slug: b.Slug, Name: b.Name, DefaultRegion: b.DefaultRegion,
+		CreatedAt: b.CreatedAt, UpdatedAt: b.UpdatedAt}
+}
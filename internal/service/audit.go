@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/sunzhqr/phonebook/internal/repository"
+)
+
+// Действия в журнале аудита
+const (
+	AuditContactCreate = "contact.create"
+	AuditContactRead   = "contact.read"
+	AuditContactUpdate = "contact.update"
+	AuditContactDelete = "contact.delete"
+	AuditContactList   = "contact.list"
+	AuditContactSearch = "contact.search"
+	AuditContactExport = "contact.export"
+	AuditContactImport = "contact.import"
+	AuditContactSync   = "contact.sync"
+	AuditContactBatch  = "contact.batch"
+	AuditPhotoRead     = "photo.read"
+	AuditPhotoUpdate   = "photo.update"
+	AuditPhotoDelete   = "photo.delete"
+	AuditRelationRead  = "relation.read"
+	AuditRelationWrite = "relation.update"
+	AuditACLRead       = "acl.read"
+	AuditACLUpdate     = "acl.update"
+)
+
+// RequestInfo — откуда пришёл запрос: id из заголовка X-Request-ID ответа и адрес клиента
+type RequestInfo struct {
+	ID string
+	IP string
+}
+
+type requestInfoKey struct{}
+
+func WithRequestInfo(ctx context.Context, ri RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, ri)
+}
+
+func requestInfoFrom(ctx context.Context) RequestInfo {
+	ri, _ := ctx.Value(requestInfoKey{}).(RequestInfo)
+	return ri
+}
+
+// SetAuditSink — куда писать аудит; задаётся до запуска серверов
+func (s *Service) SetAuditSink(a AuditSink) {
+	s.audit = a
+}
+
+// record — успешный вызов в журнал аудита: кто, что и с какими контактами
+func (s *Service) record(ctx context.Context, action string, ids ...int64) {
+	if s.audit == nil {
+		return
+	}
+	p, _ := PrincipalFrom(ctx)
+	ri := requestInfoFrom(ctx)
+	book := repository.DefaultAddressBookID
+	if b, ok := AddressBookFrom(ctx); ok {
+		book = b.ID
+	}
+	s.audit.Record(AuditEntry{At: time.Now().UTC(), AddressBookID: book, Actor: p.Subject, Action: action,
+		ContactIDs: ids, RequestID: ri.ID, IP: ri.IP})
+}
+
+// contactIDs — id выданных вызывающему контактов
+func contactIDs(list []ContactOut) []int64 {
+	ids := make([]int64, 0, len(list))
+	for _, c := range list {
+		ids = append(ids, c.ID)
+	}
+	return ids
+}
+
+// WriteAudit — пачка записей в БД; вызывается приёмником аудита
+func (s *Service) WriteAudit(ctx context.Context, entries []AuditEntry) error {
+	rows := make([]repository.AuditEntry, 0, len(entries))
+	for _, e := range entries {
+		rows = append(rows, repository.AuditEntry{At: e.At, AddressBookID: e.AddressBookID, Actor: e.Actor,
+			Action: e.Action, ContactIDs: e.ContactIDs, RequestID: e.RequestID, IP: e.IP})
+	}
+	return s.audits.Insert(ctx, rows)
+}
+
+// ListAudit — журнал книги запроса, новые сверху
+func (s *Service) ListAudit(ctx context.Context, f AuditFilter) ([]AuditEntry, error) {
+	if f.Limit <= 0 || f.Limit > 500 {
+		f.Limit = 50
+	}
+	if !f.Since.IsZero() && !f.Until.IsZero() && !f.Since.Before(f.Until) {
+		return nil, &Error{Code: http.StatusBadRequest, Message: "since must be before until"}
+	}
+	list, err := s.audits.List(ctx, repository.AuditFilter{Actor: f.Actor, Action: f.Action, ContactID: f.ContactID,
+		Since: f.Since, Until: f.Until, BeforeID: f.BeforeID, Limit: f.Limit})
+	if err != nil {
+		return nil, s.repoErr(err)
+	}
+	out := make([]AuditEntry, 0, len(list))
+	for _, e := range list {
+		out = append(out, AuditEntry{ID: e.ID, At: e.At, AddressBookID: e.AddressBookID, Actor: e.Actor,
+			Action: e.Action, ContactIDs: e.ContactIDs, RequestID: e.RequestID, IP: e.IP})
+	}
+	return out, nil
+}
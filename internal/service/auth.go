@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/repository"
+)
+
+const (
+	// apiKeyPrefix — ключи узнаваемы в конфигах и сканерами секретов
+	apiKeyPrefix = "pb_"
+	// apiKeyShownLen — сколько первых символов ключа хранится открыто для списка ключей
+	apiKeyShownLen = len(apiKeyPrefix) + 8
+	apiKeyBytes    = 32
+)
+
+var errUnauthenticated = &Error{Code: http.StatusUnauthorized, Message: "invalid or expired credentials"}
+
+// Principal — тот, от чьего имени выполняется запрос
+type Principal struct {
+	Subject string // "apikey:<id>"
+	Name    string // имя ключа
+	Scopes  []string
+	// AddressBook — slug книги, которой ограничен доступ; пустой — книга выбирается запросом
+	AddressBook string
+	// Groups — группы (роли SSO), по которым действуют выдачи group:<имя> на контакты и теги
+	Groups []string
+}
+
+// Allows — admin разрешает всё, contacts:write включает contacts:read
+func (p Principal) Allows(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope || s == ScopeAdmin || (s == ScopeContactsWrite && scope == ScopeContactsRead) {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// WithPrincipal — запрос от имени p: контакты с ограниченным доступом отбираются по его выдачам
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(repository.WithViewer(ctx, p.viewer()), principalKey{}, p)
+}
+
+// PrincipalFrom — ok=false, если запрос не аутентифицирован
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// Authenticate — проверяет ключ API; отозванный, истёкший и неизвестный ключ — 401
+func (s *Service) Authenticate(ctx context.Context, key string) (Principal, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) || base64.RawURLEncoding.DecodedLen(len(key)-len(apiKeyPrefix)) != apiKeyBytes {
+		return Principal{}, errUnauthenticated
+	}
+	sum := sha256.Sum256([]byte(key))
+	k, err := s.keys.ByHash(ctx, sum[:])
+	if errors.Is(err, repository.ErrNotFound) {
+		return Principal{}, errUnauthenticated
+	}
+	if err != nil {
+		return Principal{}, s.repoErr(err)
+	}
+	if k.RevokedAt != nil || (k.ExpiresAt != nil && !k.ExpiresAt.After(time.Now())) {
+		return Principal{}, errUnauthenticated
+	}
+	if err := s.keys.Touch(ctx, k.ID); err != nil {
+		s.lg.Warn("api key touch failed", logger.KV("key_id", k.ID), logger.Err(err))
+	}
+	return Principal{Subject: "apikey:" + strconv.FormatInt(k.ID, 10), Name: k.Name, Scopes: k.Scopes,
+		AddressBook: k.AddressBook}, nil
+}
+
+// CreateAPIKey — выпускает ключ; сам ключ есть только в этом ответе, в БД хранится его хеш
+func (s *Service) CreateAPIKey(ctx context.Context, in APIKeyCreateIn) (APIKeyOut, error) {
+	if err := s.v.Struct(in); err != nil {
+		return APIKeyOut{}, &Error{Code: http.StatusUnprocessableEntity, Message: err.Error()}
+	}
+	if in.ExpiresAt != nil && !in.ExpiresAt.After(time.Now()) {
+		return APIKeyOut{}, &Error{Code: http.StatusUnprocessableEntity, Message: "expires_at must be in the future"}
+	}
+	// привязанный к книге администратор выпускает ключи только для своей книги
+	if bound := boundTo(ctx); bound != "" {
+		if in.AddressBook != "" && in.AddressBook != bound {
+			return APIKeyOut{}, errBoundPrincipal
+		}
+		in.AddressBook = bound
+	}
+	var book *int64
+	if in.AddressBook != "" {
+		b, err := s.books.BySlug(ctx, in.AddressBook)
+		if errors.Is(err, repository.ErrNotFound) {
+			return APIKeyOut{}, &Error{Code: http.StatusUnprocessableEntity, Message: "unknown address book"}
+		}
+		if err != nil {
+			return APIKeyOut{}, s.repoErr(err)
+		}
+		book = &b.ID
+	}
+	var b [apiKeyBytes]byte
+	_, _ = rand.Read(b[:])
+	key := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b[:])
+	sum := sha256.Sum256([]byte(key))
+
+	scopes := slices.Clone(in.Scopes)
+	slices.Sort(scopes)
+	k, err := s.keys.Create(ctx, repository.APIKeyInput{
+		Name:          strings.TrimSpace(in.Name),
+		Prefix:        key[:apiKeyShownLen],
+		KeyHash:       sum[:],
+		Scopes:        slices.Compact(scopes),
+		ExpiresAt:     in.ExpiresAt,
+		AddressBookID: book,
+	})
+	if err != nil {
+		return APIKeyOut{}, s.repoErr(err)
+	}
+	out := toAPIKeyOut(k)
+	out.Key = key
+	return out, nil
+}
+
+// GetAPIKey — ключи чужих книг для привязанного к книге администратора не существуют
+func (s *Service) GetAPIKey(ctx context.Context, id int64) (APIKeyOut, error) {
+	k, err := s.keys.Get(ctx, id)
+	if err != nil {
+		return APIKeyOut{}, s.repoErr(err)
+	}
+	if bound := boundTo(ctx); bound != "" && k.AddressBook != bound {
+		return APIKeyOut{}, s.repoErr(repository.ErrNotFound)
+	}
+	return toAPIKeyOut(k), nil
+}
+
+func (s *Service) ListAPIKeys(ctx context.Context) ([]APIKeyOut, error) {
+	list, err := s.keys.List(ctx)
+	if err != nil {
+		return nil, s.repoErr(err)
+	}
+	bound := boundTo(ctx)
+	out := make([]APIKeyOut, 0, len(list))
+	for _, k := range list {
+		if bound != "" && k.AddressBook != bound {
+			continue
+		}
+		out = append(out, toAPIKeyOut(k))
+	}
+	return out, nil
+}
+
+// RevokeAPIKey — ключ перестаёт действовать сразу, запись остаётся в списке
+func (s *Service) RevokeAPIKey(ctx context.Context, id int64) (APIKeyOut, error) {
+	if _, err := s.GetAPIKey(ctx, id); err != nil {
+		return APIKeyOut{}, err
+	}
+	k, err := s.keys.Revoke(ctx, id)
+	if err != nil {
+		return APIKeyOut{}, s.repoErr(err)
+	}
+	return toAPIKeyOut(k), nil
+}
+
+func toAPIKeyOut(k repository.APIKey) APIKeyOut {
+	return APIKeyOut{ID: k.ID, Name: k.Name, Prefix: k.Prefix, Scopes: k.Scopes, CreatedAt: k.CreatedAt,
+		ExpiresAt: k.ExpiresAt, LastUsedAt: k.LastUsedAt, RevokedAt: k.RevokedAt, AddressBook: k.AddressBook}
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/sunzhqr/phonebook/internal/repository"
+)
+
+// MaxBatchOps — предел операций в одном пакете
+const MaxBatchOps = 1000
+
+const (
+	BatchCreate = repository.BatchCreate
+	BatchUpdate = repository.BatchUpdate
+	BatchDelete = repository.BatchDelete
+)
+
+// Batch — пакет create/update/delete в одной транзакции. Каждая операция проходит ту же валидацию,
+// что и одиночный запрос. atomic: любая ошибка откатывает всё, а прочие операции получают 424;
+// иначе применяются все успешные. Результаты — в порядке входа.
+func (s *Service) Batch(ctx context.Context, ops []BatchOpIn, atomic bool) (BatchOut, error) {
+	if len(ops) == 0 {
+		return BatchOut{}, &Error{Code: http.StatusUnprocessableEntity, Message: "no operations"}
+	}
+	if len(ops) > MaxBatchOps {
+		return BatchOut{}, &Error{Code: http.StatusUnprocessableEntity, Message: fmt.Sprintf("too many operations, max %d", MaxBatchOps)}
+	}
+
+	out := BatchOut{Atomic: atomic, Results: make([]BatchItemOut, len(ops))}
+	repoOps := make([]repository.BatchOp, 0, len(ops))
+	pos := make([]int, 0, len(ops)) // индекс repoOps -> индекс во входе
+	failed := false
+	for i, op := range ops {
+		out.Results[i].Index = i
+		rop, err := s.prepareBatchOp(ctx, op)
+		if err != nil {
+			var se *Error
+			if !errors.As(err, &se) || se.Code >= http.StatusInternalServerError {
+				return BatchOut{}, err
+			}
+			out.Results[i].Status, out.Results[i].Error = se.Code, se.Message
+			failed = true
+			continue
+		}
+		repoOps = append(repoOps, rop)
+		pos = append(pos, i)
+	}
+	if failed && atomic {
+		return rolledBack(out), nil
+	}
+
+	if len(repoOps) > 0 {
+		res, err := s.repo.Batch(ctx, repoOps, atomic)
+		if err != nil {
+			return BatchOut{}, s.repoErr(err)
+		}
+		for k, r := range res {
+			item := &out.Results[pos[k]]
+			if r.Err != nil {
+				se := s.repoErr(r.Err).(*Error)
+				item.Status, item.Error = se.Code, se.Message
+				failed = true
+				continue
+			}
+			item.ID = r.ID
+			switch repoOps[k].Kind {
+			case BatchCreate:
+				item.Status = http.StatusCreated
+			case BatchUpdate:
+				item.Status = http.StatusOK
+			case BatchDelete:
+				item.Status = http.StatusNoContent
+			}
+		}
+	}
+	if failed && atomic {
+		return rolledBack(out), nil
+	}
+
+	out.Applied = true
+	ids := make([]int64, 0, len(repoOps))
+	for k, rop := range repoOps {
+		if rop.Kind == BatchDelete && out.Results[pos[k]].Status == http.StatusNoContent {
+			s.dropPhotoBlobs(ctx, rop.ID)
+		}
+		if out.Results[pos[k]].Error == "" {
+			ids = append(ids, out.Results[pos[k]].ID)
+		}
+	}
+	s.record(ctx, AuditContactBatch, ids...)
+	return out, nil
+}
+
+func (s *Service) prepareBatchOp(ctx context.Context, op BatchOpIn) (repository.BatchOp, error) {
+	switch op.Op {
+	case BatchCreate:
+		ci, err := s.prepareContact(ctx, op.Create)
+		return repository.BatchOp{Kind: BatchCreate, Create: ci}, err
+	case BatchUpdate, BatchDelete:
+		if op.ID <= 0 {
+			return repository.BatchOp{}, &Error{Code: http.StatusBadRequest, Message: "bad id"}
+		}
+		if err := s.requireRole(ctx, op.ID, RoleEditor); err != nil {
+			return repository.BatchOp{}, err
+		}
+		if op.Op == BatchDelete {
+			return repository.BatchOp{Kind: BatchDelete, ID: op.ID}, nil
+		}
+		patch, err := s.preparePatch(ctx, op.Update)
+		if err == nil {
+			err = s.requireTagsPatch(ctx, op.ID, patch.Tags)
+		}
+		if err == nil {
+			err = s.keepHiddenPhones(ctx, op.ID, &patch)
+		}
+		return repository.BatchOp{Kind: BatchUpdate, ID: op.ID, Patch: patch}, err
+	}
+	return repository.BatchOp{}, &Error{Code: http.StatusBadRequest, Message: "unknown op, expected create, update or delete"}
+}
+
+// rolledBack — в откаченном атомарном пакете успешные по отдельности операции не применены.
+func rolledBack(out BatchOut) BatchOut {
+	for i := range out.Results {
+		if r := &out.Results[i]; r.Error == "" {
+			r.Status, r.ID, r.Error = http.StatusFailedDependency, 0, "not applied: batch rolled back"
+		}
+	}
+	return out
+}
@@ -0,0 +1,269 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/sunzhqr/phonebook/internal/repository"
+	"github.com/sunzhqr/phonebook/pkg/normalizer"
+)
+
+// csvRow — строка CSV, уже переложенная в ContactCreateIn; err — ошибка разбора самой строки.
+type csvRow struct {
+	line   int
+	in     ContactCreateIn
+	digits []string
+	err    error
+}
+
+// ImportCSV — импорт CSV по карте колонок. Каждая строка проходит ту же валидацию и нормализацию,
+// что и CreateContact. Строка с номером, который уже есть в книге или встречался выше в файле,
+// пропускается как дубликат. При dryRun ничего не пишется, отчёт тот же.
+func (s *Service) ImportCSV(ctx context.Context, r io.Reader, m CSVMapping, dryRun bool) (ImportReport, error) {
+	if err := s.v.Struct(m); err != nil {
+		return ImportReport{}, &Error{Code: http.StatusUnprocessableEntity, Message: err.Error()}
+	}
+	rows, err := s.readCSV(ctx, r, m)
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	var all []string
+	for _, row := range rows {
+		all = append(all, row.digits...)
+	}
+	owners, err := s.repo.PhoneOwners(ctx, all)
+	if err != nil {
+		return ImportReport{}, s.repoErr(err)
+	}
+
+	rep := ImportReport{DryRun: dryRun, Rows: make([]ImportRowOut, 0, len(rows))}
+	seen := make(map[string]int) // digits -> строка файла, в которой номер уже импортирован
+	for _, row := range rows {
+		out := ImportRowOut{Row: row.line}
+		if row.err != nil {
+			out.Status, out.Error = ImportInvalid, row.err.Error()
+			rep.Invalid++
+			rep.Rows = append(rep.Rows, out)
+			continue
+		}
+		if reason := duplicateReason(row.digits, owners, seen); reason != "" {
+			out.Status, out.Error = ImportSkipped, reason
+			rep.Skipped++
+			rep.Rows = append(rep.Rows, out)
+			continue
+		}
+
+		id, err := s.importRow(ctx, row.in, dryRun)
+		if err != nil {
+			var se *Error
+			if !errors.As(err, &se) || se.Code >= http.StatusInternalServerError {
+				return ImportReport{}, err
+			}
+			out.Status, out.Error = ImportInvalid, se.Message
+			rep.Invalid++
+			rep.Rows = append(rep.Rows, out)
+			continue
+		}
+		for _, d := range row.digits {
+			seen[d] = row.line
+		}
+		out.Status, out.ID = ImportCreated, id
+		rep.Created++
+		rep.Rows = append(rep.Rows, out)
+	}
+	return rep, nil
+}
+
+func (s *Service) importRow(ctx context.Context, in ContactCreateIn, dryRun bool) (int64, error) {
+	ci, err := s.prepareContact(ctx, in)
+	if err != nil || dryRun {
+		return 0, err
+	}
+	c, err := s.repo.Create(ctx, ci)
+	if err != nil {
+		return 0, s.repoErr(err)
+	}
+	s.record(ctx, AuditContactImport, c.ID)
+	return c.ID, nil
+}
+
+func duplicateReason(digits []string, owners map[string]int64, seen map[string]int) string {
+	for _, d := range digits {
+		if id, ok := owners[d]; ok {
+			return fmt.Sprintf("phone %s already belongs to contact %d", d, id)
+		}
+		if line, ok := seen[d]; ok {
+			return fmt.Sprintf("phone %s duplicates row %d", d, line)
+		}
+	}
+	return ""
+}
+
+// readCSV — читает заголовок, сверяет с ним карту колонок и раскладывает строки по полям контакта.
+func (s *Service) readCSV(ctx context.Context, r io.Reader, m CSVMapping) ([]csvRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	if m.Delimiter != "" {
+		cr.Comma = []rune(m.Delimiter)[0]
+	}
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, &Error{Code: http.StatusUnprocessableEntity, Message: "cannot read csv header"}
+	}
+	cols := make(map[string]int, len(header))
+	for i, h := range header {
+		if i == 0 {
+			h = strings.TrimPrefix(h, "\ufeff") // BOM из Excel
+		}
+		cols[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	idx := func(name string) (int, error) {
+		if name == "" {
+			return -1, nil
+		}
+		i, ok := cols[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return 0, &Error{Code: http.StatusUnprocessableEntity, Message: "unknown column: " + name}
+		}
+		return i, nil
+	}
+
+	var plan struct {
+		first, last, company, title, dept int
+		phones, emails                    []int
+		custom                            map[string]int
+	}
+	for _, f := range []struct {
+		dst  *int
+		name string
+	}{
+		{&plan.first, m.FirstName}, {&plan.last, m.LastName}, {&plan.company, m.Company},
+		{&plan.title, m.JobTitle}, {&plan.dept, m.Department},
+	} {
+		if *f.dst, err = idx(f.name); err != nil {
+			return nil, err
+		}
+	}
+	for _, c := range m.Phones {
+		i, err := idx(c.Column)
+		if err != nil {
+			return nil, err
+		}
+		plan.phones = append(plan.phones, i)
+	}
+	for _, c := range m.Emails {
+		i, err := idx(c.Column)
+		if err != nil {
+			return nil, err
+		}
+		plan.emails = append(plan.emails, i)
+	}
+
+	var reg map[string]repository.CustomField
+	if len(m.Custom) > 0 {
+		if reg, err = s.registry(ctx); err != nil {
+			return nil, err
+		}
+		plan.custom = make(map[string]int, len(m.Custom))
+		for name, col := range m.Custom {
+			if _, ok := reg[name]; !ok {
+				return nil, &Error{Code: http.StatusUnprocessableEntity, Message: "unknown custom field: " + name}
+			}
+			if plan.custom[name], err = idx(col); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var rows []csvRow
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		line, _ := cr.FieldPos(0)
+		var pe *csv.ParseError
+		if errors.As(err, &pe) {
+			rows = append(rows, csvRow{line: pe.StartLine, err: errors.New("malformed csv row")})
+			continue
+		}
+		if err != nil {
+			return nil, &Error{Code: http.StatusBadRequest, Message: "cannot read csv"}
+		}
+		cell := func(i int) string {
+			if i < 0 || i >= len(rec) {
+				return ""
+			}
+			return strings.TrimSpace(rec[i])
+		}
+
+		row := csvRow{line: line}
+		row.in = ContactCreateIn{
+			FirstName:  cell(plan.first),
+			LastName:   cell(plan.last),
+			Company:    cell(plan.company),
+			JobTitle:   cell(plan.title),
+			Department: cell(plan.dept),
+		}
+		for n, i := range plan.phones {
+			raw := cell(i)
+			if raw == "" {
+				continue
+			}
+			row.in.Phones = append(row.in.Phones, PhoneIn{Label: m.Phones[n].Label, PhoneRaw: raw, IsPrimary: m.Phones[n].Primary})
+			if _, digits, ok := normalizer.NormalizePhoneRegion(raw, regionOf(ctx)); ok {
+				row.digits = append(row.digits, digits)
+			}
+		}
+		for n, i := range plan.emails {
+			if v := cell(i); v != "" {
+				row.in.Emails = append(row.in.Emails, EmailIn{Label: m.Emails[n].Label, Email: v, IsPrimary: m.Emails[n].Primary})
+			}
+		}
+		for name, i := range plan.custom {
+			v := cell(i)
+			if v == "" {
+				continue
+			}
+			val, err := csvCustomValue(reg[name], v)
+			if err != nil {
+				row.err = fmt.Errorf("custom field %s: %v", name, err)
+				break
+			}
+			if row.in.Custom == nil {
+				row.in.Custom = make(map[string]any, len(plan.custom))
+			}
+			row.in.Custom[name] = val
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// csvCustomValue — в CSV всё строки; числа и флаги приводим к типам, которые ждёт validateCustom.
+func csvCustomValue(def repository.CustomField, v string) (any, error) {
+	switch def.Type {
+	case "number":
+		f, err := strconv.ParseFloat(strings.Replace(v, ",", ".", 1), 64)
+		if err != nil {
+			return nil, errors.New("expected number")
+		}
+		return f, nil
+	case "bool":
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, errors.New("expected bool")
+		}
+		return b, nil
+	}
+	return v, nil
+}
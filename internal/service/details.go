@@ -0,0 +1,118 @@
+package service
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/sunzhqr/phonebook/internal/repository"
+	"github.com/sunzhqr/phonebook/pkg/normalizer"
+)
+
+// normalizePhones — нормализует номера (без кода страны — по правилам region), отбрасывает дубликаты по digits и назначает primary.
+func normalizePhones(in []PhoneIn, region string) ([]repository.PhoneInput, error) {
+	seen := make(map[string]struct{}, len(in))
+	out := make([]repository.PhoneInput, 0, len(in))
+	hasPrimary := false
+	for _, ph := range in {
+		raw := strings.TrimSpace(ph.PhoneRaw)
+		e164, digits, ok := normalizer.NormalizePhoneRegion(raw, region)
+		if !ok {
+			return nil, &Error{Code: http.StatusUnprocessableEntity, Message: "invalid phone"}
+		}
+		if _, dup := seen[digits]; dup {
+			continue
+		}
+		seen[digits] = struct{}{}
+		primary := ph.IsPrimary && !hasPrimary
+		if primary {
+			hasPrimary = true
+		}
+		out = append(out, repository.PhoneInput{
+			Label:       strings.TrimSpace(ph.Label),
+			PhoneRaw:    raw,
+			PhoneE164:   e164,
+			PhoneDigits: digits,
+			IsPrimary:   primary,
+			Visibility:  ph.Visibility,
+		})
+	}
+	if !hasPrimary && len(out) > 0 {
+		out[0].IsPrimary = true
+	}
+	return out, nil
+}
+
+// normalizeEmails — приводит email к нижнему регистру, отбрасывает дубликаты и назначает primary.
+func normalizeEmails(in []EmailIn) []repository.EmailInput {
+	seen := make(map[string]struct{}, len(in))
+	out := make([]repository.EmailInput, 0, len(in))
+	hasPrimary := false
+	for _, e := range in {
+		email := strings.ToLower(strings.TrimSpace(e.Email))
+		if _, dup := seen[email]; dup {
+			continue
+		}
+		seen[email] = struct{}{}
+		// второй primary не допускаем (уникальный индекс в БД)
+		primary := e.IsPrimary && !hasPrimary
+		if primary {
+			hasPrimary = true
+		}
+		out = append(out, repository.EmailInput{Label: strings.TrimSpace(e.Label), Email: email, IsPrimary: primary})
+	}
+	if !hasPrimary && len(out) > 0 {
+		out[0].IsPrimary = true
+	}
+	return out
+}
+
+// normalizeAddresses — обрезает пробелы; адрес без единого заполненного поля считается ошибкой.
+func normalizeAddresses(in []AddressIn) ([]repository.AddressInput, error) {
+	out := make([]repository.AddressInput, 0, len(in))
+	hasPrimary := false
+	for _, a := range in {
+		addr := repository.AddressInput{
+			Label:      strings.TrimSpace(a.Label),
+			Street:     strings.TrimSpace(a.Street),
+			City:       strings.TrimSpace(a.City),
+			Region:     strings.TrimSpace(a.Region),
+			PostalCode: strings.TrimSpace(a.PostalCode),
+			Country:    strings.TrimSpace(a.Country),
+		}
+		if addr.Street == "" && addr.City == "" && addr.Region == "" && addr.PostalCode == "" && addr.Country == "" {
+			return nil, &Error{Code: http.StatusUnprocessableEntity, Message: "empty address"}
+		}
+		addr.IsPrimary = a.IsPrimary && !hasPrimary
+		if addr.IsPrimary {
+			hasPrimary = true
+		}
+		out = append(out, addr)
+	}
+	if !hasPrimary && len(out) > 0 {
+		out[0].IsPrimary = true
+	}
+	return out, nil
+}
+
+// normalizeWebsites — отбрасывает дубликаты URL и назначает primary.
+func normalizeWebsites(in []WebsiteIn) []repository.WebsiteInput {
+	seen := make(map[string]struct{}, len(in))
+	out := make([]repository.WebsiteInput, 0, len(in))
+	hasPrimary := false
+	for _, w := range in {
+		u := strings.TrimSpace(w.URL)
+		if _, dup := seen[u]; dup {
+			continue
+		}
+		seen[u] = struct{}{}
+		primary := w.IsPrimary && !hasPrimary
+		if primary {
+			hasPrimary = true
+		}
+		out = append(out, repository.WebsiteInput{Label: strings.TrimSpace(w.Label), URL: u, IsPrimary: primary})
+	}
+	if !hasPrimary && len(out) > 0 {
+		out[0].IsPrimary = true
+	}
+	return out
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/sunzhqr/phonebook/internal/repository"
+)
+
+const (
+	// EventsRetention — сколько хранится журнал изменений; клиент, отставший сильнее, перечитывает книгу
+	EventsRetention = 7 * 24 * time.Hour
+	maxEventsPage   = 1000
+)
+
+// EventsSince — события с позицией > after по возрастанию позиции: книги запроса, а хабу (ctx с
+// repository.WithAllBooks) — всех книг. Позиция — seq из журнала: он выдаётся в порядке коммита, поэтому
+// транзакция, закоммиченная позже, не окажется позади курсора клиента, как было бы с id, выданным при вставке.
+func (s *Service) EventsSince(ctx context.Context, after int64, limit int) ([]EventOut, error) {
+	if limit <= 0 || limit > maxEventsPage {
+		limit = maxEventsPage
+	}
+	if _, err := s.events.Sequence(ctx); err != nil {
+		return nil, s.repoErr(err)
+	}
+	evs, err := s.events.Since(ctx, after, limit)
+	if err != nil {
+		return nil, s.repoErr(err)
+	}
+	out := make([]EventOut, 0, len(evs))
+	for _, e := range evs {
+		out = append(out, toEventOut(e))
+	}
+	return out, nil
+}
+
+// EventsHead — позиция последнего события ленты; новые события получат позиции больше неё
+func (s *Service) EventsHead(ctx context.Context) (int64, error) {
+	st, err := s.events.Sequence(ctx)
+	if err != nil {
+		return 0, s.repoErr(err)
+	}
+	return st.Max, nil
+}
+
+// ListenEvents — сигнал о закоммиченных событиях; блокируется до отмены ctx или обрыва соединения с БД.
+// Позиции у событий в уведомлении ещё нет — сами события читаются EventsSince.
+func (s *Service) ListenEvents(ctx context.Context, onListen func(), notify func()) error {
+	return s.events.Listen(ctx, onListen, func(repository.ContactEvent) { notify() })
+}
+
+func (s *Service) PurgeEvents(ctx context.Context) (int64, error) {
+	return s.events.DeleteBefore(ctx, time.Now().Add(-EventsRetention))
+}
+
+func toEventOut(e repository.ContactEvent) EventOut {
+	return EventOut{ID: e.Seq, ContactID: e.ContactID, AddressBookID: e.AddressBookID, Op: e.Op, At: e.CreatedAt,
+		Readers: e.Readers}
+}
+
+// EventVisible — отдавать ли событие хаба (он общий для всех книг и читающих) подписчику из ctx: событие
+// его книги, а контакт на момент события был ему виден — как contact_visible в запросах к журналу.
+// Без вызывающего (gRPC без авторизации) видны только события контактов без ограничений.
+func EventVisible(ctx context.Context, e EventOut) bool {
+	if !inAddressBook(ctx, e) {
+		return false
+	}
+	if e.Readers == nil {
+		return true
+	}
+	p, ok := PrincipalFrom(ctx)
+	if !ok {
+		return false
+	}
+	if p.BookRole() == RoleAdmin {
+		return true
+	}
+	for _, id := range p.principals() {
+		if slices.Contains(e.Readers, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// inAddressBook — относится ли событие хаба (он общий для всех книг) к книге запроса; запрос без книги не
+// получает ничего. К книге по умолчанию относятся и события, записанные до появления книг.
+func inAddressBook(ctx context.Context, e EventOut) bool {
+	b, ok := AddressBookFrom(ctx)
+	if !ok {
+		return false
+	}
+	return e.AddressBookID == b.ID || (e.AddressBookID == 0 && b.ID == repository.DefaultAddressBookID)
+}
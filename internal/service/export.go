@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/repository"
+)
+
+// exportAuditChunk — сколько выгруженных id копится до записи в аудит: память выгрузки не растёт с книгой
+const exportAuditChunk = 1000
+
+// ExportContacts — отдаёт в fn все контакты по фильтру (Limit и AfterID-пагинация не нужны клиенту,
+// но AfterID учитывается). Ошибку из fn возвращает как есть — так вызывающий прерывает выгрузку.
+// В аудит выгрузка попадает записями по exportAuditChunk контактов.
+func (s *Service) ExportContacts(ctx context.Context, f ListFilter, fn func(ContactOut) error) error {
+	rf, err := s.repoFilter(ctx, f)
+	if err != nil {
+		return err
+	}
+	var fnErr error
+	ids := make([]int64, 0, exportAuditChunk)
+	err = s.repo.Stream(ctx, rf, func(c repository.Contact) error {
+		if fnErr = fn(toContactOut(ctx, c)); fnErr != nil {
+			return fnErr
+		}
+		if ids = append(ids, c.ID); len(ids) == exportAuditChunk {
+			s.record(ctx, AuditContactExport, ids...)
+			ids = make([]int64, 0, exportAuditChunk)
+		}
+		return nil
+	})
+	// и прерванная выгрузка успела отдать часть контактов
+	if len(ids) > 0 {
+		s.record(ctx, AuditContactExport, ids...)
+	}
+	switch {
+	case err == nil || fnErr != nil:
+		return err
+	case ctx.Err() != nil:
+		// клиент отключился — запрос отменён, это не ошибка сервиса
+		return ctx.Err()
+	}
+	s.lg.Error("export stream failed", logger.Err(err))
+	return s.repoErr(err)
+}
+
+// ExportPhoneColumns — сколько пар колонок под телефоны нужно в CSV (минимум одна).
+func (s *Service) ExportPhoneColumns(ctx context.Context) (int, error) {
+	n, err := s.repo.MaxPhones(ctx)
+	if err != nil {
+		return 0, s.repoErr(err)
+	}
+	return max(n, 1), nil
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/sunzhqr/phonebook/internal/repository"
+)
+
+// maxExternalKeyLen — предел длины источника и внешнего id
+const maxExternalKeyLen = 128
+
+// UpsertExternal — PUT по внешнему ключу: тело целиком заменяет контакт, как при создании.
+// Источник приводится к нижнему регистру: "AD" и "ad" — одна система.
+func (s *Service) UpsertExternal(ctx context.Context, source, externalID string, in ContactCreateIn) (ContactOut, bool, error) {
+	source, externalID, err := externalKey(source, externalID)
+	if err != nil {
+		return ContactOut{}, false, err
+	}
+	// существующий контакт заменяется только при праве на правку; невидимый репозиторий не тронет
+	var prev *repository.Contact
+	cur, err := s.repo.GetByExternal(ctx, source, externalID)
+	switch {
+	case err == nil:
+		if err := s.requireRole(ctx, cur.ID, RoleEditor); err != nil {
+			return ContactOut{}, false, err
+		}
+		prev = &cur
+	case !errors.Is(err, repository.ErrNotFound):
+		return ContactOut{}, false, s.repoErr(err)
+	}
+	ci, err := s.prepareReplace(ctx, in, prev)
+	if err != nil {
+		return ContactOut{}, false, err
+	}
+	if prev != nil {
+		if err := s.requireTagsRole(ctx, *prev, ci.Tags); err != nil {
+			return ContactOut{}, false, err
+		}
+	}
+	c, created, err := s.repo.UpsertExternal(ctx, source, externalID, ci)
+	if err != nil {
+		return ContactOut{}, false, s.repoErr(err)
+	}
+	if created {
+		s.record(ctx, AuditContactCreate, c.ID)
+	} else {
+		s.record(ctx, AuditContactUpdate, c.ID)
+	}
+	return toContactOut(ctx, c), created, nil
+}
+
+func (s *Service) GetByExternal(ctx context.Context, source, externalID string) (ContactOut, error) {
+	source, externalID, err := externalKey(source, externalID)
+	if err != nil {
+		return ContactOut{}, err
+	}
+	c, err := s.repo.GetByExternal(ctx, source, externalID)
+	if err != nil {
+		return ContactOut{}, s.repoErr(err)
+	}
+	s.record(ctx, AuditContactRead, c.ID)
+	return toContactOut(ctx, c), nil
+}
+
+func externalKey(source, externalID string) (string, string, error) {
+	source = strings.ToLower(strings.TrimSpace(source))
+	externalID = strings.TrimSpace(externalID)
+	if source == "" || externalID == "" || len(source) > maxExternalKeyLen || len(externalID) > maxExternalKeyLen {
+		return "", "", &Error{Code: http.StatusBadRequest, Message: "bad external id"}
+	}
+	return source, externalID, nil
+}
@@ -0,0 +1,294 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sunzhqr/phonebook/internal/repository"
+)
+
+const dateLayout = "2006-01-02"
+
+// имя поля попадает в JSON-ключи и query-параметры, поэтому держим его простым
+var fieldNameRe = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+func (s *Service) CreateCustomField(ctx context.Context, in CustomFieldIn) (CustomFieldOut, error) {
+	if err := s.v.Struct(in); err != nil {
+		return CustomFieldOut{}, &Error{Code: http.StatusUnprocessableEntity, Message: err.Error()}
+	}
+	if !fieldNameRe.MatchString(in.Name) {
+		return CustomFieldOut{}, &Error{Code: http.StatusUnprocessableEntity, Message: "invalid field name"}
+	}
+	if err := checkConstraints(in.Type, in.Constraints); err != nil {
+		return CustomFieldOut{}, err
+	}
+	f, err := s.fields.Create(ctx, repository.CustomFieldInput{
+		Name:        in.Name,
+		Label:       strings.TrimSpace(in.Label),
+		Type:        in.Type,
+		Required:    in.Required,
+		Constraints: repository.FieldConstraints(in.Constraints),
+	})
+	if err != nil {
+		return CustomFieldOut{}, s.repoErr(err)
+	}
+	return toCustomFieldOut(f), nil
+}
+
+func (s *Service) GetCustomField(ctx context.Context, name string) (CustomFieldOut, error) {
+	f, err := s.fields.Get(ctx, name)
+	if err != nil {
+		return CustomFieldOut{}, s.repoErr(err)
+	}
+	return toCustomFieldOut(f), nil
+}
+
+func (s *Service) ListCustomFields(ctx context.Context) ([]CustomFieldOut, error) {
+	list, err := s.fields.List(ctx)
+	if err != nil {
+		return nil, s.repoErr(err)
+	}
+	out := make([]CustomFieldOut, 0, len(list))
+	for _, f := range list {
+		out = append(out, toCustomFieldOut(f))
+	}
+	return out, nil
+}
+
+func (s *Service) UpdateCustomField(ctx context.Context, name string, in CustomFieldUpdateIn) (CustomFieldOut, error) {
+	if err := s.v.Struct(in); err != nil {
+		return CustomFieldOut{}, &Error{Code: http.StatusUnprocessableEntity, Message: err.Error()}
+	}
+	patch := repository.CustomFieldPatch{Label: in.Label, Required: in.Required}
+	if in.Constraints != nil {
+		cur, err := s.fields.Get(ctx, name)
+		if err != nil {
+			return CustomFieldOut{}, s.repoErr(err)
+		}
+		if err := checkConstraints(cur.Type, *in.Constraints); err != nil {
+			return CustomFieldOut{}, err
+		}
+		c := repository.FieldConstraints(*in.Constraints)
+		patch.Constraints = &c
+	}
+	f, err := s.fields.Update(ctx, name, patch)
+	if err != nil {
+		return CustomFieldOut{}, s.repoErr(err)
+	}
+	return toCustomFieldOut(f), nil
+}
+
+func (s *Service) DeleteCustomField(ctx context.Context, name string) error {
+	if err := s.fields.Delete(ctx, name); err != nil {
+		return s.repoErr(err)
+	}
+	return nil
+}
+
+// checkConstraints — проверяет, что ограничения применимы к типу поля.
+func checkConstraints(typ string, c FieldConstraints) error {
+	bad := func(msg string) error { return &Error{Code: http.StatusUnprocessableEntity, Message: msg} }
+	if (c.MinLength != nil || c.MaxLength != nil || c.Pattern != "") && typ != "string" {
+		return bad("min_length, max_length and pattern apply to string fields only")
+	}
+	if (c.Min != nil || c.Max != nil) && typ != "number" {
+		return bad("min and max apply to number fields only")
+	}
+	if typ == "enum" && len(c.Options) == 0 {
+		return bad("enum field requires options")
+	}
+	if typ != "enum" && len(c.Options) > 0 {
+		return bad("options apply to enum fields only")
+	}
+	if c.MinLength != nil && c.MaxLength != nil && *c.MinLength > *c.MaxLength {
+		return bad("min_length is greater than max_length")
+	}
+	if c.Min != nil && c.Max != nil && *c.Min > *c.Max {
+		return bad("min is greater than max")
+	}
+	if c.Pattern != "" {
+		if _, err := regexp.Compile(c.Pattern); err != nil {
+			return bad("invalid pattern")
+		}
+	}
+	return nil
+}
+
+// registry — текущий реестр пользовательских полей книги из ctx по имени.
+func (s *Service) registry(ctx context.Context) (map[string]repository.CustomField, error) {
+	list, err := s.fields.List(ctx)
+	if err != nil {
+		return nil, s.repoErr(err)
+	}
+	reg := make(map[string]repository.CustomField, len(list))
+	for _, f := range list {
+		reg[f.Name] = f
+	}
+	return reg, nil
+}
+
+// validateCustom — проверяет значения пользовательских полей по реестру и приводит их к каноничному виду.
+// При создании (create=true) nil-значения отбрасываются и проверяется наличие обязательных полей;
+// при обновлении nil означает удаление значения.
+func (s *Service) validateCustom(ctx context.Context, values map[string]any, create bool) (map[string]any, error) {
+	if len(values) == 0 && !create {
+		return nil, nil
+	}
+	reg, err := s.registry(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]any, len(values))
+	for name, v := range values {
+		def, ok := reg[name]
+		if !ok {
+			return nil, &Error{Code: http.StatusUnprocessableEntity, Message: "unknown custom field: " + name}
+		}
+		if v == nil {
+			if def.Required {
+				return nil, &Error{Code: http.StatusUnprocessableEntity, Message: "custom field is required: " + name}
+			}
+			if !create {
+				out[name] = nil
+			}
+			continue
+		}
+		norm, err := coerceCustom(def, v)
+		if err != nil {
+			return nil, &Error{Code: http.StatusUnprocessableEntity, Message: fmt.Sprintf("custom field %s: %v", name, err)}
+		}
+		out[name] = norm
+	}
+	if create {
+		for name, def := range reg {
+			if _, ok := out[name]; def.Required && !ok {
+				return nil, &Error{Code: http.StatusUnprocessableEntity, Message: "custom field is required: " + name}
+			}
+		}
+	}
+	return out, nil
+}
+
+func coerceCustom(def repository.CustomField, v any) (any, error) {
+	c := def.Constraints
+	switch def.Type {
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string")
+		}
+		str = strings.TrimSpace(str)
+		n := utf8.RuneCountInString(str)
+		if c.MinLength != nil && n < *c.MinLength {
+			return nil, fmt.Errorf("shorter than %d", *c.MinLength)
+		}
+		if c.MaxLength != nil && n > *c.MaxLength {
+			return nil, fmt.Errorf("longer than %d", *c.MaxLength)
+		}
+		if c.Pattern != "" {
+			re, err := regexp.Compile(c.Pattern)
+			if err != nil || !re.MatchString(str) {
+				return nil, fmt.Errorf("does not match pattern")
+			}
+		}
+		return str, nil
+	case "number":
+		var f float64
+		switch n := v.(type) {
+		case float64:
+			f = n
+		case int:
+			f = float64(n)
+		case int64:
+			f = float64(n)
+		case json.Number:
+			x, err := n.Float64()
+			if err != nil {
+				return nil, fmt.Errorf("expected number")
+			}
+			f = x
+		default:
+			return nil, fmt.Errorf("expected number")
+		}
+		if c.Min != nil && f < *c.Min {
+			return nil, fmt.Errorf("less than %v", *c.Min)
+		}
+		if c.Max != nil && f > *c.Max {
+			return nil, fmt.Errorf("greater than %v", *c.Max)
+		}
+		return f, nil
+	case "date":
+		str, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected date YYYY-MM-DD")
+		}
+		d, err := time.Parse(dateLayout, strings.TrimSpace(str))
+		if err != nil {
+			return nil, fmt.Errorf("expected date YYYY-MM-DD")
+		}
+		return d.Format(dateLayout), nil
+	case "enum":
+		str, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected one of %s", strings.Join(c.Options, ", "))
+		}
+		for _, o := range c.Options {
+			if o == str {
+				return str, nil
+			}
+		}
+		return nil, fmt.Errorf("expected one of %s", strings.Join(c.Options, ", "))
+	case "bool":
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected bool")
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", def.Type)
+}
+
+// customFilters — превращает query-фильтры в условия репозитория с типами из реестра.
+func customFilters(reg map[string]repository.CustomField, in map[string]string) ([]repository.CustomFilter, error) {
+	out := make([]repository.CustomFilter, 0, len(in))
+	for name, val := range in {
+		def, ok := reg[name]
+		if !ok {
+			return nil, &Error{Code: http.StatusUnprocessableEntity, Message: "unknown custom field: " + name}
+		}
+		// значение фильтра должно приводиться к типу поля, иначе упадёт каст в SQL
+		var err error
+		switch def.Type {
+		case "number":
+			_, err = strconv.ParseFloat(val, 64)
+		case "date":
+			_, err = time.Parse(dateLayout, val)
+		case "bool":
+			_, err = strconv.ParseBool(val)
+		}
+		if err != nil {
+			return nil, &Error{Code: http.StatusUnprocessableEntity, Message: "invalid filter value for custom field: " + name}
+		}
+		out = append(out, repository.CustomFilter{Field: name, Type: def.Type, Value: val})
+	}
+	return out, nil
+}
+
+func toCustomFieldOut(f repository.CustomField) CustomFieldOut {
+	return CustomFieldOut{
+		Name:        f.Name,
+		Label:       f.Label,
+		Type:        f.Type,
+		Required:    f.Required,
+		Constraints: FieldConstraints(f.Constraints),
+		CreatedAt:   f.CreatedAt,
+		UpdatedAt:   f.UpdatedAt,
+	}
+}
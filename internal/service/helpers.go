@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"net/http"
 
@@ -14,6 +15,10 @@ func (s *Service) repoErr(err error) error {
 	switch {
 	case errors.Is(err, repository.ErrNotFound):
 		return &Error{Code: http.StatusNotFound, Message: "not found"}
+	case errors.Is(err, repository.ErrConflict):
+		return &Error{Code: http.StatusConflict, Message: "conflict"}
+	case errors.Is(err, repository.ErrInvalidReference):
+		return &Error{Code: http.StatusUnprocessableEntity, Message: "invalid reference"}
 	case repository.IsBadRequest(err):
 		return &Error{Code: http.StatusBadRequest, Message: err.Error()}
 	default:
@@ -21,12 +26,70 @@ func (s *Service) repoErr(err error) error {
 	}
 }
 
-func toContactOut(c repository.Contact) ContactOut {
+// toContactOut — контакт для вызывающего из ctx: номера, которые ему не положены, замаскированы
+func toContactOut(ctx context.Context, c repository.Contact) ContactOut {
 	ph := make([]PhoneOut, 0, len(c.Phones))
 	for _, p := range c.Phones {
-		ph = append(ph, PhoneOut{Label: p.Label, PhoneRaw: p.PhoneRaw, PhoneE164: p.PhoneE164, IsPrimary: p.IsPrimary})
+		out := PhoneOut{Label: p.Label, PhoneRaw: p.PhoneRaw, PhoneE164: p.PhoneE164, IsPrimary: p.IsPrimary, Visibility: p.Visibility}
+		if !phoneVisible(ctx, c, p.Visibility) {
+			redactPhone(&out)
+		}
+		ph = append(ph, out)
+	}
+	em := make([]EmailOut, 0, len(c.Emails))
+	for _, e := range c.Emails {
+		em = append(em, EmailOut{Label: e.Label, Email: e.Email, IsPrimary: e.IsPrimary})
+	}
+	ad := make([]AddressOut, 0, len(c.Addresses))
+	for _, a := range c.Addresses {
+		ad = append(ad, AddressOut{Label: a.Label, Street: a.Street, City: a.City, Region: a.Region, PostalCode: a.PostalCode, Country: a.Country, IsPrimary: a.IsPrimary})
+	}
+	ws := make([]WebsiteOut, 0, len(c.Websites))
+	for _, w := range c.Websites {
+		ws = append(ws, WebsiteOut{Label: w.Label, URL: w.URL, IsPrimary: w.IsPrimary})
+	}
+	out := ContactOut{ID: c.ID, FirstName: c.FirstName, LastName: c.LastName, Company: c.Company, JobTitle: c.JobTitle, Department: c.Department, Phones: ph, Emails: em, Addresses: ad, Websites: ws, Custom: c.Custom, Tags: c.Tags, Owner: c.Owner, CreatedAt: c.CreatedAt, UpdatedAt: c.UpdatedAt}
+	if out.Tags == nil {
+		out.Tags = []string{}
+	}
+	if c.OrganizationID != 0 {
+		out.Organization = &OrganizationRef{ID: c.OrganizationID, Name: c.Company}
+	}
+	if c.HasPhoto {
+		out.PhotoURL = PhotoURL(c.ID)
+	}
+	if c.ExternalSource != "" {
+		out.External = &ExternalRef{Source: c.ExternalSource, ID: c.ExternalID}
+	}
+	return out
+}
+
+func toPhonesOut(in []repository.Phone) []PhoneOut {
+	out := make([]PhoneOut, 0, len(in))
+	for _, p := range in {
+		out = append(out, PhoneOut{Label: p.Label, PhoneRaw: p.PhoneRaw, PhoneE164: p.PhoneE164, IsPrimary: p.IsPrimary})
+	}
+	return out
+}
+
+func toAddressesOut(in []repository.Address) []AddressOut {
+	out := make([]AddressOut, 0, len(in))
+	for _, a := range in {
+		out = append(out, AddressOut{Label: a.Label, Street: a.Street, City: a.City, Region: a.Region, PostalCode: a.PostalCode, Country: a.Country, IsPrimary: a.IsPrimary})
+	}
+	return out
+}
+
+func toOrganizationOut(o repository.Organization) OrganizationOut {
+	return OrganizationOut{
+		ID:          o.ID,
+		Name:        o.Name,
+		Phones:      toPhonesOut(o.Phones),
+		Addresses:   toAddressesOut(o.Addresses),
+		MemberCount: o.MemberCount,
+		CreatedAt:   o.CreatedAt,
+		UpdatedAt:   o.UpdatedAt,
 	}
-	return ContactOut{ID: c.ID, FirstName: c.FirstName, LastName: c.LastName, Company: c.Company, Phones: ph, CreatedAt: c.CreatedAt, UpdatedAt: c.UpdatedAt}
 }
 
 //func rfc3339(t time.Time) string { return t.UTC().Format(time.RFC3339) }
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/repository"
+)
+
+const (
+	// IdempotencyTTL — сколько хранится ответ на запрос с Idempotency-Key
+	IdempotencyTTL = 24 * time.Hour
+	// idemLock — незавершённый ключ, который не продлевали дольше этого, считается брошенным (процесс
+	// упал) и перезанимается; пока запрос выполняется, HoldIdempotent продлевает его каждые idemLock/3
+	idemLock = time.Minute
+	// idemPoll — как часто дубликат проверяет, закончился ли первый запрос
+	idemPoll      = 100 * time.Millisecond
+	maxIdemKeyLen = 255
+)
+
+// StoredResponse — ответ, сохранённый под ключом идемпотентности
+type StoredResponse struct {
+	Status int
+	Header map[string]string
+	Body   []byte
+}
+
+// BeginIdempotent — занимает ключ. nil, nil: запрос выполняется впервые, по окончании нужно вызвать
+// CompleteIdempotent или ReleaseIdempotent. Иначе возвращается сохранённый ответ; если запрос с тем же
+// ключом ещё выполняется, ждём его. Ключ с другим отпечатком запроса — 422.
+func (s *Service) BeginIdempotent(ctx context.Context, key, fingerprint string) (*StoredResponse, error) {
+	if key == "" || len(key) > maxIdemKeyLen {
+		return nil, &Error{Code: http.StatusBadRequest, Message: "bad idempotency key"}
+	}
+	for {
+		now := time.Now()
+		k := idemKey(ctx, key)
+		k.Fingerprint, k.LockedUntil, k.ExpiresAt = fingerprint, now.Add(idemLock), now.Add(IdempotencyTTL)
+		cur, created, err := s.idem.Reserve(ctx, k)
+		switch {
+		case errors.Is(err, repository.ErrConflict):
+			// владелец только что снял ключ — пробуем занять заново
+		case err != nil:
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, s.repoErr(err)
+		case created:
+			return nil, nil
+		case cur.Fingerprint != fingerprint:
+			return nil, &Error{Code: http.StatusUnprocessableEntity, Message: "idempotency key reused with a different request"}
+		case cur.Status != 0:
+			return &StoredResponse{Status: cur.Status, Header: cur.Header, Body: cur.Body}, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(idemPoll):
+		}
+	}
+}
+
+func (s *Service) CompleteIdempotent(ctx context.Context, key string, resp StoredResponse) error {
+	if resp.Header == nil {
+		resp.Header = map[string]string{}
+	}
+	k := idemKey(ctx, key)
+	k.Status, k.Header, k.Body = resp.Status, resp.Header, resp.Body
+	return s.idem.Complete(ctx, k)
+}
+
+// idemKey — ключ в пространстве вызывающего и книги запроса
+func idemKey(ctx context.Context, key string) repository.IdempotencyKey {
+	p, _ := PrincipalFrom(ctx)
+	book := repository.DefaultAddressBookID
+	if b, ok := AddressBookFrom(ctx); ok {
+		book = b.ID
+	}
+	return repository.IdempotencyKey{Subject: p.Subject, AddressBookID: book, Key: key}
+}
+
+// HoldIdempotent — держит ключ занятым, пока выполняется первый запрос: без продления повтор долгого
+// запроса через idemLock занял бы ключ и выполнился второй раз. stop прекращает продление.
+func (s *Service) HoldIdempotent(ctx context.Context, key string) (stop func()) {
+	k := idemKey(ctx, key)
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		t := time.NewTicker(idemLock / 3)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				if err := s.idem.Extend(ctx, k, time.Now().Add(idemLock)); err != nil && ctx.Err() == nil {
+					s.lg.Warn("idempotency key extend failed", logger.Err(err))
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// ReleaseIdempotent — запрос не удался (5xx, паника): ключ снимается, повтор выполнится заново.
+func (s *Service) ReleaseIdempotent(ctx context.Context, key string) error {
+	return s.idem.Release(ctx, idemKey(ctx, key))
+}
+
+// PurgeIdempotencyKeys — удаляет ключи с истёкшим сроком хранения.
+func (s *Service) PurgeIdempotencyKeys(ctx context.Context) (int64, error) {
+	return s.idem.DeleteExpired(ctx)
+}
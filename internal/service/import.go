@@ -0,0 +1,45 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+)
+
+const (
+	ImportCreated = "created"
+	ImportSkipped = "skipped_duplicate"
+	ImportInvalid = "invalid"
+)
+
+// ImportContacts — создаёт контакты по одному через CreateContact, так что действуют те же
+// валидация и нормализация. Ошибка строки попадает в отчёт и не прерывает импорт;
+// внутренняя ошибка (5xx) прерывает.
+func (s *Service) ImportContacts(ctx context.Context, items []ImportItem) (ImportReport, error) {
+	rep := ImportReport{Rows: make([]ImportRowOut, 0, len(items))}
+	for i, it := range items {
+		row := ImportRowOut{Row: i + 1}
+		c, err := s.CreateContact(ctx, it.Contact)
+		if err != nil {
+			var se *Error
+			if !errors.As(err, &se) || se.Code >= http.StatusInternalServerError {
+				return ImportReport{}, err
+			}
+			row.Status, row.Error = ImportInvalid, se.Message
+			rep.Invalid++
+			rep.Rows = append(rep.Rows, row)
+			continue
+		}
+		row.Status, row.ID = ImportCreated, c.ID
+		rep.Created++
+		if len(it.Photo) > 0 {
+			// контакт уже создан — неподходящее фото только отмечаем в отчёте
+			if _, err := s.PutPhoto(ctx, c.ID, bytes.NewReader(it.Photo)); err != nil {
+				row.Error = "photo skipped: " + err.Error()
+			}
+		}
+		rep.Rows = append(rep.Rows, row)
+	}
+	return rep, nil
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/sunzhqr/phonebook/internal/repository"
@@ -11,8 +12,28 @@ import (
 
 // CreateContact — нормализует вход, обеспечивает инварианты и делегирует репозиторию.
 func (s *Service) CreateContact(ctx context.Context, in ContactCreateIn) (ContactOut, error) {
+	ci, err := s.prepareContact(ctx, in)
+	if err != nil {
+		return ContactOut{}, err
+	}
+	c, err := s.repo.Create(ctx, ci)
+	if err != nil {
+		return ContactOut{}, s.repoErr(err)
+	}
+	s.record(ctx, AuditContactCreate, c.ID)
+	return toContactOut(ctx, c), nil
+}
+
+// prepareContact — валидация и нормализация CreateContact без записи (нужна и для dry-run импорта).
+func (s *Service) prepareContact(ctx context.Context, in ContactCreateIn) (repository.ContactInput, error) {
+	return s.prepareReplace(ctx, in, nil)
+}
+
+// prepareReplace — prepareContact для замены существующего cur: маски скрытых номеров
+// отбрасываются, сами номера переносятся из cur
+func (s *Service) prepareReplace(ctx context.Context, in ContactCreateIn, cur *repository.Contact) (repository.ContactInput, error) {
 	if err := s.v.Struct(in); err != nil {
-		return ContactOut{}, &Error{Code: http.StatusUnprocessableEntity, Message: err.Error()}
+		return repository.ContactInput{}, &Error{Code: http.StatusUnprocessableEntity, Message: err.Error()}
 	}
 
 	seen := make(map[string]struct{}, len(in.Phones))
@@ -22,10 +43,13 @@ func (s *Service) CreateContact(ctx context.Context, in ContactCreateIn) (Contac
 	for _, ph := range in.Phones {
 		label := strings.TrimSpace(ph.Label)
 		raw := strings.TrimSpace(ph.PhoneRaw)
+		if strings.Contains(raw, "*") {
+			continue
+		}
 
-		e164, digits, ok := normalizer.NormalizePhone(raw)
+		e164, digits, ok := normalizer.NormalizePhoneRegion(raw, regionOf(ctx))
 		if !ok {
-			return ContactOut{}, &Error{Code: http.StatusUnprocessableEntity, Message: "invalid phone"}
+			return repository.ContactInput{}, &Error{Code: http.StatusUnprocessableEntity, Message: "invalid phone"}
 		}
 
 		// дубликаты по digits отбрасываем
@@ -44,39 +68,105 @@ func (s *Service) CreateContact(ctx context.Context, in ContactCreateIn) (Contac
 			PhoneE164:   e164,
 			PhoneDigits: digits,
 			IsPrimary:   ph.IsPrimary,
+			Visibility:  ph.Visibility,
 		})
 	}
+	if cur != nil {
+		phones = withHiddenPhones(ctx, *cur, phones)
+		hasPrimary = slices.ContainsFunc(phones, func(ph repository.PhoneInput) bool { return ph.IsPrimary })
+	}
 
 	if len(phones) == 0 {
-		return ContactOut{}, &Error{Code: http.StatusUnprocessableEntity, Message: "no valid phones"}
+		return repository.ContactInput{}, &Error{Code: http.StatusUnprocessableEntity, Message: "no valid phones"}
 	}
 	if !hasPrimary {
 		phones[0].IsPrimary = true
 	}
 
-	c, err := s.repo.Create(ctx, repository.ContactInput{
-		FirstName: strings.TrimSpace(in.FirstName),
-		LastName:  strings.TrimSpace(in.LastName),
-		Company:   strings.TrimSpace(in.Company),
-		Phones:    phones,
-	})
+	addrs, err := normalizeAddresses(in.Addresses)
 	if err != nil {
-		return ContactOut{}, s.repoErr(err)
+		return repository.ContactInput{}, err
+	}
+	custom, err := s.validateCustom(ctx, in.Custom, true)
+	if err != nil {
+		return repository.ContactInput{}, err
 	}
-	return toContactOut(c), nil
+	company := strings.TrimSpace(in.Company)
+	if in.OrganizationID > 0 {
+		if company, err = s.organizationName(ctx, in.OrganizationID); err != nil {
+			return repository.ContactInput{}, err
+		}
+	}
+	tags, err := normalizeTags(in.Tags)
+	if err != nil {
+		return repository.ContactInput{}, err
+	}
+
+	return repository.ContactInput{
+		FirstName:      strings.TrimSpace(in.FirstName),
+		LastName:       strings.TrimSpace(in.LastName),
+		Company:        company,
+		OrganizationID: in.OrganizationID,
+		JobTitle:       strings.TrimSpace(in.JobTitle),
+		Department:     strings.TrimSpace(in.Department),
+		Phones:         phones,
+		Emails:         normalizeEmails(in.Emails),
+		Addresses:      addrs,
+		Websites:       normalizeWebsites(in.Websites),
+		Custom:         custom,
+		Owner:          owner(ctx),
+		Tags:           tags,
+	}, nil
 }
 
-func (s *Service) GetContact(ctx context.Context, id int64) (ContactOut, error) {
+func (s *Service) GetContact(ctx context.Context, id int64, expand ...string) (ContactOut, error) {
+	for _, e := range expand {
+		if e != ExpandRelations {
+			return ContactOut{}, &Error{Code: http.StatusBadRequest, Message: "unknown expand: " + e}
+		}
+	}
 	c, err := s.repo.Get(ctx, id)
 	if err != nil {
 		return ContactOut{}, s.repoErr(err)
 	}
-	return toContactOut(c), nil
+	out := toContactOut(ctx, c)
+	for _, e := range expand {
+		if e == ExpandRelations {
+			if out.Relations, err = s.ListRelations(ctx, id); err != nil {
+				return ContactOut{}, err
+			}
+		}
+	}
+	s.record(ctx, AuditContactRead, id)
+	return out, nil
 }
 
 func (s *Service) UpdateContact(ctx context.Context, id int64, in ContactUpdateIn) (ContactOut, error) {
+	patch, err := s.preparePatch(ctx, in)
+	if err != nil {
+		return ContactOut{}, err
+	}
+	if err := s.requireRole(ctx, id, RoleEditor); err != nil {
+		return ContactOut{}, err
+	}
+	if err := s.requireTagsPatch(ctx, id, patch.Tags); err != nil {
+		return ContactOut{}, err
+	}
+	if err := s.keepHiddenPhones(ctx, id, &patch); err != nil {
+		return ContactOut{}, err
+	}
+	c, err := s.repo.Update(ctx, id, patch)
+	if err != nil {
+		return ContactOut{}, s.repoErr(err)
+	}
+	s.record(ctx, AuditContactUpdate, id)
+	return toContactOut(ctx, c), nil
+}
+
+// preparePatch — валидация и нормализация UpdateContact без записи.
+func (s *Service) preparePatch(ctx context.Context, in ContactUpdateIn) (repository.ContactPatch, error) {
 	if err := s.v.Struct(in); err != nil {
-		return ContactOut{}, &Error{Code: http.StatusUnprocessableEntity, Message: err.Error()}
+		return repository.ContactPatch{}, &Error{Code: http.StatusUnprocessableEntity, Message: err.Error()}
 	}
 
 	var phones *[]repository.PhoneInput
@@ -84,14 +174,18 @@ func (s *Service) UpdateContact(ctx context.Context, id int64, in ContactUpdateI
 		arr := make([]repository.PhoneInput, 0, len(*in.Phones))
 		hasPrimary := false
 		for _, ph := range *in.Phones {
-			e164, digits, ok := normalizer.NormalizePhone(ph.PhoneRaw)
+			// маска скрытого номера, вернувшаяся от клиента: сам номер сохранит keepHiddenPhones
+			if strings.Contains(ph.PhoneRaw, "*") {
+				continue
+			}
+			e164, digits, ok := normalizer.NormalizePhoneRegion(ph.PhoneRaw, regionOf(ctx))
 			if !ok {
-				return ContactOut{}, &Error{Code: http.StatusUnprocessableEntity, Message: "invalid phone"}
+				return repository.ContactPatch{}, &Error{Code: http.StatusUnprocessableEntity, Message: "invalid phone"}
 			}
 			if ph.IsPrimary {
 				hasPrimary = true
 			}
-			arr = append(arr, repository.PhoneInput{Label: ph.Label, PhoneRaw: ph.PhoneRaw, PhoneE164: e164, PhoneDigits: digits, IsPrimary: ph.IsPrimary})
+			arr = append(arr, repository.PhoneInput{Label: ph.Label, PhoneRaw: ph.PhoneRaw, PhoneE164: e164, PhoneDigits: digits, IsPrimary: ph.IsPrimary, Visibility: ph.Visibility})
 		}
 		if !hasPrimary && len(arr) > 0 {
 			arr[0].IsPrimary = true
@@ -99,60 +193,102 @@ func (s *Service) UpdateContact(ctx context.Context, id int64, in ContactUpdateI
 		phones = &arr
 	}
 
-	c, err := s.repo.Update(ctx, id, repository.ContactPatch{
-		FirstName: in.FirstName,
-		LastName:  in.LastName,
-		Company:   in.Company,
-		Phones:    phones,
-	})
-	if err != nil {
-		return ContactOut{}, s.repoErr(err)
+	patch := repository.ContactPatch{
+		FirstName:      in.FirstName,
+		LastName:       in.LastName,
+		Company:        in.Company,
+		OrganizationID: in.OrganizationID,
+		JobTitle:       in.JobTitle,
+		Department:     in.Department,
+		Phones:         phones,
+	}
+	if in.OrganizationID != nil && *in.OrganizationID > 0 {
+		name, err := s.organizationName(ctx, *in.OrganizationID)
+		if err != nil {
+			return repository.ContactPatch{}, err
+		}
+		patch.Company = &name
 	}
-	return toContactOut(c), nil
+	if in.Emails != nil {
+		emails := normalizeEmails(*in.Emails)
+		patch.Emails = &emails
+	}
+	if in.Addresses != nil {
+		addrs, err := normalizeAddresses(*in.Addresses)
+		if err != nil {
+			return repository.ContactPatch{}, err
+		}
+		patch.Addresses = &addrs
+	}
+	if in.Websites != nil {
+		sites := normalizeWebsites(*in.Websites)
+		patch.Websites = &sites
+	}
+	if in.Custom != nil {
+		custom, err := s.validateCustom(ctx, in.Custom, false)
+		if err != nil {
+			return repository.ContactPatch{}, err
+		}
+		patch.Custom = custom
+	}
+	if in.Tags != nil {
+		tags, err := normalizeTags(*in.Tags)
+		if err != nil {
+			return repository.ContactPatch{}, err
+		}
+		patch.Tags = &tags
+	}
+
+	return patch, nil
 }
 
+// DeleteContact — связи с другими контактами (в обе стороны) и метаданные фото удаляются каскадно в БД,
+// файлы фото — здесь же.
 func (s *Service) DeleteContact(ctx context.Context, id int64) error {
+	if err := s.requireRole(ctx, id, RoleEditor); err != nil {
+		return err
+	}
 	if err := s.repo.Delete(ctx, id); err != nil {
 		return s.repoErr(err)
 	}
+	s.dropPhotoBlobs(ctx, id)
+	s.record(ctx, AuditContactDelete, id)
 	return nil
 }
 
 func (s *Service) ListContacts(ctx context.Context, f ListFilter) (ListOut, error) {
-	// Белый список сортировок для устойчивости API
-	sort := map[string]string{"created_at": "created_at", "updated_at": "updated_at", "name": "name"}
-	order := map[string]string{"asc": "asc", "desc": "desc"}
-	sby, ok := sort[strings.ToLower(f.Sort)]
-	if !ok {
-		sby = "updated_at"
-	}
-	ord, ok := order[strings.ToLower(f.Order)]
-	if !ok {
-		ord = "desc"
+	rf, err := s.repoFilter(ctx, f)
+	if err != nil {
+		return ListOut{}, err
 	}
-
-	res, next, err := s.repo.List(ctx, repository.ListFilter{
-		FirstName: f.FirstName,
-		LastName:  f.LastName,
-		Company:   f.Company,
-		Phone:     f.Phone,
-		AfterID:   f.AfterID,
-		Limit:     f.Limit,
-		SortBy:    sby,
-		Order:     ord,
-	})
+	res, next, err := s.repo.List(ctx, rf)
 	if err != nil {
 		return ListOut{}, s.repoErr(err)
 	}
 
 	items := make([]ContactOut, 0, len(res))
 	for _, c := range res {
-		items = append(items, toContactOut(c))
+		items = append(items, toContactOut(ctx, c))
 	}
+	s.record(ctx, AuditContactList, contactIDs(items)...)
 
 	return ListOut{Items: items, Page: PageOut{NextAfterID: next, HasMore: next > 0, Limit: f.Limit}}, nil
 }
 
+// ContactsByIDs — контакты с реквизитами одним запросом; отсутствующие id пропускаются
+func (s *Service) ContactsByIDs(ctx context.Context, ids []int64) ([]ContactOut, error) {
+	res, err := s.repo.ByIDs(ctx, ids)
+	if err != nil {
+		return nil, s.repoErr(err)
+	}
+	out := make([]ContactOut, 0, len(res))
+	for _, c := range res {
+		out = append(out, toContactOut(ctx, c))
+	}
+	s.record(ctx, AuditContactRead, contactIDs(out)...)
+	return out, nil
+}
+
 func (s *Service) Search(ctx context.Context, q string, limit int) ([]ContactOut, error) {
 	res, err := s.repo.Search(ctx, q, limit)
 	if err != nil {
@@ -160,7 +296,65 @@ func (s *Service) Search(ctx context.Context, q string, limit int) ([]ContactOut
 	}
 	out := make([]ContactOut, 0, len(res))
 	for _, c := range res {
-		out = append(out, toContactOut(c))
+		out = append(out, toContactOut(ctx, c))
 	}
+	s.record(ctx, AuditContactSearch, contactIDs(out)...)
 	return out, nil
 }
+
+// repoFilter — проверяет сортировку по белому списку и пользовательские поля по реестру.
+func (s *Service) repoFilter(ctx context.Context, f ListFilter) (repository.ListFilter, error) {
+	// Белый список сортировок для устойчивости API
+	sort := map[string]string{"created_at": "created_at", "updated_at": "updated_at", "name": "name", "id": "id"}
+	order := map[string]string{"asc": "asc", "desc": "desc"}
+	sby, ok := sort[strings.ToLower(f.Sort)]
+	if !ok {
+		sby = "updated_at"
+	}
+	ord, ok := order[strings.ToLower(f.Order)]
+	if !ok {
+		ord = "desc"
+	}
+
+	// пользовательские поля: фильтры и сортировка "cf.<name>" сверяются с реестром
+	var (
+		filters   []repository.CustomFilter
+		sortField string
+		sortType  string
+	)
+	if cf, isCustom := strings.CutPrefix(f.Sort, "cf."); isCustom || len(f.Custom) > 0 {
+		reg, err := s.registry(ctx)
+		if err != nil {
+			return repository.ListFilter{}, err
+		}
+		if filters, err = customFilters(reg, f.Custom); err != nil {
+			return repository.ListFilter{}, err
+		}
+		if isCustom {
+			def, ok := reg[cf]
+			if !ok {
+				return repository.ListFilter{}, &Error{Code: http.StatusUnprocessableEntity, Message: "unknown custom field: " + cf}
+			}
+			sby, sortField, sortType = "custom", def.Name, def.Type
+		}
+	}
+
+	return repository.ListFilter{
+		FirstName:      f.FirstName,
+		LastName:       f.LastName,
+		Company:        f.Company,
+		Phone:          f.Phone,
+		Email:          f.Email,
+		OrganizationID: f.OrganizationID,
+		Tag:            strings.ToLower(strings.TrimSpace(f.Tag)),
+		Custom:         filters,
+		AfterID:        f.AfterID,
+		Limit:          f.Limit,
+		Offset:         f.Offset,
+		SortBy:         sby,
+		SortField:      sortField,
+		SortType:       sortType,
+		Order:          ord,
+		WithoutDetails: f.WithoutDetails,
+	}, nil
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/sunzhqr/phonebook/internal/repository"
+)
+
+func (s *Service) CreateOrganization(ctx context.Context, in OrganizationIn) (OrganizationOut, error) {
+	if err := s.v.Struct(in); err != nil {
+		return OrganizationOut{}, &Error{Code: http.StatusUnprocessableEntity, Message: err.Error()}
+	}
+	phones, err := normalizePhones(in.Phones, regionOf(ctx))
+	if err != nil {
+		return OrganizationOut{}, err
+	}
+	addrs, err := normalizeAddresses(in.Addresses)
+	if err != nil {
+		return OrganizationOut{}, err
+	}
+	o, err := s.orgs.Create(ctx, repository.OrganizationInput{
+		Name:      strings.TrimSpace(in.Name),
+		Phones:    phones,
+		Addresses: addrs,
+	})
+	if err != nil {
+		return OrganizationOut{}, s.repoErr(err)
+	}
+	return toOrganizationOut(o), nil
+}
+
+func (s *Service) GetOrganization(ctx context.Context, id int64) (OrganizationOut, error) {
+	o, err := s.orgs.Get(ctx, id)
+	if err != nil {
+		return OrganizationOut{}, s.repoErr(err)
+	}
+	return toOrganizationOut(o), nil
+}
+
+func (s *Service) UpdateOrganization(ctx context.Context, id int64, in OrganizationUpdateIn) (OrganizationOut, error) {
+	if err := s.v.Struct(in); err != nil {
+		return OrganizationOut{}, &Error{Code: http.StatusUnprocessableEntity, Message: err.Error()}
+	}
+	patch := repository.OrganizationPatch{Name: in.Name}
+	if in.Phones != nil {
+		phones, err := normalizePhones(*in.Phones, regionOf(ctx))
+		if err != nil {
+			return OrganizationOut{}, err
+		}
+		patch.Phones = &phones
+	}
+	if in.Addresses != nil {
+		addrs, err := normalizeAddresses(*in.Addresses)
+		if err != nil {
+			return OrganizationOut{}, err
+		}
+		patch.Addresses = &addrs
+	}
+	o, err := s.orgs.Update(ctx, id, patch)
+	if err != nil {
+		return OrganizationOut{}, s.repoErr(err)
+	}
+	return toOrganizationOut(o), nil
+}
+
+func (s *Service) DeleteOrganization(ctx context.Context, id int64) error {
+	if err := s.orgs.Delete(ctx, id); err != nil {
+		return s.repoErr(err)
+	}
+	return nil
+}
+
+func (s *Service) ListOrganizations(ctx context.Context, f OrganizationListFilter) (OrganizationListOut, error) {
+	res, next, err := s.orgs.List(ctx, repository.OrganizationFilter{
+		Name:    strings.TrimSpace(f.Name),
+		AfterID: f.AfterID,
+		Limit:   f.Limit,
+	})
+	if err != nil {
+		return OrganizationListOut{}, s.repoErr(err)
+	}
+	items := make([]OrganizationOut, 0, len(res))
+	for _, o := range res {
+		items = append(items, toOrganizationOut(o))
+	}
+	return OrganizationListOut{Items: items, Page: PageOut{NextAfterID: next, HasMore: next > 0, Limit: f.Limit}}, nil
+}
+
+// ListOrganizationMembers — контакты организации; фильтры и сортировка как у ListContacts.
+func (s *Service) ListOrganizationMembers(ctx context.Context, id int64, f ListFilter) (ListOut, error) {
+	if _, err := s.orgs.Get(ctx, id); err != nil {
+		return ListOut{}, s.repoErr(err)
+	}
+	f.OrganizationID = id
+	return s.ListContacts(ctx, f)
+}
+
+// organizationName — проверяет ссылку на организацию из контакта и возвращает её имя.
+func (s *Service) organizationName(ctx context.Context, id int64) (string, error) {
+	o, err := s.orgs.Get(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return "", &Error{Code: http.StatusUnprocessableEntity, Message: "unknown organization"}
+	}
+	if err != nil {
+		return "", s.repoErr(err)
+	}
+	return o.Name, nil
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sunzhqr/phonebook/internal/blob"
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/repository"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// PhotoConfig - ограничения на загружаемые фото (реализуется адаптером конфига)
+type PhotoConfig interface {
+	GetMaxBytes() int64
+	GetThumbSize() int
+}
+
+// maxPhotoPixels — предел ширины×высоты: сжатый файл в пределах MaxBytes может
+// раскрыться в гигабайты пикселей, поэтому размеры проверяются по заголовку до декодирования
+const maxPhotoPixels = 40_000_000
+
+var photoTypes = map[string]struct{}{
+	"image/jpeg": {},
+	"image/png":  {},
+	"image/webp": {},
+}
+
+// PhotoData — поток фото для отдачи клиенту; Body закрывает вызывающий.
+type PhotoData struct {
+	ContentType string
+	ETag        string
+	UpdatedAt   time.Time
+	Body        io.ReadCloser
+}
+
+func photoKey(contactID int64) string { return fmt.Sprintf("contacts/%d/photo", contactID) }
+func thumbKey(contactID int64) string { return fmt.Sprintf("contacts/%d/thumb", contactID) }
+
+// PhotoURL — адрес фото контакта в API.
+func PhotoURL(contactID int64) string { return fmt.Sprintf("/api/v1/contacts/%d/photo", contactID) }
+
+// PutPhoto — проверяет размер и тип по содержимому (не по заголовку), сохраняет оригинал и миниатюру.
+// Права проверяются до чтения тела: без них картинку незачем распаковывать.
+func (s *Service) PutPhoto(ctx context.Context, contactID int64, r io.Reader) (PhotoOut, error) {
+	if err := s.requireRole(ctx, contactID, RoleEditor); err != nil {
+		return PhotoOut{}, err
+	}
+	limit := s.photoCfg.GetMaxBytes()
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return PhotoOut{}, &Error{Code: http.StatusBadRequest, Message: "cannot read body"}
+	}
+	if int64(len(data)) > limit {
+		return PhotoOut{}, &Error{Code: http.StatusRequestEntityTooLarge, Message: fmt.Sprintf("photo is larger than %d bytes", limit)}
+	}
+	ct := http.DetectContentType(data)
+	if _, ok := photoTypes[ct]; !ok {
+		return PhotoOut{}, &Error{Code: http.StatusUnsupportedMediaType, Message: "photo must be JPEG, PNG or WebP"}
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return PhotoOut{}, &Error{Code: http.StatusUnprocessableEntity, Message: "cannot decode image"}
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxPhotoPixels {
+		return PhotoOut{}, &Error{Code: http.StatusUnprocessableEntity, Message: fmt.Sprintf("photo is larger than %d pixels", maxPhotoPixels)}
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return PhotoOut{}, &Error{Code: http.StatusUnprocessableEntity, Message: "cannot decode image"}
+	}
+
+	thumb, err := makeThumbnail(img, s.photoCfg.GetThumbSize())
+	if err != nil {
+		return PhotoOut{}, &Error{Code: http.StatusInternalServerError, Message: "internal"}
+	}
+	if err := s.blobs.Put(ctx, photoKey(contactID), bytes.NewReader(data)); err != nil {
+		s.lg.Error("photo store failed", logger.Err(err))
+		return PhotoOut{}, &Error{Code: http.StatusInternalServerError, Message: "internal"}
+	}
+	if err := s.blobs.Put(ctx, thumbKey(contactID), bytes.NewReader(thumb)); err != nil {
+		s.lg.Error("thumbnail store failed", logger.Err(err))
+		return PhotoOut{}, &Error{Code: http.StatusInternalServerError, Message: "internal"}
+	}
+
+	sum := sha256.Sum256(data)
+	b := img.Bounds()
+	p, err := s.photos.Upsert(ctx, repository.Photo{
+		ContactID:   contactID,
+		ContentType: ct,
+		Size:        int64(len(data)),
+		Width:       b.Dx(),
+		Height:      b.Dy(),
+		ETag:        hex.EncodeToString(sum[:16]),
+	})
+	if err != nil {
+		return PhotoOut{}, s.repoErr(err)
+	}
+	s.record(ctx, AuditPhotoUpdate, contactID)
+	return toPhotoOut(p), nil
+}
+
+// GetPhoto — оригинал или JPEG-миниатюра (thumb=true).
+func (s *Service) GetPhoto(ctx context.Context, contactID int64, thumb bool) (PhotoData, error) {
+	p, err := s.photos.Get(ctx, contactID)
+	if err != nil {
+		return PhotoData{}, s.repoErr(err)
+	}
+	key, ct, etag := photoKey(contactID), p.ContentType, p.ETag
+	if thumb {
+		key, ct, etag = thumbKey(contactID), "image/jpeg", p.ETag+"-thumb"
+	}
+	body, err := s.blobs.Get(ctx, key)
+	if errors.Is(err, blob.ErrNotFound) {
+		return PhotoData{}, &Error{Code: http.StatusNotFound, Message: "not found"}
+	}
+	if err != nil {
+		s.lg.Error("photo read failed", logger.Err(err))
+		return PhotoData{}, &Error{Code: http.StatusInternalServerError, Message: "internal"}
+	}
+	s.record(ctx, AuditPhotoRead, contactID)
+	return PhotoData{ContentType: ct, ETag: etag, UpdatedAt: p.UpdatedAt, Body: body}, nil
+}
+
+func (s *Service) DeletePhoto(ctx context.Context, contactID int64) error {
+	if err := s.requireRole(ctx, contactID, RoleEditor); err != nil {
+		return err
+	}
+	if err := s.photos.Delete(ctx, contactID); err != nil {
+		return s.repoErr(err)
+	}
+	s.dropPhotoBlobs(ctx, contactID)
+	s.record(ctx, AuditPhotoDelete, contactID)
+	return nil
+}
+
+// dropPhotoBlobs — best-effort: метаданные уже удалены, осиротевший файл не мешает работе.
+func (s *Service) dropPhotoBlobs(ctx context.Context, contactID int64) {
+	for _, key := range []string{photoKey(contactID), thumbKey(contactID)} {
+		if err := s.blobs.Delete(ctx, key); err != nil {
+			s.lg.Warn("photo blob delete failed", logger.KV("key", key), logger.Err(err))
+		}
+	}
+}
+
+// makeThumbnail — вписывает изображение в квадрат size×size и кодирует в JPEG.
+func makeThumbnail(src image.Image, size int) ([]byte, error) {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > size || h > size {
+		if w >= h {
+			w, h = size, max(1, h*size/w)
+		} else {
+			w, h = max(1, w*size/h), size
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	// у JPEG нет альфа-канала — прозрачные области PNG/WebP заливаем белым
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Over, nil)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func toPhotoOut(p repository.Photo) PhotoOut {
+	return PhotoOut{
+		URL:         PhotoURL(p.ContactID),
+		ThumbURL:    PhotoURL(p.ContactID) + "?size=thumb",
+		ContentType: p.ContentType,
+		Size:        p.Size,
+		Width:       p.Width,
+		Height:      p.Height,
+		UpdatedAt:   p.UpdatedAt,
+	}
+}
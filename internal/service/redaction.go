@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"slices"
+
+	"github.com/sunzhqr/phonebook/internal/repository"
+	"github.com/sunzhqr/phonebook/pkg/normalizer"
+)
+
+// Видимость номера. public — всем, кто видит контакт, включая LDAP без субъекта; internal —
+// редакторам и администраторам книги; private — администраторам книги и владельцу контакта.
+const (
+	PhonePublic   = "public"
+	PhoneInternal = "internal"
+	PhonePrivate  = "private"
+)
+
+// phoneLevels — уровни номеров, видимые p на чужих контактах; nil — все
+func (p Principal) phoneLevels() []string {
+	switch p.BookRole() {
+	case RoleAdmin:
+		return nil
+	case RoleEditor:
+		return []string{PhonePublic, PhoneInternal}
+	}
+	return []string{PhonePublic}
+}
+
+// phoneVisible — увидит ли вызывающий номер с видимостью vis на контакте c
+func phoneVisible(ctx context.Context, c repository.Contact, vis string) bool {
+	if vis == "" || vis == PhonePublic {
+		return true
+	}
+	p, ok := PrincipalFrom(ctx)
+	if !ok {
+		return false
+	}
+	if c.Owner != "" && c.Owner == p.Subject {
+		return true
+	}
+	levels := p.phoneLevels()
+	return levels == nil || slices.Contains(levels, vis)
+}
+
+// redactPhone — скрытый номер отдаётся маской в phone_raw и phone_e164
+func redactPhone(out *PhoneOut) {
+	out.PhoneRaw = normalizer.MaskPhone(out.PhoneE164)
+	out.PhoneE164 = out.PhoneRaw
+	out.Redacted = true
+}
+
+// keepHiddenPhones — замена телефонов не затирает номера, которых вызывающий не видит: они
+// остаются у контакта, а их маски в запросе (клиент прислал контакт обратно) отброшены в preparePatch.
+// Номер без видимости сохраняет ту, что была у него в контакте.
+func (s *Service) keepHiddenPhones(ctx context.Context, id int64, patch *repository.ContactPatch) error {
+	if patch.Phones == nil {
+		return nil
+	}
+	cur, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return s.repoErr(err)
+	}
+	phones := withHiddenPhones(ctx, cur, *patch.Phones)
+	patch.Phones = &phones
+	return nil
+}
+
+// withHiddenPhones — новый набор телефонов для cur. Видимость не пришла (gRPC, CardDAV, vCard
+// её не передают) — берётся у того же номера в cur, иначе public. Скрытые от вызывающего номера
+// cur дописываются; основной среди них остаётся основным, только если в наборе основного нет.
+func withHiddenPhones(ctx context.Context, cur repository.Contact, phones []repository.PhoneInput) []repository.PhoneInput {
+	stored := make(map[string]string, len(cur.Phones))
+	for _, ph := range cur.Phones {
+		stored[ph.PhoneDigits] = ph.Visibility
+	}
+	hasPrimary := false
+	seen := make(map[string]bool, len(phones))
+	for i, ph := range phones {
+		if ph.Visibility == "" {
+			phones[i].Visibility = stored[ph.PhoneDigits]
+		}
+		seen[ph.PhoneDigits] = true
+		hasPrimary = hasPrimary || ph.IsPrimary
+	}
+	for _, ph := range cur.Phones {
+		if phoneVisible(ctx, cur, ph.Visibility) || seen[ph.PhoneDigits] {
+			continue
+		}
+		phones = append(phones, repository.PhoneInput{Label: ph.Label, PhoneRaw: ph.PhoneRaw, PhoneE164: ph.PhoneE164,
+			PhoneDigits: ph.PhoneDigits, IsPrimary: ph.IsPrimary && !hasPrimary, Visibility: ph.Visibility})
+		hasPrimary = hasPrimary || ph.IsPrimary
+	}
+	return phones
+}
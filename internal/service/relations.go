@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/sunzhqr/phonebook/internal/repository"
+)
+
+// ExpandRelations — значение expand для GetContact, подгружающее связи контакта
+const ExpandRelations = "relations"
+
+// relationInverse — допустимые типы связей и их обратные типы.
+// Запись "A assistant_of B" при bidirectional даёт обратную "B has_assistant A".
+var relationInverse = map[string]string{
+	"assistant_of":  "has_assistant",
+	"has_assistant": "assistant_of",
+	"manager_of":    "reports_to",
+	"reports_to":    "manager_of",
+	"spouse_of":     "spouse_of",
+	"partner_of":    "partner_of",
+	"parent_of":     "child_of",
+	"child_of":      "parent_of",
+	"sibling_of":    "sibling_of",
+	"colleague_of":  "colleague_of",
+	"friend_of":     "friend_of",
+}
+
+// RelationTypes — список допустимых типов связей (для документации и ошибок валидации).
+func RelationTypes() []string {
+	out := make([]string, 0, len(relationInverse))
+	for t := range relationInverse {
+		out = append(out, t)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (s *Service) CreateRelation(ctx context.Context, contactID int64, in RelationIn) (RelationOut, error) {
+	if err := s.v.Struct(in); err != nil {
+		return RelationOut{}, &Error{Code: http.StatusUnprocessableEntity, Message: err.Error()}
+	}
+	typ := strings.ToLower(strings.TrimSpace(in.Type))
+	inverse, ok := relationInverse[typ]
+	if !ok {
+		return RelationOut{}, &Error{Code: http.StatusUnprocessableEntity, Message: "unknown relation type, expected one of: " + strings.Join(RelationTypes(), ", ")}
+	}
+	if in.RelatedID == contactID {
+		return RelationOut{}, &Error{Code: http.StatusUnprocessableEntity, Message: "contact cannot relate to itself"}
+	}
+	// 404 для самого контакта; несуществующий или невидимый related_id — 422
+	if err := s.requireRole(ctx, contactID, RoleEditor); err != nil {
+		return RelationOut{}, err
+	}
+	ri := repository.RelationInput{ContactID: contactID, RelatedID: in.RelatedID, Type: typ}
+	if in.Bidirectional {
+		ri.InverseType = inverse
+	}
+	rel, err := s.rels.Create(ctx, ri)
+	if err != nil {
+		return RelationOut{}, s.repoErr(err)
+	}
+	s.record(ctx, AuditRelationWrite, contactID, in.RelatedID)
+	return toRelationOut(rel), nil
+}
+
+func (s *Service) ListRelations(ctx context.Context, contactID int64) ([]RelationOut, error) {
+	list, err := s.rels.List(ctx, contactID)
+	if err != nil {
+		return nil, s.repoErr(err)
+	}
+	out := make([]RelationOut, 0, len(list))
+	for _, rel := range list {
+		out = append(out, toRelationOut(rel))
+	}
+	s.record(ctx, AuditRelationRead, contactID)
+	return out, nil
+}
+
+func (s *Service) DeleteRelation(ctx context.Context, contactID, relationID int64) error {
+	if err := s.requireRole(ctx, contactID, RoleEditor); err != nil {
+		return err
+	}
+	if err := s.rels.Delete(ctx, contactID, relationID); err != nil {
+		return s.repoErr(err)
+	}
+	s.record(ctx, AuditRelationWrite, contactID)
+	return nil
+}
+
+func toRelationOut(rel repository.Relation) RelationOut {
+	return RelationOut{
+		ID:   rel.ID,
+		Type: rel.Type,
+		Related: ContactRef{
+			ID:        rel.RelatedID,
+			FirstName: rel.RelatedFirstName,
+			LastName:  rel.RelatedLastName,
+		},
+		Bidirectional: rel.Bidirectional,
+		CreatedAt:     rel.CreatedAt,
+	}
+}
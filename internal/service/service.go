@@ -2,8 +2,10 @@ package service
 
 import (
 	"context"
+	"io"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/sunzhqr/phonebook/internal/blob"
 	"github.com/sunzhqr/phonebook/internal/logger"
 	"github.com/sunzhqr/phonebook/internal/repository"
 )
@@ -11,24 +13,190 @@ import (
 // ContactsService - интерфейс(контракт) для взаимодействия со слоем сервиса
 type ContactsService interface {
 	CreateContact(ctx context.Context, in ContactCreateIn) (ContactOut, error)
-	GetContact(ctx context.Context, id int64) (ContactOut, error)
+	// GetContact — expand подгружает связанные данные ("relations")
+	GetContact(ctx context.Context, id int64, expand ...string) (ContactOut, error)
 	UpdateContact(ctx context.Context, id int64, in ContactUpdateIn) (ContactOut, error)
 	DeleteContact(ctx context.Context, id int64) error
 	ListContacts(ctx context.Context, f ListFilter) (ListOut, error)
 	Search(ctx context.Context, q string, limit int) ([]ContactOut, error)
+	ContactsByIDs(ctx context.Context, ids []int64) ([]ContactOut, error)
+	// UpsertExternal — created=true, если контакта с таким внешним ключом ещё не было
+	UpsertExternal(ctx context.Context, source, externalID string, in ContactCreateIn) (out ContactOut, created bool, err error)
+	GetByExternal(ctx context.Context, source, externalID string) (ContactOut, error)
+}
+
+// IdempotencyService - сохранённые ответы на запросы с заголовком Idempotency-Key
+type IdempotencyService interface {
+	BeginIdempotent(ctx context.Context, key, fingerprint string) (*StoredResponse, error)
+	CompleteIdempotent(ctx context.Context, key string, resp StoredResponse) error
+	HoldIdempotent(ctx context.Context, key string) (stop func())
+	ReleaseIdempotent(ctx context.Context, key string) error
+}
+
+// EventsService - лента изменений контактов
+type EventsService interface {
+	EventsSince(ctx context.Context, after int64, limit int) ([]EventOut, error)
+	EventsHead(ctx context.Context) (int64, error)
+	ListenEvents(ctx context.Context, onListen func(), notify func()) error
+}
+
+// WebhooksService - подписки на события контактов и журнал их доставок
+type WebhooksService interface {
+	CreateWebhook(ctx context.Context, in WebhookCreateIn) (WebhookOut, error)
+	GetWebhook(ctx context.Context, id int64) (WebhookOut, error)
+	ListWebhooks(ctx context.Context) ([]WebhookOut, error)
+	UpdateWebhook(ctx context.Context, id int64, in WebhookUpdateIn) (WebhookOut, error)
+	DeleteWebhook(ctx context.Context, id int64) error
+	ListDeliveries(ctx context.Context, webhookID int64, f DeliveryListFilter) ([]DeliveryOut, error)
+	Redeliver(ctx context.Context, webhookID, deliveryID int64) error
+}
+
+// DeliveryQueue - очередь доставок для диспетчера вебхуков
+type DeliveryQueue interface {
+	FanOutOutbox(ctx context.Context) (int64, error)
+	ClaimDeliveries(ctx context.Context, limit int) ([]PendingDelivery, error)
+	CompleteDelivery(ctx context.Context, d PendingDelivery, res DeliveryResult) error
+}
+
+// SyncService - инкрементальная синхронизация офлайн-копий клиентов
+type SyncService interface {
+	Sync(ctx context.Context, token string) (SyncOut, error)
+	SyncToken(ctx context.Context) (string, error)
+}
+
+// BatchService - интерфейс пакетных операций над контактами
+type BatchService interface {
+	Batch(ctx context.Context, ops []BatchOpIn, atomic bool) (BatchOut, error)
+}
+
+// CustomFieldsService - интерфейс реестра пользовательских полей
+type CustomFieldsService interface {
+	CreateCustomField(ctx context.Context, in CustomFieldIn) (CustomFieldOut, error)
+	GetCustomField(ctx context.Context, name string) (CustomFieldOut, error)
+	ListCustomFields(ctx context.Context) ([]CustomFieldOut, error)
+	UpdateCustomField(ctx context.Context, name string, in CustomFieldUpdateIn) (CustomFieldOut, error)
+	DeleteCustomField(ctx context.Context, name string) error
+}
+
+// OrganizationsService - интерфейс для работы с организациями
+type OrganizationsService interface {
+	CreateOrganization(ctx context.Context, in OrganizationIn) (OrganizationOut, error)
+	GetOrganization(ctx context.Context, id int64) (OrganizationOut, error)
+	UpdateOrganization(ctx context.Context, id int64, in OrganizationUpdateIn) (OrganizationOut, error)
+	DeleteOrganization(ctx context.Context, id int64) error
+	ListOrganizations(ctx context.Context, f OrganizationListFilter) (OrganizationListOut, error)
+	ListOrganizationMembers(ctx context.Context, id int64, f ListFilter) (ListOut, error)
+}
+
+// RelationsService - интерфейс для управления связями между контактами
+type RelationsService interface {
+	CreateRelation(ctx context.Context, contactID int64, in RelationIn) (RelationOut, error)
+	ListRelations(ctx context.Context, contactID int64) ([]RelationOut, error)
+	DeleteRelation(ctx context.Context, contactID, relationID int64) error
+}
+
+// PhotosService - интерфейс для загрузки и отдачи фото контактов
+type PhotosService interface {
+	PutPhoto(ctx context.Context, contactID int64, r io.Reader) (PhotoOut, error)
+	GetPhoto(ctx context.Context, contactID int64, thumb bool) (PhotoData, error)
+	DeletePhoto(ctx context.Context, contactID int64) error
+}
+
+// ImportService - интерфейс массового импорта контактов
+type ImportService interface {
+	ImportContacts(ctx context.Context, items []ImportItem) (ImportReport, error)
+	ImportCSV(ctx context.Context, r io.Reader, m CSVMapping, dryRun bool) (ImportReport, error)
+}
+
+// ExportService - интерфейс потоковой выгрузки контактов
+type ExportService interface {
+	ExportContacts(ctx context.Context, f ListFilter, fn func(ContactOut) error) error
+	ExportPhoneColumns(ctx context.Context) (int, error)
+}
+
+// AuthService - проверка учётных данных запроса
+type AuthService interface {
+	Authenticate(ctx context.Context, key string) (Principal, error)
+}
+
+// APIKeysService - выпуск и отзыв ключей API
+type APIKeysService interface {
+	CreateAPIKey(ctx context.Context, in APIKeyCreateIn) (APIKeyOut, error)
+	GetAPIKey(ctx context.Context, id int64) (APIKeyOut, error)
+	ListAPIKeys(ctx context.Context) ([]APIKeyOut, error)
+	RevokeAPIKey(ctx context.Context, id int64) (APIKeyOut, error)
+}
+
+// AccessService - выдачи ролей на отдельные контакты и теги
+type AccessService interface {
+	GetContactACL(ctx context.Context, id int64) (ACLOut, error)
+	SetContactACL(ctx context.Context, id int64, in ACLIn) (ACLOut, error)
+	GetTagACL(ctx context.Context, tag string) (ACLOut, error)
+	SetTagACL(ctx context.Context, tag string, in ACLIn) (ACLOut, error)
+}
+
+// AddressBooksService - адресные книги и выбор книги запроса
+type AddressBooksService interface {
+	// ResolveAddressBook — книга по slug; неизвестная — 404
+	ResolveAddressBook(ctx context.Context, slug string) (AddressBookOut, error)
+	CreateAddressBook(ctx context.Context, in AddressBookIn) (AddressBookOut, error)
+	ListAddressBooks(ctx context.Context) ([]AddressBookOut, error)
+	UpdateAddressBook(ctx context.Context, slug string, in AddressBookUpdateIn) (AddressBookOut, error)
+}
+
+// AuditService - журнал аудита
+type AuditService interface {
+	ListAudit(ctx context.Context, f AuditFilter) ([]AuditEntry, error)
+}
+
+// AuditSink - приёмник записей аудита; Record не блокирует вызов сервиса
+type AuditSink interface {
+	Record(e AuditEntry)
+}
+
+// AuditStore - запись пачки аудита в БД для приёмника (audit.Log)
+type AuditStore interface {
+	WriteAudit(ctx context.Context, entries []AuditEntry) error
 }
 
 type Service struct {
-	lg   *logger.Logger
-	repo repository.ContactsRepository
-	v    *validator.Validate
+	lg       *logger.Logger
+	repo     repository.ContactsRepository
+	fields   repository.CustomFieldsRepository
+	orgs     repository.OrganizationsRepository
+	rels     repository.RelationsRepository
+	photos   repository.PhotosRepository
+	idem     repository.IdempotencyRepository
+	events   repository.EventsRepository
+	hooks    repository.WebhooksRepository
+	keys     repository.APIKeysRepository
+	books    repository.AddressBooksRepository
+	acl      repository.ACLRepository
+	audits   repository.AuditRepository
+	audit    AuditSink // nil — аудит не ведётся
+	blobs    blob.Store
+	photoCfg PhotoConfig
+	v        *validator.Validate
 }
 
-func New(lg *logger.Logger, repo repository.ContactsRepository) *Service {
+func New(lg *logger.Logger, repos *repository.Repos, blobs blob.Store, photoCfg PhotoConfig) *Service {
 	v := validator.New(validator.WithRequiredStructEnabled())
 	return &Service{
-		lg:   lg,
-		repo: repo,
-		v:    v,
+		lg:       lg,
+		repo:     repos.Contacts,
+		fields:   repos.CustomFields,
+		orgs:     repos.Organizations,
+		rels:     repos.Relations,
+		photos:   repos.Photos,
+		idem:     repos.Idempotency,
+		events:   repos.Events,
+		hooks:    repos.Webhooks,
+		keys:     repos.APIKeys,
+		books:    repos.AddressBooks,
+		acl:      repos.ACL,
+		audits:   repos.Audit,
+		blobs:    blobs,
+		photoCfg: photoCfg,
+		v:        v,
 	}
 }
@@ -1,11 +1,24 @@
 package service_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
 	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/sunzhqr/phonebook/internal/blob"
 	"github.com/sunzhqr/phonebook/internal/logger"
 	"github.com/sunzhqr/phonebook/internal/repository"
 	"github.com/sunzhqr/phonebook/internal/service"
@@ -18,6 +31,11 @@ type mockRepo struct {
 	DeleteFn func(context.Context, int64) error
 	ListFn   func(context.Context, repository.ListFilter) ([]repository.Contact, int64, error)
 	SearchFn func(context.Context, string, int) ([]repository.Contact, error)
+	BatchFn  func(context.Context, []repository.BatchOp, bool) ([]repository.BatchResult, error)
+	UpsertFn func(context.Context, string, string, repository.ContactInput) (repository.Contact, bool, error)
+	ExtFn    func(context.Context, string, string) (repository.Contact, error)
+	Owners   map[string]int64
+	Stored   []repository.Contact
 }
 
 func (m *mockRepo) Create(ctx context.Context, in repository.ContactInput) (repository.Contact, error) {
@@ -36,9 +54,344 @@ func (m *mockRepo) List(ctx context.Context, f repository.ListFilter) ([]reposit
 func (m *mockRepo) Search(ctx context.Context, q string, limit int) ([]repository.Contact, error) {
 	return m.SearchFn(ctx, q, limit)
 }
+func (m *mockRepo) Stream(_ context.Context, _ repository.ListFilter, fn func(repository.Contact) error) error {
+	for _, c := range m.Stored {
+		if err := fn(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func (m *mockRepo) Batch(ctx context.Context, ops []repository.BatchOp, atomic bool) ([]repository.BatchResult, error) {
+	return m.BatchFn(ctx, ops, atomic)
+}
+func (m *mockRepo) UpsertExternal(ctx context.Context, source, extID string, in repository.ContactInput) (repository.Contact, bool, error) {
+	return m.UpsertFn(ctx, source, extID, in)
+}
+func (m *mockRepo) GetByExternal(ctx context.Context, source, extID string) (repository.Contact, error) {
+	if m.ExtFn != nil {
+		return m.ExtFn(ctx, source, extID)
+	}
+	return repository.Contact{}, repository.ErrNotFound
+}
+func (m *mockRepo) ByIDs(_ context.Context, ids []int64) ([]repository.Contact, error) {
+	out := []repository.Contact{}
+	for _, c := range m.Stored {
+		if slices.Contains(ids, c.ID) {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+func (m *mockRepo) MaxPhones(context.Context) (int, error) {
+	n := 0
+	for _, c := range m.Stored {
+		n = max(n, len(c.Phones))
+	}
+	return n, nil
+}
+func (m *mockRepo) PhoneOwners(_ context.Context, digits []string) (map[string]int64, error) {
+	out := make(map[string]int64)
+	for _, d := range digits {
+		if id, ok := m.Owners[d]; ok {
+			out[d] = id
+		}
+	}
+	return out, nil
+}
+
+type mockFields struct {
+	fields []repository.CustomField
+}
+
+func (m *mockFields) Create(_ context.Context, in repository.CustomFieldInput) (repository.CustomField, error) {
+	return repository.CustomField{Name: in.Name, Type: in.Type, Required: in.Required, Constraints: in.Constraints}, nil
+}
+func (m *mockFields) Get(_ context.Context, name string) (repository.CustomField, error) {
+	for _, f := range m.fields {
+		if f.Name == name {
+			return f, nil
+		}
+	}
+	return repository.CustomField{}, repository.ErrNotFound
+}
+func (m *mockFields) List(context.Context) ([]repository.CustomField, error) { return m.fields, nil }
+func (m *mockFields) Update(_ context.Context, name string, _ repository.CustomFieldPatch) (repository.CustomField, error) {
+	return m.Get(context.Background(), name)
+}
+func (m *mockFields) Delete(context.Context, string) error { return nil }
+
+type mockOrgs struct {
+	orgs map[int64]repository.Organization
+}
+
+func (m *mockOrgs) Create(_ context.Context, in repository.OrganizationInput) (repository.Organization, error) {
+	return repository.Organization{ID: int64(len(m.orgs) + 1), Name: in.Name}, nil
+}
+func (m *mockOrgs) Get(_ context.Context, id int64) (repository.Organization, error) {
+	if o, ok := m.orgs[id]; ok {
+		return o, nil
+	}
+	return repository.Organization{}, repository.ErrNotFound
+}
+func (m *mockOrgs) Update(ctx context.Context, id int64, _ repository.OrganizationPatch) (repository.Organization, error) {
+	return m.Get(ctx, id)
+}
+func (m *mockOrgs) Delete(context.Context, int64) error { return nil }
+func (m *mockOrgs) List(context.Context, repository.OrganizationFilter) ([]repository.Organization, int64, error) {
+	return nil, 0, nil
+}
+
+type mockRels struct {
+	created []repository.RelationInput
+}
+
+func (m *mockRels) Create(_ context.Context, in repository.RelationInput) (repository.Relation, error) {
+	m.created = append(m.created, in)
+	return repository.Relation{ID: int64(len(m.created)), ContactID: in.ContactID, RelatedID: in.RelatedID, Type: in.Type, Bidirectional: in.InverseType != ""}, nil
+}
+func (m *mockRels) List(_ context.Context, contactID int64) ([]repository.Relation, error) {
+	out := make([]repository.Relation, 0, len(m.created))
+	for i, in := range m.created {
+		if in.ContactID == contactID {
+			out = append(out, repository.Relation{ID: int64(i + 1), ContactID: in.ContactID, RelatedID: in.RelatedID, Type: in.Type})
+		}
+	}
+	return out, nil
+}
+func (m *mockRels) Delete(context.Context, int64, int64) error { return nil }
+
+// mockACL — доступ по умолчанию: контакт без выдач, если он есть в mockRepo
+type mockACL struct {
+	contacts *mockRepo
+	access   map[int64]repository.ContactAccess
+	grants   map[int64][]repository.Grant
+}
+
+func (m *mockACL) Access(ctx context.Context, id int64) (repository.ContactAccess, error) {
+	if a, ok := m.access[id]; ok {
+		return a, nil
+	}
+	if m.contacts.GetFn != nil {
+		if _, err := m.contacts.GetFn(ctx, id); err != nil {
+			return repository.ContactAccess{}, err
+		}
+	}
+	return repository.ContactAccess{}, nil
+}
+func (m *mockACL) ContactGrants(_ context.Context, id int64) ([]repository.Grant, error) {
+	return m.grants[id], nil
+}
+func (m *mockACL) SetContactGrants(_ context.Context, id int64, g []repository.Grant) error {
+	if m.grants == nil {
+		m.grants = make(map[int64][]repository.Grant)
+	}
+	m.grants[id] = g
+	return nil
+}
+func (m *mockACL) TagGrants(context.Context, string) ([]repository.Grant, error)  { return nil, nil }
+func (m *mockACL) SetTagGrants(context.Context, string, []repository.Grant) error { return nil }
+
+type mockPhotos struct {
+	photos map[int64]repository.Photo
+}
+
+func (m *mockPhotos) Upsert(_ context.Context, p repository.Photo) (repository.Photo, error) {
+	p.UpdatedAt = time.Now().UTC()
+	m.photos[p.ContactID] = p
+	return p, nil
+}
+func (m *mockPhotos) Get(_ context.Context, contactID int64) (repository.Photo, error) {
+	if p, ok := m.photos[contactID]; ok {
+		return p, nil
+	}
+	return repository.Photo{}, repository.ErrNotFound
+}
+func (m *mockPhotos) Delete(_ context.Context, contactID int64) error {
+	delete(m.photos, contactID)
+	return nil
+}
+
+// mockIdem — ключи идемпотентности в памяти; mu — дубликаты в тесте идут из разных горутин
+type mockIdem struct {
+	mu   sync.Mutex
+	keys map[string]repository.IdempotencyKey
+}
+
+func idemID(k repository.IdempotencyKey) string {
+	return fmt.Sprintf("%s|%d|%s", k.Subject, k.AddressBookID, k.Key)
+}
+
+func (m *mockIdem) Reserve(_ context.Context, k repository.IdempotencyKey) (repository.IdempotencyKey, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cur, ok := m.keys[idemID(k)]; ok {
+		return cur, false, nil
+	}
+	m.keys[idemID(k)] = k
+	return k, true, nil
+}
+func (m *mockIdem) Get(_ context.Context, id repository.IdempotencyKey) (repository.IdempotencyKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if k, ok := m.keys[idemID(id)]; ok {
+		return k, nil
+	}
+	return repository.IdempotencyKey{}, repository.ErrNotFound
+}
+func (m *mockIdem) Complete(_ context.Context, k repository.IdempotencyKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cur := m.keys[idemID(k)]
+	cur.Status, cur.Header, cur.Body = k.Status, k.Header, k.Body
+	m.keys[idemID(k)] = cur
+	return nil
+}
+func (m *mockIdem) Extend(_ context.Context, k repository.IdempotencyKey, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cur, ok := m.keys[idemID(k)]; ok && cur.Status == 0 {
+		cur.LockedUntil = until
+		m.keys[idemID(k)] = cur
+	}
+	return nil
+}
+func (m *mockIdem) Release(_ context.Context, k repository.IdempotencyKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.keys[idemID(k)].Status == 0 {
+		delete(m.keys, idemID(k))
+	}
+	return nil
+}
+func (m *mockIdem) DeleteExpired(context.Context) (int64, error) { return 0, nil }
+
+// mockHooks — подписки и итоги доставок в памяти
+type mockHooks struct {
+	hooks    map[int64]repository.Webhook
+	finished map[int64]repository.DeliveryUpdate
+}
+
+func (m *mockHooks) Create(_ context.Context, in repository.WebhookInput) (repository.Webhook, error) {
+	w := repository.Webhook{ID: int64(len(m.hooks) + 1), URL: in.URL, Secret: in.Secret, Events: in.Events, Active: true}
+	m.hooks[w.ID] = w
+	return w, nil
+}
+func (m *mockHooks) Get(_ context.Context, id int64) (repository.Webhook, error) {
+	if w, ok := m.hooks[id]; ok {
+		return w, nil
+	}
+	return repository.Webhook{}, repository.ErrNotFound
+}
+func (m *mockHooks) List(context.Context) ([]repository.Webhook, error) { return nil, nil }
+func (m *mockHooks) Update(ctx context.Context, id int64, _ repository.WebhookPatch) (repository.Webhook, error) {
+	return m.Get(ctx, id)
+}
+func (m *mockHooks) Delete(context.Context, int64) error { return nil }
+func (m *mockHooks) Deliveries(context.Context, repository.DeliveryFilter) ([]repository.Delivery, error) {
+	return nil, nil
+}
+func (m *mockHooks) Redeliver(context.Context, int64, int64) error         { return nil }
+func (m *mockHooks) FanOut(context.Context, int) (int64, error)            { return 0, nil }
+func (m *mockHooks) PurgeOutbox(context.Context, time.Time) (int64, error) { return 0, nil }
+func (m *mockHooks) Claim(context.Context, int, time.Duration) ([]repository.PendingDelivery, error) {
+	return nil, nil
+}
+func (m *mockHooks) Finish(_ context.Context, id int64, u repository.DeliveryUpdate) error {
+	m.finished[id] = u
+	return nil
+}
+
+// mockEventLog — журнал изменений с уже выданными seq
+type mockEventLog struct {
+	log   []repository.ContactEvent
+	state repository.SyncState
+}
+
+func (m *mockEventLog) Since(context.Context, int64, int) ([]repository.ContactEvent, error) {
+	return nil, nil
+}
+func (m *mockEventLog) Listen(context.Context, func(), func(repository.ContactEvent)) error {
+	return nil
+}
+func (m *mockEventLog) DeleteBefore(context.Context, time.Time) (int64, error) { return 0, nil }
+func (m *mockEventLog) Sequence(context.Context) (repository.SyncState, error) { return m.state, nil }
+func (m *mockEventLog) ChangesSince(_ context.Context, seq int64, limit int) ([]repository.ContactEvent, error) {
+	var out []repository.ContactEvent
+	for _, e := range m.log {
+		if e.Seq > seq && len(out) < limit {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+type mockKeys struct {
+	keys []repository.APIKey
+}
+
+func (m *mockKeys) Create(_ context.Context, in repository.APIKeyInput) (repository.APIKey, error) {
+	k := repository.APIKey{ID: int64(len(m.keys) + 1), Name: in.Name, Prefix: in.Prefix, KeyHash: in.KeyHash, Scopes: in.Scopes, ExpiresAt: in.ExpiresAt}
+	m.keys = append(m.keys, k)
+	return k, nil
+}
+func (m *mockKeys) Get(_ context.Context, id int64) (repository.APIKey, error) {
+	return m.keys[id-1], nil
+}
+func (m *mockKeys) ByHash(_ context.Context, hash []byte) (repository.APIKey, error) {
+	for _, k := range m.keys {
+		if bytes.Equal(k.KeyHash, hash) {
+			return k, nil
+		}
+	}
+	return repository.APIKey{}, repository.ErrNotFound
+}
+func (m *mockKeys) List(context.Context) ([]repository.APIKey, error) { return m.keys, nil }
+func (m *mockKeys) Revoke(_ context.Context, id int64) (repository.APIKey, error) {
+	now := time.Now()
+	m.keys[id-1].RevokedAt = &now
+	return m.keys[id-1], nil
+}
+func (m *mockKeys) Touch(context.Context, int64) error { return nil }
+
+type memBlobs map[string][]byte
+
+func (m memBlobs) Put(_ context.Context, key string, r io.Reader) error {
+	b, err := io.ReadAll(r)
+	m[key] = b
+	return err
+}
+func (m memBlobs) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	b, ok := m[key]
+	if !ok {
+		return nil, blob.ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
+func (m memBlobs) Delete(_ context.Context, key string) error {
+	delete(m, key)
+	return nil
+}
+
+type photoCfg struct{}
+
+func (photoCfg) GetMaxBytes() int64 { return 64 << 10 }
+func (photoCfg) GetThumbSize() int  { return 32 }
+
+func newService(mr *mockRepo, fields ...repository.CustomField) *service.Service {
+	return service.New(logger.New("dev"), &repository.Repos{
+		Contacts:      mr,
+		ACL:           &mockACL{contacts: mr},
+		CustomFields:  &mockFields{fields: fields},
+		Organizations: &mockOrgs{orgs: map[int64]repository.Organization{7: {ID: 7, Name: "Forte Bank"}}},
+		Relations:     &mockRels{},
+		Photos:        &mockPhotos{photos: map[int64]repository.Photo{}},
+		Idempotency:   &mockIdem{keys: map[string]repository.IdempotencyKey{}},
+		Webhooks:      &mockHooks{hooks: map[int64]repository.Webhook{}, finished: map[int64]repository.DeliveryUpdate{}},
+	}, memBlobs{}, photoCfg{})
+}
 
 func TestService_CreateContact_Normalizes_And_Primary(t *testing.T) {
-	lg := logger.New("dev")
 	now := time.Now().UTC()
 	mr := &mockRepo{
 		CreateFn: func(_ context.Context, in repository.ContactInput) (repository.Contact, error) {
@@ -52,7 +405,7 @@ func TestService_CreateContact_Normalizes_And_Primary(t *testing.T) {
 			}, nil
 		},
 	}
-	svc := service.New(lg, mr)
+	svc := newService(mr)
 
 	out, err := svc.CreateContact(context.Background(), service.ContactCreateIn{
 		FirstName: " Sanzhar ", LastName: "Sanzharrov", Company: "",
@@ -70,9 +423,11 @@ func TestService_CreateContact_Normalizes_And_Primary(t *testing.T) {
 }
 
 func TestService_UpdateContact_Pointers_Semantics(t *testing.T) {
-	lg := logger.New("dev")
 	now := time.Now().UTC()
 	mr := &mockRepo{
+		GetFn: func(_ context.Context, id int64) (repository.Contact, error) {
+			return repository.Contact{ID: id, CreatedAt: now, UpdatedAt: now}, nil
+		},
 		UpdateFn: func(_ context.Context, _ int64, p repository.ContactPatch) (repository.Contact, error) {
 			if p.Phones == nil {
 				return repository.Contact{ID: 42, CreatedAt: now, UpdatedAt: now}, nil
@@ -83,7 +438,7 @@ func TestService_UpdateContact_Pointers_Semantics(t *testing.T) {
 			return repository.Contact{ID: 42, Phones: nil, CreatedAt: now, UpdatedAt: now}, nil
 		},
 	}
-	svc := service.New(lg, mr)
+	svc := newService(mr)
 
 	out, err := svc.UpdateContact(context.Background(), 42, service.ContactUpdateIn{})
 	if err != nil || out.ID != 42 {
@@ -108,9 +463,1069 @@ func TestService_List_Maps_Repo(t *testing.T) {
 			}, 0, nil
 		},
 	}
-	svc := service.New(logger.New("dev"), mr)
+	svc := newService(mr)
 	out, err := svc.ListContacts(context.Background(), service.ListFilter{Limit: 2})
 	if err != nil || len(out.Items) != 2 {
 		t.Fatalf("list err=%v out=%+v", err, out)
 	}
 }
+
+func TestService_CreateContact_Emails_Lowercased_And_Validated(t *testing.T) {
+	now := time.Now().UTC()
+	mr := &mockRepo{
+		CreateFn: func(_ context.Context, in repository.ContactInput) (repository.Contact, error) {
+			if len(in.Emails) != 2 {
+				return repository.Contact{}, errors.New("expected duplicate email to be dropped")
+			}
+			if in.Emails[0].Email != "sanzhar@forte.kz" || !in.Emails[0].IsPrimary || in.Emails[1].IsPrimary {
+				return repository.Contact{}, errors.New("email normalization failed")
+			}
+			if len(in.Addresses) != 1 || !in.Addresses[0].IsPrimary || in.Addresses[0].City != "Astana" {
+				return repository.Contact{}, errors.New("address normalization failed")
+			}
+			return repository.Contact{ID: 1, CreatedAt: now, UpdatedAt: now}, nil
+		},
+	}
+	svc := newService(mr)
+
+	base := service.ContactCreateIn{
+		FirstName: "Sanzhar", LastName: "Sanzharov",
+		Phones: []service.PhoneIn{{PhoneRaw: "+77711234567"}},
+	}
+
+	in := base
+	in.Emails = []service.EmailIn{
+		{Label: "work", Email: "Sanzhar@Forte.KZ"},
+		{Label: "dup", Email: "sanzhar@forte.kz"},
+		{Label: "home", Email: "s@example.com"},
+	}
+	in.Addresses = []service.AddressIn{{Label: "office", City: " Astana "}}
+	if _, err := svc.CreateContact(context.Background(), in); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	bad := base
+	bad.Emails = []service.EmailIn{{Email: "not-an-email"}}
+	_, err := svc.CreateContact(context.Background(), bad)
+	var se *service.Error
+	if !errors.As(err, &se) || se.Code != 422 {
+		t.Fatalf("expected 422 for invalid email, got %v", err)
+	}
+
+	bad = base
+	bad.Addresses = []service.AddressIn{{Label: "empty"}}
+	_, err = svc.CreateContact(context.Background(), bad)
+	if !errors.As(err, &se) || se.Code != 422 {
+		t.Fatalf("expected 422 for empty address, got %v", err)
+	}
+}
+
+func TestService_CustomFields_Validate_And_Filter(t *testing.T) {
+	now := time.Now().UTC()
+	maxLen := 10
+	minNum := 1.0
+	fields := []repository.CustomField{
+		{Name: "employee_id", Type: "string", Required: true, Constraints: repository.FieldConstraints{MaxLength: &maxLen, Pattern: `^E\d+$`}},
+		{Name: "department", Type: "enum", Constraints: repository.FieldConstraints{Options: []string{"Sales", "IT"}}},
+		{Name: "grade", Type: "number", Constraints: repository.FieldConstraints{Min: &minNum}},
+		{Name: "hired", Type: "date"},
+		{Name: "remote", Type: "bool"},
+	}
+	var got repository.ListFilter
+	mr := &mockRepo{
+		CreateFn: func(_ context.Context, in repository.ContactInput) (repository.Contact, error) {
+			return repository.Contact{ID: 1, Custom: in.Custom, CreatedAt: now, UpdatedAt: now}, nil
+		},
+		UpdateFn: func(_ context.Context, id int64, p repository.ContactPatch) (repository.Contact, error) {
+			return repository.Contact{ID: id, Custom: p.Custom, CreatedAt: now, UpdatedAt: now}, nil
+		},
+		ListFn: func(_ context.Context, f repository.ListFilter) ([]repository.Contact, int64, error) {
+			got = f
+			return nil, 0, nil
+		},
+	}
+	svc := newService(mr, fields...)
+	ctx := context.Background()
+	base := service.ContactCreateIn{FirstName: "A", LastName: "B", Phones: []service.PhoneIn{{PhoneRaw: "+77711234567"}}}
+
+	in := base
+	in.Custom = map[string]any{"employee_id": " E42 ", "department": "IT", "grade": float64(3), "hired": "2024-02-01", "remote": true}
+	out, err := svc.CreateContact(ctx, in)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if out.Custom["employee_id"] != "E42" || out.Custom["grade"] != float64(3) {
+		t.Fatalf("bad custom: %+v", out.Custom)
+	}
+
+	var se *service.Error
+	for name, custom := range map[string]map[string]any{
+		"missing required": {"department": "IT"},
+		"unknown field":    {"employee_id": "E1", "shoe_size": float64(42)},
+		"bad pattern":      {"employee_id": "X1"},
+		"bad enum":         {"employee_id": "E1", "department": "HR"},
+		"below min":        {"employee_id": "E1", "grade": float64(0)},
+		"bad date":         {"employee_id": "E1", "hired": "01.02.2024"},
+		"bad bool":         {"employee_id": "E1", "remote": "yes"},
+	} {
+		in := base
+		in.Custom = custom
+		if _, err := svc.CreateContact(ctx, in); !errors.As(err, &se) || se.Code != 422 {
+			t.Fatalf("%s: expected 422, got %v", name, err)
+		}
+	}
+
+	// nil удаляет необязательное поле, но не обязательное
+	if out, err := svc.UpdateContact(ctx, 1, service.ContactUpdateIn{Custom: map[string]any{"department": nil}}); err != nil {
+		t.Fatalf("update: %v", err)
+	} else if v, ok := out.Custom["department"]; !ok || v != nil {
+		t.Fatalf("expected department removal marker, got %+v", out.Custom)
+	}
+	if _, err := svc.UpdateContact(ctx, 1, service.ContactUpdateIn{Custom: map[string]any{"employee_id": nil}}); !errors.As(err, &se) || se.Code != 422 {
+		t.Fatalf("expected 422 when clearing required field, got %v", err)
+	}
+
+	if _, err := svc.ListContacts(ctx, service.ListFilter{Custom: map[string]string{"grade": "3"}, Sort: "cf.hired", Order: "asc"}); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if got.SortBy != "custom" || got.SortField != "hired" || got.SortType != "date" || len(got.Custom) != 1 || got.Custom[0].Type != "number" {
+		t.Fatalf("bad repo filter: %+v", got)
+	}
+	if _, err := svc.ListContacts(ctx, service.ListFilter{Custom: map[string]string{"grade": "abc"}}); !errors.As(err, &se) || se.Code != 422 {
+		t.Fatalf("expected 422 for bad number filter, got %v", err)
+	}
+	if _, err := svc.ListContacts(ctx, service.ListFilter{Sort: "cf.nope"}); !errors.As(err, &se) || se.Code != 422 {
+		t.Fatalf("expected 422 for unknown sort field, got %v", err)
+	}
+}
+
+func TestService_Contact_Organization_Link(t *testing.T) {
+	now := time.Now().UTC()
+	var listed repository.ListFilter
+	mr := &mockRepo{
+		CreateFn: func(_ context.Context, in repository.ContactInput) (repository.Contact, error) {
+			return repository.Contact{ID: 1, Company: in.Company, OrganizationID: in.OrganizationID, JobTitle: in.JobTitle, CreatedAt: now, UpdatedAt: now}, nil
+		},
+		ListFn: func(_ context.Context, f repository.ListFilter) ([]repository.Contact, int64, error) {
+			listed = f
+			return nil, 0, nil
+		},
+	}
+	svc := newService(mr)
+	ctx := context.Background()
+
+	out, err := svc.CreateContact(ctx, service.ContactCreateIn{
+		FirstName: "A", LastName: "B", Company: "forte", OrganizationID: 7, JobTitle: " CTO ",
+		Phones: []service.PhoneIn{{PhoneRaw: "+77711234567"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if out.Organization == nil || out.Organization.ID != 7 || out.Company != "Forte Bank" || out.JobTitle != "CTO" {
+		t.Fatalf("bad organization link: %+v", out)
+	}
+
+	var se *service.Error
+	_, err = svc.CreateContact(ctx, service.ContactCreateIn{
+		FirstName: "A", LastName: "B", OrganizationID: 99,
+		Phones: []service.PhoneIn{{PhoneRaw: "+77711234567"}},
+	})
+	if !errors.As(err, &se) || se.Code != 422 {
+		t.Fatalf("expected 422 for unknown organization, got %v", err)
+	}
+
+	if _, err := svc.ListOrganizationMembers(ctx, 7, service.ListFilter{Limit: 10}); err != nil || listed.OrganizationID != 7 {
+		t.Fatalf("members: err=%v filter=%+v", err, listed)
+	}
+	if _, err := svc.ListOrganizationMembers(ctx, 99, service.ListFilter{}); !errors.As(err, &se) || se.Code != 404 {
+		t.Fatalf("expected 404 for unknown organization, got %v", err)
+	}
+}
+
+func TestService_Relations_Create_And_Expand(t *testing.T) {
+	now := time.Now().UTC()
+	mr := &mockRepo{
+		GetFn: func(_ context.Context, id int64) (repository.Contact, error) {
+			if id > 100 {
+				return repository.Contact{}, repository.ErrNotFound
+			}
+			return repository.Contact{ID: id, CreatedAt: now, UpdatedAt: now}, nil
+		},
+	}
+	svc := newService(mr)
+	ctx := context.Background()
+
+	rel, err := svc.CreateRelation(ctx, 1, service.RelationIn{RelatedID: 2, Type: "Assistant_Of", Bidirectional: true})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if rel.Type != "assistant_of" || !rel.Bidirectional || rel.Related.ID != 2 {
+		t.Fatalf("bad relation: %+v", rel)
+	}
+
+	var se *service.Error
+	for name, in := range map[string]service.RelationIn{
+		"unknown type": {RelatedID: 2, Type: "enemy_of"},
+		"self":         {RelatedID: 1, Type: "friend_of"},
+	} {
+		if _, err := svc.CreateRelation(ctx, 1, in); !errors.As(err, &se) || se.Code != 422 {
+			t.Fatalf("%s: expected 422, got %v", name, err)
+		}
+	}
+	if _, err := svc.CreateRelation(ctx, 404, service.RelationIn{RelatedID: 2, Type: "friend_of"}); !errors.As(err, &se) || se.Code != 404 {
+		t.Fatalf("expected 404 for missing contact, got %v", err)
+	}
+
+	out, err := svc.GetContact(ctx, 1, service.ExpandRelations)
+	if err != nil || len(out.Relations) != 1 || out.Relations[0].Type != "assistant_of" {
+		t.Fatalf("expand relations: err=%v out=%+v", err, out)
+	}
+	if out, err := svc.GetContact(ctx, 1); err != nil || out.Relations != nil {
+		t.Fatalf("relations must not be loaded without expand: err=%v out=%+v", err, out)
+	}
+	if _, err := svc.GetContact(ctx, 1, "everything"); !errors.As(err, &se) || se.Code != 400 {
+		t.Fatalf("expected 400 for unknown expand, got %v", err)
+	}
+}
+
+func TestService_PutPhoto(t *testing.T) {
+	mr := &mockRepo{
+		GetFn: func(_ context.Context, id int64) (repository.Contact, error) {
+			if id != 1 {
+				return repository.Contact{}, repository.ErrNotFound
+			}
+			return repository.Contact{ID: 1}, nil
+		},
+	}
+	svc := newService(mr)
+	ctx := context.Background()
+
+	img := image.NewNRGBA(image.Rect(0, 0, 200, 100))
+	for x := 0; x < 200; x++ {
+		img.Set(x, x/2, color.NRGBA{R: 255, A: 255})
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := svc.PutPhoto(ctx, 1, bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if out.ContentType != "image/png" || out.Width != 200 || out.Height != 100 {
+		t.Fatalf("unexpected photo: %+v", out)
+	}
+
+	th, err := svc.GetPhoto(ctx, 1, true)
+	if err != nil {
+		t.Fatalf("get thumb: %v", err)
+	}
+	defer th.Body.Close()
+	thumb, _, err := image.Decode(th.Body)
+	if err != nil {
+		t.Fatalf("decode thumb: %v", err)
+	}
+	if b := thumb.Bounds(); b.Dx() != 32 || b.Dy() != 16 {
+		t.Fatalf("thumb size = %v", b)
+	}
+
+	cases := []struct {
+		name string
+		id   int64
+		body []byte
+		code int
+	}{
+		{"not an image", 1, []byte("hello, world"), http.StatusUnsupportedMediaType},
+		{"too large", 1, make([]byte, 64<<10+1), http.StatusRequestEntityTooLarge},
+		{"unknown contact", 2, buf.Bytes(), http.StatusNotFound},
+		{"too many pixels", 1, pngSized(t, 50000, 50000), http.StatusUnprocessableEntity},
+		// права проверяются до разбора тела
+		{"unknown contact, not an image", 2, []byte("hello, world"), http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		_, err := svc.PutPhoto(ctx, tc.id, bytes.NewReader(tc.body))
+		var se *service.Error
+		if !errors.As(err, &se) || se.Code != tc.code {
+			t.Fatalf("%s: want %d, got %v", tc.name, tc.code, err)
+		}
+	}
+	viewer := service.WithPrincipal(ctx, service.Principal{Subject: "user:v", Scopes: []string{service.ScopeContactsRead}})
+	var se *service.Error
+	if _, err := svc.PutPhoto(viewer, 1, bytes.NewReader(pngSized(t, 50000, 50000))); !errors.As(err, &se) || se.Code != http.StatusForbidden {
+		t.Fatalf("viewer: want 403 before decoding, got %v", err)
+	}
+}
+
+// pngSized — PNG 1×1 с размерами width×height в заголовке: DecodeConfig поверит им, а полное
+// декодирование распаковывало бы width×height пикселей
+func pngSized(t *testing.T, width, height uint32) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	// сигнатура (8), длина IHDR (4), тип (4), ширина и высота (8); CRC считается по типу и данным
+	binary.BigEndian.PutUint32(b[16:], width)
+	binary.BigEndian.PutUint32(b[20:], height)
+	binary.BigEndian.PutUint32(b[29:], crc32.ChecksumIEEE(b[12:29]))
+	return b
+}
+
+func TestService_ImportCSV_Report(t *testing.T) {
+	created := 0
+	mr := &mockRepo{
+		CreateFn: func(_ context.Context, in repository.ContactInput) (repository.Contact, error) {
+			created++
+			return repository.Contact{ID: int64(100 + created), FirstName: in.FirstName}, nil
+		},
+		Owners: map[string]int64{"77010000001": 5},
+	}
+	svc := newService(mr, repository.CustomField{Name: "tab_no", Type: "number"})
+
+	in := "Имя;Фамилия;Рабочий;Мобильный;Таб. номер\n" +
+		"Sanzhar;Sanzharov;+7 727 111 22 33;+7 701 234 56 78;17\n" + // line 2: created
+		"Anna;Petrova;;+7 701 000 00 01;\n" + // line 3: номер уже в книге
+		"Ivan;Ivanov;+77271112233;;\n" + // line 4: дубликат строки 2
+		"Oleg;;+7 702 111 11 11;;\n" + // line 5: нет фамилии
+		"Dana;Ospanova;+7 705 111 11 11;;abc\n" // line 6: custom не число
+	m := service.CSVMapping{
+		Delimiter: ";", FirstName: "Имя", LastName: "Фамилия",
+		Phones: []service.CSVColumn{{Column: "Рабочий", Label: "work"}, {Column: "Мобильный", Label: "mobile", Primary: true}},
+		Custom: map[string]string{"tab_no": "Таб. номер"},
+	}
+
+	for _, dry := range []bool{true, false} {
+		created = 0
+		rep, err := svc.ImportCSV(context.Background(), strings.NewReader(in), m, dry)
+		if err != nil {
+			t.Fatalf("dry=%v: %v", dry, err)
+		}
+		if rep.Created != 1 || rep.Skipped != 2 || rep.Invalid != 2 || len(rep.Rows) != 5 {
+			t.Fatalf("dry=%v: unexpected report %+v", dry, rep)
+		}
+		want := []string{service.ImportCreated, service.ImportSkipped, service.ImportSkipped, service.ImportInvalid, service.ImportInvalid}
+		for i, row := range rep.Rows {
+			if row.Row != i+2 || row.Status != want[i] {
+				t.Fatalf("dry=%v: row %d = %+v, want status %s", dry, i, row, want[i])
+			}
+		}
+		if dry && created != 0 || !dry && created != 1 {
+			t.Fatalf("dry=%v: repo.Create called %d times", dry, created)
+		}
+	}
+
+	m.Phones = []service.CSVColumn{{Column: "Домашний"}}
+	_, err := svc.ImportCSV(context.Background(), strings.NewReader(in), m, true)
+	var se *service.Error
+	if !errors.As(err, &se) || se.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("unknown column: want 422, got %v", err)
+	}
+}
+
+func TestService_ExportContacts_StopsOnCallbackError(t *testing.T) {
+	mr := &mockRepo{Stored: []repository.Contact{{ID: 1}, {ID: 2, Phones: []repository.Phone{{PhoneE164: "+1"}, {PhoneE164: "+2"}}}, {ID: 3}}}
+	svc := newService(mr)
+
+	stop := errors.New("client gone")
+	var got []int64
+	err := svc.ExportContacts(context.Background(), service.ListFilter{}, func(c service.ContactOut) error {
+		got = append(got, c.ID)
+		if c.ID == 2 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) || len(got) != 2 {
+		t.Fatalf("err=%v got=%v", err, got)
+	}
+	if n, _ := svc.ExportPhoneColumns(context.Background()); n != 2 {
+		t.Fatalf("phone columns = %d", n)
+	}
+
+	err = svc.ExportContacts(context.Background(), service.ListFilter{Sort: "cf.missing"}, func(service.ContactOut) error { return nil })
+	var se *service.Error
+	if !errors.As(err, &se) || se.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("unknown sort field: want 422, got %v", err)
+	}
+}
+
+func TestService_Batch_AtomicAndPartial(t *testing.T) {
+	mr := &mockRepo{
+		BatchFn: func(_ context.Context, ops []repository.BatchOp, _ bool) ([]repository.BatchResult, error) {
+			res := make([]repository.BatchResult, len(ops))
+			for i, op := range ops {
+				switch {
+				case op.Kind == repository.BatchCreate:
+					res[i].ID = int64(100 + i)
+				case op.ID == 404:
+					res[i].Err = repository.ErrNotFound
+				default:
+					res[i].ID = op.ID
+				}
+			}
+			return res, nil
+		},
+	}
+	svc := newService(mr)
+	ops := []service.BatchOpIn{
+		{Op: service.BatchCreate, Create: service.ContactCreateIn{FirstName: "Aru", LastName: "Sadykova", Phones: []service.PhoneIn{{PhoneRaw: "+7 701 123 45 67"}}}},
+		{Op: service.BatchCreate, Create: service.ContactCreateIn{FirstName: "Bad", LastName: "Phone", Phones: []service.PhoneIn{{PhoneRaw: "abc"}}}},
+		{Op: service.BatchDelete, ID: 404},
+		{Op: service.BatchDelete, ID: 5},
+		{Op: "merge", ID: 6},
+	}
+
+	out, err := svc.Batch(context.Background(), ops, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []int{http.StatusCreated, http.StatusUnprocessableEntity, http.StatusNotFound, http.StatusNoContent, http.StatusBadRequest}
+	for i, r := range out.Results {
+		if r.Index != i || r.Status != want[i] {
+			t.Fatalf("partial result %d = %+v, want status %d", i, r, want[i])
+		}
+	}
+	if !out.Applied || out.Results[0].ID == 0 {
+		t.Fatalf("partial: %+v", out)
+	}
+
+	// в атомарном режиме ошибка валидации останавливает пакет до обращения к БД
+	mr.BatchFn = func(context.Context, []repository.BatchOp, bool) ([]repository.BatchResult, error) {
+		t.Fatal("repo must not be called")
+		return nil, nil
+	}
+	out, err = svc.Batch(context.Background(), ops, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Applied || out.Results[0].Status != http.StatusFailedDependency || out.Results[0].ID != 0 ||
+		out.Results[1].Status != http.StatusUnprocessableEntity {
+		t.Fatalf("atomic: %+v", out)
+	}
+
+	if _, err := svc.Batch(context.Background(), nil, true); err == nil {
+		t.Fatal("empty batch must fail")
+	}
+}
+
+func TestService_Idempotency_ReplayWaitAndMismatch(t *testing.T) {
+	svc := newService(&mockRepo{})
+	ctx := context.Background()
+
+	stored, err := svc.BeginIdempotent(ctx, "k1", "fp")
+	if err != nil || stored != nil {
+		t.Fatalf("first begin: %v %v", stored, err)
+	}
+
+	// дубликат ждёт, пока первый запрос не сохранит ответ
+	got := make(chan *service.StoredResponse, 1)
+	go func() {
+		r, err := svc.BeginIdempotent(ctx, "k1", "fp")
+		if err != nil {
+			t.Error(err)
+		}
+		got <- r
+	}()
+	select {
+	case <-got:
+		t.Fatal("duplicate must wait for the first request")
+	case <-time.After(150 * time.Millisecond):
+	}
+	if err := svc.CompleteIdempotent(ctx, "k1", service.StoredResponse{Status: http.StatusCreated, Body: []byte(`{"id":1}`)}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case r := <-got:
+		if r == nil || r.Status != http.StatusCreated || string(r.Body) != `{"id":1}` {
+			t.Fatalf("replay = %+v", r)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("duplicate did not get the stored response")
+	}
+
+	_, err = svc.BeginIdempotent(ctx, "k1", "other")
+	var se *service.Error
+	if !errors.As(err, &se) || se.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("different fingerprint: want 422, got %v", err)
+	}
+
+	// снятый после ошибки ключ можно занять снова
+	if r, _ := svc.BeginIdempotent(ctx, "k2", "fp"); r != nil {
+		t.Fatal("k2 must be new")
+	}
+	_ = svc.ReleaseIdempotent(ctx, "k2")
+	if r, err := svc.BeginIdempotent(ctx, "k2", "fp"); r != nil || err != nil {
+		t.Fatalf("released key: %v %v", r, err)
+	}
+}
+
+// тот же Idempotency-Key с тем же телом от другого вызывающего или в другой книге — новый запрос
+func TestService_Idempotency_ScopedToCaller(t *testing.T) {
+	svc := newService(&mockRepo{})
+	alice := service.WithPrincipal(context.Background(), service.Principal{Subject: "apikey:1"})
+	if r, err := svc.BeginIdempotent(alice, "k1", "fp"); r != nil || err != nil {
+		t.Fatalf("first begin: %v %v", r, err)
+	}
+	if err := svc.CompleteIdempotent(alice, "k1", service.StoredResponse{Status: http.StatusCreated, Body: []byte(`{"id":1}`)}); err != nil {
+		t.Fatal(err)
+	}
+	others := map[string]context.Context{
+		"other principal": service.WithPrincipal(context.Background(), service.Principal{Subject: "apikey:2"}),
+		"no principal":    context.Background(),
+		"other book":      service.WithAddressBook(alice, service.AddressBookOut{ID: 2, Slug: "sales"}),
+	}
+	for name, ctx := range others {
+		if r, err := svc.BeginIdempotent(ctx, "k1", "fp"); r != nil || err != nil {
+			t.Fatalf("%s: got stored response %+v, %v", name, r, err)
+		}
+	}
+	if r, err := svc.BeginIdempotent(alice, "k1", "fp"); err != nil || r == nil || string(r.Body) != `{"id":1}` {
+		t.Fatalf("owner replay: %+v, %v", r, err)
+	}
+}
+
+func TestService_UpsertExternal(t *testing.T) {
+	seen := map[string]int64{}
+	mr := &mockRepo{
+		UpsertFn: func(_ context.Context, source, extID string, in repository.ContactInput) (repository.Contact, bool, error) {
+			key := source + "/" + extID
+			id, ok := seen[key]
+			if !ok {
+				id = int64(len(seen) + 1)
+				seen[key] = id
+			}
+			return repository.Contact{ID: id, FirstName: in.FirstName, ExternalSource: source, ExternalID: extID}, !ok, nil
+		},
+	}
+	svc := newService(mr)
+	in := service.ContactCreateIn{FirstName: "Aru", LastName: "Sadykova", Phones: []service.PhoneIn{{PhoneRaw: "+7 701 123 45 67"}}}
+
+	out, created, err := svc.UpsertExternal(context.Background(), " AD ", "S-1-5-21", in)
+	if err != nil || !created {
+		t.Fatalf("first upsert: created=%v err=%v", created, err)
+	}
+	if out.External == nil || out.External.Source != "ad" || out.External.ID != "S-1-5-21" {
+		t.Fatalf("external = %+v", out.External)
+	}
+	again, created, err := svc.UpsertExternal(context.Background(), "ad", "S-1-5-21", in)
+	if err != nil || created || again.ID != out.ID {
+		t.Fatalf("second upsert: id=%d created=%v err=%v", again.ID, created, err)
+	}
+
+	_, _, err = svc.UpsertExternal(context.Background(), "ad", " ", in)
+	var se *service.Error
+	if !errors.As(err, &se) || se.Code != http.StatusBadRequest {
+		t.Fatalf("empty external id: want 400, got %v", err)
+	}
+	if _, err := svc.GetByExternal(context.Background(), "erp", "42"); !errors.As(err, &se) || se.Code != http.StatusNotFound {
+		t.Fatalf("missing: want 404, got %v", err)
+	}
+}
+
+func TestService_Webhooks_CreateAndDeliveryOutcome(t *testing.T) {
+	hooks := &mockHooks{hooks: map[int64]repository.Webhook{}, finished: map[int64]repository.DeliveryUpdate{}}
+	svc := service.New(logger.New("dev"), &repository.Repos{Contacts: &mockRepo{}, Webhooks: hooks}, memBlobs{}, photoCfg{})
+	ctx := context.Background()
+
+	w, err := svc.CreateWebhook(ctx, service.WebhookCreateIn{URL: "https://erp.local/hooks", Events: []string{"contact.created", "contact.created"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(w.Secret, "whsec_") || len(w.Events) != 1 {
+		t.Fatalf("created = %+v", w)
+	}
+	if got, _ := svc.GetWebhook(ctx, w.ID); got.Secret != "" {
+		t.Fatal("secret must be shown only on create")
+	}
+	_, err = svc.CreateWebhook(ctx, service.WebhookCreateIn{URL: "https://erp.local/hooks", Events: []string{"contact.merged"}})
+	var se *service.Error
+	if !errors.As(err, &se) || se.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("unknown event: want 422, got %v", err)
+	}
+
+	_ = svc.CompleteDelivery(ctx, service.PendingDelivery{ID: 1, Attempt: 1}, service.DeliveryResult{StatusCode: http.StatusOK})
+	_ = svc.CompleteDelivery(ctx, service.PendingDelivery{ID: 2, Attempt: 3}, service.DeliveryResult{StatusCode: http.StatusBadGateway})
+	_ = svc.CompleteDelivery(ctx, service.PendingDelivery{ID: 3, Attempt: service.MaxDeliveryAttempts}, service.DeliveryResult{Err: "connection refused"})
+
+	if u := hooks.finished[1]; u.Status != repository.DeliveryDelivered {
+		t.Fatalf("2xx: %+v", u)
+	}
+	u := hooks.finished[2]
+	if wait := time.Until(u.NextAttemptAt); u.Status != repository.DeliveryPending || u.LastError != "unexpected status 502" ||
+		wait < service.DeliveryBackoff(3)-time.Second || service.DeliveryBackoff(3) != 2*service.DeliveryBackoff(2) {
+		t.Fatalf("retry: %+v", u)
+	}
+	if u := hooks.finished[3]; u.Status != repository.DeliveryDead || u.LastError != "connection refused" {
+		t.Fatalf("dead letter: %+v", u)
+	}
+	if service.DeliveryBackoff(100) != 6*time.Hour {
+		t.Fatalf("backoff cap = %v", service.DeliveryBackoff(100))
+	}
+}
+
+// изменённый контакт, которого читающий уже не видит, не выдаётся за удалённый: id скрытого контакта
+// не раскрывается, а настоящее удаление придёт своим событием
+func TestService_Sync_InvisibleContactIsNotDeleted(t *testing.T) {
+	mr := &mockRepo{
+		Stored: []repository.Contact{{ID: 1, FirstName: "A"}},
+		ListFn: func(context.Context, repository.ListFilter) ([]repository.Contact, int64, error) {
+			return nil, 0, nil
+		},
+	}
+	evs := &mockEventLog{}
+	svc := service.New(logger.New("dev"), &repository.Repos{Contacts: mr, Events: evs}, memBlobs{}, photoCfg{})
+	full, err := svc.Sync(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 2 изменён, но читающему больше не виден (ByIDs его не вернул); 3 удалён
+	evs.log = []repository.ContactEvent{
+		{Seq: 1, ContactID: 1, Op: "updated"},
+		{Seq: 2, ContactID: 2, Op: "updated"},
+		{Seq: 3, ContactID: 3, Op: "deleted"},
+	}
+	evs.state.Max = 3
+	inc, err := svc.Sync(context.Background(), full.NextToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inc.Changed) != 1 || inc.Changed[0].ID != 1 || !slices.Equal(inc.Deleted, []int64{3}) {
+		t.Fatalf("incremental: changed=%v deleted=%v", inc.Changed, inc.Deleted)
+	}
+}
+
+func TestService_Sync_FullThenIncremental(t *testing.T) {
+	mr := &mockRepo{
+		Stored: []repository.Contact{{ID: 1, FirstName: "A"}, {ID: 2, FirstName: "B"}},
+		ListFn: func(_ context.Context, f repository.ListFilter) ([]repository.Contact, int64, error) {
+			if f.SortBy != "id" {
+				t.Errorf("full sync must walk by id, got sort %q", f.SortBy)
+			}
+			return []repository.Contact{{ID: 1}, {ID: 2}}, 0, nil
+		},
+	}
+	evs := &mockEventLog{state: repository.SyncState{Max: 10, PurgedThrough: 3}}
+	svc := service.New(logger.New("dev"), &repository.Repos{Contacts: mr, Events: evs}, memBlobs{}, photoCfg{})
+	ctx := context.Background()
+
+	full, err := svc.Sync(ctx, "")
+	if err != nil || len(full.Changed) != 2 || full.HasMore || full.NextToken == "" {
+		t.Fatalf("full sync: %+v err=%v", full, err)
+	}
+
+	// после выгрузки: 1 изменён, 3 создан и удалён, 2 удалён
+	evs.log = []repository.ContactEvent{
+		{Seq: 11, ContactID: 1, Op: "updated"},
+		{Seq: 12, ContactID: 3, Op: "created"},
+		{Seq: 13, ContactID: 3, Op: "deleted"},
+		{Seq: 14, ContactID: 2, Op: "deleted"},
+	}
+	evs.state.Max = 14
+	mr.Stored = mr.Stored[:1]
+	inc, err := svc.Sync(ctx, full.NextToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inc.Changed) != 1 || inc.Changed[0].ID != 1 || !slices.Equal(inc.Deleted, []int64{3, 2}) {
+		t.Fatalf("incremental: changed=%v deleted=%v", inc.Changed, inc.Deleted)
+	}
+	again, _ := svc.Sync(ctx, inc.NextToken)
+	if len(again.Changed)+len(again.Deleted) != 0 || again.NextToken != inc.NextToken {
+		t.Fatalf("caught up: %+v", again)
+	}
+
+	evs.state.PurgedThrough = 12
+	var se *service.Error
+	if _, err := svc.Sync(ctx, full.NextToken); !errors.As(err, &se) || se.Code != http.StatusGone {
+		t.Fatalf("expired token: want 410, got %v", err)
+	}
+	if _, err := svc.Sync(ctx, "garbage"); !errors.As(err, &se) || se.Code != http.StatusBadRequest {
+		t.Fatalf("bad token: want 400, got %v", err)
+	}
+}
+
+func TestService_APIKeys_IssueAuthenticateRevoke(t *testing.T) {
+	keys := &mockKeys{}
+	svc := service.New(logger.New("dev"), &repository.Repos{Contacts: &mockRepo{}, APIKeys: keys}, memBlobs{}, photoCfg{})
+	ctx := context.Background()
+
+	k, err := svc.CreateAPIKey(ctx, service.APIKeyCreateIn{Name: "erp", Scopes: []string{"contacts:write", "contacts:write"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(k.Key, "pb_") || !strings.HasPrefix(k.Key, k.Prefix) || len(k.Scopes) != 1 {
+		t.Fatalf("issued = %+v", k)
+	}
+	if bytes.Contains(keys.keys[0].KeyHash, []byte(k.Key)) || len(keys.keys[0].KeyHash) != 32 {
+		t.Fatal("key must be stored hashed")
+	}
+	if got, _ := svc.GetAPIKey(ctx, k.ID); got.Key != "" {
+		t.Fatal("key must be shown only on create")
+	}
+
+	p, err := svc.Authenticate(ctx, k.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Allows(service.ScopeContactsRead) || !p.Allows(service.ScopeContactsWrite) || p.Allows(service.ScopeAdmin) {
+		t.Fatalf("scopes of write key: %+v", p)
+	}
+
+	var se *service.Error
+	_, err = svc.CreateAPIKey(ctx, service.APIKeyCreateIn{Name: "x", Scopes: []string{"contacts:delete"}})
+	if !errors.As(err, &se) || se.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("unknown scope: want 422, got %v", err)
+	}
+	past := time.Now().Add(-time.Minute)
+	keys.keys[0].ExpiresAt = &past
+	if _, err := svc.Authenticate(ctx, k.Key); !errors.As(err, &se) || se.Code != http.StatusUnauthorized {
+		t.Fatalf("expired: want 401, got %v", err)
+	}
+	keys.keys[0].ExpiresAt = nil
+	_, _ = svc.RevokeAPIKey(ctx, k.ID)
+	if _, err := svc.Authenticate(ctx, k.Key); !errors.As(err, &se) || se.Code != http.StatusUnauthorized {
+		t.Fatalf("revoked: want 401, got %v", err)
+	}
+	if _, err := svc.Authenticate(ctx, "not-a-key"); !errors.As(err, &se) || se.Code != http.StatusUnauthorized {
+		t.Fatalf("malformed: want 401, got %v", err)
+	}
+}
+
+func TestService_ContactACL_Roles(t *testing.T) {
+	now := time.Now().UTC()
+	mr := &mockRepo{
+		GetFn: func(_ context.Context, id int64) (repository.Contact, error) {
+			return repository.Contact{ID: id, CreatedAt: now, UpdatedAt: now}, nil
+		},
+		UpdateFn: func(_ context.Context, id int64, _ repository.ContactPatch) (repository.Contact, error) {
+			return repository.Contact{ID: id, CreatedAt: now, UpdatedAt: now}, nil
+		},
+	}
+	acl := &mockACL{contacts: mr, access: map[int64]repository.ContactAccess{
+		1: {},                                 // открытый
+		2: {Restricted: true, Role: "viewer"}, // выдача viewer
+		3: {Restricted: true, Role: "editor"}, // выдача editor
+		4: {Role: "admin"},                    // открытый, свой
+		5: {Restricted: true, Role: "admin"},  // закрытый, свой
+	}}
+	svc := service.New(logger.New("dev"), &repository.Repos{Contacts: mr, ACL: acl, CustomFields: &mockFields{}}, memBlobs{}, photoCfg{})
+
+	reader := service.Principal{Subject: "user:r", Scopes: []string{service.ScopeContactsRead}}
+	writer := service.Principal{Subject: "user:w", Scopes: []string{service.ScopeContactsWrite}}
+	admin := service.Principal{Subject: "user:a", Scopes: []string{service.ScopeAdmin}}
+	cases := []struct {
+		name       string
+		p          service.Principal
+		id         int64
+		edit, mgmt int // коды UpdateContact и SetContactACL; 0 — успех
+	}{
+		{"reader open", reader, 1, 403, 403},
+		{"writer open", writer, 1, 0, 403},
+		{"writer viewer grant", writer, 2, 403, 403},
+		{"writer editor grant", writer, 3, 0, 403},
+		{"reader editor grant", reader, 3, 403, 403},
+		{"writer own open", writer, 4, 0, 0},
+		{"writer own restricted", writer, 5, 0, 0},
+		{"reader own", reader, 5, 403, 403},
+		{"admin restricted", admin, 2, 0, 0},
+	}
+	code := func(err error) int {
+		var se *service.Error
+		if errors.As(err, &se) {
+			return se.Code
+		}
+		if err != nil {
+			return 500
+		}
+		return 0
+	}
+	for _, tc := range cases {
+		ctx := service.WithPrincipal(context.Background(), tc.p)
+		_, err := svc.UpdateContact(ctx, tc.id, service.ContactUpdateIn{})
+		if got := code(err); got != tc.edit {
+			t.Errorf("%s: update = %d, want %d", tc.name, got, tc.edit)
+		}
+		_, err = svc.SetContactACL(ctx, tc.id, service.ACLIn{Grants: []service.GrantIn{{Principal: "group:sales", Role: "viewer"}}})
+		if got := code(err); got != tc.mgmt {
+			t.Errorf("%s: set acl = %d, want %d", tc.name, got, tc.mgmt)
+		}
+		// теги несут выдачи: их смена — как правка выдач, прежний набор — обычная правка
+		_, err = svc.UpdateContact(ctx, tc.id, service.ContactUpdateIn{Tags: &[]string{"vip"}})
+		if got := code(err); got != tc.mgmt {
+			t.Errorf("%s: update tags = %d, want %d", tc.name, got, tc.mgmt)
+		}
+		_, err = svc.UpdateContact(ctx, tc.id, service.ContactUpdateIn{Tags: &[]string{}})
+		if got := code(err); got != tc.edit {
+			t.Errorf("%s: update same tags = %d, want %d", tc.name, got, tc.edit)
+		}
+	}
+
+	// PUT по внешнему ключу без тегов снял бы тег vip — редактору открытого контакта нельзя
+	mr.ExtFn = func(context.Context, string, string) (repository.Contact, error) {
+		return repository.Contact{ID: 1, Tags: []string{"vip"}}, nil
+	}
+	mr.UpsertFn = func(_ context.Context, _, _ string, in repository.ContactInput) (repository.Contact, bool, error) {
+		return repository.Contact{ID: 1, Tags: in.Tags}, false, nil
+	}
+	in := service.ContactCreateIn{FirstName: "Aru", LastName: "Sadykova", Phones: []service.PhoneIn{{PhoneRaw: "+7 701 123 45 67"}}}
+	wctx := service.WithPrincipal(context.Background(), writer)
+	if _, _, err := svc.UpsertExternal(wctx, "ad", "S-1", in); code(err) != http.StatusForbidden {
+		t.Fatalf("upsert dropping tag: want 403, got %v", err)
+	}
+	in.Tags = []string{"VIP"}
+	if _, _, err := svc.UpsertExternal(wctx, "ad", "S-1", in); err != nil {
+		t.Fatalf("upsert keeping tag: %v", err)
+	}
+	mr.BatchFn = func(_ context.Context, ops []repository.BatchOp, _ bool) ([]repository.BatchResult, error) {
+		return make([]repository.BatchResult, len(ops)), nil
+	}
+	out, err := svc.Batch(wctx, []service.BatchOpIn{{Op: service.BatchUpdate, ID: 1, Update: service.ContactUpdateIn{Tags: &[]string{"vip"}}}}, false)
+	if err != nil || out.Results[0].Status != http.StatusForbidden {
+		t.Fatalf("batch tags: %+v, %v", out, err)
+	}
+
+	ctx := service.WithPrincipal(context.Background(), admin)
+	var se *service.Error
+	if _, err := svc.SetContactACL(ctx, 1, service.ACLIn{Grants: []service.GrantIn{{Principal: "bob", Role: "viewer"}}}); !errors.As(err, &se) || se.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("bad principal: want 422, got %v", err)
+	}
+	if _, err := svc.SetTagACL(service.WithPrincipal(context.Background(), writer), "vip", service.ACLIn{}); !errors.As(err, &se) || se.Code != http.StatusForbidden {
+		t.Fatalf("tag acl by writer: want 403, got %v", err)
+	}
+	// без субъекта (CLI, фоновые задачи) роль не проверяется
+	if _, err := svc.UpdateContact(context.Background(), 2, service.ContactUpdateIn{}); err != nil {
+		t.Fatalf("internal call: %v", err)
+	}
+}
+
+func TestService_CreateContact_OwnerAndTags(t *testing.T) {
+	var got repository.ContactInput
+	mr := &mockRepo{CreateFn: func(_ context.Context, in repository.ContactInput) (repository.Contact, error) {
+		got = in
+		return repository.Contact{ID: 1, Owner: in.Owner, Tags: in.Tags}, nil
+	}}
+	svc := newService(mr)
+	ctx := service.WithPrincipal(context.Background(), service.Principal{Subject: "user:ann", Scopes: []string{service.ScopeContactsWrite}})
+
+	in := service.ContactCreateIn{FirstName: "Ann", LastName: "Lee", Phones: []service.PhoneIn{{Label: "work", PhoneRaw: "+7 771 123 45 67", IsPrimary: true}}}
+	in.Tags = []string{" VIP", "vip", "board"}
+	out, err := svc.CreateContact(ctx, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Owner != "user:ann" || !slices.Equal(got.Tags, []string{"board", "vip"}) || out.Owner != "user:ann" {
+		t.Fatalf("owner/tags = %q %v", got.Owner, got.Tags)
+	}
+	var se *service.Error
+	in.Tags = []string{"a b"}
+	if _, err := svc.CreateContact(ctx, in); !errors.As(err, &se) || se.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("bad tag: want 422, got %v", err)
+	}
+}
+
+func TestService_PhoneRedaction(t *testing.T) {
+	stored := repository.Contact{ID: 9, FirstName: "Aigerim", Owner: "user:o", Phones: []repository.Phone{
+		{Label: "work", PhoneE164: "+77172000001", PhoneRaw: "+77172000001", PhoneDigits: "77172000001", IsPrimary: true, Visibility: "public"},
+		{Label: "mobile", PhoneE164: "+77011234567", PhoneRaw: "8 701 123 45 67", PhoneDigits: "77011234567", Visibility: "internal"},
+		{Label: "home", PhoneE164: "+77771112233", PhoneRaw: "+77771112233", PhoneDigits: "77771112233", Visibility: "private"},
+	}}
+	var patched repository.ContactPatch
+	mr := &mockRepo{
+		GetFn: func(context.Context, int64) (repository.Contact, error) { return stored, nil },
+		UpdateFn: func(_ context.Context, _ int64, p repository.ContactPatch) (repository.Contact, error) {
+			patched = p
+			return stored, nil
+		},
+	}
+	svc := newService(mr)
+	as := func(scope, sub string) context.Context {
+		return service.WithPrincipal(context.Background(), service.Principal{Subject: sub, Scopes: []string{scope}})
+	}
+	cases := []struct {
+		name string
+		ctx  context.Context
+		want []bool // скрыт ли public, internal, private
+	}{
+		{"no principal", context.Background(), []bool{false, true, true}},
+		{"viewer", as(service.ScopeContactsRead, "user:v"), []bool{false, true, true}},
+		{"editor", as(service.ScopeContactsWrite, "user:e"), []bool{false, false, true}},
+		{"admin", as(service.ScopeAdmin, "user:a"), []bool{false, false, false}},
+		{"owner", as(service.ScopeContactsRead, "user:o"), []bool{false, false, false}},
+	}
+	for _, tc := range cases {
+		out, err := svc.GetContact(tc.ctx, 9)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, p := range out.Phones {
+			if p.Redacted != tc.want[i] {
+				t.Errorf("%s: phone %s redacted = %v", tc.name, p.Label, p.Redacted)
+			}
+			if p.Redacted && (strings.Contains(p.PhoneRaw, "1234") || p.PhoneE164 != p.PhoneRaw) {
+				t.Errorf("%s: phone %s leaks: %+v", tc.name, p.Label, p)
+			}
+		}
+	}
+	out, _ := svc.GetContact(context.Background(), 9)
+	if out.Phones[1].PhoneE164 != "+7 701 ***-**-67" {
+		t.Fatalf("mask = %q", out.Phones[1].PhoneE164)
+	}
+
+	// редактор присылает контакт обратно с маской private-номера — номер не теряется
+	ctx := as(service.ScopeContactsWrite, "user:e")
+	seen, _ := svc.GetContact(ctx, 9)
+	phones := make([]service.PhoneIn, 0, len(seen.Phones))
+	for _, p := range seen.Phones {
+		phones = append(phones, service.PhoneIn{Label: p.Label, PhoneRaw: p.PhoneRaw, IsPrimary: p.IsPrimary, Visibility: p.Visibility})
+	}
+	if _, err := svc.UpdateContact(ctx, 9, service.ContactUpdateIn{Phones: &phones}); err != nil {
+		t.Fatal(err)
+	}
+	got := *patched.Phones
+	if len(got) != 3 || got[2].PhoneDigits != "77771112233" || got[2].Visibility != "private" || got[2].IsPrimary {
+		t.Fatalf("hidden phone lost: %+v", got)
+	}
+}
+
+func TestService_UpsertExternal_KeepsHiddenPhones(t *testing.T) {
+	stored := repository.Contact{ID: 9, FirstName: "Aigerim", ExternalSource: "ad", ExternalID: "S-1", Phones: []repository.Phone{
+		{Label: "home", PhoneE164: "+77771112233", PhoneRaw: "+77771112233", PhoneDigits: "77771112233", IsPrimary: true, Visibility: "private"},
+		{Label: "work", PhoneE164: "+77172000001", PhoneRaw: "+77172000001", PhoneDigits: "77172000001", Visibility: "public"},
+	}}
+	var upserted repository.ContactInput
+	mr := &mockRepo{
+		GetFn: func(context.Context, int64) (repository.Contact, error) { return stored, nil },
+		ExtFn: func(context.Context, string, string) (repository.Contact, error) { return stored, nil },
+		UpsertFn: func(_ context.Context, _, _ string, in repository.ContactInput) (repository.Contact, bool, error) {
+			upserted = in
+			return stored, false, nil
+		},
+	}
+	svc := newService(mr)
+	ctx := service.WithPrincipal(context.Background(), service.Principal{Subject: "user:e", Scopes: []string{service.ScopeContactsWrite}})
+
+	// клиент без доступа к private прислал контакт обратно: маска вместо номера, основной — рабочий
+	seen, err := svc.GetContact(ctx, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := service.ContactCreateIn{FirstName: "Aigerim", LastName: "Nurova", Phones: []service.PhoneIn{
+		{Label: "home", PhoneRaw: seen.Phones[0].PhoneRaw, Visibility: "private"},
+		{Label: "work", PhoneRaw: "+77172000001", IsPrimary: true},
+	}}
+	if _, _, err := svc.UpsertExternal(ctx, "ad", "S-1", in); err != nil {
+		t.Fatalf("upsert with masked phone: %v", err)
+	}
+	got := upserted.Phones
+	if len(got) != 2 || got[0].PhoneDigits != "77172000001" || !got[0].IsPrimary {
+		t.Fatalf("phones = %+v", got)
+	}
+	if got[1].PhoneDigits != "77771112233" || got[1].Visibility != "private" || got[1].IsPrimary {
+		t.Fatalf("hidden phone lost: %+v", got[1])
+	}
+
+	// без своего основного номера основным остаётся скрытый
+	in.Phones = in.Phones[1:]
+	in.Phones[0].IsPrimary = false
+	if _, _, err := svc.UpsertExternal(ctx, "ad", "S-1", in); err != nil {
+		t.Fatal(err)
+	}
+	primaries := 0
+	for _, ph := range upserted.Phones {
+		if ph.IsPrimary {
+			primaries++
+		}
+	}
+	if len(upserted.Phones) != 2 || primaries != 1 || !upserted.Phones[1].IsPrimary {
+		t.Fatalf("phones = %+v", upserted.Phones)
+	}
+}
+
+// auditSink — записи аудита в памяти
+type auditSink []service.AuditEntry
+
+func (a *auditSink) Record(e service.AuditEntry) { *a = append(*a, e) }
+
+func TestService_AuditRecordsCaller(t *testing.T) {
+	mr := &mockRepo{
+		GetFn: func(_ context.Context, id int64) (repository.Contact, error) {
+			if id != 5 {
+				return repository.Contact{}, repository.ErrNotFound
+			}
+			return repository.Contact{ID: 5}, nil
+		},
+		SearchFn: func(context.Context, string, int) ([]repository.Contact, error) {
+			return []repository.Contact{{ID: 5}, {ID: 6}}, nil
+		},
+	}
+	svc := newService(mr)
+	var sink auditSink
+	svc.SetAuditSink(&sink)
+
+	ctx := service.WithPrincipal(context.Background(), service.Principal{Subject: "apikey:3", Scopes: []string{service.ScopeContactsRead}})
+	ctx = service.WithAddressBook(ctx, service.AddressBookOut{ID: 2, Slug: "kaspi"})
+	ctx = service.WithRequestInfo(ctx, service.RequestInfo{ID: "req-1", IP: "10.0.0.7"})
+	if _, err := svc.GetContact(ctx, 5); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Search(ctx, "ann", 10); err != nil {
+		t.Fatal(err)
+	}
+	// отказ в журнал не попадает
+	if _, err := svc.GetContact(ctx, 404); err == nil {
+		t.Fatal("want not found")
+	}
+
+	if len(sink) != 2 {
+		t.Fatalf("entries = %+v", sink)
+	}
+	e := sink[0]
+	if e.Actor != "apikey:3" || e.Action != service.AuditContactRead || !slices.Equal(e.ContactIDs, []int64{5}) ||
+		e.RequestID != "req-1" || e.IP != "10.0.0.7" || e.AddressBookID != 2 || e.At.IsZero() {
+		t.Fatalf("read entry = %+v", e)
+	}
+	if sink[1].Action != service.AuditContactSearch || !slices.Equal(sink[1].ContactIDs, []int64{5, 6}) {
+		t.Fatalf("search entry = %+v", sink[1])
+	}
+}
+
+// выгрузка пишет аудит частями: список id не растёт вместе с книгой
+func TestService_ExportAuditInChunks(t *testing.T) {
+	mr := &mockRepo{}
+	for i := range 2500 {
+		mr.Stored = append(mr.Stored, repository.Contact{ID: int64(i + 1)})
+	}
+	svc := newService(mr)
+	var sink auditSink
+	svc.SetAuditSink(&sink)
+
+	stop := errors.New("client gone")
+	n := 0
+	err := svc.ExportContacts(context.Background(), service.ListFilter{}, func(service.ContactOut) error {
+		if n++; n > 2300 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) {
+		t.Fatalf("err = %v", err)
+	}
+	var sizes []int
+	var total int64
+	for _, e := range sink {
+		if e.Action != service.AuditContactExport {
+			t.Fatalf("entry = %+v", e)
+		}
+		sizes = append(sizes, len(e.ContactIDs))
+		for _, id := range e.ContactIDs {
+			total += id
+		}
+	}
+	// отданы контакты 1..2300, не отданный 2301-й в аудит не попал
+	if !slices.Equal(sizes, []int{1000, 1000, 300}) || total != 2300*2301/2 {
+		t.Fatalf("chunks = %v, sum of ids = %d", sizes, total)
+	}
+}
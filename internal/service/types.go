@@ -15,47 +15,135 @@ type PhoneIn struct {
 	Label     string `validate:"max=40"`
 	PhoneRaw  string `validate:"required,min=5,max=32"`
 	IsPrimary bool
+	// Visibility — public (по умолчанию), internal или private
+	Visibility string `validate:"omitempty,oneof=public internal private"`
+}
+
+type EmailIn struct {
+	Label     string `validate:"max=40"`
+	Email     string `validate:"required,email,max=254"`
+	IsPrimary bool
+}
+
+type AddressIn struct {
+	Label      string `validate:"max=40"`
+	Street     string `validate:"max=200"`
+	City       string `validate:"max=100"`
+	Region     string `validate:"max=100"`
+	PostalCode string `validate:"max=20"`
+	Country    string `validate:"max=100"`
+	IsPrimary  bool
+}
+
+type WebsiteIn struct {
+	Label     string `validate:"max=40"`
+	URL       string `validate:"required,http_url,max=2048"`
+	IsPrimary bool
 }
 
 type ContactCreateIn struct {
-	FirstName string    `validate:"required,min=1,max=40"`
-	LastName  string    `validate:"required,min=1,max=40"`
-	Company   string    `validate:"max=40"`
-	Phones    []PhoneIn `validate:"required,min=1,dive"`
+	FirstName string `validate:"required,min=1,max=40"`
+	LastName  string `validate:"required,min=1,max=40"`
+	Company   string `validate:"max=40"`
+	// OrganizationID — ссылка на организацию; её имя подставляется в Company
+	OrganizationID int64       `validate:"omitempty,min=1"`
+	JobTitle       string      `validate:"max=80"`
+	Department     string      `validate:"max=80"`
+	Phones         []PhoneIn   `validate:"required,min=1,dive"`
+	Emails         []EmailIn   `validate:"omitempty,dive"`
+	Addresses      []AddressIn `validate:"omitempty,dive"`
+	Websites       []WebsiteIn `validate:"omitempty,dive"`
+	Custom         map[string]any
+	// Tags — метки контакта; на тег можно выдать роли (SetTagACL)
+	Tags []string `validate:"omitempty,max=20"`
 }
 
 type ContactUpdateIn struct {
-	FirstName *string    `validate:"omitempty,min=1,max=40"`
-	LastName  *string    `validate:"omitempty,min=1,max=40"`
-	Company   *string    `validate:"omitempty,max=40"`
-	Phones    *[]PhoneIn `validate:"omitempty,dive"`
+	FirstName *string `validate:"omitempty,min=1,max=40"`
+	LastName  *string `validate:"omitempty,min=1,max=40"`
+	Company   *string `validate:"omitempty,max=40"`
+	// OrganizationID: 0 — отвязать контакт от организации
+	OrganizationID *int64       `validate:"omitempty,min=0"`
+	JobTitle       *string      `validate:"omitempty,max=80"`
+	Department     *string      `validate:"omitempty,max=80"`
+	Phones         *[]PhoneIn   `validate:"omitempty,dive"`
+	Emails         *[]EmailIn   `validate:"omitempty,dive"`
+	Addresses      *[]AddressIn `validate:"omitempty,dive"`
+	Websites       *[]WebsiteIn `validate:"omitempty,dive"`
+	// Custom мержится с текущими значениями; nil-значение удаляет поле
+	Custom map[string]any
+	Tags   *[]string `validate:"omitempty,max=20"`
 }
 type ListFilter struct {
-	FirstName string
-	LastName  string
-	Company   string
-	Phone     string
-	AfterID   int64
-	Limit     int
-	Sort      string
-	Order     string
+	FirstName      string
+	LastName       string
+	Company        string
+	Phone          string
+	Email          string
+	OrganizationID int64
+	Tag            string
+	Custom         map[string]string // имя пользовательского поля -> значение
+	AfterID        int64
+	Limit          int
+	Offset         int    // сколько первых контактов пропустить — для постраничных справочников
+	Sort           string // поддерживает "cf.<name>" для сортировки по пользовательскому полю
+	Order          string
+	// WithoutDetails — контакты без телефонов, email, адресов и сайтов; их догружают через ContactsByIDs
+	WithoutDetails bool
 }
 
 type PhoneOut struct {
+	Label      string `json:"label"`
+	PhoneRaw   string `json:"phone_raw"`
+	PhoneE164  string `json:"phone_e164"`
+	IsPrimary  bool   `json:"is_primary"`
+	Visibility string `json:"visibility,omitempty"`
+	// Redacted — номер скрыт от вызывающего, в phone_raw и phone_e164 маска
+	Redacted bool `json:"redacted,omitempty"`
+}
+
+type EmailOut struct {
 	Label     string `json:"label"`
-	PhoneRaw  string `json:"phone_raw"`
-	PhoneE164 string `json:"phone_e164"`
+	Email     string `json:"email"`
+	IsPrimary bool   `json:"is_primary"`
+}
+
+type AddressOut struct {
+	Label      string `json:"label"`
+	Street     string `json:"street"`
+	City       string `json:"city"`
+	Region     string `json:"region"`
+	PostalCode string `json:"postal_code"`
+	Country    string `json:"country"`
+	IsPrimary  bool   `json:"is_primary"`
+}
+
+type WebsiteOut struct {
+	Label     string `json:"label"`
+	URL       string `json:"url"`
 	IsPrimary bool   `json:"is_primary"`
 }
 
 type ContactOut struct {
-	ID        int64      `json:"id"`
-	FirstName string     `json:"first_name"`
-	LastName  string     `json:"last_name"`
-	Company   string     `json:"company"`
-	Phones    []PhoneOut `json:"phones"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
+	ID           int64            `json:"id"`
+	FirstName    string           `json:"first_name"`
+	LastName     string           `json:"last_name"`
+	Company      string           `json:"company"`
+	Organization *OrganizationRef `json:"organization,omitempty"`
+	JobTitle     string           `json:"job_title"`
+	Department   string           `json:"department"`
+	Phones       []PhoneOut       `json:"phones"`
+	Emails       []EmailOut       `json:"emails"`
+	Addresses    []AddressOut     `json:"addresses"`
+	Websites     []WebsiteOut     `json:"websites"`
+	Custom       map[string]any   `json:"custom"`
+	Tags         []string         `json:"tags"`
+	Owner        string           `json:"owner,omitempty"`
+	Relations    []RelationOut    `json:"relations,omitempty"`
+	PhotoURL     string           `json:"photo_url,omitempty"`
+	External     *ExternalRef     `json:"external,omitempty"`
+	CreatedAt    time.Time        `json:"created_at"`
+	UpdatedAt    time.Time        `json:"updated_at"`
 }
 
 type PageOut struct {
@@ -68,3 +156,357 @@ type ListOut struct {
 	Items []ContactOut `json:"items"`
 	Page  PageOut      `json:"page"`
 }
+
+type FieldConstraints struct {
+	MinLength *int     `json:"min_length,omitempty" validate:"omitempty,min=0"`
+	MaxLength *int     `json:"max_length,omitempty" validate:"omitempty,min=1"`
+	Pattern   string   `json:"pattern,omitempty" validate:"max=200"`
+	Min       *float64 `json:"min,omitempty"`
+	Max       *float64 `json:"max,omitempty"`
+	Options   []string `json:"options,omitempty" validate:"omitempty,unique,dive,required,max=80"`
+}
+
+type CustomFieldIn struct {
+	Name        string `validate:"required,max=40"`
+	Label       string `validate:"max=80"`
+	Type        string `validate:"required,oneof=string number date enum bool"`
+	Required    bool
+	Constraints FieldConstraints
+}
+
+type CustomFieldUpdateIn struct {
+	Label       *string `validate:"omitempty,max=80"`
+	Required    *bool
+	Constraints *FieldConstraints
+}
+
+type CustomFieldOut struct {
+	Name        string           `json:"name"`
+	Label       string           `json:"label"`
+	Type        string           `json:"type"`
+	Required    bool             `json:"required"`
+	Constraints FieldConstraints `json:"constraints"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+// ExternalRef — ключ контакта в системе-источнике (AD, ERP)
+type ExternalRef struct {
+	Source string `json:"source"`
+	ID     string `json:"id"`
+}
+
+type OrganizationRef struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type OrganizationIn struct {
+	Name      string      `validate:"required,min=1,max=120"`
+	Phones    []PhoneIn   `validate:"omitempty,dive"`
+	Addresses []AddressIn `validate:"omitempty,dive"`
+}
+
+type OrganizationUpdateIn struct {
+	Name      *string      `validate:"omitempty,min=1,max=120"`
+	Phones    *[]PhoneIn   `validate:"omitempty,dive"`
+	Addresses *[]AddressIn `validate:"omitempty,dive"`
+}
+
+type OrganizationListFilter struct {
+	Name    string
+	AfterID int64
+	Limit   int
+}
+
+type OrganizationOut struct {
+	ID          int64        `json:"id"`
+	Name        string       `json:"name"`
+	Phones      []PhoneOut   `json:"phones"`
+	Addresses   []AddressOut `json:"addresses"`
+	MemberCount int64        `json:"member_count"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+type OrganizationListOut struct {
+	Items []OrganizationOut `json:"items"`
+	Page  PageOut           `json:"page"`
+}
+
+type RelationIn struct {
+	RelatedID     int64  `validate:"required,min=1"`
+	Type          string `validate:"required"`
+	Bidirectional bool
+}
+
+type ContactRef struct {
+	ID        int64  `json:"id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+type RelationOut struct {
+	ID            int64      `json:"id"`
+	Type          string     `json:"type"`
+	Related       ContactRef `json:"related"`
+	Bidirectional bool       `json:"bidirectional"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type PhotoOut struct {
+	URL         string    `json:"url"`
+	ThumbURL    string    `json:"thumb_url"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ImportItem — контакт из внешнего формата (vCard, CSV); Photo — необязательное встроенное фото.
+type ImportItem struct {
+	Contact ContactCreateIn
+	Photo   []byte
+}
+
+type ImportRowOut struct {
+	Row    int    `json:"row"`
+	Status string `json:"status"`
+	ID     int64  `json:"id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type ImportReport struct {
+	DryRun  bool           `json:"dry_run,omitempty"`
+	Created int            `json:"created"`
+	Skipped int            `json:"skipped"`
+	Invalid int            `json:"invalid"`
+	Rows    []ImportRowOut `json:"rows"`
+}
+
+// CSVColumn — колонка CSV с телефоном или email и меткой для неё
+type CSVColumn struct {
+	Column  string `validate:"required"`
+	Label   string `validate:"max=40"`
+	Primary bool
+}
+
+// CSVMapping — соответствие колонок CSV (по заголовку) полям контакта
+type CSVMapping struct {
+	Delimiter  string `validate:"omitempty,len=1"`
+	FirstName  string `validate:"required"`
+	LastName   string `validate:"required"`
+	Company    string
+	JobTitle   string
+	Department string
+	Phones     []CSVColumn       `validate:"required,min=1,dive"`
+	Emails     []CSVColumn       `validate:"omitempty,dive"`
+	Custom     map[string]string // имя пользовательского поля -> колонка
+}
+
+// BatchOpIn — операция пакета: Op = "create" (Create), "update" (ID, Update) или "delete" (ID)
+type BatchOpIn struct {
+	Op     string
+	ID     int64
+	Create ContactCreateIn
+	Update ContactUpdateIn
+}
+
+type BatchItemOut struct {
+	Index  int    `json:"index"`
+	Status int    `json:"status"`
+	ID     int64  `json:"id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type BatchOut struct {
+	Atomic bool `json:"atomic"`
+	// Applied — false, если атомарный пакет откачен целиком
+	Applied bool           `json:"applied"`
+	Results []BatchItemOut `json:"results"`
+}
+
+// EventOut — изменение контакта в ленте; Op: created, updated или deleted
+type EventOut struct {
+	// ID — позиция в ленте (seq журнала), она же Last-Event-ID и after_event_id
+	ID            int64     `json:"id"`
+	ContactID     int64     `json:"contact_id"`
+	AddressBookID int64     `json:"address_book_id,omitempty"`
+	Op            string    `json:"op"`
+	At            time.Time `json:"at"`
+	// Readers — кому, кроме администраторов книги, было видно событие; nil — всем читателям книги
+	Readers []string `json:"-"`
+}
+
+// Типы событий вебхуков
+const (
+	EventContactCreated = "contact.created"
+	EventContactUpdated = "contact.updated"
+	EventContactDeleted = "contact.deleted"
+)
+
+// WebhookCreateIn — Secret пустой: сгенерируется и вернётся один раз в ответе на создание
+type WebhookCreateIn struct {
+	URL    string   `validate:"required,http_url,max=2048"`
+	Secret string   `validate:"omitempty,min=16,max=128"`
+	Events []string `validate:"omitempty,dive,oneof=contact.created contact.updated contact.deleted"`
+}
+
+type WebhookUpdateIn struct {
+	URL    *string   `validate:"omitempty,http_url,max=2048"`
+	Secret *string   `validate:"omitempty,min=16,max=128"`
+	Events *[]string `validate:"omitempty,dive,oneof=contact.created contact.updated contact.deleted"`
+	Active *bool
+}
+
+type WebhookOut struct {
+	ID     int64    `json:"id"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Active bool     `json:"active"`
+	// Secret — только в ответе на создание и смену секрета
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type DeliveryListFilter struct {
+	Status   string
+	BeforeID int64
+	Limit    int
+}
+
+type DeliveryOut struct {
+	ID            int64      `json:"id"`
+	EventID       int64      `json:"event_id"`
+	EventType     string     `json:"event_type"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	LastStatus    int        `json:"last_status,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+}
+
+// PendingDelivery — доставка, которую диспетчер должен отправить сейчас
+type PendingDelivery struct {
+	ID        int64
+	WebhookID int64
+	Attempt   int
+	URL       string
+	Secret    string
+	EventType string
+	Payload   []byte
+}
+
+// DeliveryResult — итог HTTP-запроса: StatusCode = 0, если ответа не было (Err — причина)
+type DeliveryResult struct {
+	StatusCode int
+	Err        string
+}
+
+// SyncOut — страница синхронизации: Changed — созданные и изменённые контакты целиком, Deleted — id удалённых
+// (DeletedExternal — их внешние ключи, если были). NextToken передаётся в следующий запрос;
+// HasMore=false — клиент догнал сервер.
+type SyncOut struct {
+	Changed         []ContactOut          `json:"changed"`
+	Deleted         []int64               `json:"deleted"`
+	DeletedExternal map[int64]ExternalRef `json:"deleted_external,omitempty"`
+	NextToken       string                `json:"next_token"`
+	HasMore         bool                  `json:"has_more"`
+}
+
+// Области доступа ключей API
+const (
+	ScopeContactsRead  = "contacts:read"
+	ScopeContactsWrite = "contacts:write"
+	ScopeAdmin         = "admin"
+)
+
+// APIKeyCreateIn — ExpiresAt пустой: ключ бессрочный
+type APIKeyCreateIn struct {
+	Name      string   `validate:"required,max=100"`
+	Scopes    []string `validate:"required,min=1,dive,oneof=contacts:read contacts:write admin"`
+	ExpiresAt *time.Time
+	// AddressBook — slug книги, к которой привязан ключ; пустой — ключ работает с любой книгой
+	AddressBook string
+}
+
+type APIKeyOut struct {
+	ID     int64    `json:"id"`
+	Name   string   `json:"name"`
+	Prefix string   `json:"prefix"`
+	Scopes []string `json:"scopes"`
+	// Key — только в ответе на выпуск ключа
+	Key         string     `json:"key,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	AddressBook string     `json:"address_book,omitempty"`
+}
+
+// AddressBookIn — DefaultRegion: код страны ISO 3166-1 для номеров, записанных без кода страны
+type AddressBookIn struct {
+	Slug          string `validate:"required,max=63"`
+	Name          string `validate:"required,max=200"`
+	DefaultRegion string `validate:"omitempty,len=2"`
+}
+
+type AddressBookUpdateIn struct {
+	Name          *string `validate:"omitempty,min=1,max=200"`
+	DefaultRegion *string `validate:"omitempty,max=2"`
+}
+
+type AddressBookOut struct {
+	ID            int64     `json:"id"`
+	Slug          string    `json:"slug"`
+	Name          string    `json:"name"`
+	DefaultRegion string    `json:"default_region,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// GrantIn — выдача роли viewer, editor или admin; Principal — user:<sub>, apikey:<id> или group:<имя>
+type GrantIn struct {
+	Principal string
+	Role      string
+}
+
+// ACLIn — полный список выдач; пустой снимает ограничение доступа
+type ACLIn struct {
+	Grants []GrantIn
+}
+
+type GrantOut struct {
+	Principal string `json:"principal"`
+	Role      string `json:"role"`
+}
+
+type ACLOut struct {
+	Grants []GrantOut `json:"grants"`
+}
+
+// AuditEntry — запись журнала аудита. Actor пустой у вызовов без субъекта (LDAP, фоновые задачи)
+type AuditEntry struct {
+	ID            int64     `json:"id"`
+	At            time.Time `json:"at"`
+	AddressBookID int64     `json:"address_book_id"`
+	Actor         string    `json:"actor"`
+	Action        string    `json:"action"`
+	ContactIDs    []int64   `json:"contact_ids"`
+	RequestID     string    `json:"request_id,omitempty"`
+	IP            string    `json:"ip,omitempty"`
+}
+
+type AuditFilter struct {
+	Actor     string
+	Action    string
+	ContactID int64
+	Since     time.Time
+	Until     time.Time
+	BeforeID  int64
+	Limit     int
+}
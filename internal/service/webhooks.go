@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sunzhqr/phonebook/internal/repository"
+)
+
+const (
+	// MaxDeliveryAttempts — после стольких неудачных попыток доставка уходит в dead
+	MaxDeliveryAttempts = 10
+	// deliveryBackoff — задержка перед второй попыткой, дальше удваивается до maxDeliveryBackoff
+	deliveryBackoff    = 30 * time.Second
+	maxDeliveryBackoff = 6 * time.Hour
+	// deliveryLease — сколько взятая в работу доставка недоступна другим диспетчерам
+	deliveryLease  = 2 * time.Minute
+	fanOutBatch    = 500
+	maxErrorLength = 500
+)
+
+func (s *Service) CreateWebhook(ctx context.Context, in WebhookCreateIn) (WebhookOut, error) {
+	if err := s.v.Struct(in); err != nil {
+		return WebhookOut{}, &Error{Code: http.StatusUnprocessableEntity, Message: err.Error()}
+	}
+	secret := in.Secret
+	if secret == "" {
+		var b [24]byte
+		_, _ = rand.Read(b[:])
+		secret = "whsec_" + hex.EncodeToString(b[:])
+	}
+	w, err := s.hooks.Create(ctx, repository.WebhookInput{URL: strings.TrimSpace(in.URL), Secret: secret, Events: dedupEvents(in.Events)})
+	if err != nil {
+		return WebhookOut{}, s.repoErr(err)
+	}
+	out := toWebhookOut(w)
+	out.Secret = w.Secret
+	return out, nil
+}
+
+func (s *Service) GetWebhook(ctx context.Context, id int64) (WebhookOut, error) {
+	w, err := s.hooks.Get(ctx, id)
+	if err != nil {
+		return WebhookOut{}, s.repoErr(err)
+	}
+	return toWebhookOut(w), nil
+}
+
+func (s *Service) ListWebhooks(ctx context.Context) ([]WebhookOut, error) {
+	list, err := s.hooks.List(ctx)
+	if err != nil {
+		return nil, s.repoErr(err)
+	}
+	out := make([]WebhookOut, 0, len(list))
+	for _, w := range list {
+		out = append(out, toWebhookOut(w))
+	}
+	return out, nil
+}
+
+func (s *Service) UpdateWebhook(ctx context.Context, id int64, in WebhookUpdateIn) (WebhookOut, error) {
+	if err := s.v.Struct(in); err != nil {
+		return WebhookOut{}, &Error{Code: http.StatusUnprocessableEntity, Message: err.Error()}
+	}
+	patch := repository.WebhookPatch{URL: in.URL, Secret: in.Secret, Active: in.Active}
+	if in.URL != nil {
+		u := strings.TrimSpace(*in.URL)
+		patch.URL = &u
+	}
+	if in.Events != nil {
+		events := dedupEvents(*in.Events)
+		patch.Events = &events
+	}
+	w, err := s.hooks.Update(ctx, id, patch)
+	if err != nil {
+		return WebhookOut{}, s.repoErr(err)
+	}
+	out := toWebhookOut(w)
+	if in.Secret != nil {
+		out.Secret = w.Secret
+	}
+	return out, nil
+}
+
+func (s *Service) DeleteWebhook(ctx context.Context, id int64) error {
+	if err := s.hooks.Delete(ctx, id); err != nil {
+		return s.repoErr(err)
+	}
+	return nil
+}
+
+// ListDeliveries — журнал доставок подписки, новые сверху.
+func (s *Service) ListDeliveries(ctx context.Context, webhookID int64, f DeliveryListFilter) ([]DeliveryOut, error) {
+	switch f.Status {
+	case "", repository.DeliveryPending, repository.DeliveryDelivered, repository.DeliveryDead:
+	default:
+		return nil, &Error{Code: http.StatusBadRequest, Message: "bad status, expected pending, delivered or dead"}
+	}
+	if f.Limit <= 0 || f.Limit > 100 {
+		f.Limit = 20
+	}
+	if _, err := s.hooks.Get(ctx, webhookID); err != nil {
+		return nil, s.repoErr(err)
+	}
+	list, err := s.hooks.Deliveries(ctx, repository.DeliveryFilter{WebhookID: webhookID, Status: f.Status, BeforeID: f.BeforeID, Limit: f.Limit})
+	if err != nil {
+		return nil, s.repoErr(err)
+	}
+	out := make([]DeliveryOut, 0, len(list))
+	for _, d := range list {
+		o := DeliveryOut{ID: d.ID, EventID: d.EventID, EventType: d.EventType, Status: d.Status, Attempts: d.Attempts,
+			LastStatus: d.LastStatus, LastError: d.LastError, CreatedAt: d.CreatedAt, DeliveredAt: d.DeliveredAt}
+		if d.Status == repository.DeliveryPending {
+			next := d.NextAttemptAt
+			o.NextAttemptAt = &next
+		}
+		out = append(out, o)
+	}
+	return out, nil
+}
+
+// Redeliver — повторная отправка, в том числе из dead.
+func (s *Service) Redeliver(ctx context.Context, webhookID, deliveryID int64) error {
+	if err := s.hooks.Redeliver(ctx, webhookID, deliveryID); err != nil {
+		return s.repoErr(err)
+	}
+	return nil
+}
+
+// FanOutOutbox — раскладывает новые события outbox по подпискам.
+func (s *Service) FanOutOutbox(ctx context.Context) (int64, error) {
+	return s.hooks.FanOut(ctx, fanOutBatch)
+}
+
+func (s *Service) ClaimDeliveries(ctx context.Context, limit int) ([]PendingDelivery, error) {
+	list, err := s.hooks.Claim(ctx, limit, deliveryLease)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]PendingDelivery, 0, len(list))
+	for _, d := range list {
+		out = append(out, PendingDelivery{ID: d.ID, WebhookID: d.WebhookID, Attempt: d.Attempts, URL: d.URL,
+			Secret: d.Secret, EventType: d.EventType, Payload: d.Payload})
+	}
+	return out, nil
+}
+
+// CompleteDelivery — 2xx считается доставкой; иначе следующая попытка через deliveryBackoff·2^(n-1),
+// после MaxDeliveryAttempts — dead.
+func (s *Service) CompleteDelivery(ctx context.Context, d PendingDelivery, res DeliveryResult) error {
+	u := repository.DeliveryUpdate{Status: repository.DeliveryDelivered, NextAttemptAt: time.Now(), LastStatus: res.StatusCode}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		u.LastError = res.Err
+		if u.LastError == "" {
+			u.LastError = fmt.Sprintf("unexpected status %d", res.StatusCode)
+		}
+		if len(u.LastError) > maxErrorLength {
+			u.LastError = u.LastError[:maxErrorLength]
+		}
+		u.Status = repository.DeliveryPending
+		u.NextAttemptAt = time.Now().Add(DeliveryBackoff(d.Attempt))
+		if d.Attempt >= MaxDeliveryAttempts {
+			u.Status = repository.DeliveryDead
+		}
+	}
+	return s.hooks.Finish(ctx, d.ID, u)
+}
+
+// DeliveryBackoff — пауза после неудачной попытки номер attempt (с единицы).
+func DeliveryBackoff(attempt int) time.Duration {
+	d := deliveryBackoff
+	for i := 1; i < attempt && d < maxDeliveryBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxDeliveryBackoff)
+}
+
+func (s *Service) PurgeOutbox(ctx context.Context) (int64, error) {
+	return s.hooks.PurgeOutbox(ctx, time.Now().Add(-EventsRetention))
+}
+
+func dedupEvents(in []string) []string {
+	out := make([]string, 0, len(in))
+	seen := make(map[string]bool, len(in))
+	for _, e := range in {
+		if !seen[e] {
+			seen[e] = true
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func toWebhookOut(w repository.Webhook) WebhookOut {
+	events := w.Events
+	if events == nil {
+		events = []string{}
+	}
+	return WebhookOut{ID: w.ID, URL: w.URL, Events: events, Active: w.Active, CreatedAt: w.CreatedAt, UpdatedAt: w.UpdatedAt}
+}
@@ -0,0 +1,382 @@
+package vcard
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime/quotedprintable"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sunzhqr/phonebook/internal/service"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+var ErrNoCards = errors.New("no vCards found")
+
+// Card — разобранная карточка; Photo — встроенное фото, если было.
+type Card struct {
+	Contact service.ContactCreateIn
+	Photo   []byte
+}
+
+// property — строка содержимого после разворачивания, с декодированным значением.
+type property struct {
+	name   string
+	params map[string][]string
+	value  string
+}
+
+// Decode разбирает файл с одной или несколькими карточками (2.1, 3.0, 4.0).
+// Понимает свёрнутые строки, QUOTED-PRINTABLE с мягкими переносами, CHARSET и base64-фото.
+// Неизвестные свойства пропускаются; валидация контактов — забота сервиса.
+func Decode(r io.Reader) ([]Card, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		cards []Card
+		cur   *builder
+	)
+	for _, l := range lines {
+		p, ok := parseLine(l)
+		if !ok {
+			continue
+		}
+		switch {
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VCARD"):
+			if cur == nil {
+				cur = &builder{}
+			}
+		case p.name == "END" && strings.EqualFold(p.value, "VCARD"):
+			if cur != nil {
+				cards = append(cards, cur.card())
+				cur = nil
+			}
+		case cur != nil:
+			cur.add(p)
+		}
+	}
+	// карточку без END не теряем
+	if cur != nil {
+		cards = append(cards, cur.card())
+	}
+	if len(cards) == 0 {
+		return nil, ErrNoCards
+	}
+	return cards, nil
+}
+
+// unfold склеивает продолжения (строки с ведущим пробелом/табом) и мягкие переносы
+// QUOTED-PRINTABLE из vCard 2.1, где продолжение начинается без пробела.
+func unfold(r io.Reader) ([]string, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), 32<<20)
+	var out []string
+	qpSoft := false
+	for sc.Scan() {
+		l := strings.TrimRight(sc.Text(), "\r")
+		switch {
+		case qpSoft && len(out) > 0:
+			out[len(out)-1] += l
+		case (strings.HasPrefix(l, " ") || strings.HasPrefix(l, "\t")) && len(out) > 0:
+			out[len(out)-1] += l[1:]
+		case l == "":
+			continue
+		default:
+			out = append(out, l)
+		}
+		last := out[len(out)-1]
+		qpSoft = strings.HasSuffix(last, "=") && isQP(last)
+		if qpSoft {
+			out[len(out)-1] = strings.TrimSuffix(last, "=")
+		}
+	}
+	if len(out) > 0 {
+		out[0] = strings.TrimPrefix(out[0], "\ufeff")
+	}
+	return out, sc.Err()
+}
+
+func isQP(line string) bool {
+	i := strings.IndexByte(line, ':')
+	return i > 0 && strings.Contains(strings.ToUpper(line[:i]), "QUOTED-PRINTABLE")
+}
+
+// parseLine — [group.]NAME *(;param) : value. Двоеточие внутри кавычек в параметрах не разделитель.
+func parseLine(l string) (property, bool) {
+	quoted := false
+	colon := -1
+	for i := 0; i < len(l) && colon < 0; i++ {
+		switch l[i] {
+		case '"':
+			quoted = !quoted
+		case ':':
+			if !quoted {
+				colon = i
+			}
+		}
+	}
+	if colon <= 0 {
+		return property{}, false
+	}
+
+	head := splitUnquoted(l[:colon], ';')
+	name := strings.ToUpper(head[0])
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		name = name[i+1:]
+	}
+	p := property{name: name, params: map[string][]string{}}
+	for _, raw := range head[1:] {
+		k, v, ok := strings.Cut(raw, "=")
+		if !ok {
+			// vCard 2.1: TEL;WORK;VOICE:... и NOTE;QUOTED-PRINTABLE:...
+			k, v = "TYPE", raw
+			switch strings.ToUpper(raw) {
+			case "QUOTED-PRINTABLE", "BASE64", "8BIT":
+				k = "ENCODING"
+			}
+		}
+		k = strings.ToUpper(strings.TrimSpace(k))
+		for _, item := range splitUnquoted(v, ',') {
+			item = strings.Trim(strings.TrimSpace(item), `"`)
+			if item != "" {
+				p.params[k] = append(p.params[k], item)
+			}
+		}
+	}
+
+	value := []byte(l[colon+1:])
+	switch strings.ToUpper(p.param("ENCODING")) {
+	case "QUOTED-PRINTABLE":
+		if dec, err := io.ReadAll(quotedprintable.NewReader(bytes.NewReader(value))); err == nil {
+			value = dec
+		}
+	case "B", "BASE64":
+		// бинарное значение (фото) — кодировку символов не трогаем
+		p.value = string(value)
+		return p, true
+	}
+	p.value = decodeCharset(value, p.param("CHARSET"))
+	return p, true
+}
+
+func (p property) param(name string) string {
+	if v := p.params[name]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (p property) hasType(t string) bool {
+	for _, v := range p.params["TYPE"] {
+		if strings.EqualFold(v, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeCharset — перекодирует в UTF-8 по CHARSET; без него невалидный UTF-8 заменяется.
+func decodeCharset(b []byte, charset string) string {
+	if charset != "" {
+		if enc, err := htmlindex.Get(charset); err == nil {
+			if out, err := enc.NewDecoder().Bytes(b); err == nil {
+				return string(out)
+			}
+		}
+	}
+	if utf8.Valid(b) {
+		return string(b)
+	}
+	return strings.ToValidUTF8(string(b), "\uFFFD")
+}
+
+func splitUnquoted(s string, sep byte) []string {
+	var out []string
+	quoted := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			quoted = !quoted
+		case sep:
+			if !quoted {
+				out = append(out, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(out, s[start:])
+}
+
+// components — разбивает структурированное значение по неэкранированным ';' и снимает экранирование.
+func components(v string) []string {
+	var (
+		out []string
+		b   strings.Builder
+	)
+	for i := 0; i < len(v); i++ {
+		switch {
+		case v[i] == '\\' && i+1 < len(v):
+			i++
+			switch v[i] {
+			case 'n', 'N':
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(v[i])
+			}
+		case v[i] == ';':
+			out = append(out, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(v[i])
+		}
+	}
+	return append(out, b.String())
+}
+
+// unescape — значение-текст целиком (точка с запятой в нём — часть текста).
+func unescape(v string) string {
+	return strings.Join(components(v), ";")
+}
+
+func component(parts []string, i int) string {
+	if i < len(parts) {
+		return strings.TrimSpace(parts[i])
+	}
+	return ""
+}
+
+type builder struct {
+	c     service.ContactCreateIn
+	fn    string
+	photo []byte
+}
+
+func (b *builder) add(p property) {
+	switch p.name {
+	case "N":
+		parts := components(p.value)
+		b.c.LastName = component(parts, 0)
+		b.c.FirstName = component(parts, 1)
+	case "FN":
+		b.fn = strings.TrimSpace(unescape(p.value))
+	case "ORG":
+		parts := components(p.value)
+		b.c.Company = component(parts, 0)
+		b.c.Department = component(parts, 1)
+	case "TITLE":
+		b.c.JobTitle = strings.TrimSpace(unescape(p.value))
+	case "TEL":
+		num := strings.TrimSpace(unescape(p.value))
+		num = strings.TrimPrefix(num, "tel:")
+		if num == "" {
+			return
+		}
+		b.c.Phones = append(b.c.Phones, service.PhoneIn{Label: typeToLabel(p), PhoneRaw: num, IsPrimary: isPref(p)})
+	case "EMAIL":
+		if m := strings.TrimSpace(unescape(p.value)); m != "" {
+			b.c.Emails = append(b.c.Emails, service.EmailIn{Label: typeToLabel(p), Email: m, IsPrimary: isPref(p)})
+		}
+	case "ADR":
+		parts := components(p.value)
+		a := service.AddressIn{
+			Label:      typeToLabel(p),
+			Street:     strings.TrimSpace(strings.Join(nonEmpty(component(parts, 1), component(parts, 2)), ", ")),
+			City:       component(parts, 3),
+			Region:     component(parts, 4),
+			PostalCode: component(parts, 5),
+			Country:    component(parts, 6),
+			IsPrimary:  isPref(p),
+		}
+		if a.Street != "" || a.City != "" || a.Region != "" || a.PostalCode != "" || a.Country != "" {
+			b.c.Addresses = append(b.c.Addresses, a)
+		}
+	case "URL":
+		if u := strings.TrimSpace(unescape(p.value)); u != "" {
+			b.c.Websites = append(b.c.Websites, service.WebsiteIn{Label: typeToLabel(p), URL: u, IsPrimary: isPref(p)})
+		}
+	case "PHOTO":
+		b.photo = decodePhoto(p)
+	}
+}
+
+func (b *builder) card() Card {
+	// без N имя берём из FN: последнее слово — фамилия
+	if b.c.FirstName == "" && b.c.LastName == "" && b.fn != "" {
+		if i := strings.LastIndexByte(b.fn, ' '); i > 0 {
+			b.c.FirstName, b.c.LastName = strings.TrimSpace(b.fn[:i]), b.fn[i+1:]
+		} else {
+			b.c.FirstName = b.fn
+		}
+	}
+	return Card{Contact: b.c, Photo: b.photo}
+}
+
+// decodePhoto — base64 (ENCODING=b в 3.0/2.1) или data: URI (4.0); ссылки на внешние URL не скачиваем.
+func decodePhoto(p property) []byte {
+	v := strings.TrimSpace(p.value)
+	if strings.HasPrefix(v, "data:") {
+		meta, data, ok := strings.Cut(v[len("data:"):], ",")
+		if !ok || !strings.HasSuffix(meta, ";base64") {
+			return nil
+		}
+		v = data
+	} else if enc := strings.ToUpper(p.param("ENCODING")); enc != "B" && enc != "BASE64" {
+		return nil
+	}
+	v = strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\t' {
+			return -1
+		}
+		return r
+	}, v)
+	out, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		return nil
+	}
+	return out
+}
+
+// isPref — TYPE=PREF (2.1/3.0) или PREF=1..100 (4.0); основным считаем любой PREF.
+func isPref(p property) bool {
+	if p.hasType("PREF") {
+		return true
+	}
+	if v := p.param("PREF"); v != "" {
+		n, err := strconv.Atoi(v)
+		return err == nil && n >= 1
+	}
+	return false
+}
+
+// typeToLabel — обратное labelToType: первый значимый TYPE становится меткой.
+func typeToLabel(p property) string {
+	for _, t := range p.params["TYPE"] {
+		t = strings.ToLower(t)
+		switch t {
+		case "pref", "voice", "internet", "x400":
+			continue
+		case "cell", "mobile", "iphone":
+			return "mobile"
+		}
+		return strings.TrimPrefix(t, "x-")
+	}
+	return ""
+}
+
+func nonEmpty(s ...string) []string {
+	out := s[:0]
+	for _, v := range s {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
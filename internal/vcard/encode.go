@@ -0,0 +1,182 @@
+package vcard
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+const (
+	V3 = "3.0"
+	V4 = "4.0"
+
+	// MediaType — Content-Type для ответов и импорта
+	MediaType = "text/vcard"
+
+	maxLineOctets = 75
+
+	// UIDSource — источник внешнего ключа контактов, заведённых клиентами CardDAV: ключ — их имя
+	// ресурса (обычно совпадает с UID карточки), его же отдаём в UID, чтобы клиент узнал свою карточку
+	UIDSource = "carddav"
+)
+
+// Photo — встраиваемое в карточку изображение
+type Photo struct {
+	ContentType string
+	Data        []byte
+}
+
+// Encoder пишет карточки в w; Flush обязателен после последней.
+type Encoder struct {
+	w       *bufio.Writer
+	version string
+}
+
+// NewEncoder — неизвестная версия трактуется как 3.0 (её понимают все адресные книги).
+func NewEncoder(w io.Writer, version string) *Encoder {
+	if version != V4 {
+		version = V3
+	}
+	return &Encoder{w: bufio.NewWriter(w), version: version}
+}
+
+func (e *Encoder) Flush() error { return e.w.Flush() }
+
+// Encode сериализует контакт; photo может быть nil.
+func (e *Encoder) Encode(c service.ContactOut, photo *Photo) error {
+	e.line("BEGIN", nil, "VCARD")
+	e.line("VERSION", nil, e.version)
+	e.line("UID", nil, escape(UID(c)))
+	e.line("FN", nil, escape(strings.TrimSpace(c.FirstName+" "+c.LastName)))
+	e.line("N", nil, compound(c.LastName, c.FirstName, "", "", ""))
+	if c.Company != "" || c.Department != "" {
+		e.line("ORG", nil, compound(c.Company, c.Department))
+	}
+	if c.JobTitle != "" {
+		e.line("TITLE", nil, escape(c.JobTitle))
+	}
+	for _, p := range c.Phones {
+		num := p.PhoneE164
+		if num == "" {
+			num = p.PhoneRaw
+		}
+		if e.version == V4 {
+			e.line("TEL", e.params(p.Label, p.IsPrimary, "VALUE=uri"), "tel:"+num)
+		} else {
+			e.line("TEL", e.params(p.Label, p.IsPrimary), num)
+		}
+	}
+	for _, m := range c.Emails {
+		if e.version == V4 {
+			e.line("EMAIL", e.params(m.Label, m.IsPrimary), escape(m.Email))
+		} else {
+			e.line("EMAIL", e.params(m.Label, m.IsPrimary, "TYPE=INTERNET"), escape(m.Email))
+		}
+	}
+	for _, a := range c.Addresses {
+		e.line("ADR", e.params(a.Label, a.IsPrimary), compound("", "", a.Street, a.City, a.Region, a.PostalCode, a.Country))
+	}
+	for _, w := range c.Websites {
+		e.line("URL", e.params(w.Label, w.IsPrimary), w.URL)
+	}
+	if photo != nil && len(photo.Data) > 0 {
+		data := base64.StdEncoding.EncodeToString(photo.Data)
+		if e.version == V4 {
+			e.line("PHOTO", nil, "data:"+photo.ContentType+";base64,"+data)
+		} else {
+			typ := strings.ToUpper(strings.TrimPrefix(photo.ContentType, "image/"))
+			e.line("PHOTO", []string{"ENCODING=b", "TYPE=" + typ}, data)
+		}
+	}
+	e.line("REV", nil, c.UpdatedAt.UTC().Format("20060102T150405Z"))
+	e.line("END", nil, "VCARD")
+	return e.w.Flush()
+}
+
+// UID — значение UID карточки контакта.
+func UID(c service.ContactOut) string {
+	if c.External != nil && c.External.Source == UIDSource {
+		return c.External.ID
+	}
+	return fmt.Sprintf("urn:phonebook:contact:%d", c.ID)
+}
+
+// params — TYPE из метки и признак основного значения: в 3.0 это TYPE=PREF, в 4.0 — PREF=1.
+func (e *Encoder) params(label string, primary bool, extra ...string) []string {
+	out := append([]string(nil), extra...)
+	if t := labelToType(label); t != "" {
+		out = append(out, "TYPE="+t)
+	}
+	if primary {
+		if e.version == V4 {
+			out = append(out, "PREF=1")
+		} else {
+			out = append(out, "TYPE=PREF")
+		}
+	}
+	return out
+}
+
+// line пишет свёрнутую строку содержимого (RFC 6350 §3.2): не длиннее 75 октетов,
+// перенос — CRLF и пробел, разрыв только по границе UTF-8 символа.
+func (e *Encoder) line(name string, params []string, value string) {
+	s := name
+	for _, p := range params {
+		s += ";" + p
+	}
+	s += ":" + value
+
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		e.w.WriteString(s[:cut])
+		e.w.WriteString("\r\n ")
+		s = s[cut:]
+		limit = maxLineOctets - 1 // ведущий пробел продолжения тоже считается
+	}
+	e.w.WriteString(s)
+	e.w.WriteString("\r\n")
+}
+
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// compound — структурированное значение (N, ADR, ORG): компоненты через ';'.
+func compound(parts ...string) string {
+	for i, p := range parts {
+		parts[i] = escape(p)
+	}
+	return strings.Join(parts, ";")
+}
+
+// labelToType — метки сервиса в значения TYPE; прочие метки передаются как x-name.
+func labelToType(label string) string {
+	l := strings.ToLower(strings.TrimSpace(label))
+	switch l {
+	case "":
+		return ""
+	case "mobile", "cell":
+		return "cell"
+	case "work", "home", "fax", "pager", "voice", "text", "video":
+		return l
+	}
+	var b strings.Builder
+	for _, r := range l {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' {
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return "x-" + b.String()
+}
@@ -0,0 +1,94 @@
+package vcard_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sunzhqr/phonebook/internal/service"
+	"github.com/sunzhqr/phonebook/internal/vcard"
+)
+
+func TestEncode_Decode_RoundTrip(t *testing.T) {
+	c := service.ContactOut{
+		ID: 1, FirstName: "Sanzhar", LastName: "Sanzharov", Company: "Forte; Bank", Department: "IT", JobTitle: "Engineer",
+		Phones: []service.PhoneOut{
+			{Label: "work", PhoneRaw: "+7 771 123 45 67", PhoneE164: "+77711234567"},
+			{Label: "mobile", PhoneRaw: "+7 701 765 43 21", PhoneE164: "+77017654321", IsPrimary: true},
+		},
+		Emails:    []service.EmailOut{{Label: "work", Email: "s@forte.kz", IsPrimary: true}},
+		Addresses: []service.AddressOut{{Label: "work", Street: "Dostyk 1", City: "Алматы", Country: "KZ"}},
+		UpdatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	photo := &vcard.Photo{ContentType: "image/jpeg", Data: bytes.Repeat([]byte{0xff, 0xd8, 0x01}, 100)}
+
+	for _, v := range []string{vcard.V3, vcard.V4} {
+		var buf bytes.Buffer
+		enc := vcard.NewEncoder(&buf, v)
+		if err := enc.Encode(c, photo); err != nil {
+			t.Fatal(err)
+		}
+		for _, l := range strings.Split(buf.String(), "\r\n") {
+			if len(l) > 75 {
+				t.Fatalf("%s: line longer than 75 octets: %q", v, l)
+			}
+		}
+
+		cards, err := vcard.Decode(&buf)
+		if err != nil || len(cards) != 1 {
+			t.Fatalf("%s: decode: %v, %d cards", v, err, len(cards))
+		}
+		got := cards[0]
+		if got.Contact.FirstName != "Sanzhar" || got.Contact.LastName != "Sanzharov" ||
+			got.Contact.Company != "Forte; Bank" || got.Contact.Department != "IT" || got.Contact.JobTitle != "Engineer" {
+			t.Fatalf("%s: unexpected contact: %+v", v, got.Contact)
+		}
+		if len(got.Contact.Phones) != 2 || got.Contact.Phones[1].Label != "mobile" || !got.Contact.Phones[1].IsPrimary || got.Contact.Phones[0].IsPrimary {
+			t.Fatalf("%s: unexpected phones: %+v", v, got.Contact.Phones)
+		}
+		if len(got.Contact.Addresses) != 1 || got.Contact.Addresses[0].City != "Алматы" {
+			t.Fatalf("%s: unexpected addresses: %+v", v, got.Contact.Addresses)
+		}
+		if !bytes.Equal(got.Photo, photo.Data) {
+			t.Fatalf("%s: photo mismatch", v)
+		}
+	}
+}
+
+func TestDecode_Legacy(t *testing.T) {
+	// vCard 2.1 из старого телефона: QUOTED-PRINTABLE с мягким переносом, windows-1251, группы
+	in := "BEGIN:VCARD\r\n" +
+		"VERSION:2.1\r\n" +
+		"N;CHARSET=windows-1251;ENCODING=QUOTED-PRINTABLE:=C8=E2=E0=ED=EE=E2;=C8=E2=E0=\r\n" +
+		"=ED\r\n" +
+		"TEL;CELL;PREF:+77011112233\r\n" +
+		"item1.TEL;WORK:8 727 222 33 44\r\n" +
+		"END:VCARD\r\n" +
+		"BEGIN:VCARD\r\n" +
+		"VERSION:3.0\r\n" +
+		"FN:Anna Maria\r\n" +
+		"  Petrova\r\n" +
+		"NOTE:folded\r\n" +
+		"\tnote\r\n" +
+		"END:VCARD\r\n"
+
+	cards, err := vcard.Decode(strings.NewReader(in))
+	if err != nil || len(cards) != 2 {
+		t.Fatalf("decode: %v, %d cards", err, len(cards))
+	}
+	c := cards[0].Contact
+	if c.LastName != "Иванов" || c.FirstName != "Иван" {
+		t.Fatalf("charset/qp: got %q %q", c.FirstName, c.LastName)
+	}
+	if len(c.Phones) != 2 || c.Phones[0].Label != "mobile" || !c.Phones[0].IsPrimary || c.Phones[1].Label != "work" {
+		t.Fatalf("phones: %+v", c.Phones)
+	}
+	if c := cards[1].Contact; c.FirstName != "Anna Maria" || c.LastName != "Petrova" {
+		t.Fatalf("fn fallback: got %q %q", c.FirstName, c.LastName)
+	}
+
+	if _, err := vcard.Decode(strings.NewReader("hello")); err != vcard.ErrNoCards {
+		t.Fatalf("want ErrNoCards, got %v", err)
+	}
+}
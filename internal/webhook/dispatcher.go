@@ -0,0 +1,162 @@
+// Package webhook — доставка событий outbox на URL подписок с подписью HMAC-SHA256.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+)
+
+const (
+	SignatureHeader = "X-Phonebook-Signature"
+	EventHeader     = "X-Phonebook-Event"
+	DeliveryHeader  = "X-Phonebook-Delivery"
+
+	pollInterval = 2 * time.Second
+	claimBatch   = 50
+	workers      = 8
+	// requestTimeout — меньше lease доставки, чтобы зависший получатель не привёл к двойной отправке
+	requestTimeout = 10 * time.Second
+	maxErrorBody   = 256
+)
+
+type Dispatcher struct {
+	lg     *logger.Logger
+	queue  service.DeliveryQueue
+	client *http.Client
+}
+
+func NewDispatcher(lg *logger.Logger, queue service.DeliveryQueue) *Dispatcher {
+	return &Dispatcher{lg: lg, queue: queue, client: &http.Client{Timeout: requestTimeout}}
+}
+
+// Run — опрашивает очередь до отмены ctx.
+func (d *Dispatcher) Run(ctx context.Context) {
+	t := time.NewTicker(pollInterval)
+	defer t.Stop()
+	for {
+		if err := d.Tick(ctx); err != nil && ctx.Err() == nil {
+			d.lg.Warn("webhook dispatch failed", logger.Err(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Tick — один проход: разложить новые события по подпискам и отправить всё, чему пришло время.
+func (d *Dispatcher) Tick(ctx context.Context) error {
+	if _, err := d.queue.FanOutOutbox(ctx); err != nil {
+		return err
+	}
+	for {
+		batch, err := d.queue.ClaimDeliveries(ctx, claimBatch)
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		d.sendAll(ctx, batch)
+		if len(batch) < claimBatch {
+			return nil
+		}
+	}
+}
+
+func (d *Dispatcher) sendAll(ctx context.Context, batch []service.PendingDelivery) {
+	jobs := make(chan service.PendingDelivery)
+	var wg sync.WaitGroup
+	for range min(workers, len(batch)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range jobs {
+				res := d.send(ctx, p)
+				// итог записываем и при остановке сервера: иначе доставка повторится только после lease
+				if err := d.queue.CompleteDelivery(context.WithoutCancel(ctx), p, res); err != nil {
+					d.lg.Error("webhook delivery state not saved", logger.KV("delivery_id", p.ID), logger.Err(err))
+				}
+			}
+		}()
+	}
+	for _, p := range batch {
+		jobs <- p
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+func (d *Dispatcher) send(ctx context.Context, p service.PendingDelivery) service.DeliveryResult {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(p.Payload))
+	if err != nil {
+		return service.DeliveryResult{Err: err.Error()}
+	}
+	ts := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "phonebook-webhooks/1.0")
+	req.Header.Set(EventHeader, p.EventType)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(p.ID, 10))
+	req.Header.Set(SignatureHeader, fmt.Sprintf("t=%d,v1=%s", ts, Sign(p.Secret, ts, p.Payload)))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return service.DeliveryResult{Err: err.Error()}
+	}
+	defer resp.Body.Close()
+	res := service.DeliveryResult{StatusCode: resp.StatusCode}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		res.Err = strings.TrimSpace(fmt.Sprintf("status %d: %s", resp.StatusCode, b))
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) // для переиспользования соединения
+	return res
+}
+
+// Sign — hex(HMAC-SHA256(secret, "<t>.<body>")). Метка времени в подписи не даёт переиграть старый запрос:
+// получатель сверяет подпись и отбрасывает t старше нескольких минут.
+func Sign(secret string, ts int64, body []byte) string {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write([]byte(strconv.FormatInt(ts, 10)))
+	m.Write([]byte{'.'})
+	m.Write(body)
+	return hex.EncodeToString(m.Sum(nil))
+}
+
+// Verify — проверка заголовка X-Phonebook-Signature на стороне получателя.
+func Verify(secret, header string, body []byte, tolerance time.Duration) bool {
+	var (
+		ts  int64
+		sig string
+	)
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			ts, _ = strconv.ParseInt(v, 10, 64)
+		case "v1":
+			sig = v
+		}
+	}
+	if ts == 0 || sig == "" {
+		return false
+	}
+	if age := time.Since(time.Unix(ts, 0)); age > tolerance || age < -tolerance {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(Sign(secret, ts, body)))
+}
@@ -0,0 +1,99 @@
+package webhook_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sunzhqr/phonebook/internal/logger"
+	"github.com/sunzhqr/phonebook/internal/service"
+	"github.com/sunzhqr/phonebook/internal/webhook"
+)
+
+// memQueue — очередь в памяти: Claim отдаёт всё pending ровно один раз
+type memQueue struct {
+	mu      sync.Mutex
+	pending []service.PendingDelivery
+	results map[int64]service.DeliveryResult
+}
+
+func (q *memQueue) FanOutOutbox(context.Context) (int64, error) { return 0, nil }
+func (q *memQueue) ClaimDeliveries(_ context.Context, limit int) ([]service.PendingDelivery, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := min(limit, len(q.pending))
+	out := q.pending[:n]
+	q.pending = q.pending[n:]
+	return out, nil
+}
+func (q *memQueue) CompleteDelivery(_ context.Context, d service.PendingDelivery, res service.DeliveryResult) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.results[d.ID] = res
+	return nil
+}
+
+func TestDispatcher_SignsAndRecordsResults(t *testing.T) {
+	const secret = "whsec_test_secret_value"
+	var (
+		mu   sync.Mutex
+		seen = map[string]string{}
+	)
+	recv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !webhook.Verify(secret, r.Header.Get(webhook.SignatureHeader), body, 5*time.Minute) {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		seen[r.Header.Get(webhook.DeliveryHeader)] = r.Header.Get(webhook.EventHeader)
+		mu.Unlock()
+		if r.URL.Path == "/fail" {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer recv.Close()
+
+	payload := []byte(`{"id":1,"type":"contact.created","contact_id":5}`)
+	q := &memQueue{results: map[int64]service.DeliveryResult{}, pending: []service.PendingDelivery{
+		{ID: 1, URL: recv.URL + "/ok", Secret: secret, EventType: service.EventContactCreated, Payload: payload},
+		{ID: 2, URL: recv.URL + "/fail", Secret: secret, EventType: service.EventContactCreated, Payload: payload},
+		{ID: 3, URL: recv.URL + "/ok", Secret: "wrong_secret_wrong_secret", EventType: service.EventContactDeleted, Payload: payload},
+		{ID: 4, URL: "http://127.0.0.1:1/unreachable", Secret: secret, Payload: payload},
+	}}
+
+	if err := webhook.NewDispatcher(logger.New("dev"), q).Tick(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if r := q.results[1]; r.StatusCode != http.StatusNoContent || r.Err != "" {
+		t.Fatalf("ok delivery: %+v", r)
+	}
+	if r := q.results[2]; r.StatusCode != http.StatusInternalServerError || r.Err != "status 500: boom" {
+		t.Fatalf("failed delivery: %+v", r)
+	}
+	if r := q.results[3]; r.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("wrong secret must be rejected by receiver: %+v", r)
+	}
+	if r := q.results[4]; r.StatusCode != 0 || r.Err == "" {
+		t.Fatalf("unreachable: %+v", r)
+	}
+	if seen["1"] != service.EventContactCreated {
+		t.Fatalf("receiver saw %v", seen)
+	}
+}
+
+func TestVerify_RejectsStaleTimestamp(t *testing.T) {
+	body := []byte(`{}`)
+	old := time.Now().Add(-time.Hour).Unix()
+	header := "t=" + strconv.FormatInt(old, 10) + ",v1=" + webhook.Sign("s", old, body)
+	if webhook.Verify("s", header, body, 5*time.Minute) {
+		t.Fatal("stale signature accepted")
+	}
+}
@@ -0,0 +1,42 @@
+package normalizer
+
+import (
+	"strings"
+	"unicode"
+)
+
+// MaskPhone — номер для того, кому он не положен: код страны, первые три и последние две цифры
+// национального номера, остальное звёздочками: +77711234567 — "+7 771 ***-**-67". Код страны
+// определяется по таблице регионов; неизвестный не выделяется.
+func MaskPhone(e164 string) string {
+	ds := make([]rune, 0, len(e164))
+	for _, r := range e164 {
+		if unicode.IsDigit(r) {
+			ds = append(ds, r)
+		}
+	}
+	digits := string(ds)
+	cc := ""
+	for _, reg := range regions {
+		if len(reg.cc) > len(cc) && strings.HasPrefix(digits, reg.cc) && len(digits)-len(reg.cc) >= reg.nsnMin {
+			cc = reg.cc
+		}
+	}
+	nsn := digits[len(cc):]
+	if len(nsn) < 7 {
+		if len(nsn) <= 2 {
+			return "+" + cc + " " + strings.Repeat("*", len(nsn))
+		}
+		return "+" + cc + " " + strings.Repeat("*", len(nsn)-2) + "-" + nsn[len(nsn)-2:]
+	}
+	hidden := len(nsn) - 5
+	stars := strings.Repeat("*", min(hidden, 3))
+	if hidden > 3 {
+		stars += "-" + strings.Repeat("*", hidden-3)
+	}
+	out := "+" + cc
+	if cc != "" {
+		out += " "
+	}
+	return out + nsn[:3] + " " + stars + "-" + nsn[len(nsn)-2:]
+}
@@ -16,3 +16,46 @@ func Test_NormalizePhone(t *testing.T) {
 		t.Fatalf("too short should be false")
 	}
 }
+
+func Test_NormalizePhoneRegion(t *testing.T) {
+	cases := []struct {
+		raw, region, want string
+	}{
+		{"8 (701) 123-45-67", "KZ", "+77011234567"},
+		{"7 701 123 45 67", "KZ", "+77011234567"},
+		{"+7 701 123 45 67", "US", "+77011234567"},
+		{"810 44 20 7946 0018", "RU", "+442079460018"},
+		{"(202) 555-0123", "US", "+12025550123"},
+		{"1 202 555 0123", "US", "+12025550123"},
+		{"011 49 30 123456", "US", "+4930123456"},
+		{"020 7946 0018", "GB", "+442079460018"},
+		{"030 123456", "DE", "+4930123456"},
+		{"8 701 123 45 67", "", "+87011234567"},
+		{"8 701 123 45 67", "XX", "+87011234567"},
+	}
+	for _, tc := range cases {
+		got, _, ok := normalizer.NormalizePhoneRegion(tc.raw, tc.region)
+		if !ok || got != tc.want {
+			t.Errorf("NormalizePhoneRegion(%q, %q) = %q, %v; want %q", tc.raw, tc.region, got, ok, tc.want)
+		}
+	}
+	if _, _, ok := normalizer.NormalizePhoneRegion("123", "KZ"); ok {
+		t.Fatalf("too short should be false")
+	}
+}
+
+func Test_MaskPhone(t *testing.T) {
+	cases := map[string]string{
+		"+77711234567":  "+7 771 ***-**-67",
+		"+12025550123":  "+1 202 ***-**-23",
+		"+442079460018": "+44 207 ***-**-18",
+		"+4930123456":   "+49 301 ***-56",
+		"+998901234567": "+998 901 ***-*-67",
+		"+3801234567":   "+380 ***-**-67",
+	}
+	for in, want := range cases {
+		if got := normalizer.MaskPhone(in); got != want {
+			t.Errorf("MaskPhone(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
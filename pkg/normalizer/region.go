@@ -0,0 +1,69 @@
+package normalizer
+
+import (
+	"strings"
+	"unicode"
+)
+
+// region — правила набора номера в стране: код страны, префикс междугородной связи внутри страны
+// (trunk), префикс международного вызова и допустимая длина национального номера без них
+type region struct {
+	cc     string
+	trunk  string
+	intl   []string
+	nsnMin int
+	nsnMax int
+}
+
+var regions = map[string]region{
+	"KZ": {cc: "7", trunk: "8", intl: []string{"810", "00"}, nsnMin: 10, nsnMax: 10},
+	"RU": {cc: "7", trunk: "8", intl: []string{"810", "00"}, nsnMin: 10, nsnMax: 10},
+	"BY": {cc: "375", trunk: "80", intl: []string{"810", "00"}, nsnMin: 9, nsnMax: 9},
+	"UZ": {cc: "998", intl: []string{"00", "810"}, nsnMin: 9, nsnMax: 9},
+	"KG": {cc: "996", trunk: "0", intl: []string{"00"}, nsnMin: 9, nsnMax: 9},
+	"US": {cc: "1", trunk: "1", intl: []string{"011"}, nsnMin: 10, nsnMax: 10},
+	"CA": {cc: "1", trunk: "1", intl: []string{"011"}, nsnMin: 10, nsnMax: 10},
+	"GB": {cc: "44", trunk: "0", intl: []string{"00"}, nsnMin: 9, nsnMax: 10},
+	"DE": {cc: "49", trunk: "0", intl: []string{"00"}, nsnMin: 6, nsnMax: 11},
+	"FR": {cc: "33", trunk: "0", intl: []string{"00"}, nsnMin: 9, nsnMax: 9},
+	"TR": {cc: "90", trunk: "0", intl: []string{"00"}, nsnMin: 10, nsnMax: 10},
+	"CN": {cc: "86", trunk: "0", intl: []string{"00"}, nsnMin: 9, nsnMax: 11},
+}
+
+// IsRegion — известен ли регион NormalizePhoneRegion (код ISO 3166-1 alpha-2 в верхнем регистре)
+func IsRegion(code string) bool {
+	_, ok := regions[code]
+	return ok
+}
+
+// NormalizePhoneRegion — как NormalizePhone, но номер без "+" разбирается по правилам набора региона:
+// 8 701 123 45 67 в KZ — +77011234567, (202) 555-0123 в US — +12025550123, 00 44 20… — +4420….
+// Пустой или неизвестный регион — поведение NormalizePhone.
+func NormalizePhoneRegion(raw, code string) (string, string, bool) {
+	reg, ok := regions[code]
+	raw = strings.TrimSpace(raw)
+	if !ok || strings.HasPrefix(raw, "+") {
+		return NormalizePhone(raw)
+	}
+	ds := make([]rune, 0, len(raw))
+	for _, r := range raw {
+		if unicode.IsDigit(r) {
+			ds = append(ds, r)
+		}
+	}
+	digits := string(ds)
+	for _, p := range reg.intl {
+		if strings.HasPrefix(digits, p) && len(digits)-len(p) >= 7 {
+			return NormalizePhone(digits[len(p):])
+		}
+	}
+	national := func(n string) bool { return len(n) >= reg.nsnMin && len(n) <= reg.nsnMax }
+	switch {
+	case reg.trunk != "" && strings.HasPrefix(digits, reg.trunk) && national(digits[len(reg.trunk):]):
+		return NormalizePhone(reg.cc + digits[len(reg.trunk):])
+	case national(digits) && !(strings.HasPrefix(digits, reg.cc) && national(digits[len(reg.cc):])):
+		return NormalizePhone(reg.cc + digits)
+	}
+	// уже с кодом страны, просто без "+"
+	return NormalizePhone(digits)
+}